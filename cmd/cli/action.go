@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"ordiff/internal/changelog"
+	"ordiff/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	maxCommits     int
+	maxFiles       int
+	maxChurn       int
+	failOnBreaking bool
+)
+
+var ActionCmd = &cobra.Command{
+	Use:   "action <from> <to>",
+	Short: "Report a comparison in a GitHub Actions job and gate on churn",
+	Long: `Compares two releases for use as a GitHub Actions step:
+
+  - the Markdown report is appended to $GITHUB_STEP_SUMMARY
+  - commit_count, pr_count, files_changed, additions, deletions, breaking
+    (true or false) and breaking_count are written to $GITHUB_OUTPUT
+  - the step fails when a threshold is exceeded, with an error annotation
+    for each
+
+Outside Actions, the outputs and the report are printed instead. Breaking
+changes are detected like in draft_release_notes: breaking labels, "!"
+after the conventional-commit type, or a BREAKING CHANGE footer.
+
+Example:
+  ordiff action v1.2.0 v1.3.0
+  ordiff action v1.2.0 HEAD --max-files 200 --max-churn 10000 --fail-on-breaking`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from := resolveRef(db, owner, repo, args[0])
+		to := resolveRef(db, owner, repo, args[1])
+
+		result, err := newFetcher(owner, repo).Compare(db, from, to, false)
+		if err != nil {
+			log.Fatalf("Failed to compare: %v", err)
+		}
+
+		additions, deletions := 0, 0
+		for _, f := range result.Files {
+			additions += f.Additions
+			deletions += f.Deletions
+		}
+		notes := changelog.DraftReleaseNotes(result.FromRelease.TagName, result.ToRelease.TagName, result.Commits, result.PullRequests)
+		breaking := len(notes.Breaking)
+
+		var failures []string
+		if maxCommits > 0 && len(result.Commits) > maxCommits {
+			failures = append(failures, fmt.Sprintf("%d commits exceed the limit of %d", len(result.Commits), maxCommits))
+		}
+		if maxFiles > 0 && len(result.Files) > maxFiles {
+			failures = append(failures, fmt.Sprintf("%d changed files exceed the limit of %d", len(result.Files), maxFiles))
+		}
+		if maxChurn > 0 && additions+deletions > maxChurn {
+			failures = append(failures, fmt.Sprintf("%d changed lines exceed the limit of %d", additions+deletions, maxChurn))
+		}
+		if failOnBreaking && breaking > 0 {
+			failures = append(failures, fmt.Sprintf("%d breaking changes", breaking))
+		}
+
+		outputs := [][2]string{
+			{"commit_count", strconv.Itoa(len(result.Commits))},
+			{"pr_count", strconv.Itoa(result.PrCount)},
+			{"files_changed", strconv.Itoa(len(result.Files))},
+			{"additions", strconv.Itoa(additions)},
+			{"deletions", strconv.Itoa(deletions)},
+			{"breaking", strconv.FormatBool(breaking > 0)},
+			{"breaking_count", strconv.Itoa(breaking)},
+		}
+		withActionsFile("GITHUB_OUTPUT", func(w io.Writer) {
+			for _, o := range outputs {
+				fmt.Fprintf(w, "%s=%s\n", o[0], o[1])
+			}
+		})
+
+		withActionsFile("GITHUB_STEP_SUMMARY", func(w io.Writer) {
+			if len(failures) > 0 {
+				fmt.Fprintln(w, "> [!CAUTION]")
+				fmt.Fprintln(w, "> Release gate failed:")
+				for _, f := range failures {
+					fmt.Fprintf(w, "> - %s\n", f)
+				}
+				fmt.Fprintln(w)
+			}
+			report.Markdown(w, result)
+		})
+
+		if len(failures) > 0 {
+			for _, f := range failures {
+				fmt.Printf("::error title=ordiff::%s\n", f)
+			}
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(ActionCmd)
+	ActionCmd.Flags().IntVar(&maxCommits, "max-commits", 0, "Fail when there are more commits than this (0 disables)")
+	ActionCmd.Flags().IntVar(&maxFiles, "max-files", 0, "Fail when more files changed than this (0 disables)")
+	ActionCmd.Flags().IntVar(&maxChurn, "max-churn", 0, "Fail when more lines were added and deleted than this (0 disables)")
+	ActionCmd.Flags().BoolVar(&failOnBreaking, "fail-on-breaking", false, "Fail when there are breaking changes")
+}
+
+// withActionsFile appends to the file a GitHub Actions environment variable
+// names, or writes to stdout when the variable is unset.
+func withActionsFile(env string, write func(w io.Writer)) {
+	path := os.Getenv(env)
+	if path == "" {
+		write(os.Stdout)
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Fatalf("Failed to open $%s: %v", env, err)
+	}
+	defer f.Close()
+	write(f)
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"ordiff/internal/analyzer"
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/table"
+
+	"github.com/spf13/viper"
+)
+
+var skipAnalyzers bool
+
+// analyzers returns the compiled-in analyzers followed by the external ones
+// of the config, with their defaults filled in.
+func analyzers() []analyzer.Analyzer {
+	var external []analyzer.External
+	if err := viper.UnmarshalKey("analyzers", &external); err != nil {
+		log.Fatalf("Invalid analyzers in config: %v", err)
+	}
+	list := analyzer.Registered()
+	for i := range external {
+		if err := external[i].Validate(); err != nil {
+			log.Fatalf("Invalid analyzers in config: %v", err)
+		}
+		list = append(list, &external[i])
+	}
+	return list
+}
+
+// runAnalyzers runs the analyzers over a copy of a comparison with its
+// patches loaded, leaving result as it was, or returns nil when there are
+// none or --no-analyzers is given.
+func runAnalyzers(db cache.Store, fetcher *github.Fetcher, owner, repo string, result *github.CompareResult) []analyzer.Report {
+	if skipAnalyzers {
+		return nil
+	}
+	list := analyzers()
+	if len(list) == 0 {
+		return nil
+	}
+	withPatches := *result
+	withPatches.Files = append([]cache.FileChange{}, result.Files...)
+	if err := fetcher.LoadPatches(db, &withPatches); err != nil {
+		log.Fatalf("Failed to load patches: %v", err)
+	}
+	reports := analyzer.Run(list, owner, repo, &withPatches)
+	for _, r := range reports {
+		if r.Error != "" {
+			slog.Warn("Failed to run analyzer", "analyzer", r.Analyzer, "err", r.Error)
+		}
+	}
+	return reports
+}
+
+func printAnalyzerReports(reports []analyzer.Report) {
+	for _, r := range reports {
+		if r.Error != "" {
+			fmt.Printf("%s: failed (%s)\n\n", r.Analyzer, r.Error)
+			continue
+		}
+		for _, s := range r.Sections {
+			fmt.Printf("%s:\n", s.Name)
+			if s.Text != "" {
+				for _, line := range strings.Split(strings.TrimRight(s.Text, "\n"), "\n") {
+					fmt.Printf("  %s\n", line)
+				}
+			}
+			if len(s.Findings) > 0 {
+				t := table.New(table.Column{Color: table.Yellow}, table.Column{Color: table.Dim}, table.Column{Truncate: table.TrimEnd})
+				t.Indent = "  "
+				for _, f := range s.Findings {
+					where := f.File
+					if f.Line > 0 {
+						where += ":" + strconv.Itoa(f.Line)
+					}
+					t.Row(f.Severity, where, f.Message)
+				}
+				printTable(t)
+			}
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	"ordiff/internal/apidiff"
+	"ordiff/internal/cache"
+	"ordiff/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+var apidiffAll bool
+
+var ApidiffCmd = &cobra.Command{
+	Use:   "apidiff <from> <to>",
+	Short: "Report exported Go API changes between two releases",
+	Long: `Reads the Go source of both releases (from the repository on disk for
+--local repositories, or as a tarball from GitHub) and compares the exported
+functions, methods, types, struct fields, constants and variables of every
+non-main, non-internal package.
+
+Removed and changed symbols can break importers and are always listed;
+additions are counted, and listed with --all. The comparison works on
+declarations as written, without type checking, so changing a type to an
+equivalent alias is reported as a change.
+
+Example:
+  ordiff apidiff v1.4.0 v1.5.0
+  ordiff apidiff v1.4.0 v1.5.0 --all --json`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from := releaseOrFatal(db, owner, repo, resolveRef(db, owner, repo, args[0]))
+		to := releaseOrFatal(db, owner, repo, resolveRef(db, owner, repo, args[1]))
+
+		name, location, err := db.GetRepositoryProvider(owner, repo)
+		if err != nil {
+			slog.Warn("Failed to look up repository provider", "err", err)
+		}
+		sources, ok := newProviderFetcher(owner, repo, name, location).(provider.Sources)
+		if !ok {
+			log.Fatalf("apidiff is not supported for %s repositories", name)
+		}
+
+		oldAPI := readAPI(sources, from)
+		newAPI := readAPI(sources, to)
+
+		changes := apidiff.Compare(oldAPI, newAPI)
+		var incompatible, added []apidiff.Change
+		for _, c := range changes {
+			if c.Incompatible() {
+				incompatible = append(incompatible, c)
+			} else {
+				added = append(added, c)
+			}
+		}
+
+		if jsonOutput {
+			out := map[string]interface{}{
+				"from_release": from.TagName,
+				"to_release":   to.TagName,
+				"incompatible": incompatible,
+				"added_count":  len(added),
+			}
+			if apidiffAll {
+				out["added"] = added
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(out)
+			return
+		}
+
+		fmt.Printf("\n=== API changes %s → %s ===\n\n", from.TagName, to.TagName)
+		if len(incompatible) == 0 {
+			fmt.Println("No incompatible changes.")
+		} else {
+			fmt.Printf("Incompatible changes: %d\n", len(incompatible))
+			printChanges(incompatible)
+		}
+
+		fmt.Printf("\nCompatible additions: %d", len(added))
+		if !apidiffAll {
+			if len(added) > 0 {
+				fmt.Print(" (use --all to list them)")
+			}
+			fmt.Println()
+			return
+		}
+		fmt.Println()
+		printChanges(added)
+	},
+}
+
+func init() {
+	addRepoFlag(ApidiffCmd)
+	ApidiffCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+	ApidiffCmd.Flags().BoolVar(&apidiffAll, "all", false, "Also list added symbols")
+}
+
+func releaseOrFatal(db cache.Store, owner, repo, tag string) *cache.Release {
+	release, err := db.GetRelease(owner, repo, tag)
+	if err != nil {
+		log.Fatalf("Release %s is not cached. Run 'ordiff index' first.", tag)
+	}
+	if release.CommitSHA == "" {
+		log.Fatalf("Release %s has no commit", tag)
+	}
+	return release
+}
+
+func readAPI(sources provider.Sources, release *cache.Release) apidiff.API {
+	slog.Info("Reading Go sources", "tag", release.TagName)
+	files, err := sources.SourceFiles(release.CommitSHA, apidiff.IsAPIFile)
+	if err != nil {
+		log.Fatalf("Failed to read sources of %s: %v", release.TagName, err)
+	}
+
+	api, skipped := apidiff.Extract(files)
+	for _, name := range skipped {
+		slog.Warn("Skipped file with a parse error", "path", name, "tag", release.TagName)
+	}
+	return api
+}
+
+func printChanges(changes []apidiff.Change) {
+	pkg := ""
+	for _, c := range changes {
+		if c.Package != pkg {
+			pkg = c.Package
+			fmt.Printf("\n  %s\n", pkg)
+		}
+		switch c.Kind {
+		case apidiff.Removed:
+			fmt.Printf("    - %s\n", c.Old)
+		case apidiff.Added:
+			fmt.Printf("    + %s\n", c.New)
+		case apidiff.Changed:
+			fmt.Printf("    ~ %s\n", c.Symbol)
+			fmt.Printf("        was: %s\n", c.Old)
+			fmt.Printf("        now: %s\n", c.New)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"ordiff/internal/assets"
+	"ordiff/internal/cache"
+	"ordiff/internal/table"
+
+	"github.com/spf13/cobra"
+)
+
+type assetsReport struct {
+	Tag       string               `json:"tag"`
+	TotalSize int64                `json:"total_size"`
+	Assets    []cache.ReleaseAsset `json:"assets"`
+}
+
+var AssetsCmd = &cobra.Command{
+	Use:   "assets <tag>",
+	Short: "List the files attached to a release",
+	Long: `Lists the assets attached to a cached release with their sizes, download
+counts and checksums. Assets are cached when releases are listed by 'ordiff
+index' and 'ordiff update', so download counts are as of then. Releases
+indexed from tags, GitLab or a local clone have none.
+
+'ordiff compare' shows how the size of every asset changed between two
+releases.
+
+Example:
+  ordiff assets v1.30.0
+  ordiff assets v1.x --json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		tag := resolveRef(db, owner, repo, args[0])
+		if _, err := db.GetRelease(owner, repo, tag); err != nil {
+			log.Fatalf("Release %s is not cached for %s/%s", tag, owner, repo)
+		}
+
+		list, err := db.GetReleaseAssets(owner, repo, tag)
+		if err != nil {
+			log.Fatalf("Failed to read release assets: %v", err)
+		}
+		report := assetsReport{Tag: tag, TotalSize: assets.Total(list), Assets: list}
+		if report.Assets == nil {
+			report.Assets = []cache.ReleaseAsset{}
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(report)
+			return
+		}
+
+		fmt.Printf("\n=== Assets of %s/%s %s ===\n\n", owner, repo, tag)
+		if len(list) == 0 {
+			fmt.Println("No assets cached for this release.")
+			return
+		}
+		for _, a := range list {
+			fmt.Printf("  %10s  %8d downloads  %s\n", formatBytes(a.Size), a.DownloadCount, a.Name)
+			if a.Digest != "" {
+				fmt.Printf("  %10s  %s\n", "", a.Digest)
+			}
+		}
+		fmt.Printf("\n%d assets, %s in total\n", len(list), formatBytes(report.TotalSize))
+	},
+}
+
+func init() {
+	addRepoFlag(AssetsCmd)
+	AssetsCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
+
+// assetChanges compares the cached assets of two releases, nil if neither
+// has any.
+func assetChanges(db cache.Store, owner, repo, from, to string) []assets.Change {
+	fromAssets, err := db.GetReleaseAssets(owner, repo, from)
+	if err != nil {
+		log.Fatalf("Failed to read release assets: %v", err)
+	}
+	toAssets, err := db.GetReleaseAssets(owner, repo, to)
+	if err != nil {
+		log.Fatalf("Failed to read release assets: %v", err)
+	}
+	return assets.Diff(from, fromAssets, to, toAssets)
+}
+
+func printAssetChanges(changes []assets.Change) {
+	fmt.Println("Asset Size Changes:")
+	t := table.New(table.Column{Signed: true}, table.Column{Right: true, Signed: true}, table.Column{Truncate: table.TrimStart}, table.Column{Color: table.Dim, Truncate: table.TrimEnd})
+	for _, c := range changes {
+		switch c.Kind {
+		case assets.KindAdded:
+			t.Row("+", formatBytes(c.ToSize), c.To)
+		case assets.KindRemoved:
+			t.Row("-", formatBytes(c.FromSize), c.From)
+		default:
+			sign := "+"
+			if c.Delta < 0 {
+				sign = "-"
+			}
+			t.Row("~", sign+formatBytes(abs(c.Delta)), c.Name, fmt.Sprintf("%s → %s, %+.1f%%",
+				formatBytes(c.FromSize), formatBytes(c.ToSize), float64(c.Delta)*100/float64(max(c.FromSize, 1))))
+		}
+	}
+	printTable(t)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
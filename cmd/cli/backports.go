@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+
+	"ordiff/internal/backport"
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/table"
+)
+
+// findBackports finds the commits of a comparison that were also shipped in
+// another release line. Patches are compared from the cache, or with --live
+// fetched from GitHub for the commits that lack them.
+func findBackports(db cache.Store, fetcher *github.Fetcher, owner, repo string, result *github.CompareResult) []backport.Backport {
+	files := func(sha string) ([]cache.FileChange, error) {
+		return db.GetCommitFiles(owner, repo, sha)
+	}
+	if compareLive {
+		files = func(sha string) ([]cache.FileChange, error) {
+			d, err := fetcher.CommitDetails(db, sha)
+			if err != nil {
+				slog.Warn("Failed to fetch commit files", "sha", sha, "err", err)
+				return nil, nil
+			}
+			return d.Files, nil
+		}
+	}
+	backports, err := backport.Find(db, owner, repo, result.Commits, result.ToRelease.PublishedAt, files)
+	if err != nil {
+		log.Fatalf("Failed to find backports: %v", err)
+	}
+	return backports
+}
+
+func printBackports(backports []backport.Backport) {
+	fmt.Println("Backports:")
+	t := table.New(table.Column{Color: table.Yellow}, table.Column{Truncate: table.TrimEnd}, table.Column{Color: table.Dim})
+	t.Indent = "  "
+	for _, b := range backports[:min(10, len(backports))] {
+		copied := b.Copy[:min(7, len(b.Copy))]
+		note := fmt.Sprintf("copied as %s, unreleased (%s)", copied, b.Match)
+		switch {
+		case b.ShippedIn != "":
+			note = fmt.Sprintf("already in %s as %s (%s)", strings.Join(b.Releases, ", "), copied, b.Match)
+		case len(b.Releases) > 0:
+			note = fmt.Sprintf("also in %s as %s (%s)", strings.Join(b.Releases, ", "), copied, b.Match)
+		}
+		t.Row(b.SHA[:min(7, len(b.SHA))], b.Subject, note)
+	}
+	printTable(t)
+	if len(backports) > 10 {
+		fmt.Printf("  ... and %d more\n", len(backports)-10)
+	}
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchPairsFile string
+	batchAllPairs  bool
+	batchFormat    string
+)
+
+var BatchCompareCmd = &cobra.Command{
+	Use:   "batch-compare",
+	Short: "Compare many release pairs in one run",
+	Long: `Runs a comparison for every release pair listed in a file, or for every
+pair of consecutive cached releases, and emits a combined JSON array or a
+concatenated Markdown document. Only cached data is used.
+
+The pairs file holds one "<from> <to>" pair per line; blank lines and lines
+starting with # are ignored.
+
+Example:
+  ordiff batch-compare --pairs pairs.txt
+  ordiff batch-compare --all-pairs --format md > HISTORY.md`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (batchPairsFile == "") == !batchAllPairs {
+			log.Fatal("Specify exactly one of --pairs or --all-pairs")
+		}
+		if batchFormat != "json" && batchFormat != "md" {
+			log.Fatalf("Unknown format %q (expected json or md)", batchFormat)
+		}
+
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		var pairs [][2]string
+		var err error
+		if batchAllPairs {
+			pairs, err = consecutivePairs(db, owner, repo)
+		} else {
+			pairs, err = readPairsFile(batchPairsFile)
+		}
+		if err != nil {
+			log.Fatalf("Failed to determine release pairs: %v", err)
+		}
+
+		fetcher := github.NewFetcher(owner, repo, nil)
+
+		var results []*github.CompareResult
+		for _, p := range pairs {
+			result, err := fetcher.GetCompareData(db, p[0], p[1])
+			if err != nil {
+				slog.Warn("Skipping pair", "from", p[0], "to", p[1], "err", err)
+				continue
+			}
+			results = append(results, result)
+		}
+
+		if batchFormat == "md" {
+			fmt.Printf("# %s/%s release history\n\n", owner, repo)
+			for _, r := range results {
+				report.Markdown(os.Stdout, r)
+			}
+			return
+		}
+
+		out := make([]map[string]interface{}, len(results))
+		for i, r := range results {
+			out[i] = convertToJSON(r)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+	},
+}
+
+// consecutivePairs returns every adjacent pair of cached releases, oldest first.
+func consecutivePairs(db cache.Store, owner, repo string) ([][2]string, error) {
+	releases, err := db.GetReleases(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs [][2]string
+	for i := len(releases) - 1; i > 0; i-- {
+		pairs = append(pairs, [2]string{releases[i].TagName, releases[i-1].TagName})
+	}
+	return pairs, nil
+}
+
+func readPairsFile(path string) ([][2]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pairs [][2]string
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<from> <to>\"", path, lineNo)
+		}
+		pairs = append(pairs, [2]string{fields[0], fields[1]})
+	}
+	return pairs, scanner.Err()
+}
+
+func init() {
+	addRepoFlag(BatchCompareCmd)
+	BatchCompareCmd.Flags().StringVar(&batchPairsFile, "pairs", "", "File with one \"<from> <to>\" pair per line")
+	BatchCompareCmd.Flags().BoolVar(&batchAllPairs, "all-pairs", false, "Compare every pair of consecutive cached releases")
+	BatchCompareCmd.Flags().StringVarP(&batchFormat, "format", "f", "json", "Output format: json or md")
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var CacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and clean up the cache",
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the cache size and rows cached per repository",
+	Long: `Shows the size of the cache file, how much of it a vacuum would free, and
+the number of rows cached per repository and table.
+
+Example:
+  ordiff cache stats
+  ordiff cache stats --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+
+		db := openDB()
+		defer db.Close()
+
+		stats, err := db.Stats()
+		if err != nil {
+			log.Fatalf("Failed to read cache stats: %v", err)
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(stats)
+			return
+		}
+
+		fmt.Printf("\n=== Cache ===\n\n")
+		fmt.Printf("  Size:           %s (%s free)\n", formatBytes(stats.SizeBytes), formatBytes(stats.FreeBytes))
+		fmt.Printf("  Schema version: %d\n", stats.SchemaVersion)
+		fmt.Printf("  HTTP responses: %d\n", stats.HTTPResponses)
+
+		if len(stats.Repos) == 0 {
+			fmt.Println("\nNo repositories cached.")
+			return
+		}
+		for _, r := range stats.Repos {
+			fmt.Printf("\n%s/%s\n", r.Owner, r.Repo)
+			for _, c := range r.Rows {
+				fmt.Printf("  %-20s %8d\n", c.Table, c.Rows)
+			}
+			fmt.Printf("  %-20s %8d\n", "http_cache", r.HTTPResponses)
+		}
+	},
+}
+
+var cachePurgeCmd = &cobra.Command{
+	Use:   "purge <owner> <repo>",
+	Short: "Delete everything cached for a repository",
+	Long: `Deletes the releases, commits, pull requests, file changes, stored
+comparisons and HTTP responses cached for one repository. Other
+repositories are left alone. Run 'ordiff cache vacuum' afterwards to shrink
+the file.
+
+Example:
+  ordiff cache purge ollama ollama`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+		owner, repo := args[0], args[1]
+
+		db := openDB()
+		defer db.Close()
+
+		removed, err := db.Purge(owner, repo)
+		if err != nil {
+			log.Fatalf("Failed to purge %s/%s: %v", owner, repo, err)
+		}
+		if removed == 0 {
+			fmt.Printf("Nothing cached for %s/%s\n", owner, repo)
+			return
+		}
+		fmt.Printf("Purged %d rows for %s/%s\n", removed, owner, repo)
+	},
+}
+
+var cacheVacuumCmd = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Rebuild the cache file to reclaim free space",
+	Long: `Runs SQLite's VACUUM, which rewrites the cache file without the pages freed
+by purges and re-indexing. It needs free disk space about the size of the
+cache while it runs.
+
+Example:
+  ordiff cache vacuum`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+
+		db := openDB()
+		defer db.Close()
+
+		before, err := db.Stats()
+		if err != nil {
+			log.Fatalf("Failed to read cache stats: %v", err)
+		}
+		if err := db.Vacuum(); err != nil {
+			log.Fatalf("Failed to vacuum: %v", err)
+		}
+		after, err := db.Stats()
+		if err != nil {
+			log.Fatalf("Failed to read cache stats: %v", err)
+		}
+
+		fmt.Printf("Vacuumed cache: %s → %s\n", formatBytes(before.SizeBytes), formatBytes(after.SizeBytes))
+	},
+}
+
+func init() {
+	cacheStatsCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+	CacheCmd.AddCommand(cacheStatsCmd, cachePurgeCmd, cacheVacuumCmd)
+}
+
+// formatBytes renders a size with a binary unit, e.g. 12.3 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"ordiff/internal/changelog"
+	"ordiff/internal/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var ChangelogCmd = &cobra.Command{
+	Use:   "changelog [<from> <to>]",
+	Short: "Render a Markdown changelog between two releases",
+	Long: `Renders a Keep a Changelog style Markdown document from the cached commits
+and pull requests between two releases, grouped by conventional-commit type.
+
+--template takes a Go text/template file. It is executed with the owner,
+repo, from and to tags, the release date, the grouped sections, the merged
+pull requests and the breaking changes and deprecations; the helper
+functions are listed in the README. --json prints that data instead, and
+--schema its JSON Schema.
+
+Example:
+  ordiff changelog v0.1.0 v0.2.0
+  ordiff changelog --since v2.0.0 > CHANGELOG.md
+  ordiff changelog v0.1.0 v0.2.0 --template release-notes.tmpl`,
+	Args: rangeArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if showSchema {
+			printSchema(schema.Changelog)
+			return
+		}
+
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from, to := resolveRange(db, owner, repo, args)
+
+		doc, err := changelog.NewDocument(db, owner, repo, from, to)
+		if err != nil {
+			log.Fatalf("Failed to build changelog: %v", err)
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(doc)
+			return
+		}
+
+		if err := changelog.Render(os.Stdout, doc, readTemplate()); err != nil {
+			log.Fatalf("Failed to render changelog: %v", err)
+		}
+	},
+}
+
+func init() {
+	addRangeFlags(ChangelogCmd)
+	addSchemaFlag(ChangelogCmd)
+	addTemplateFlag(ChangelogCmd)
+}
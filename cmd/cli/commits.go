@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"log"
+
+	"ordiff/internal/cache"
+
+	"github.com/spf13/cobra"
+)
+
+var commitQuery cache.CommitQuery
+
+var CommitsCmd = &cobra.Command{
+	Use:   "commits [<from> <to>]",
+	Short: "Search the commits between two releases",
+	Long: `Lists the commits between two releases that match every given filter:
+
+  --author  part of the author name or email, after the mailmap is applied
+  --grep    part of the commit message
+  --pr      the pull request the commit belongs to
+  --type    the conventional-commit type, such as feat or fix
+  --scope   the conventional-commit scope
+
+Matching ignores case.
+
+Example:
+  ordiff commits v0.1.0 v0.2.0 --author alice --grep fix
+  ordiff commits --since v2.0.0 --pr 1234 --json
+  ordiff commits v0.1.0 v0.2.0 --type feat --scope api`,
+	Args: rangeArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from, to := resolveRange(db, owner, repo, args)
+
+		commits, err := db.SearchCommitsBetween(owner, repo, from, to, commitQuery)
+		if err != nil {
+			log.Fatalf("Failed to search commits: %v", err)
+		}
+
+		printCommits(from, to, commits)
+	},
+}
+
+func init() {
+	addRangeFlags(CommitsCmd)
+	CommitsCmd.Flags().StringVar(&commitQuery.Author, "author", "", "Only commits whose author name or email contains this")
+	CommitsCmd.Flags().StringVar(&commitQuery.Grep, "grep", "", "Only commits whose message contains this")
+	CommitsCmd.Flags().IntVar(&commitQuery.PR, "pr", 0, "Only commits of this pull request")
+	CommitsCmd.Flags().StringVar(&commitQuery.Type, "type", "", "Only commits of this conventional-commit type")
+	CommitsCmd.Flags().StringVar(&commitQuery.Scope, "scope", "", "Only commits of this conventional-commit scope")
+}
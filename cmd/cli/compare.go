@@ -4,83 +4,679 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"sort"
+	"strings"
 
+	"ordiff/internal/analyzer"
+	"ordiff/internal/assets"
+	"ordiff/internal/backport"
 	"ordiff/internal/cache"
+	"ordiff/internal/changelog"
+	"ordiff/internal/codeowners"
+	"ordiff/internal/config"
+	"ordiff/internal/deps"
+	"ordiff/internal/filter"
 	"ordiff/internal/github"
+	"ordiff/internal/issues"
+	"ordiff/internal/language"
+	"ordiff/internal/metrics"
+	"ordiff/internal/milestone"
+	"ordiff/internal/report"
+	"ordiff/internal/risk"
+	"ordiff/internal/schema"
+	"ordiff/internal/submodule"
+	"ordiff/internal/table"
+	"ordiff/internal/vendored"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+)
+
+var (
+	demoteGenerated bool
+	compareLive     bool
+	cacheRefs       bool
+	includePaths    []string
+	excludePaths    []string
+	compareFormat   string
+	compareOut      string
+	compareNotes    bool
+	showAllFiles    bool
+	milestoneTitle  string
+	compareByDir    bool
+	compareDepth    int
+
+	compareNoPatches bool
 )
 
 var CompareCmd = &cobra.Command{
-	Use:   "compare <from> <to>",
+	Use:   "compare [<from> <to>]",
 	Short: "Compare two releases",
 	Long: `Shows a comparison between two releases including commits, PRs, and file changes.
 
+Only adjacent release pairs are indexed. For non-adjacent releases the file
+changes of every intervening pair are aggregated, or fetched directly from
+the GitHub compare API with --live.
+
+Refs that are not cached releases (branches, commit SHAs, unindexed tags)
+are compared on demand through the GitHub compare API. Add --cache-refs to
+keep the result in the cache until the next index.
+
+Version patterns such as v0.1.x resolve to the newest cached release they
+match.
+
+Run without arguments on a terminal to pick the two releases from a list
+with a fuzzy search.
+
+Vendored directories (vendor/, node_modules/, third_party/), lockfiles,
+generated code and binary files are left out so they do not crowd the top
+files list; the output counts them. Patterns can be overridden with
+ignore_patterns in .ordiff.yaml, and --all shows every file.
+
+With --demote-generated, files that look machine generated (lockfiles,
+protobuf output, dist/ bundles, or huge one-sided modifications) are moved
+below the top files list. Patterns can be overridden with generated_patterns
+in .ordiff.yaml.
+
+--path and --exclude limit the file changes to paths (a file, a directory or
+a glob) and may be repeated. The cache stores file changes per release pair
+rather than per commit, so the commit list is not filtered.
+
+--notes adds what the maintainers wrote in the release notes of every
+release in the range, leaving out lines the previous release's notes
+already had. Headings the previous notes lacked are marked as new.
+
+When components are configured (see 'ordiff components --help'), the
+changes are also summarized per component. When the repository has a
+CODEOWNERS file, they are also attributed to their owners (see 'ordiff
+owners --help').
+
+Submodules the range moved are listed with the commits between, when the
+submodule's repository is cached too. Dependencies vendored from upstream
+and configured under vendored in .ordiff.yaml are reported with the number
+of upstream commits they were bumped by.
+
+Commits that also landed in another release line are listed as backports:
+those with a "cherry picked from commit" trailer pointing at, or pointed at
+by, a commit outside the range, and those whose patch matches a commit of
+the same subject outside it. Commits already shipped in an earlier release
+are counted next to the total, so a maintenance release does not take
+credit for work a newer line shipped first. Patches are compared from the
+cache; with --live the missing ones are fetched.
+
+Every comparison carries an upgrade risk score from 0 to 100 weighing the
+churn in core paths, breaking changes, dependency major version bumps and
+the number of contributors. The weights are configured under risk in
+.ordiff.yaml.
+
+Analyzers listed under analyzers in .ordiff.yaml run over every text or
+JSON comparison: executables that read the comparison, patches included,
+as JSON on stdin and write named sections of text and findings as JSON on
+stdout, for checks of your own such as license headers or migration files.
+--no-analyzers skips them.
+
+--by-dir rolls the file changes up into directory totals, cut to --depth
+path segments, for a structural view of where the churn is.
+
+--milestone cross-checks a GitHub milestone against the range: which of its
+issues and pull requests shipped between the two releases, which are still
+open (slipped), which were closed elsewhere or without a closing keyword,
+and which were dropped, with the percentage shipped. The milestone is
+fetched each time and cached for when GitHub cannot be reached.
+
+--format md and --format html render a shareable report with summary stats,
+top files, merged PRs, commits and the diff of every file. --format csv and
+tsv print one row per changed file; use 'ordiff export commits' for commits.
+--schema prints the JSON Schema of --format json.
+
+--template renders the comparison with a Go text/template file instead, for
+release notes in a house style. The fields and helper functions it can use
+are listed in the README.
+
 Example:
   ordiff compare v0.1.0 v0.2.0
-  ordiff compare abc123 def456  # by commit SHA`,
-	Args: cobra.ExactArgs(2),
+  ordiff compare abc123 def456  # by commit SHA
+  ordiff compare v0.2.0 main --cache-refs
+  ordiff compare v0.1.x v0.3.x
+  ordiff compare v0.1.0 v0.2.0 --demote-generated
+  ordiff compare v0.1.0 v0.2.0 --all
+  ordiff compare v0.5.0 v0.6.0 --path server/ --exclude '*_test.go'
+  ordiff compare v0.5.0 v0.6.0 --by-dir --depth 2
+  ordiff compare v0.1.0 v0.5.0 --live
+  ordiff compare v0.1.0 v0.5.0 --notes
+  ordiff compare v1.9.0 v2.0.0 --milestone v2.0
+  ordiff compare v0.1.0 v0.2.0 --format html --out report.html
+  ordiff compare v0.1.0 v0.2.0 --template notes.tmpl
+  ordiff compare --schema`,
+	ValidArgsFunction: completeTags(2),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if showSchema {
+			return cobra.NoArgs(cmd, args)
+		}
+		if len(args) == 0 && interactive() {
+			return nil
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		from := args[0]
-		to := args[1]
-
-		viper.SetConfigName(".ordiff")
-		viper.SetConfigType("yaml")
-		viper.AddConfigPath(".")
-		if err := viper.ReadInConfig(); err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				log.Printf("Warning: could not read config: %v\n", err)
+		if showSchema {
+			printSchema(schema.Compare)
+			return
+		}
+		if jsonOutput {
+			compareFormat = "json"
+		}
+		switch compareFormat {
+		case "text":
+			if compareOut != "" && templateFile == "" {
+				log.Fatal("--out needs --template or --format json, md, html, csv or tsv")
 			}
+		case "json", "md", "html", "csv", "tsv":
+		default:
+			log.Fatalf("Invalid --format %q (expected text, json, md, html, csv or tsv)", compareFormat)
+		}
+		if templateFile != "" && compareFormat != "text" {
+			log.Fatal("--template cannot be combined with --format")
+		}
+		if compareNotes && compareFormat != "text" && compareFormat != "json" {
+			log.Fatal("--notes needs --format text or json")
+		}
+		if milestoneTitle != "" && compareFormat != "text" && compareFormat != "json" {
+			log.Fatal("--milestone needs --format text or json")
+		}
+		if compareByDir && compareFormat != "text" && compareFormat != "json" {
+			log.Fatal("--by-dir needs --format text or json")
+		}
+		if compareNoPatches && compareFormat != "json" {
+			log.Fatal("--no-patches needs --format json")
 		}
 
-		owner := viper.GetString("default_owner")
-		repo := viper.GetString("default_repo")
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
 
-		if owner == "" || repo == "" {
-			log.Fatal("No default repository. Run 'ordiff index <owner> <repo>' first.")
+		if len(args) == 0 {
+			args = pickRange(db, owner, repo)
 		}
+		from := resolveRef(db, owner, repo, args[0])
+		to := resolveRef(db, owner, repo, args[1])
 
-		db, err := cache.NewDB("ordiff.db")
+		fetcher := newFetcher(owner, repo)
+		result, err := fetcher.Compare(db, from, to, cacheRefs)
 		if err != nil {
-			log.Fatalf("Failed to open database: %v", err)
+			log.Fatalf("Failed to compare: %v", err)
 		}
-		defer db.Close()
 
-		fetcher := github.NewFetcher(owner, repo, nil)
-		result, err := fetcher.GetCompareData(db, from, to)
+		if compareLive && result.FileSource != github.FilesDirect && result.FileSource != github.FilesLive {
+			if err := fetcher.FetchLiveFiles(result); err != nil {
+				log.Fatalf("Failed to compare: %v", err)
+			}
+		}
+
+		vendoredDeps := vendoredDeps()
+		if len(vendoredDeps) > 0 {
+			if err := fetcher.LoadPatchesFor(db, result, vendored.IsVersionFile(vendoredDeps)); err != nil {
+				log.Fatalf("Failed to load patches: %v", err)
+			}
+		}
+		loadSubmodulePatches(db, fetcher, result)
+
+		result.Files = filter.Paths(result.Files, includePaths, excludePaths)
+
+		var owners *codeowners.Summary
+		if rules, source := codeownerRules(db, owner, repo); source != "" {
+			s := codeowners.Summarize(source, rules, result.Files)
+			owners = &s
+		}
+
+		var ignored []cache.FileChange
+		if !showAllFiles {
+			result.Files, ignored = filter.PartitionIgnored(result.Files, config.IgnorePatterns())
+		}
+
+		out := os.Stdout
+		if compareOut != "" {
+			f, err := os.Create(compareOut)
+			if err != nil {
+				log.Fatalf("Failed to create %s: %v", compareOut, err)
+			}
+			defer func() {
+				if err := f.Close(); err != nil {
+					log.Fatalf("Failed to write %s: %v", compareOut, err)
+				}
+				fmt.Printf("Wrote %s\n", compareOut)
+			}()
+			out = f
+		}
+
+		if compareFormat == "md" || compareFormat == "html" || compareFormat == "json" && !compareNoPatches {
+			if err := fetcher.LoadPatches(db, result); err != nil {
+				log.Fatalf("Failed to load patches: %v", err)
+			}
+		}
+
+		switch compareFormat {
+		case "md":
+			report.Markdown(out, result)
+			report.MarkdownDiffs(out, result.Files)
+			return
+		case "html":
+			if err := report.HTML(out, result); err != nil {
+				log.Fatalf("Failed to render report: %v", err)
+			}
+			return
+		case "csv", "tsv":
+			for i := range result.Files {
+				result.Files[i].Owner, result.Files[i].Repo = owner, repo
+				result.Files[i].FromRelease, result.Files[i].ToRelease = result.FromRelease.TagName, result.ToRelease.TagName
+			}
+			if err := report.FileChangesCSV(report.NewCSVWriter(out, compareFormat == "tsv"), result.Files, false); err != nil {
+				log.Fatalf("Failed to write %s: %v", compareFormat, err)
+			}
+			return
+		}
+
+		var notes []changelog.NotesDiff
+		if compareNotes {
+			notes = releaseNotesBetween(db, owner, repo, result.FromRelease.TagName, result.ToRelease.TagName)
+		}
+
+		assetDiff := assetChanges(db, owner, repo, result.FromRelease.TagName, result.ToRelease.TagName)
+		metricDiff := metricChanges(db, owner, repo, result.FromRelease.TagName, result.ToRelease.TagName)
+		analyses := runAnalyzers(db, fetcher, owner, repo, result)
+
+		closed, err := issues.Between(db, owner, repo, result.Commits, result.PullRequests)
 		if err != nil {
-			log.Fatalf("Failed to compare: %v", err)
+			log.Fatalf("Failed to read closed issues: %v", err)
 		}
 
-		if jsonOutput {
-			enc := json.NewEncoder(os.Stdout)
+		var planned *milestone.Report
+		if milestoneTitle != "" {
+			planned = milestone.Check(fetchMilestone(db, fetcher, owner, repo), result.Commits, result.PullRequests, closed)
+		}
+
+		var generated []cache.FileChange
+		if demoteGenerated {
+			result.Files, generated = filter.PartitionGenerated(result.Files, generatedPatterns())
+		}
+
+		changed := append(append([]cache.FileChange{}, result.Files...), generated...)
+		allFiles := append(append([]cache.FileChange{}, changed...), ignored...)
+		bumps := vendoredBumps(vendoredDeps, allFiles)
+		submodules := submoduleChanges(db, fetcher, owner, result.ToRelease.TagName, allFiles, vendoredDeps)
+		backports := findBackports(db, fetcher, owner, repo, result)
+
+		report := compareTemplate{
+			CompareResult:     result,
+			Owner:             owner,
+			Repo:              repo,
+			Generated:         generated,
+			Ignored:           ignored,
+			Risk:              risk.Assess(riskConfig(), result.Commits, result.PullRequests, changed),
+			CommitTypes:       changelog.CommitTypes(result.Commits),
+			Breaking:          changelog.Notices(result.Commits, result.PullRequests),
+			DependencyChanges: deps.ManifestDiffs(changed),
+			AssetChanges:      assetDiff,
+			Metrics:           metricDiff,
+			Analyzers:         analyses,
+			ClosedIssues:      closed,
+			Milestone:         planned,
+			ReleaseNotes:      notes,
+			Vendored:          bumps,
+			Submodules:        submodules,
+			Backports:         backports,
+			Languages:         language.Breakdown(changed),
+			Directories:       directories(changed),
+		}
+
+		if templateFile != "" {
+			executeTemplate(out, report)
+			return
+		}
+
+		if compareFormat == "json" {
+			enc := json.NewEncoder(out)
 			enc.SetIndent("", "  ")
-			enc.Encode(convertToJSON(result))
+			enc.Encode(compareJSON(report, changed, owners))
 			return
 		}
 
-		printHumanOutput(result)
+		printHumanOutput(result, changed, generated, ignored, owners, assetDiff, metricDiff, closed, bumps, submodules, backports)
+		printAnalyzerReports(analyses)
+		if compareNotes {
+			printReleaseNotes(notes)
+		}
+		if planned != nil {
+			printMilestone(planned)
+		}
 	},
 }
 
+// compareTemplate is the data a compare --template is executed with: the
+// fields of the comparison (FromRelease, ToRelease, Commits, Files, PrCount,
+// PullRequests, FileSource and MergeBase) and what the text output derives from them.
+type compareTemplate struct {
+	*github.CompareResult
+	Owner             string
+	Repo              string
+	Generated         []cache.FileChange
+	Ignored           []cache.FileChange
+	Risk              risk.Score
+	CommitTypes       changelog.TypeStats
+	Breaking          []changelog.Notice
+	DependencyChanges []deps.FileDiff
+	AssetChanges      []assets.Change
+	Metrics           []metrics.Delta
+	Analyzers         []analyzer.Report
+	ClosedIssues      []issues.Closed
+	Milestone         *milestone.Report
+	ReleaseNotes      []changelog.NotesDiff
+	Vendored          []vendored.Bump
+	Submodules        []submodule.Change
+	Backports         []backport.Backport
+	Languages         []language.Stat
+	Directories       []filter.DirChanges
+}
+
+// directories rolls files up with --by-dir, or returns nil without it.
+func directories(files []cache.FileChange) []filter.DirChanges {
+	if !compareByDir {
+		return nil
+	}
+	return filter.GroupByDir(files, max(compareDepth, 1))
+}
+
+// vendoredBumps reports what happened to the vendored dependencies in the
+// files of a range, counting the upstream commits of every bump.
+func vendoredBumps(vendoredDeps []vendored.Dependency, files []cache.FileChange) []vendored.Bump {
+	bumps := vendored.Bumps(vendoredDeps, files, report.BaseURL)
+	for i, b := range bumps {
+		if b.From == "" || b.To == "" || !b.Bumped() {
+			continue
+		}
+		owner, repo, _ := strings.Cut(b.Upstream, "/")
+		n, err := newFetcher(owner, repo).CountCommits(b.From, b.To)
+		if err != nil {
+			slog.Warn("Failed to count upstream commits", "upstream", b.Upstream, "from", b.From, "to", b.To, "err", err)
+			continue
+		}
+		bumps[i].UpstreamCommits = &n
+	}
+	return bumps
+}
+
+// fetchMilestone fetches --milestone from GitHub, falling back to the copy
+// cached by an earlier run.
+func fetchMilestone(db cache.Store, fetcher *github.Fetcher, owner, repo string) *cache.Milestone {
+	m, err := fetcher.FetchMilestone(db, milestoneTitle)
+	if err == nil {
+		return m
+	}
+	cached, cacheErr := db.GetMilestone(owner, repo, milestoneTitle)
+	if cacheErr != nil {
+		log.Fatalf("Failed to fetch milestone: %v", err)
+	}
+	slog.Warn("Failed to fetch milestone, using the cached copy", "fetched", cached.FetchedAt.Local().Format("2006-01-02 15:04"), "err", err)
+	return cached
+}
+
+func printMilestone(r *milestone.Report) {
+	fmt.Println()
+	fmt.Printf("Milestone %s (%s", r.Title, r.State)
+	if r.DueOn != nil {
+		fmt.Printf(", due %s", r.DueOn.Format("2006-01-02"))
+	}
+	fmt.Printf("): %d of %d shipped in this range (%.0f%%)\n", r.Shipped, r.Total-r.Dropped, r.Completion)
+	if r.Dropped > 0 {
+		fmt.Printf("  %d dropped items not counted\n", r.Dropped)
+	}
+
+	sections := []struct{ status, title string }{
+		{milestone.StatusSlipped, "Slipped (still open)"},
+		{milestone.StatusOutside, "Closed outside this range"},
+		{milestone.StatusDropped, "Dropped"},
+		{milestone.StatusShipped, "Shipped"},
+	}
+	for _, s := range sections {
+		var items []milestone.Item
+		for _, i := range r.Items {
+			if i.Status == s.status {
+				items = append(items, i)
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		fmt.Printf("  [%s]\n", s.title)
+		for _, i := range items {
+			kind := "issue"
+			if i.PullRequest {
+				kind = "PR"
+			}
+			fmt.Printf("    #%-6d %-5s %s\n", i.Number, kind, i.Title)
+		}
+	}
+}
+
+// releaseNotesBetween diffs the notes of every release after from up to and
+// including to against the notes of the release before it, oldest first.
+// Refs that are not cached releases have no notes.
+func releaseNotesBetween(db cache.Store, owner, repo, from, to string) []changelog.NotesDiff {
+	releases, err := db.GetReleases(owner, repo)
+	if err != nil {
+		log.Fatalf("Failed to get releases: %v", err)
+	}
+
+	// GetReleases is newest first.
+	start, end := -1, -1
+	for i, r := range releases {
+		switch r.TagName {
+		case from:
+			start = i
+		case to:
+			end = i
+		}
+	}
+	if start < 0 || end < 0 || end > start {
+		slog.Warn("Release notes are only compared between cached releases")
+		return nil
+	}
+
+	var diffs []changelog.NotesDiff
+	for i := start - 1; i >= end; i-- {
+		sections := changelog.DiffNotes(releases[i+1].Body, releases[i].Body)
+		if len(sections) == 0 {
+			continue
+		}
+		diffs = append(diffs, changelog.NotesDiff{Tag: releases[i].TagName, Previous: releases[i+1].TagName, Sections: sections})
+	}
+	return diffs
+}
+
+func printReleaseNotes(diffs []changelog.NotesDiff) {
+	fmt.Println()
+	fmt.Println("Release Notes:")
+	if len(diffs) == 0 {
+		fmt.Println("  (nothing new in the release notes)")
+		return
+	}
+	for _, d := range diffs {
+		fmt.Printf("  %s\n", d.Tag)
+		for _, s := range d.Sections {
+			if s.Title != "" {
+				marker := ""
+				if s.New {
+					marker = " (new)"
+				}
+				fmt.Printf("    [%s]%s\n", s.Title, marker)
+			}
+			for _, l := range s.Lines {
+				fmt.Printf("      %s\n", l)
+			}
+		}
+	}
+}
+
+// compareJSON is the document compare --json writes: convertToJSON of the
+// comparison plus the sections derived from it. changed holds the files and
+// the generated files.
+func compareJSON(c compareTemplate, changed []cache.FileChange, owners *codeowners.Summary) map[string]interface{} {
+	data := convertToJSON(c.CompareResult)
+	data["files_changed"] = len(c.Files) + len(c.Generated) + len(c.Ignored)
+	if !showAllFiles {
+		data["ignored_files"] = nonNilFiles(c.Ignored)
+	}
+	if demoteGenerated {
+		data["generated_files"] = c.Generated
+		data["dependency_changes"] = nonNilDiffs(c.DependencyChanges)
+		data["risk"] = c.Risk
+	}
+	data["languages"] = c.Languages
+	if compareByDir {
+		data["directories"] = c.Directories
+	}
+	if comps := components(); len(comps) > 0 {
+		data["components"] = filter.GroupByComponent(changed, comps)
+	}
+	if owners != nil {
+		data["owners"] = owners
+	}
+	if c.AssetChanges == nil {
+		c.AssetChanges = []assets.Change{}
+	}
+	data["asset_changes"] = c.AssetChanges
+	if c.Metrics == nil {
+		c.Metrics = []metrics.Delta{}
+	}
+	data["metrics"] = c.Metrics
+	if c.Analyzers != nil {
+		data["analyzers"] = c.Analyzers
+	}
+	data["closed_issues"] = c.ClosedIssues
+	if len(vendoredDeps()) > 0 {
+		if c.Vendored == nil {
+			c.Vendored = []vendored.Bump{}
+		}
+		data["vendored"] = c.Vendored
+	}
+	if c.Submodules == nil {
+		c.Submodules = []submodule.Change{}
+	}
+	data["submodules"] = c.Submodules
+	if c.Backports == nil {
+		c.Backports = []backport.Backport{}
+	}
+	data["backports"] = c.Backports
+	if c.Milestone != nil {
+		data["milestone"] = c.Milestone
+	}
+	if compareNotes {
+		if c.ReleaseNotes == nil {
+			c.ReleaseNotes = []changelog.NotesDiff{}
+		}
+		data["release_notes"] = c.ReleaseNotes
+	}
+	return data
+}
+
 func convertToJSON(r *github.CompareResult) map[string]interface{} {
 	return map[string]interface{}{
-		"from_release":  r.FromRelease.TagName,
-		"to_release":    r.ToRelease.TagName,
-		"commit_count":  len(r.Commits),
-		"pr_count":      r.PrCount,
-		"files_changed": len(r.Files),
-		"commits":       r.Commits,
-		"files":         r.Files,
+		"from_release":       r.FromRelease.TagName,
+		"to_release":         r.ToRelease.TagName,
+		"commit_count":       len(r.Commits),
+		"pr_count":           r.PrCount,
+		"files_changed":      len(r.Files),
+		"commits":            r.Commits,
+		"files":              r.Files,
+		"pull_requests":      r.PullRequests,
+		"file_source":        r.FileSource,
+		"merge_base":         r.MergeBase,
+		"etag":               r.ETag(),
+		"dependency_changes": nonNilDiffs(deps.ManifestDiffs(r.Files)),
+		"risk":               risk.Assess(riskConfig(), r.Commits, r.PullRequests, r.Files),
+		"commit_types":       changelog.CommitTypes(r.Commits),
+		"breaking_changes":   changelog.Notices(r.Commits, r.PullRequests),
+	}
+}
+
+func nonNilFiles(files []cache.FileChange) []cache.FileChange {
+	if files == nil {
+		return []cache.FileChange{}
+	}
+	return files
+}
+
+func nonNilDiffs(diffs []deps.FileDiff) []deps.FileDiff {
+	if diffs == nil {
+		return []deps.FileDiff{}
 	}
+	return diffs
 }
 
-func printHumanOutput(r *github.CompareResult) {
+func printHumanOutput(r *github.CompareResult, changed, generated, ignored []cache.FileChange, owners *codeowners.Summary, assetDiff []assets.Change, metricDiff []metrics.Delta, closed []issues.Closed, bumps []vendored.Bump, submodules []submodule.Change, backports []backport.Backport) {
 	fmt.Printf("\n=== %s → %s ===\n\n", r.FromRelease.TagName, r.ToRelease.TagName)
-	fmt.Printf("Commits: %d | PRs: %d | Files Changed: %d\n\n", len(r.Commits), r.PrCount, len(r.Files))
+	shipped := ""
+	if releases, n := backport.Shipped(backports); n > 0 {
+		shipped = fmt.Sprintf(" (%d already shipped in %s)", n, strings.Join(releases, ", "))
+	}
+	fmt.Printf("Commits: %d%s | PRs: %d | Files Changed: %d\n", len(r.Commits), shipped, r.PrCount, len(r.Files)+len(generated)+len(ignored))
+	if stats := language.Breakdown(changed); len(stats) > 0 {
+		fmt.Printf("Languages: %s\n", language.Summary(stats))
+	}
+	if types := changelog.CommitTypes(r.Commits); types.Conventional > 0 {
+		printCommitTypes(types)
+	}
+	fmt.Println()
+
+	if notices := changelog.Notices(r.Commits, r.PullRequests); len(notices) > 0 {
+		printNotices(notices)
+		fmt.Println()
+	}
+
+	score := risk.Assess(riskConfig(), r.Commits, r.PullRequests, changed)
+	fmt.Printf("Upgrade Risk: %d/100 (%s)\n", score.Score, score.Level)
+	for _, f := range score.Factors {
+		fmt.Printf("  %5.1f/%-4.0f %s\n", f.Points, f.Max, f.Detail)
+		for _, item := range f.Items[:min(3, len(f.Items))] {
+			fmt.Printf("              %s\n", item)
+		}
+		if len(f.Items) > 3 {
+			fmt.Printf("              ... and %d more\n", len(f.Items)-3)
+		}
+	}
+	fmt.Println()
+
+	if r.MergeBase != "" && r.MergeBase != r.FromRelease.CommitSHA {
+		fmt.Printf("Note: %s is not an ancestor of %s; they diverged at %s. Commits only on %s's branch are left out.\n",
+			r.FromRelease.TagName, r.ToRelease.TagName, shortVersion(r.MergeBase), r.FromRelease.TagName)
+		if r.FileSource == github.FilesAggregated {
+			fmt.Println("      Aggregated file changes may still include them; use --live for the exact diff.")
+		}
+		fmt.Println()
+	}
+
+	switch r.FileSource {
+	case github.FilesAggregated:
+		fmt.Println("Note: releases are not adjacent; file changes aggregated across intervening release pairs.")
+		fmt.Println()
+	case github.FilesLive:
+		fmt.Println("Note: file changes fetched live from the GitHub compare API.")
+		fmt.Println()
+	case github.FilesNone:
+		fmt.Println("Note: no cached file changes for this pair. Use --live to fetch them from GitHub.")
+		fmt.Println()
+	}
+
+	if len(ignored) > 0 {
+		additions, deletions := 0, 0
+		for _, f := range ignored {
+			additions += f.Additions
+			deletions += f.Deletions
+		}
+		fmt.Printf("Note: %d vendored, generated or binary files hidden (+%d -%d). Use --all to show them.\n\n", len(ignored), additions, deletions)
+	}
 
 	if len(r.Files) > 0 {
 		sort.Slice(r.Files, func(i, j int) bool {
@@ -88,31 +684,259 @@ func printHumanOutput(r *github.CompareResult) {
 		})
 
 		fmt.Println("Top Changed Files:")
-		fmt.Println("  +Add  -Del  File")
-		fmt.Println("  ---- ----  ----")
+		t := table.New(
+			table.Column{Header: "+Add", Right: true, Signed: true},
+			table.Column{Header: "-Del", Right: true, Signed: true},
+			table.Column{Header: "File", Truncate: table.TrimStart},
+		)
 		for _, f := range r.Files[:min(10, len(r.Files))] {
-			fmt.Printf("  %+4d %-4d  %s\n", f.Additions, f.Deletions, f.Filename)
+			t.Row(fmt.Sprintf("+%d", f.Additions), fmt.Sprintf("-%d", f.Deletions), f.Filename+renamedFrom(f))
+		}
+		printTable(t)
+		fmt.Println()
+	}
+
+	if dirs := directories(changed); len(dirs) > 0 {
+		fmt.Println("Directories:")
+		printDirectories(dirs)
+		fmt.Println()
+	}
+
+	if comps := components(); len(comps) > 0 {
+		fmt.Println("Components:")
+		printComponents(filter.GroupByComponent(changed, comps), 0)
+		fmt.Println()
+	}
+
+	if owners != nil {
+		fmt.Printf("Owners (%s):\n", owners.Source)
+		printOwners(*owners, false)
+		fmt.Println()
+	}
+
+	if len(generated) > 0 {
+		additions, deletions := 0, 0
+		for _, f := range generated {
+			additions += f.Additions
+			deletions += f.Deletions
+		}
+		fmt.Printf("Likely Generated Files: %d (+%d -%d, collapsed)\n", len(generated), additions, deletions)
+		for _, f := range generated[:min(5, len(generated))] {
+			fmt.Printf("  %s\n", f.Filename)
+		}
+		if len(generated) > 5 {
+			fmt.Printf("  ... and %d more\n", len(generated)-5)
 		}
 		fmt.Println()
 	}
 
+	if diffs := deps.ManifestDiffs(changed); len(diffs) > 0 {
+		fmt.Println("Dependency Changes:")
+		for _, d := range diffs {
+			fmt.Printf("  %s\n", d.File)
+			if d.NoPatch {
+				fmt.Println("    (patch not available)")
+				continue
+			}
+			for _, c := range d.Changes {
+				switch c.Kind {
+				case deps.KindAdded:
+					fmt.Printf("    + %s %s\n", c.Name, c.To)
+				case deps.KindRemoved:
+					fmt.Printf("    - %s %s\n", c.Name, c.From)
+				default:
+					fmt.Printf("    ~ %s %s → %s\n", c.Name, c.From, c.To)
+				}
+			}
+		}
+		fmt.Println()
+	}
+
+	if len(bumps) > 0 {
+		printVendored(bumps)
+		fmt.Println()
+	}
+
+	if len(submodules) > 0 {
+		printSubmodules(submodules)
+		fmt.Println()
+	}
+
+	if len(backports) > 0 {
+		printBackports(backports)
+		fmt.Println()
+	}
+
+	if len(assetDiff) > 0 {
+		printAssetChanges(assetDiff)
+		fmt.Println()
+	}
+
+	if len(metricDiff) > 0 {
+		printMetricChanges(metricDiff)
+		fmt.Println()
+	}
+
+	if len(r.PullRequests) > 0 {
+		fmt.Println("Merged PRs:")
+		labels, groups := r.PullRequestsByLabel()
+		for _, label := range labels {
+			fmt.Printf("  [%s]\n", label)
+			t := table.New(table.Column{Color: table.Yellow}, table.Column{Truncate: table.TrimEnd}, table.Column{Color: table.Dim})
+			t.Indent = "    "
+			for _, pr := range groups[label] {
+				t.Row(fmt.Sprintf("#%d", pr.Number), pr.Title, "@"+pr.Author)
+			}
+			printTable(t)
+		}
+		fmt.Println()
+	}
+
+	if len(closed) > 0 {
+		fmt.Println("Closed Issues:")
+		t := table.New(table.Column{Color: table.Yellow}, table.Column{Truncate: table.TrimEnd}, table.Column{Color: table.Dim})
+		for _, i := range closed {
+			t.Row(fmt.Sprintf("#%d", i.Number), i.Title, "closed by "+i.ClosedBy)
+		}
+		printTable(t)
+		fmt.Println()
+	}
+
 	fmt.Println("Recent Commits:")
+	t := table.New(table.Column{Color: table.Yellow}, table.Column{Truncate: table.TrimEnd})
 	for _, c := range r.Commits[:min(5, len(r.Commits))] {
-		msg := c.Message
-		if len(msg) > 60 {
-			msg = msg[:57] + "..."
-		}
-		fmt.Printf("  %s  %s\n", c.SHA[:7], msg)
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		t.Row(c.SHA[:min(7, len(c.SHA))], subject)
 	}
+	printTable(t)
 
 	if len(r.Commits) > 5 {
 		fmt.Printf("  ... and %d more commits\n", len(r.Commits)-5)
 	}
 }
 
+// printVendored reports the upstream bumps of vendored dependencies and the
+// changes made under their paths.
+func printVendored(bumps []vendored.Bump) {
+	fmt.Println("Vendored Dependencies:")
+	for _, b := range bumps {
+		fmt.Printf("  %s (%s)", b.Name, b.Path)
+		switch {
+		case b.NoPatch && !b.Bumped():
+			fmt.Print(": version file changed, patch not available")
+		case b.Bumped() && b.From == "":
+			fmt.Printf(": added at %s", shortVersion(b.To))
+		case b.Bumped() && b.To == "":
+			fmt.Printf(": removed, was %s", shortVersion(b.From))
+		case b.Bumped():
+			fmt.Printf(": %s bumped from %s to %s", b.Upstream, shortVersion(b.From), shortVersion(b.To))
+			if n := b.UpstreamCommits; n != nil && *n >= 0 {
+				fmt.Printf(" (~%d upstream commits)", *n)
+			} else if n != nil {
+				fmt.Printf(" (moved back %d upstream commits)", -*n)
+			}
+		default:
+			fmt.Print(": upstream version unchanged")
+		}
+		fmt.Println()
+		if b.URL != "" {
+			fmt.Printf("    %s\n", b.URL)
+		}
+		if b.Files > 0 {
+			fmt.Printf("    %s changed under %s (+%d -%d)\n", plural(b.Files, "file"), b.Path, b.Additions, b.Deletions)
+		}
+	}
+}
+
+// shortVersion abbreviates a commit SHA the way the rest of the output does
+// and leaves tags alone.
+func shortVersion(v string) string {
+	if len(v) == 40 && strings.Trim(v, "0123456789abcdef") == "" {
+		return v[:7]
+	}
+	return v
+}
+
+// printDirectories lists the most changed directories with their share of
+// the changed lines.
+func printDirectories(dirs []filter.DirChanges) {
+	total := 0
+	for _, d := range dirs {
+		total += d.Additions + d.Deletions
+	}
+	t := table.New(
+		table.Column{Header: "Files", Right: true},
+		table.Column{Header: "+Add", Right: true, Signed: true},
+		table.Column{Header: "-Del", Right: true, Signed: true},
+		table.Column{Header: "Share", Right: true},
+		table.Column{Header: "Directory", Truncate: table.TrimStart},
+	)
+	for _, d := range dirs[:min(20, len(dirs))] {
+		share := 0.0
+		if total > 0 {
+			share = float64(d.Additions+d.Deletions) * 100 / float64(total)
+		}
+		t.Row(fmt.Sprint(d.Files), fmt.Sprintf("+%d", d.Additions), fmt.Sprintf("-%d", d.Deletions), fmt.Sprintf("%.0f%%", share), d.Dir)
+	}
+	printTable(t)
+	if len(dirs) > 20 {
+		fmt.Printf("  ... and %d more directories\n", len(dirs)-20)
+	}
+}
+
+// printNotices lists the breaking changes of a range, then its deprecations.
+func printNotices(notices []changelog.Notice) {
+	for _, kind := range []string{changelog.KindBreaking, changelog.KindDeprecation} {
+		t := table.New(table.Column{Color: table.Yellow}, table.Column{Truncate: table.TrimEnd})
+		for _, n := range notices {
+			if n.Kind == kind {
+				t.Row(n.Ref, n.Title)
+			}
+		}
+		if t.Len() == 0 {
+			continue
+		}
+		if kind == changelog.KindBreaking {
+			fmt.Println("⚠ Breaking Changes:")
+		} else {
+			fmt.Println("Deprecations:")
+		}
+		printTable(t)
+	}
+}
+
+func init() {
+	addRepoFlag(CompareCmd)
+	CompareCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON (same as --format json)")
+	CompareCmd.Flags().StringVarP(&compareFormat, "format", "f", "text", "Output format: text, json, md, html, csv or tsv")
+	CompareCmd.Flags().StringVarP(&compareOut, "out", "o", "", "Write the output to a file instead of stdout")
+	CompareCmd.Flags().BoolVar(&compareLive, "live", false, "Fetch file changes from GitHub when the pair is not directly cached, and commit patches to detect backports")
+	CompareCmd.Flags().BoolVar(&cacheRefs, "cache-refs", false, "Cache on-demand comparisons of refs that are not cached releases")
+	CompareCmd.Flags().BoolVar(&showAllFiles, "all", false, "Include vendored, generated and binary files")
+	CompareCmd.Flags().BoolVar(&demoteGenerated, "demote-generated", false, "Move likely generated files out of the top files list")
+	CompareCmd.Flags().StringSliceVar(&includePaths, "path", nil, "Only show files under this path or matching this glob (repeatable)")
+	CompareCmd.Flags().BoolVar(&compareNotes, "notes", false, "Show what the release notes in the range added")
+	CompareCmd.Flags().BoolVar(&compareByDir, "by-dir", false, "Roll the file changes up into directory totals")
+	CompareCmd.Flags().IntVar(&compareDepth, "depth", 1, "Path segments of the directories of --by-dir")
+	CompareCmd.Flags().BoolVar(&skipAnalyzers, "no-analyzers", false, "Skip the analyzers of the config")
+	CompareCmd.Flags().BoolVar(&compareNoPatches, "no-patches", false, "Leave the file patches out of the JSON output")
+	CompareCmd.Flags().StringVar(&milestoneTitle, "milestone", "", "Check which items of this GitHub milestone shipped in the range")
+	addTemplateFlag(CompareCmd)
+	addSchemaFlag(CompareCmd)
+	CompareCmd.Flags().StringSliceVar(&excludePaths, "exclude", nil, "Hide files under this path or matching this glob (repeatable)")
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
 	}
 	return b
 }
+
+// renamedFrom notes the path a renamed file was moved from.
+func renamedFrom(f cache.FileChange) string {
+	if f.PreviousFilename == "" {
+		return ""
+	}
+	return " (from " + f.PreviousFilename + ")"
+}
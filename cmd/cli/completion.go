@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var CompletionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish>",
+	Short: "Generate a shell completion script",
+	Long: `Prints a completion script for bash, zsh or fish. Besides commands and
+flags, it completes the cached release tags of compare and diff and the
+cached repositories of --repo, read from the cache when Tab is pressed.
+
+Example:
+  source <(ordiff completion bash)
+  ordiff completion zsh > "${fpath[1]}/_ordiff"
+  ordiff completion fish > ~/.config/fish/completions/ordiff.fish`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		root := cmd.Root()
+		var err error
+		switch args[0] {
+		case "bash":
+			err = root.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = root.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = root.GenFishCompletion(os.Stdout, true)
+		}
+		if err != nil {
+			log.Fatalf("Failed to generate completion: %v", err)
+		}
+	},
+}
+
+// completionDB opens the cache for completion, or returns nil when there is
+// none yet. Completion must never fail loudly, nor create an empty database.
+func completionDB() cache.Store {
+	backend, dsn := config.Storage()
+	if backend == "sqlite" {
+		if _, err := os.Stat(dsn); err != nil {
+			return nil
+		}
+	}
+	db, err := cache.OpenStore(backend, dsn)
+	if err != nil {
+		return nil
+	}
+	return db
+}
+
+// completeTags completes the first n arguments with the cached release tags
+// of the selected repository, newest first, described by their publish
+// date.
+func completeTags(n int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= n {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		owner, repo, ok := completionRepo()
+		if !ok {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		db := completionDB()
+		if db == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		defer db.Close()
+
+		releases, err := db.GetReleases(owner, repo)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var tags []string
+		for _, r := range releases {
+			if strings.HasPrefix(r.TagName, toComplete) {
+				tags = append(tags, r.TagName+"\t"+r.PublishedAt.Format("2006-01-02"))
+			}
+		}
+		return tags, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	}
+}
+
+// completionRepo is defaultRepo without exiting: false when no repository
+// is selected.
+func completionRepo() (string, string, bool) {
+	if repoFlag != "" {
+		owner, repo, ok := strings.Cut(repoFlag, "/")
+		return owner, repo, ok && owner != "" && repo != ""
+	}
+	loadConfig()
+	owner := viper.GetString("default_owner")
+	repo := viper.GetString("default_repo")
+	return owner, repo, owner != "" && repo != ""
+}
+
+// completeRepos completes --repo with the repositories in the cache.
+func completeRepos(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	db := completionDB()
+	if db == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer db.Close()
+
+	repos, err := db.GetRepositories()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var names []string
+	for _, r := range repos {
+		if name := r.Owner + "/" + r.Repo; strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"ordiff/internal/filter"
+
+	"github.com/spf13/cobra"
+)
+
+var componentFiles int
+
+var ComponentsCmd = &cobra.Command{
+	Use:   "components [<from> <to>]",
+	Short: "Summarize changes per monorepo component",
+	Long: `Groups the file changes between two releases by the components configured
+in .ordiff.yaml, so the parts of a monorepo that changed stand out:
+
+  components:
+    - name: api
+      paths: [api/, server/]
+    - name: cli
+      paths: [cmd/]
+    - name: docs
+      paths: ["docs/", "*.md"]
+
+A file belongs to the first component with a matching path (a file, a
+directory or a glob). Files no component matches are listed as "other".
+
+Example:
+  ordiff components v1.29.0 v1.30.0
+  ordiff components --since v1.29.0 --files 5`,
+	Args: rangeArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		comps := components()
+		if len(comps) == 0 {
+			log.Fatal("No components configured. Add a components section to the config (see 'ordiff components --help').")
+		}
+
+		db := openDB()
+		defer db.Close()
+
+		from, to := resolveRange(db, owner, repo, args)
+
+		result, err := newFetcher(owner, repo).Compare(db, from, to, false)
+		if err != nil {
+			log.Fatalf("Failed to compare: %v", err)
+		}
+
+		groups := filter.GroupByComponent(result.Files, comps)
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(map[string]interface{}{
+				"from_release": result.FromRelease.TagName,
+				"to_release":   result.ToRelease.TagName,
+				"components":   groups,
+			})
+			return
+		}
+
+		fmt.Printf("\n=== Components %s → %s ===\n\n", result.FromRelease.TagName, result.ToRelease.TagName)
+		printComponents(groups, componentFiles)
+	},
+}
+
+func init() {
+	addRangeFlags(ComponentsCmd)
+	ComponentsCmd.Flags().IntVar(&componentFiles, "files", 0, "List the N most changed files of each component")
+}
+
+// printComponents prints a table of files and churn per component, and with
+// files above zero the most changed files of each.
+func printComponents(groups []filter.ComponentChanges, files int) {
+	width := len("Component")
+	for _, g := range groups {
+		width = max(width, len(g.Name))
+	}
+
+	fmt.Printf("  %-*s  %5s  %7s  %7s\n", width, "Component", "Files", "+Add", "-Del")
+	for _, g := range groups {
+		if len(g.Files) == 0 {
+			fmt.Printf("  %-*s  %5s\n", width, g.Name, "-")
+			continue
+		}
+		fmt.Printf("  %-*s  %5d  %+7d  %7d\n", width, g.Name, len(g.Files), g.Additions, -g.Deletions)
+		if files <= 0 {
+			continue
+		}
+		sort.Slice(g.Files, func(i, j int) bool {
+			return g.Files[i].Changes > g.Files[j].Changes
+		})
+		for _, f := range g.Files[:min(files, len(g.Files))] {
+			fmt.Printf("  %-*s    %+4d %-4d  %s\n", width, "", f.Additions, f.Deletions, f.Filename)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/config"
+	"ordiff/internal/filter"
+	"ordiff/internal/github"
+	"ordiff/internal/gitlab"
+	"ordiff/internal/local"
+	"ordiff/internal/provider"
+	"ordiff/internal/report"
+	"ordiff/internal/retry"
+	"ordiff/internal/risk"
+	"ordiff/internal/vendored"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func loadConfig() {
+	config.Load()
+}
+
+// ConfigureGitHubHost points GitHub requests and links at a GitHub Enterprise
+// Server instance when one is configured.
+func ConfigureGitHubHost() {
+	apiURL := config.GitHubAPIURL()
+	if apiURL == "" {
+		return
+	}
+	web, err := github.UseEnterprise(apiURL)
+	if err != nil {
+		log.Fatalf("Invalid GitHub API URL %q: %v", apiURL, err)
+	}
+	report.BaseURL = web
+}
+
+var repoFlag string
+
+// defaultRepo returns the repository selected with --repo, falling back to
+// the default repository from the config.
+func defaultRepo() (string, string) {
+	loadConfig()
+
+	if repoFlag != "" {
+		owner, repo, ok := strings.Cut(repoFlag, "/")
+		if !ok || owner == "" || repo == "" {
+			log.Fatalf("Invalid --repo %q, expected owner/name", repoFlag)
+		}
+		return owner, repo
+	}
+
+	owner := viper.GetString("default_owner")
+	repo := viper.GetString("default_repo")
+
+	if owner == "" || repo == "" {
+		log.Fatal("No default repository. Run 'ordiff index <owner> <repo>' first.")
+	}
+	return owner, repo
+}
+
+func newProviderFetcher(owner, repo, name, location string) provider.Fetcher {
+	switch name {
+	case provider.GitHub, "":
+		return newFetcher(owner, repo)
+	case provider.GitLab:
+		fetcher := gitlab.NewFetcher(owner, repo, os.Getenv("GITLAB_TOKEN"))
+		fetcher.SetRetryPolicy(retryPolicy())
+		return fetcher
+	case provider.Local:
+		fetcher, err := local.NewFetcher(owner, repo, location)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return fetcher
+	}
+	log.Fatalf("Unknown provider %q (expected github or gitlab)", name)
+	return nil
+}
+
+func addRepoFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&repoFlag, "repo", "r", "", "Repository as owner/name (defaults to the configured repository)")
+	cmd.RegisterFlagCompletionFunc("repo", completeRepos)
+}
+
+// components returns the monorepo components from the config, in order.
+func components() []filter.Component {
+	var comps []filter.Component
+	if err := viper.UnmarshalKey("components", &comps); err != nil {
+		log.Fatalf("Invalid components in config: %v", err)
+	}
+	for _, c := range comps {
+		if c.Name == "" || len(c.Paths) == 0 {
+			log.Fatalf("Invalid components in config: every component needs a name and paths")
+		}
+	}
+	return comps
+}
+
+// vendoredDeps returns the vendored dependencies from the config, with
+// their defaults filled in.
+func vendoredDeps() []vendored.Dependency {
+	var vendoredDeps []vendored.Dependency
+	if err := viper.UnmarshalKey("vendored", &vendoredDeps); err != nil {
+		log.Fatalf("Invalid vendored in config: %v", err)
+	}
+	for i := range vendoredDeps {
+		if err := vendoredDeps[i].Validate(); err != nil {
+			log.Fatalf("Invalid vendored in config: %v", err)
+		}
+	}
+	return vendoredDeps
+}
+
+func riskConfig() risk.Config {
+	cfg, err := config.Risk()
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	return cfg
+}
+
+func generatedPatterns() []string {
+	if patterns := viper.GetStringSlice("generated_patterns"); len(patterns) > 0 {
+		return patterns
+	}
+	return filter.DefaultGeneratedPatterns
+}
+
+func openDB() cache.Store {
+	db, err := cache.OpenStore(config.Storage())
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+
+	m, err := config.Mailmap()
+	if err != nil {
+		log.Fatalf("Failed to read mailmap: %v", err)
+	}
+	db.SetMailmap(m)
+	return db
+}
+
+var (
+	appID             int64
+	appInstallationID int64
+	appPrivateKeyFile string
+)
+
+func newFetcher(owner, repo string) *github.Fetcher {
+	creds, err := github.AppCredentialsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid GitHub App configuration: %v", err)
+	}
+
+	if appID != 0 {
+		key, err := os.ReadFile(appPrivateKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to read app private key: %v", err)
+		}
+		creds = &github.AppCredentials{AppID: appID, InstallationID: appInstallationID, PrivateKey: key}
+	}
+
+	var fetcher *github.Fetcher
+	if creds == nil {
+		token := config.GitHubToken()
+		fetcher = github.NewFetcher(owner, repo, &token)
+	} else if fetcher, err = github.NewAppFetcher(owner, repo, *creds); err != nil {
+		log.Fatalf("Failed to configure GitHub App auth: %v", err)
+	}
+	fetcher.SetRetryPolicy(retryPolicy())
+	return fetcher
+}
+
+// retries is set by --retries; below 0 the config decides.
+var retries = -1
+
+// retryPolicy returns the retry policy of the config, with the attempts of
+// --retries if given.
+func retryPolicy() retry.Policy {
+	p, err := config.Retry()
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	if retries >= 0 {
+		p.Attempts = retries
+	}
+	return p
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"ordiff/internal/github"
+
+	"github.com/spf13/cobra"
+)
+
+var ContainsCmd = &cobra.Command{
+	Use:   "contains <sha|pr>",
+	Short: "Find the first release that contains a commit or pull request",
+	Long: `Finds the earliest release whose range includes a commit or a pull request.
+
+The argument is a commit SHA (at least 7 characters) or a pull request number
+such as #1234. The range comes from the indexed release pairs; for changes no
+pair contains, the first release published after the change is shown instead.
+
+Example:
+  ordiff contains 3f2a9c1
+  ordiff contains '#1234' --repo ollama/ollama
+  ordiff contains 1234 --json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		c, err := github.NewFetcher(owner, repo, nil).ContainingRelease(db, args[0])
+		if err != nil {
+			log.Fatalf("Failed to find release: %v", err)
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(c)
+			return
+		}
+
+		subject := c.Ref
+		if c.Kind == "pull_request" {
+			subject = fmt.Sprintf("#%d", *c.PrNumber)
+		} else if len(c.SHA) > 7 {
+			subject = c.SHA[:7]
+		}
+
+		if c.Release == "" {
+			fmt.Printf("%s is not released yet\n", subject)
+			return
+		}
+		fmt.Printf("%s first shipped in %s (%s)\n", subject, c.Release, c.PublishedAt.Format("2006-01-02"))
+		if c.FromRelease != "" {
+			fmt.Printf("  Range:  %s..%s", c.FromRelease, c.Release)
+			if c.ByDate {
+				fmt.Print(" (by date)")
+			}
+			fmt.Println()
+		} else if c.ByDate {
+			fmt.Println("  (by date)")
+		}
+		if c.Kind == "pull_request" && c.SHA != "" {
+			fmt.Printf("  Commit: %s\n", c.SHA[:min(7, len(c.SHA))])
+		} else if c.Kind == "commit" && c.PrNumber != nil {
+			fmt.Printf("  PR:     #%d\n", *c.PrNumber)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(ContainsCmd)
+	ContainsCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"ordiff/internal/cache"
+
+	"github.com/spf13/cobra"
+)
+
+type contributor struct {
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Commits int    `json:"commits"`
+}
+
+var ContributorsCmd = &cobra.Command{
+	Use:   "contributors [<from> <to>]",
+	Short: "List commit authors between two releases",
+	Long: `Ranks commit authors by the number of commits they landed between two
+releases.
+
+Example:
+  ordiff contributors v0.1.0 v0.2.0
+  ordiff contributors --since v2.0.0`,
+	Args: rangeArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from, to := resolveRange(db, owner, repo, args)
+
+		commits, err := db.GetCommitsBetween(owner, repo, from, to)
+		if err != nil {
+			log.Fatalf("Failed to get commits: %v", err)
+		}
+
+		contributors := rankContributors(commits)
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(contributors)
+			return
+		}
+
+		fmt.Printf("\n=== Contributors %s → %s ===\n\n", from, to)
+		for _, c := range contributors {
+			fmt.Printf("  %5d  %s\n", c.Commits, c.Name)
+		}
+	},
+}
+
+func rankContributors(commits []cache.Commit) []contributor {
+	byName := map[string]*contributor{}
+	var contributors []*contributor
+	for _, c := range commits {
+		ct, ok := byName[c.Author]
+		if !ok {
+			ct = &contributor{Name: c.Author, Email: c.AuthorEmail}
+			byName[c.Author] = ct
+			contributors = append(contributors, ct)
+		}
+		ct.Commits++
+	}
+
+	sort.SliceStable(contributors, func(i, j int) bool {
+		return contributors[i].Commits > contributors[j].Commits
+	})
+
+	out := make([]contributor, len(contributors))
+	for i, c := range contributors {
+		out[i] = *c
+	}
+	return out
+}
+
+func init() {
+	addRangeFlags(ContributorsCmd)
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/table"
+
+	"github.com/spf13/cobra"
+)
+
+var crossCompareLimit int
+
+var CrossCompareCmd = &cobra.Command{
+	Use:   "cross-compare <upstream/repo[@ref]> <fork/repo[@ref]>",
+	Short: "Compare a fork against upstream",
+	Long: `Compares a ref of a fork with a ref of the repository it was forked from:
+the commits only in the fork, the commits only upstream (how far behind the
+fork is, also in time since the last shared commit), and the files changed
+on both sides since then, which are where merging upstream may conflict.
+
+Each side is owner/repo@ref, where ref is a tag, branch or commit SHA; the
+default branch is used without one. Both repositories must be in the same
+GitHub fork network. Nothing is read from or written to the cache.
+
+Example:
+  ordiff cross-compare ggml-org/llama.cpp@b4000 someone/llama.cpp@main
+  ordiff cross-compare ollama/ollama someone/ollama@v0.5.0-custom --json`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		upstream := parseRepoRef(args[0])
+		fork := parseRepoRef(args[1])
+
+		up := newFetcher(upstream.Owner, upstream.Repo)
+		fk := newFetcher(fork.Owner, fork.Repo)
+		for _, side := range []struct {
+			ref *github.RepoRef
+			f   *github.Fetcher
+		}{{&upstream, up}, {&fork, fk}} {
+			if side.ref.Ref != "" {
+				continue
+			}
+			branch, err := side.f.DefaultBranch()
+			if err != nil {
+				log.Fatalf("Failed to find the default branch of %s/%s: %v", side.ref.Owner, side.ref.Repo, err)
+			}
+			side.ref.Ref = branch
+		}
+
+		d, err := up.Diverge(fk, upstream.Ref, fork.Ref)
+		if err != nil {
+			log.Fatalf("Failed to compare: %v", err)
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(d)
+			return
+		}
+		printDivergence(d)
+	},
+}
+
+// parseRepoRef parses owner/repo@ref; the ref is optional.
+func parseRepoRef(arg string) github.RepoRef {
+	name, ref, _ := strings.Cut(arg, "@")
+	owner, repo, ok := strings.Cut(name, "/")
+	if !ok || owner == "" || repo == "" || strings.HasSuffix(arg, "@") {
+		log.Fatalf("Invalid %q, expected owner/repo@ref", arg)
+	}
+	return github.RepoRef{Owner: owner, Repo: repo, Ref: ref}
+}
+
+func printDivergence(d *github.Divergence) {
+	fmt.Printf("\n=== %s → %s ===\n\n", d.Upstream, d.Fork)
+
+	switch d.Status {
+	case github.DivergenceIdentical:
+		fmt.Println("The fork is identical to upstream.")
+		return
+	case github.DivergenceAhead:
+		fmt.Printf("The fork is %d commits ahead and up to date with upstream.\n", d.AheadBy)
+	case github.DivergenceBehind:
+		fmt.Printf("The fork is %d commits behind upstream, with no commits of its own.\n", d.BehindBy)
+	default:
+		fmt.Printf("The fork has diverged: %d commits ahead, %d commits behind.\n", d.AheadBy, d.BehindBy)
+	}
+	if d.MergeBase != "" {
+		fmt.Printf("Last shared commit: %s (%s)", d.MergeBase[:min(7, len(d.MergeBase))], d.MergeBaseDate.Format("2006-01-02"))
+		if d.BehindBy > 0 {
+			fmt.Printf(", %d days older than %s", int(d.UpstreamDate.Sub(d.MergeBaseDate).Hours()/24), d.Upstream.Ref)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+
+	printSideCommits("Commits only in the fork", d.ForkCommits, d.AheadBy)
+	printSideCommits("Commits only upstream", d.UpstreamCommits, d.BehindBy)
+
+	fmt.Printf("Files changed since the last shared commit: %d in the fork, %d upstream\n", d.ForkFiles, d.UpstreamFiles)
+	if len(d.DivergedFiles) == 0 {
+		fmt.Println("No file was changed on both sides.")
+		return
+	}
+	fmt.Printf("\nChanged on both sides (%d):\n", len(d.DivergedFiles))
+	t := table.New(
+		table.Column{Header: "Fork", Right: true, Signed: true},
+		table.Column{Right: true, Signed: true},
+		table.Column{Header: "Upstream", Right: true, Signed: true},
+		table.Column{Right: true, Signed: true},
+		table.Column{Header: "File", Truncate: table.TrimStart},
+	)
+	shown := d.DivergedFiles
+	if crossCompareLimit > 0 {
+		shown = shown[:min(crossCompareLimit, len(shown))]
+	}
+	for _, f := range shown {
+		t.Row(fmt.Sprintf("+%d", f.ForkAdditions), fmt.Sprintf("-%d", f.ForkDeletions),
+			fmt.Sprintf("+%d", f.UpstreamAdditions), fmt.Sprintf("-%d", f.UpstreamDeletions), f.Filename)
+	}
+	printTable(t)
+	if len(shown) < len(d.DivergedFiles) {
+		fmt.Printf("  ... and %d more files\n", len(d.DivergedFiles)-len(shown))
+	}
+}
+
+// printSideCommits lists the newest commits of one side, newest first.
+// total is the count GitHub reports, which may exceed the commits listed.
+func printSideCommits(title string, commits []cache.Commit, total int) {
+	if total == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, total)
+	t := table.New(table.Column{Color: table.Yellow}, table.Column{Color: table.Dim}, table.Column{Truncate: table.TrimEnd})
+	shown := 0
+	for i := len(commits) - 1; i >= 0 && (crossCompareLimit <= 0 || shown < crossCompareLimit); i-- {
+		c := commits[i]
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		t.Row(c.SHA[:min(7, len(c.SHA))], c.Date.Format("2006-01-02"), subject)
+		shown++
+	}
+	printTable(t)
+	if total > shown {
+		fmt.Printf("  ... and %d more commits\n", total-shown)
+	}
+	fmt.Println()
+}
+
+func init() {
+	CrossCompareCmd.Flags().IntVarP(&crossCompareLimit, "limit", "n", 20, "Commits per side and files to show (0 for all)")
+	CrossCompareCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var migrateDryRun bool
+
+var DBCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the cache database",
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Bring the cache schema up to date",
+	Long: `Applies the schema migrations the cache is missing, oldest first. Every
+command migrates the cache when it opens it, so this is only needed to
+upgrade ahead of time or to see what would change with --dry-run.
+
+Caches created before schema versioning start at version 0 and are
+upgraded in place; nothing needs to be deleted or re-indexed.
+
+Example:
+  ordiff db migrate
+  ordiff db migrate --dry-run`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		backend, path := config.Storage()
+		if backend != cache.BackendSQLite {
+			log.Fatalf("Schema migrations apply to the sqlite backend, not %s", backend)
+		}
+
+		db, err := cache.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		current, err := db.SchemaVersion()
+		if err != nil {
+			log.Fatalf("Failed to read schema version: %v", err)
+		}
+		fmt.Printf("Schema version: %d (latest %d)\n", current, cache.LatestSchemaVersion())
+
+		pending, err := db.PendingMigrations()
+		if err != nil {
+			log.Fatalf("Failed to list migrations: %v", err)
+		}
+		if len(pending) == 0 {
+			fmt.Println("Schema is up to date")
+			return
+		}
+
+		if migrateDryRun {
+			fmt.Printf("%d pending migrations:\n", len(pending))
+			for _, m := range pending {
+				fmt.Printf("  %d  %s\n", m.Version, m.Description)
+			}
+			return
+		}
+
+		applied, err := db.Migrate()
+		for _, m := range applied {
+			fmt.Printf("Applied %d  %s\n", m.Version, m.Description)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		fmt.Println("Migration complete!")
+	},
+}
+
+func init() {
+	dbMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "List pending migrations without applying them")
+	DBCmd.AddCommand(dbMigrateCmd)
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/exec"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/filter"
+	"ordiff/internal/github"
+	"ordiff/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffColor   string
+	diffNoPager bool
+)
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff <from> <to> [path]",
+	Short: "Show the unified diff between two releases",
+	Long: `Prints the cached patches of the files changed between two releases as a
+unified diff. An optional path limits the output to one file, a directory,
+or a glob such as '*.go' or 'server/**/*.go'.
+
+For non-adjacent releases the hunks of every intervening pair are shown one
+after another; use compare --live for a single direct comparison.
+
+Output is colored and piped through $PAGER (default 'less -FRX') when
+writing to a terminal. --color auto leaves colors out with --no-color or
+NO_COLOR.
+
+Example:
+  ordiff diff v0.1.0 v0.2.0
+  ordiff diff v0.1.0 v0.2.0 server/
+  ordiff diff v0.1.0 v0.2.0 go.mod --color never`,
+	ValidArgsFunction: completeTags(2),
+	Args:              cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from := resolveRef(db, owner, repo, args[0])
+		to := resolveRef(db, owner, repo, args[1])
+
+		fetcher := newFetcher(owner, repo)
+		result, err := fetcher.Compare(db, from, to, false)
+		if err != nil {
+			log.Fatalf("Failed to compare: %v", err)
+		}
+		if err := fetcher.LoadPatches(db, result); err != nil {
+			log.Fatalf("Failed to load patches: %v", err)
+		}
+
+		var files []cache.FileChange
+		for _, fc := range result.Files {
+			if len(args) < 3 || filter.MatchPath(args[2], fc.Filename) {
+				files = append(files, fc)
+			}
+		}
+		if len(files) == 0 {
+			if len(args) == 3 {
+				log.Fatalf("No changes to %s between %s and %s", args[2], from, to)
+			}
+			fmt.Println("No file changes.")
+			return
+		}
+
+		tty := isTerminal(os.Stdout)
+		var color bool
+		switch diffColor {
+		case "always":
+			color = true
+		case "never":
+			color = false
+		case "auto":
+			color = useColor()
+		default:
+			log.Fatalf("Invalid --color %q (expected auto, always or never)", diffColor)
+		}
+
+		var out io.Writer = os.Stdout
+		if tty && !diffNoPager {
+			pager, err := startPager()
+			if err != nil {
+				slog.Warn("Failed to start pager", "err", err)
+			} else {
+				defer pager.Close()
+				out = pager
+			}
+		}
+
+		w := bufio.NewWriter(out)
+		defer w.Flush()
+
+		if result.FileSource == github.FilesAggregated {
+			fmt.Fprintf(w, "# %s and %s are not adjacent; hunks of each intervening release pair follow each other.\n", from, to)
+		}
+		for _, fc := range files {
+			report.UnifiedDiff(w, fc, color)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(DiffCmd)
+	DiffCmd.Flags().StringVar(&diffColor, "color", "auto", "Color the diff: auto, always or never")
+	DiffCmd.Flags().BoolVar(&diffNoPager, "no-pager", false, "Do not pipe the output through a pager")
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// pager is the stdin of a running pager process. Close waits for the user to
+// quit it.
+type pager struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func (p *pager) Close() error {
+	p.WriteCloser.Close()
+	return p.cmd.Wait()
+}
+
+func startPager() (*pager, error) {
+	command := os.Getenv("PAGER")
+	if command == "" {
+		command = "less -FRX"
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &pager{WriteCloser: stdin, cmd: cmd}, nil
+}
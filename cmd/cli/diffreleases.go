@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	"ordiff/internal/version"
+
+	"github.com/spf13/cobra"
+)
+
+type releaseSetDiff struct {
+	Owner       string           `json:"owner"`
+	Repo        string           `json:"repo"`
+	GitHubCount int              `json:"github_count"`
+	CachedCount int              `json:"cached_count"`
+	Missing     []string         `json:"missing"`
+	Deleted     []string         `json:"deleted"`
+	UpToDate    bool             `json:"up_to_date"`
+	StaleData   *staleDataReport `json:"stale_data,omitempty"`
+}
+
+type staleDataReport struct {
+	Releases          int      `json:"releases"`
+	Pairs             int      `json:"pairs"`
+	OldestDataVersion int      `json:"oldest_data_version"`
+	Fixes             []string `json:"fixes"`
+}
+
+var DiffReleasesCmd = &cobra.Command{
+	Use:   "diff-releases",
+	Short: "Check which upstream releases are missing from the cache",
+	Long: `Fetches only the release list from GitHub (or GitLab) and compares its tags with the
+cached releases, reporting releases that are not cached yet and cached
+releases that no longer exist on GitHub. Exits with status 1 when the cache
+is out of date.
+
+Example:
+  ordiff diff-releases
+  ordiff diff-releases --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		cached, err := db.GetReleases(owner, repo)
+		if err != nil {
+			log.Fatalf("Failed to get releases: %v", err)
+		}
+
+		providerName, location, err := db.GetRepositoryProvider(owner, repo)
+		if err != nil {
+			slog.Warn("Failed to look up repository provider", "err", err)
+		}
+
+		remote, err := newProviderFetcher(owner, repo, providerName, location).FetchReleases()
+		if err != nil {
+			log.Fatalf("Failed to fetch releases: %v", err)
+		}
+
+		diff := releaseSetDiff{
+			Owner:       owner,
+			Repo:        repo,
+			GitHubCount: len(remote),
+			CachedCount: len(cached),
+			Missing:     []string{},
+			Deleted:     []string{},
+		}
+
+		cachedTags := map[string]bool{}
+		for _, r := range cached {
+			cachedTags[r.TagName] = true
+		}
+		remoteTags := map[string]bool{}
+		for _, r := range remote {
+			remoteTags[r.TagName] = true
+			if !cachedTags[r.TagName] {
+				diff.Missing = append(diff.Missing, r.TagName)
+			}
+		}
+		for _, r := range cached {
+			if !remoteTags[r.TagName] {
+				diff.Deleted = append(diff.Deleted, r.TagName)
+			}
+		}
+		diff.UpToDate = len(diff.Missing) == 0 && len(diff.Deleted) == 0
+
+		if minVersion := version.StaleBefore(); minVersion > 0 {
+			stale, err := db.StaleDataBefore(owner, repo, minVersion)
+			if err != nil {
+				slog.Warn("Failed to check cached data versions", "err", err)
+			} else if stale.Releases > 0 || stale.Pairs > 0 {
+				report := &staleDataReport{
+					Releases:          stale.Releases,
+					Pairs:             stale.Pairs,
+					OldestDataVersion: stale.OldestDataVersion,
+				}
+				for _, fix := range version.FixesSince(stale.OldestDataVersion) {
+					report.Fixes = append(report.Fixes, fix.Description)
+				}
+				diff.StaleData = report
+			}
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(diff)
+		} else {
+			fmt.Printf("Releases for %s/%s: %d on GitHub, %d cached\n\n", owner, repo, diff.GitHubCount, diff.CachedCount)
+			if diff.UpToDate {
+				fmt.Println("Cache is up to date.")
+			}
+			if len(diff.Missing) > 0 {
+				fmt.Println("Not cached:")
+				for _, t := range diff.Missing {
+					fmt.Printf("  %s\n", t)
+				}
+				fmt.Println()
+			}
+			if len(diff.Deleted) > 0 {
+				fmt.Println("Cached but no longer on GitHub:")
+				for _, t := range diff.Deleted {
+					fmt.Printf("  %s\n", t)
+				}
+				fmt.Println()
+			}
+			if !diff.UpToDate {
+				fmt.Printf("Run 'ordiff index %s %s' to refresh the cache.\n", owner, repo)
+			}
+			if diff.StaleData != nil {
+				fmt.Printf("\nWarning: %d releases and %d release pairs were cached before these fixes:\n", diff.StaleData.Releases, diff.StaleData.Pairs)
+				for _, fix := range diff.StaleData.Fixes {
+					fmt.Printf("  - %s\n", fix)
+				}
+				fmt.Printf("Re-run 'ordiff index %s %s' to refresh them.\n", owner, repo)
+			}
+		}
+
+		if !diff.UpToDate {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(DiffReleasesCmd)
+	DiffReleasesCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
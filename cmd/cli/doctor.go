@@ -0,0 +1,429 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/config"
+	"ordiff/internal/filter"
+	"ordiff/internal/github"
+	"ordiff/internal/provider"
+	"ordiff/internal/table"
+	"ordiff/internal/version"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// doctorTimeout bounds each GitHub request of doctor, so an unreachable API
+// is reported instead of waited on.
+const doctorTimeout = 15 * time.Second
+
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the config, cache and GitHub access for problems",
+	Long: `Checks everything ordiff depends on and prints how to fix what is wrong:
+the config file and its settings, the cache (schema version, integrity and
+the releases cached for the repository), the GitHub token and its scopes,
+whether the API is reachable, and the rate limit left.
+
+Run it when commands or MCP tools come back empty: a repository that was
+never indexed, an interrupted index, a cache at another path than expected
+or a token without access to a private repository all show up here.
+
+The repository checked is the one given with --repo, else the default
+repository. Exits with status 1 when a check fails.
+
+Example:
+  ordiff doctor
+  ordiff doctor --repo ollama/ollama
+  ordiff doctor --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var d diagnosis
+		owner, repo, haveRepo := d.checkConfig()
+		prov := d.checkCache(owner, repo, haveRepo)
+		d.checkGitHub(owner, repo, haveRepo && (prov == provider.GitHub || prov == ""))
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(d.checks)
+		} else {
+			d.print()
+		}
+		if d.failed() {
+			os.Exit(1)
+		}
+	},
+}
+
+// Check statuses.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+type check struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Detail  string `json:"detail"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+type diagnosis struct {
+	section string
+	checks  []check
+}
+
+func (d *diagnosis) add(status, name, detail, fix string) {
+	d.checks = append(d.checks, check{Section: d.section, Name: name, Status: status, Detail: detail, Fix: fix})
+}
+
+func (d *diagnosis) ok(name, detail string) {
+	d.add(checkOK, name, detail, "")
+}
+
+func (d *diagnosis) warn(name, detail, fix string) {
+	d.add(checkWarn, name, detail, fix)
+}
+
+func (d *diagnosis) fail(name, detail, fix string) {
+	d.add(checkFail, name, detail, fix)
+}
+
+func (d *diagnosis) failed() bool {
+	return slices.ContainsFunc(d.checks, func(c check) bool { return c.Status == checkFail })
+}
+
+// checkConfig checks the config file and its settings, and returns the
+// repository the other checks are about.
+func (d *diagnosis) checkConfig() (string, string, bool) {
+	d.section = "Config"
+
+	path := config.Path()
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	switch err := v.ReadInConfig(); {
+	case errors.Is(err, fs.ErrNotExist):
+		d.ok("Config file", "none at "+path+", defaults in use")
+	case err != nil:
+		d.fail("Config file", fmt.Sprintf("%s: %v", path, err), "Fix the YAML in "+path+", or point --config or ORDIFF_CONFIG at another file")
+	default:
+		d.ok("Config file", path)
+	}
+
+	var problems []string
+	if _, err := config.Risk(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := config.Retry(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := config.Mailmap(); err != nil {
+		problems = append(problems, "mailmap: "+err.Error())
+	}
+	var comps []filter.Component
+	if err := viper.UnmarshalKey("components", &comps); err != nil {
+		problems = append(problems, "components: "+err.Error())
+	}
+	for _, c := range comps {
+		if c.Name == "" || len(c.Paths) == 0 {
+			problems = append(problems, "components: every component needs a name and paths")
+			break
+		}
+	}
+	if len(problems) > 0 {
+		d.fail("Settings", strings.Join(problems, "; "), "Correct the settings in "+path)
+	} else {
+		d.ok("Settings", "valid")
+	}
+
+	if repoFlag != "" {
+		owner, repo, ok := strings.Cut(repoFlag, "/")
+		if !ok || owner == "" || repo == "" {
+			d.fail("Repository", fmt.Sprintf("invalid --repo %q", repoFlag), "Pass --repo as owner/name")
+			return "", "", false
+		}
+		d.ok("Repository", owner+"/"+repo+" (--repo)")
+		return owner, repo, true
+	}
+	owner, repo := viper.GetString("default_owner"), viper.GetString("default_repo")
+	if owner == "" || repo == "" {
+		d.warn("Repository", "no default repository", "Run 'ordiff index <owner> <repo>', which makes it the default, or pass --repo")
+		return "", "", false
+	}
+	d.ok("Repository", owner+"/"+repo+" (default)")
+	return owner, repo, true
+}
+
+// checkCache checks the cache database and what it holds for the
+// repository, and returns the repository's provider when it is cached.
+func (d *diagnosis) checkCache(owner, repo string, haveRepo bool) string {
+	d.section = "Cache"
+
+	backend, dsn := config.Storage()
+	var db *cache.DB
+	if backend == cache.BackendSQLite {
+		if _, err := os.Stat(dsn); err != nil {
+			d.fail("Database", "no cache at "+dsn, "Run 'ordiff index <owner> <repo>', or point --db or ORDIFF_DB at an existing cache")
+			return ""
+		}
+		// Opened without migrating, to report the version as found.
+		var err error
+		if db, err = cache.Open(dsn); err != nil {
+			d.fail("Database", fmt.Sprintf("%s: %v", dsn, err), "Check that "+dsn+" is a readable SQLite file")
+			return ""
+		}
+		d.ok("Database", dsn)
+	} else {
+		store, err := cache.OpenStore(backend, dsn)
+		if err != nil {
+			d.fail("Database", fmt.Sprintf("%s: %v", backend, err), "Check storage.backend and storage.dsn in the config, and that the server is running")
+			return ""
+		}
+		if db, _ = store.(*cache.DB); db == nil {
+			store.Close()
+			d.ok("Database", backend+" (not inspected further)")
+			return ""
+		}
+		d.ok("Database", backend)
+	}
+	defer db.Close()
+
+	schemaVersion, err := db.SchemaVersion()
+	latest := cache.LatestSchemaVersion()
+	switch {
+	case err != nil:
+		d.fail("Schema", err.Error(), "Run 'ordiff db migrate'")
+	case schemaVersion < latest:
+		d.warn("Schema", fmt.Sprintf("version %d, latest %d", schemaVersion, latest), "Run 'ordiff db migrate'; any other command also migrates the cache when it opens it")
+	case schemaVersion > latest:
+		d.fail("Schema", fmt.Sprintf("version %d is newer than this ordiff knows (%d)", schemaVersion, latest), "Upgrade ordiff to the version that last wrote the cache")
+	default:
+		d.ok("Schema", fmt.Sprintf("version %d (latest)", schemaVersion))
+	}
+
+	problems, err := db.IntegrityCheck()
+	switch {
+	case err != nil:
+		d.fail("Integrity", err.Error(), "Restore the cache from a backup, or delete it and index again")
+	case len(problems) > 0:
+		d.fail("Integrity", fmt.Sprintf("%d problems, first: %s", len(problems), problems[0]), "Restore the cache from a backup, or delete it and index again")
+	default:
+		d.ok("Integrity", "ok")
+	}
+
+	repos, err := db.GetRepositories()
+	if err != nil {
+		d.fail("Repositories", err.Error(), "Run 'ordiff db migrate'")
+		return ""
+	}
+	if len(repos) == 0 {
+		d.warn("Repositories", "none cached", "Run 'ordiff index <owner> <repo>'")
+	} else {
+		d.ok("Repositories", fmt.Sprintf("%d cached", len(repos)))
+	}
+	// An older schema may lack the data version columns; its warning comes
+	// first.
+	if len(repos) > 0 && schemaVersion == latest {
+		d.checkStaleData(db, repos)
+	}
+	if !haveRepo {
+		return ""
+	}
+
+	name := owner + "/" + repo
+	i := slices.IndexFunc(repos, func(r cache.Repository) bool { return r.Owner == owner && r.Repo == repo })
+	if i == -1 {
+		d.fail("Releases", "no releases cached for "+name, fmt.Sprintf("Run 'ordiff index %s %s'; commands and MCP tools return nothing until it is indexed", owner, repo))
+		return ""
+	}
+	d.ok("Releases", fmt.Sprintf("%d cached for %s", repos[i].Releases, name))
+
+	if run, err := db.GetIndexRun(owner, repo); err == nil && run != nil && !run.Finished {
+		d.warn("Index", fmt.Sprintf("interrupted after %d of %d release pairs (%s)", run.DonePairs, run.TotalPairs, run.UpdatedAt.Local().Format("2006-01-02 15:04")),
+			fmt.Sprintf("Run 'ordiff index --resume %s %s' to fetch the rest", owner, repo))
+	}
+	if repos[i].Provider == "" {
+		return provider.GitHub
+	}
+	return repos[i].Provider
+}
+
+// checkStaleData warns about every repository with releases or pairs cached
+// before a later data version fixed how they are stored.
+func (d *diagnosis) checkStaleData(db *cache.DB, repos []cache.Repository) {
+	minVersion := version.StaleBefore()
+	if minVersion == 0 {
+		return
+	}
+	stale := 0
+	for _, r := range repos {
+		name := r.Owner + "/" + r.Repo
+		s, err := db.StaleDataBefore(r.Owner, r.Repo, minVersion)
+		if err != nil {
+			d.fail("Data", fmt.Sprintf("%s: %v", name, err), "Run 'ordiff db migrate'")
+			continue
+		}
+		if s.Releases == 0 && s.Pairs == 0 {
+			continue
+		}
+		stale++
+		var fixes []string
+		for _, f := range version.FixesSince(s.OldestDataVersion) {
+			fixes = append(fixes, f.Description)
+		}
+		fix := fmt.Sprintf("Re-index with 'ordiff index %s %s'", r.Owner, r.Repo)
+		if r.Provider == provider.Local {
+			fix = "Re-index with 'ordiff index --local <path>'"
+		}
+		d.warn("Data", fmt.Sprintf("%s: %s and %s cached by an older ordiff", name, plural(s.Releases, "release"), plural(s.Pairs, "release pair")),
+			fix+", which refreshes them: "+strings.Join(fixes, "; "))
+	}
+	if stale == 0 {
+		d.ok("Data", "current for every repository")
+	}
+}
+
+// checkGitHub checks the credentials, the API and, with checkRepo, that
+// the repository can be read.
+func (d *diagnosis) checkGitHub(owner, repo string, checkRepo bool) {
+	d.section = "GitHub"
+
+	host := "api.github.com"
+	if u := config.GitHubAPIURL(); u != "" {
+		host = u
+	}
+
+	creds, err := github.AppCredentialsFromEnv()
+	source := config.GitHubTokenSource()
+	switch {
+	case err != nil:
+		d.fail("Credentials", err.Error(), "Fix or unset ORDIFF_APP_ID, ORDIFF_APP_INSTALLATION_ID and ORDIFF_APP_PRIVATE_KEY_FILE")
+		return
+	case creds != nil:
+		d.ok("Credentials", fmt.Sprintf("GitHub App %d, installation %d", creds.AppID, creds.InstallationID))
+	case source == "":
+		d.warn("Credentials", "no token, limited to 60 requests an hour and public repositories", "Set GITHUB_TOKEN, add github_token to the config, or log in with 'gh auth login'")
+	default:
+		d.ok("Credentials", "token from "+source)
+	}
+
+	fetcher := newFetcher(owner, repo)
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	fetcher.SetContext(ctx)
+	access, err := fetcher.CheckAccess()
+	cancel()
+	if err != nil {
+		if github.StatusCode(err) == http.StatusUnauthorized {
+			d.fail("API", "credentials rejected by "+host, fmt.Sprintf("The token from %s is expired or revoked; create a new one", source))
+		} else {
+			d.fail("API", fmt.Sprintf("%s unreachable: %v", host, err), "Check the network connection, proxy settings and --api-url or api_base_url")
+		}
+		return
+	}
+	d.ok("API", host+" reachable")
+
+	if q := access.Quota; q != nil {
+		detail := fmt.Sprintf("%d of %d requests left, resets at %s", q.Remaining, q.Limit, q.Reset.Local().Format("15:04"))
+		switch {
+		case q.Remaining == 0:
+			d.fail("Rate limit", detail, "Wait for the reset, or use a token; indexing and --live comparisons need requests")
+		case q.Remaining < q.Limit/10:
+			d.warn("Rate limit", detail, "Wait for the reset before indexing large repositories")
+		default:
+			d.ok("Rate limit", detail)
+		}
+	}
+
+	if access.ScopesKnown {
+		scopes := strings.Join(access.Scopes, ", ")
+		if scopes == "" {
+			scopes = "none"
+		}
+		if slices.Contains(access.Scopes, "repo") {
+			d.ok("Token scopes", scopes)
+		} else {
+			d.warn("Token scopes", scopes+" (no repo scope)", "Public repositories work; private ones need a token with the repo scope")
+		}
+	}
+
+	if !checkRepo {
+		return
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), doctorTimeout)
+	fetcher.SetContext(ctx)
+	err = fetcher.CheckRepository()
+	cancel()
+	name := owner + "/" + repo
+	switch {
+	case err == nil:
+		d.ok("Repository", name+" readable")
+	case github.StatusCode(err) == http.StatusNotFound:
+		d.fail("Repository", name+" not found", "Check the name; a private repository needs a token that can read it")
+	default:
+		d.fail("Repository", fmt.Sprintf("%s: %v", name, err), "")
+	}
+}
+
+func (d *diagnosis) print() {
+	color := useColor()
+	marks := map[string]string{checkOK: "✓", checkWarn: "!", checkFail: "✗"}
+	colors := map[string]string{checkOK: table.Green, checkWarn: table.Yellow, checkFail: table.Red}
+
+	section := ""
+	warnings, failures := 0, 0
+	for _, c := range d.checks {
+		if c.Section != section {
+			section = c.Section
+			fmt.Printf("\n%s\n", section)
+		}
+		mark := marks[c.Status]
+		if color {
+			mark = colors[c.Status] + mark + "\033[0m"
+		}
+		fmt.Printf("  %s %-13s %s\n", mark, c.Name, c.Detail)
+		if c.Fix != "" {
+			fmt.Printf("  %-15s → %s\n", "", c.Fix)
+		}
+		switch c.Status {
+		case checkWarn:
+			warnings++
+		case checkFail:
+			failures++
+		}
+	}
+
+	fmt.Println()
+	if failures == 0 && warnings == 0 {
+		fmt.Println("No problems found.")
+		return
+	}
+	fmt.Printf("%s, %s\n", plural(failures, "problem"), plural(warnings, "warning"))
+}
+
+// plural renders n things with the noun in the singular or plural.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func init() {
+	DoctorCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+	addRepoFlag(DoctorCmd)
+}
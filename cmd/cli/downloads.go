@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"ordiff/internal/assets"
+	"ordiff/internal/cache"
+	"ordiff/internal/timeline"
+
+	"github.com/spf13/cobra"
+)
+
+var downloadsAsset string
+
+type downloadsReport struct {
+	Tag         string         `json:"tag"`
+	PublishedAt string         `json:"published_at"`
+	Asset       string         `json:"asset,omitempty"`
+	Curve       []assets.Point `json:"curve"`
+	Previous    string         `json:"previous,omitempty"`
+	// PreviousCurve is the curve of the previous release, for comparing
+	// how fast the two were picked up.
+	PreviousCurve []assets.Point `json:"previous_curve,omitempty"`
+}
+
+var DownloadsCmd = &cobra.Command{
+	Use:   "downloads <tag>",
+	Short: "Show how the downloads of a release grew over time",
+	Long: `Shows the adoption curve of a release: the total download count of its
+assets each time 'ordiff index', 'update' or 'watch' found it changed, with
+the days since the release was published and the downloads per day. The
+previous release's downloads at the same age show whether users pick up
+new releases faster or slower than before.
+
+Download counts are recorded from the GitHub release listing, which update
+re-reads for the newest 100 releases, so run it regularly, e.g. with
+'ordiff watch', to get a curve.
+
+--asset counts a single asset, named as in 'ordiff assets' or with the
+version taken out, e.g. ordiff_{version}_linux_amd64.tar.gz.
+
+Example:
+  ordiff downloads v1.30.0
+  ordiff downloads v1.30.0 --asset 'ordiff_{version}_linux_amd64.tar.gz'`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		tag := resolveRef(db, owner, repo, args[0])
+		releases := cachedReleases(db, owner, repo)
+		var release, previous *cache.Release
+		for i := range releases {
+			if releases[i].TagName == tag {
+				release = &releases[i]
+				if i+1 < len(releases) {
+					previous = &releases[i+1]
+				}
+				break
+			}
+		}
+		if release == nil {
+			log.Fatalf("Release %s is not cached for %s/%s", tag, owner, repo)
+		}
+
+		report := downloadsReport{
+			Tag:         tag,
+			PublishedAt: release.PublishedAt.Format("2006-01-02"),
+			Asset:       downloadsAsset,
+			Curve:       downloadCurve(db, owner, repo, tag),
+		}
+		if previous != nil {
+			report.Previous = previous.TagName
+			report.PreviousCurve = downloadCurve(db, owner, repo, previous.TagName)
+		}
+
+		if jsonOutput {
+			if report.Curve == nil {
+				report.Curve = []assets.Point{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(report)
+			return
+		}
+
+		fmt.Printf("\n=== Downloads of %s/%s %s (published %s) ===\n\n", owner, repo, tag, report.PublishedAt)
+		if downloadsAsset != "" {
+			fmt.Printf("Asset: %s\n\n", downloadsAsset)
+		}
+		if len(report.Curve) == 0 {
+			fmt.Println("No download counts recorded for this release. Run 'ordiff update' to record them.")
+			return
+		}
+
+		fmt.Printf("  %-16s  %6s  %10s  %8s  %8s\n", "Recorded", "Days", "Downloads", "New", "Per day")
+		values := make([]int, 0, len(report.Curve))
+		for i, p := range report.Curve {
+			days := p.Time.Sub(release.PublishedAt).Hours() / 24
+			if i == 0 {
+				fmt.Printf("  %-16s  %6.1f  %10d  %8s  %8s\n", p.Time.Local().Format("2006-01-02 15:04"), days, p.Downloads, "-", "-")
+			} else {
+				prev := report.Curve[i-1]
+				elapsed := p.Time.Sub(prev.Time).Hours() / 24
+				fmt.Printf("  %-16s  %6.1f  %10d  %+8d  %8.1f\n", p.Time.Local().Format("2006-01-02 15:04"), days,
+					p.Downloads, p.Downloads-prev.Downloads, float64(p.Downloads-prev.Downloads)/max(elapsed, 1.0/24))
+			}
+			values = append(values, int(p.Downloads))
+		}
+		fmt.Printf("\nAdoption: %s\n", timeline.Sparkline(values))
+
+		if previous == nil {
+			return
+		}
+		last := report.Curve[len(report.Curve)-1]
+		age := last.Time.Sub(release.PublishedAt)
+		if n, ok := assets.At(report.PreviousCurve, previous.PublishedAt.Add(age)); ok {
+			fmt.Printf("At the same age (%.1f days), %s had %d downloads; %s has %d.\n", age.Hours()/24, previous.TagName, n, tag, last.Downloads)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(DownloadsCmd)
+	DownloadsCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+	DownloadsCmd.Flags().StringVar(&downloadsAsset, "asset", "", "Only count this asset")
+}
+
+// downloadCurve reads the download history of a release and adds it up,
+// counting only --asset when it is set.
+func downloadCurve(db cache.Store, owner, repo, tag string) []assets.Point {
+	history, err := db.GetDownloadHistory(owner, repo, tag)
+	if err != nil {
+		log.Fatalf("Failed to read download history: %v", err)
+	}
+	var keep func(string) bool
+	if downloadsAsset != "" {
+		keep = func(name string) bool {
+			return name == downloadsAsset || assets.Key(name, tag) == downloadsAsset
+		}
+	}
+	return assets.Curve(history, keep)
+}
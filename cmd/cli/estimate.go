@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/provider"
+)
+
+// runEstimate lists the releases of a repository and reports how many of
+// them and of their release pairs are cached, and what fetching the rest
+// would take, without fetching any pair.
+func runEstimate(db cache.Store, owner, repo string, fetcher provider.Fetcher) {
+	start := time.Now()
+	releases, err := fetcher.FetchReleases()
+	if err != nil {
+		log.Fatalf("Failed to list releases: %v", err)
+	}
+	listing := time.Since(start)
+
+	cached, err := db.GetReleases(owner, repo)
+	if err != nil {
+		log.Fatalf("Failed to read cached releases: %v", err)
+	}
+	known := make(map[string]bool, len(cached))
+	for _, r := range cached {
+		known[r.TagName] = true
+	}
+	cachedReleases := 0
+	for _, r := range releases {
+		if known[r.TagName] {
+			cachedReleases++
+		}
+	}
+
+	pairs := max(len(releases)-1, 0)
+	cachedPairs, stale := 0, 0
+	for i := 0; i < pairs; i++ {
+		from, to := releases[i+1].TagName, releases[i].TagName
+		ok, err := db.HasFileChangesCached(owner, repo, from, to)
+		if err != nil {
+			log.Fatalf("Failed to check cache: %v", err)
+		}
+		switch {
+		case ok && db.IsPairStale(owner, repo, from, to):
+			stale++
+		case ok:
+			cachedPairs++
+		}
+	}
+	missing := pairs - cachedPairs
+
+	fmt.Printf("\n=== Index estimate for %s/%s ===\n\n", owner, repo)
+	fmt.Printf("  Releases:       %d (%d cached)\n", len(releases), cachedReleases)
+	fmt.Printf("  Release pairs:  %d (%d cached", pairs, cachedPairs)
+	if stale > 0 {
+		fmt.Printf(", %d cached by an older ordiff to refresh", stale)
+	}
+	fmt.Printf(", %d to fetch)\n", missing)
+
+	est, ok := fetcher.(provider.Estimator)
+	if !ok {
+		fmt.Printf("  API requests:   none, the repository is read from disk\n\n")
+		return
+	}
+
+	calls := missing * est.CallsPerPair()
+	fmt.Printf("  API requests:   at least %d (%d per pair, more for pairs of over 100 commits)\n", calls, est.CallsPerPair())
+
+	quota, err := est.RateLimit()
+	if err != nil {
+		slog.Warn("Failed to read rate limit", "err", err)
+	}
+	if quota != nil {
+		fmt.Printf("  Rate limit:     %d of %d requests left, resets at %s\n", quota.Remaining, quota.Limit, quota.Reset.Local().Format("15:04"))
+	}
+
+	// The release list took about a request per 100 releases, which gives
+	// the time of a request.
+	perCall := listing / time.Duration(max(1, (len(releases)+99)/100))
+	workers := max(concurrency, 1)
+	fmt.Printf("  Time:           about %s (at %s a request, concurrency %d)\n",
+		approxDuration(estimateDuration(calls, perCall, workers, quota)), perCall.Round(time.Millisecond), workers)
+	if quota != nil && calls > quota.Remaining {
+		fmt.Printf("                  including waits for the rate limit to reset\n")
+	}
+	fmt.Println()
+}
+
+// estimateDuration is how long calls requests take at perCall each over
+// workers, waiting for quota to reset whenever it runs out.
+func estimateDuration(calls int, perCall time.Duration, workers int, quota *provider.Quota) time.Duration {
+	if quota == nil || quota.Limit <= 0 || calls <= quota.Remaining {
+		return time.Duration(calls) * perCall / time.Duration(workers)
+	}
+	over := calls - quota.Remaining
+	resets := (over - 1) / quota.Limit
+	wait := max(time.Until(quota.Reset), 0) + time.Duration(resets)*time.Hour
+	return wait + time.Duration(over-resets*quota.Limit)*perCall/time.Duration(workers)
+}
+
+// approxDuration rounds d to what matters at its scale, such as "3m" or
+// "2h10m".
+func approxDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		d = d.Round(time.Second)
+	case d < time.Hour:
+		d = d.Round(time.Minute)
+	default:
+		d = d.Round(10 * time.Minute)
+	}
+	s := d.String()
+	if d >= time.Minute {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
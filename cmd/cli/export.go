@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"ordiff/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	exportOut    string
+	exportPatch  bool
+)
+
+var ExportCmd = &cobra.Command{
+	Use:   "export <releases|commits|file_changes>",
+	Short: "Export cached data as CSV or TSV",
+	Long: `Dumps the cached releases, commits or file changes of a repository as CSV
+(or TSV with --format tsv) for spreadsheets and BI tools.
+
+File changes have one row per file and release pair. Patches are left out
+unless --patch is given.
+
+Example:
+  ordiff export releases > releases.csv
+  ordiff export commits --format tsv --out commits.tsv
+  ordiff export file_changes --repo ollama/ollama --out files.csv`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"releases", "commits", "file_changes"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if exportFormat != "csv" && exportFormat != "tsv" {
+			log.Fatalf("Invalid --format %q, expected csv or tsv", exportFormat)
+		}
+
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		var out io.Writer = os.Stdout
+		if exportOut != "" {
+			f, err := os.Create(exportOut)
+			if err != nil {
+				log.Fatalf("Failed to create %s: %v", exportOut, err)
+			}
+			defer f.Close()
+			out = f
+		}
+		w := report.NewCSVWriter(out, exportFormat == "tsv")
+
+		var rows int
+		var err error
+		switch args[0] {
+		case "releases":
+			releases, qerr := db.GetReleases(owner, repo)
+			if qerr != nil {
+				log.Fatalf("Failed to get releases: %v", qerr)
+			}
+			rows, err = len(releases), report.ReleasesCSV(w, releases)
+		case "commits":
+			commits, qerr := db.GetCommits(owner, repo)
+			if qerr != nil {
+				log.Fatalf("Failed to get commits: %v", qerr)
+			}
+			rows, err = len(commits), report.CommitsCSV(w, commits)
+		case "file_changes":
+			files, qerr := db.GetAllFileChanges(owner, repo, exportPatch)
+			if qerr != nil {
+				log.Fatalf("Failed to get file changes: %v", qerr)
+			}
+			rows, err = len(files), report.FileChangesCSV(w, files, exportPatch)
+		default:
+			log.Fatalf("Unknown table %q, expected releases, commits or file_changes", args[0])
+		}
+		if err != nil {
+			log.Fatalf("Failed to write %s: %v", exportFormat, err)
+		}
+
+		if exportOut != "" {
+			fmt.Printf("Wrote %d rows to %s\n", rows, exportOut)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(ExportCmd)
+	ExportCmd.Flags().StringVarP(&exportFormat, "format", "f", "csv", "Output format: csv or tsv")
+	ExportCmd.Flags().StringVarP(&exportOut, "out", "o", "", "Write to a file instead of stdout")
+	ExportCmd.Flags().BoolVar(&exportPatch, "patch", false, "Include patches in file_changes")
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const historyBarWidth = 30
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history <filepath>",
+	Short: "Show how a file changed across releases",
+	Long: `Lists every indexed release pair that touched a file, with whether it was
+added, modified, renamed or removed and its line churn, so hot files can be
+tracked across versions. Release pairs that did not touch the file are
+omitted. Renames are followed, so changes made under the file's earlier
+paths are listed too.
+
+Example:
+  ordiff history server/routes.go
+  ordiff history go.mod --json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		path := strings.TrimPrefix(args[0], "./")
+		history, err := db.GetFileHistory(owner, repo, path)
+		if err != nil {
+			log.Fatalf("Failed to read file history: %v", err)
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(history)
+			return
+		}
+
+		fmt.Printf("\n=== History of %s ===\n\n", path)
+		if len(history) == 0 {
+			fmt.Println("No cached changes to this file.")
+			return
+		}
+
+		fromWidth, toWidth, peak := len("From"), len("To"), 0
+		additions, deletions := 0, 0
+		for _, fc := range history {
+			fromWidth = max(fromWidth, len(fc.FromRelease))
+			toWidth = max(toWidth, len(fc.ToRelease))
+			peak = max(peak, fc.Additions+fc.Deletions)
+			additions += fc.Additions
+			deletions += fc.Deletions
+		}
+
+		fmt.Printf("  %-*s  %-*s  %-9s  %6s  %6s\n", fromWidth, "From", toWidth, "To", "Status", "+Add", "-Del")
+		for _, fc := range history {
+			fmt.Printf("  %-*s  %-*s  %-9s  %+6d  %6d  %s%s\n",
+				fromWidth, fc.FromRelease, toWidth, fc.ToRelease, fc.Status, fc.Additions, -fc.Deletions,
+				churnBar(fc.Additions, fc.Deletions, peak), renamedFrom(fc))
+		}
+		fmt.Printf("\nChanged in %d release pairs, +%d -%d in total\n", len(history), additions, deletions)
+	},
+}
+
+func init() {
+	addRepoFlag(HistoryCmd)
+	HistoryCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
+
+// churnBar draws additions and deletions as a bar scaled to the largest
+// change in the history.
+func churnBar(additions, deletions, peak int) string {
+	if peak == 0 {
+		return ""
+	}
+	add := (additions*historyBarWidth + peak - 1) / peak
+	del := (deletions*historyBarWidth + peak - 1) / peak
+	return strings.Repeat("+", add) + strings.Repeat("-", del)
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"ordiff/internal/config"
+	"ordiff/internal/filter"
+	"ordiff/internal/hotspot"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	hotspotsTop   int
+	hotspotsDirs  bool
+	hotspotsDepth int
+	hotspotsSort  string
+)
+
+var HotspotsCmd = &cobra.Command{
+	Use:   "hotspots",
+	Short: "Rank files by churn and change frequency across releases",
+	Long: `Aggregates the file changes of every indexed release pair and ranks files by
+their cumulative churn and the number of releases that changed them. Files
+that change a lot in many releases are the risky areas of a codebase.
+
+The score (0-100) is the geometric mean of a file's churn and change
+frequency, each relative to the highest. --sort churn or --sort frequency
+ranks by one of them instead.
+
+--dirs ranks directories cut to --depth path segments. --since limits the
+window to the release pairs after a release; --path and --exclude limit the
+files, e.g. --exclude '*_test.go'. Vendored, generated and binary files are
+left out unless --all is given (see 'ordiff compare --help').
+
+Example:
+  ordiff hotspots
+  ordiff hotspots --since v0.5.0 --top 10
+  ordiff hotspots --dirs --depth 2
+  ordiff hotspots --sort frequency --all --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		switch hotspotsSort {
+		case hotspot.ByScore, hotspot.ByChurn, hotspot.ByFrequency:
+		default:
+			log.Fatalf("Invalid --sort %q (expected score, churn or frequency)", hotspotsSort)
+		}
+
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		releases := cachedReleases(db, owner, repo)
+		since := ""
+		if sinceTag != "" {
+			since = resolveSince(releases, sinceTag)
+		}
+
+		files, err := db.GetAllFileChanges(owner, repo, false)
+		if err != nil {
+			log.Fatalf("Failed to get file changes: %v", err)
+		}
+		files, pairs := hotspot.Window(files, releases, since)
+		files = filter.Paths(files, includePaths, excludePaths)
+		if !showAllFiles {
+			files, _ = filter.PartitionIgnored(files, config.IgnorePatterns())
+		}
+
+		depth := 0
+		if hotspotsDirs {
+			depth = max(hotspotsDepth, 1)
+		}
+		hotspots := hotspot.Rank(files, releases, depth, hotspotsSort)
+		hotspots = hotspots[:min(hotspotsTop, len(hotspots))]
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(hotspots)
+			return
+		}
+
+		from := releases[len(releases)-1].TagName
+		if since != "" {
+			from = since
+		}
+		fmt.Printf("\n=== Hotspots %s → %s (%d release pairs) ===\n\n", from, releases[0].TagName, pairs)
+		if len(hotspots) == 0 {
+			fmt.Println("No cached file changes in this window.")
+			return
+		}
+
+		fmt.Printf("  %5s  %5s  %7s  %7s  %-12s  %s\n", "Score", "Pairs", "+Add", "-Del", "Last", "Path")
+		for _, h := range hotspots {
+			path := h.Path
+			if hotspotsDirs {
+				path = fmt.Sprintf("%s (%d files)", h.Path, h.Files)
+			}
+			fmt.Printf("  %5.1f  %5d  %+7d  %7d  %-12s  %s\n", h.Score, h.Pairs, h.Additions, -h.Deletions, h.LastChanged, path)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(HotspotsCmd)
+	HotspotsCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+	HotspotsCmd.Flags().StringVar(&sinceTag, "since", "", "Only count release pairs after this version (tag, v1.2.0 or v1.x)")
+	HotspotsCmd.Flags().IntVar(&hotspotsTop, "top", 20, "Number of paths to show")
+	HotspotsCmd.Flags().BoolVar(&hotspotsDirs, "dirs", false, "Rank directories instead of files")
+	HotspotsCmd.Flags().IntVar(&hotspotsDepth, "depth", 1, "Path segments of the directories ranked with --dirs")
+	HotspotsCmd.Flags().StringVar(&hotspotsSort, "sort", hotspot.ByScore, "Rank by score, churn or frequency")
+	HotspotsCmd.Flags().StringSliceVar(&includePaths, "path", nil, "Only count files under this path or matching this glob (repeatable)")
+	HotspotsCmd.Flags().BoolVar(&showAllFiles, "all", false, "Include vendored, generated and binary files")
+	HotspotsCmd.Flags().StringSliceVar(&excludePaths, "exclude", nil, "Leave out files under this path or matching this glob (repeatable)")
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"ordiff/internal/deps"
+	"ordiff/internal/image"
+	"ordiff/internal/semver"
+	"ordiff/internal/table"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	imageName     string
+	imageTag      string
+	imagePlatform string
+	imageNoPkgs   bool
+)
+
+var ImageDiffCmd = &cobra.Command{
+	Use:   "image-diff <from> <to>",
+	Short: "Compare the container images of two releases",
+	Long: `Resolves the container image published for each release, then compares
+their layers and sizes and the OS packages installed in them (from the dpkg
+or apk database), listed like lockdiff. Layers shared by both images are
+only read once, and only the top layers down to the package database are
+downloaded.
+
+The image defaults to owner/repo on Docker Hub, tagged like the release.
+Set another one in the config, with a Go template turning the release tag
+into the image tag (with .Tag, .Version without a leading v, .Owner and
+.Repo):
+
+  image:
+    name: ghcr.io/ollama/ollama
+    tag: "{{.Version}}"
+    platform: linux/arm64
+    username: me          # for private images
+    password: ghp_...
+
+Example:
+  ordiff image-diff v0.5.0 v0.6.0
+  ordiff image-diff v0.5.0 v0.6.0 --image ollama/ollama --tag-template '{{.Version}}-rocm'
+  ordiff image-diff v0.5.0 v0.6.0 --no-packages --json`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		var cfg image.Config
+		if err := viper.UnmarshalKey("image", &cfg); err != nil {
+			log.Fatalf("Invalid image in config: %v", err)
+		}
+		if imageName != "" {
+			cfg.Name = imageName
+		}
+		if imageTag != "" {
+			cfg.Tag = imageTag
+		}
+		if imagePlatform != "" {
+			cfg.Platform = imagePlatform
+		}
+		if cfg.Platform == "" {
+			cfg.Platform = image.DefaultPlatform
+		}
+
+		from, to := args[0], args[1]
+		if semver.IsPattern(from) || semver.IsPattern(to) {
+			db := openDB()
+			from, to = resolveRef(db, owner, repo, from), resolveRef(db, owner, repo, to)
+			db.Close()
+		}
+
+		client := image.NewClient(cfg.Username, cfg.Password)
+		client.SetRetryPolicy(retryPolicy())
+		fetch := func(tag string) *image.Image {
+			ref, err := cfg.Resolve(owner, repo, tag)
+			if err != nil {
+				log.Fatalf("Failed to resolve the image of %s: %v", tag, err)
+			}
+			img, err := client.Fetch(ref, cfg.Platform)
+			if err != nil {
+				log.Fatalf("Failed to fetch image: %v", err)
+			}
+			return img
+		}
+		fromImg, toImg := fetch(from), fetch(to)
+
+		diff := image.Compare(fromImg, toImg)
+		if !imageNoPkgs {
+			fromMgr, fromPkgs, err := client.Packages(fromImg)
+			if err != nil {
+				log.Fatalf("Failed to read the packages of %s: %v", fromImg.Ref, err)
+			}
+			toMgr, toPkgs, err := client.Packages(toImg)
+			if err != nil {
+				log.Fatalf("Failed to read the packages of %s: %v", toImg.Ref, err)
+			}
+			diff.PackageManager = toMgr
+			if toMgr == "" {
+				diff.PackageManager = fromMgr
+			}
+			if diff.PackageManager != "" {
+				diff.Packages = deps.DiffVersions(fromPkgs, toPkgs, image.CompareVersions)
+			}
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(diff)
+			return
+		}
+		printImageDiff(diff)
+	},
+}
+
+func printImageDiff(d *image.Diff) {
+	fmt.Printf("\n=== Image changes %s → %s (%s) ===\n\n", d.From.Ref, d.To.Ref, d.To.Platform)
+	for _, img := range []*image.Image{d.From, d.To} {
+		created := ""
+		if !img.Created.IsZero() {
+			created = ", built " + img.Created.Format("2006-01-02")
+		}
+		fmt.Printf("%s  %s, %s%s\n", img.Ref, formatBytes(img.Size), plural(len(img.Layers), "layer"), created)
+	}
+	delta := d.To.Size - d.From.Size
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+	}
+	fmt.Printf("\nSize: %s%s (%+.1f%%)\n", sign, formatBytes(abs(delta)), float64(delta)*100/float64(max(d.From.Size, 1)))
+	fmt.Printf("Layers: %d shared, %d added, %d removed\n\n", d.Count(image.LayerShared), d.Count(image.LayerAdded), d.Count(image.LayerRemoved))
+
+	t := table.New(table.Column{}, table.Column{Right: true}, table.Column{Color: table.Dim}, table.Column{Truncate: table.TrimEnd})
+	t.Indent = "  "
+	for _, l := range d.Layers {
+		mark := " "
+		switch l.Status {
+		case image.LayerAdded:
+			mark = "+"
+		case image.LayerRemoved:
+			mark = "-"
+		}
+		t.Row(mark, formatBytes(l.Size), shortDigest(l.Digest), l.CreatedBy)
+	}
+	printTable(t)
+
+	if imageNoPkgs {
+		return
+	}
+	fmt.Println()
+	if d.PackageManager == "" {
+		fmt.Println("No package database found in either image.")
+		return
+	}
+	fmt.Printf("Packages (%s):\n", d.PackageManager)
+	if len(d.Packages) == 0 {
+		fmt.Println("  No package changes")
+		return
+	}
+	width := len("Package")
+	for _, c := range d.Packages {
+		width = max(width, len(c.Name))
+	}
+	fmt.Printf("  %-*s  %-24s  %-24s  %s\n", width, "Package", "From", "To", "Change")
+	for _, c := range d.Packages {
+		fmt.Printf("  %-*s  %-24s  %-24s  %s\n", width, c.Name, orDash(c.From), orDash(c.To), c.Kind)
+	}
+}
+
+// shortDigest abbreviates a layer digest like docker does.
+func shortDigest(digest string) string {
+	if _, hex, ok := strings.Cut(digest, ":"); ok {
+		digest = hex
+	}
+	return digest[:min(12, len(digest))]
+}
+
+func init() {
+	addRepoFlag(ImageDiffCmd)
+	ImageDiffCmd.Flags().StringVar(&imageName, "image", "", "Image name, such as ghcr.io/owner/repo (overrides image.name in config)")
+	ImageDiffCmd.Flags().StringVar(&imageTag, "tag-template", "", "Template of the image tag, such as '{{.Version}}' (overrides image.tag in config)")
+	ImageDiffCmd.Flags().StringVar(&imagePlatform, "platform", "", "Platform of multi-platform images (default linux/amd64)")
+	ImageDiffCmd.Flags().BoolVar(&imageNoPkgs, "no-packages", false, "Compare layers only, without downloading them for the package lists")
+	ImageDiffCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
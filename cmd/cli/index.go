@@ -1,53 +1,423 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"ordiff/internal/cache"
+	"ordiff/internal/changelog"
+	"ordiff/internal/config"
+	"ordiff/internal/deps"
 	"ordiff/internal/github"
+	"ordiff/internal/provider"
+	"ordiff/internal/submodule"
+	"ordiff/internal/vendored"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+)
+
+var (
+	precomputeChangelogs bool
+	providerName         string
+	localPath            string
+	concurrency          int
+	useGraphQL           bool
+	resumeIndex          bool
+	cancelIndex          bool
+	useTags              bool
+	noPatches            bool
+	estimateIndex        bool
+	fromTag              string
+	toTag                string
+	sinceDate            string
 )
 
 var IndexCmd = &cobra.Command{
-	Use:   "index <owner> <repo>",
-	Short: "Index a GitHub repository's releases and commits",
+	Use:   "index [<owner> <repo>]",
+	Short: "Index a repository's releases and commits",
 	Long: `Fetches all releases, commits, PRs and file changes from a GitHub repository
 and stores them in a local SQLite cache for fast comparisons.
 
+With --provider gitlab the project is fetched from GitLab instead (gitlab.com
+or the instance in GITLAB_URL, authenticated with GITLAB_TOKEN). The owner may
+be a nested group path.
+
+--concurrency fetches several release pairs from GitHub at once. Secondary
+rate limits pause every worker and slow requests down for a while.
+
+Requests failing with a server error or a network failure are retried with
+backoff, --retries times (default retry.attempts in the config, else 3). A
+release pair that still fails is listed at the end and the command exits
+with an error; the other pairs stay cached and --resume fetches the rest.
+
+--graphql fetches releases and each pair's commit history 100 at a time
+together with their pull requests, which takes far fewer API calls on large
+repositories. It requires GITHUB_TOKEN or GitHub App credentials.
+
+--from-tag, --to-tag and --since index part of the release history: the
+releases from one tag to another, both included, and those published on or
+after a date. Repositories with hundreds of releases can be indexed from a
+recent point on; a later index without them fills in the rest.
+
+--estimate only lists the releases, then prints how many of them and of
+their release pairs are cached, the API requests the missing pairs take and
+how long that should last given the current rate limit.
+
+Repositories without GitHub Releases are indexed from their git tags, each
+dated by the commit it points at. --tags does so even when the repository has
+releases.
+
+With --local the tags and commits of a git repository on disk are indexed
+without any API access. Owner and repo default to "local" and the directory
+name.
+
+When the rate limit runs out, indexing pauses until the reset time GitHub
+reports and carries on. Progress is stored in the cache, so an index that was
+interrupted can be picked up with --resume without refetching the release
+list or the pairs that are already cached.
+
+--no-patches leaves out the patch of every file but dependency manifests
+and lockfiles, for a much smaller cache of big repositories. Comparisons,
+history and hotspots still work; diffs are not available.
+
+--cancel stops the 'ordiff index' of a repository that is running in another
+terminal, as Ctrl-C does: GitHub indexing finishes the release pairs in
+flight and exits, leaving the rest for --resume.
+
+Authenticate as a GitHub App with --app-id, --app-installation-id and
+--app-private-key-file (or ORDIFF_APP_ID, ORDIFF_APP_INSTALLATION_ID and
+ORDIFF_APP_PRIVATE_KEY_FILE). Installation tokens are refreshed automatically.
+
 Example:
-  ordiff index ollama ollama`,
-	Args: cobra.ExactArgs(2),
+  ordiff index ollama ollama
+  ordiff index --provider gitlab gitlab-org gitlab-runner
+  ordiff index --local ~/src/myproject
+  ordiff index kubernetes kubernetes --concurrency 4
+  ordiff index kubernetes kubernetes --estimate
+  ordiff index kubernetes kubernetes --since 2024-01-01
+  ordiff index kubernetes kubernetes --from-tag v1.28.0 --to-tag v1.30.0
+  ordiff index kubernetes kubernetes --graphql
+  ordiff index torvalds linux --tags
+  ordiff index kubernetes kubernetes --no-patches
+  ordiff index --resume
+  ordiff index --cancel kubernetes kubernetes
+  ordiff index ollama ollama --app-id 12345 --app-installation-id 678 --app-private-key-file app.pem`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if localPath != "" || resumeIndex || cancelIndex {
+			return rangeArgs(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if cancelIndex {
+			runCancel(args)
+			return
+		}
+		if resumeIndex {
+			runResume(args)
+			return
+		}
+
+		loadConfig()
+
+		location := ""
+		if localPath != "" {
+			abs, err := filepath.Abs(localPath)
+			if err != nil {
+				log.Fatalf("Invalid path %s: %v", localPath, err)
+			}
+			location = abs
+			providerName = provider.Local
+			args = append(args, "local", filepath.Base(abs))
+		}
+
 		owner := args[0]
 		repo := args[1]
 
-		fmt.Printf("Indexing %s/%s...\n", owner, repo)
+		db := openDB()
+		defer db.Close()
 
-		db, err := cache.NewDB("ordiff.db")
-		if err != nil {
-			log.Fatalf("Failed to open database: %v", err)
+		fetcher := configureFetcher(newProviderFetcher(owner, repo, providerName, location), db)
+		if r := indexRange(); !r.IsZero() {
+			fetcher.(provider.Ranged).SetRange(r)
+		}
+		if estimateIndex {
+			runEstimate(db, owner, repo, fetcher)
+			return
 		}
-		defer db.Close()
 
-		fetcher := github.NewFetcher(owner, repo, nil)
-		if err := fetcher.IndexAll(db); err != nil {
+		fmt.Printf("Indexing %s/%s...\n", owner, repo)
+		err := runIndexJob(db, "index", owner, repo, fetcher, func() error { return fetcher.IndexAll(indexStore(db)) })
+		if errors.Is(err, context.Canceled) {
+			if _, ok := fetcher.(provider.Resumer); !ok {
+				fmt.Println("Indexing cancelled; the release pairs fetched so far are cached. Run index again to continue.")
+				return
+			}
+			fmt.Println("Indexing cancelled; the release pairs fetched so far are cached. Run 'ordiff index --resume' to continue.")
+			return
+		}
+		if err != nil && !provider.Partial(err) {
 			log.Fatalf("Failed to index: %v", err)
 		}
+		if err := db.SaveRepository(owner, repo, providerName, location); err != nil {
+			slog.Warn("Failed to record repository", "err", err)
+		}
 
-		viper.Set("default_owner", owner)
-		viper.Set("default_repo", repo)
-		if err := viper.WriteConfig(); err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-				viper.SafeWriteConfigAs(".ordiff.yaml")
-			} else {
-				log.Printf("Warning: could not save config: %v\n", err)
+		if precomputeChangelogs {
+			n, err := changelog.Precompute(db, owner, repo)
+			if err != nil {
+				slog.Warn("Failed to precompute changelogs", "err", err)
 			}
+			fmt.Printf("Precomputed %d changelogs\n", n)
+		}
+
+		if err := config.SaveDefaultRepo(owner, repo); err != nil {
+			slog.Warn("Could not save config", "err", err)
 		}
 
+		if err != nil {
+			exitIncomplete(err, fetcher)
+		}
 		fmt.Println("Indexing complete!")
 		fmt.Printf("Run 'ordiff list' to see releases.\n")
 	},
 }
+
+func init() {
+	IndexCmd.Flags().Int64Var(&appID, "app-id", 0, "GitHub App ID")
+	IndexCmd.Flags().Int64Var(&appInstallationID, "app-installation-id", 0, "GitHub App installation ID")
+	IndexCmd.Flags().StringVar(&appPrivateKeyFile, "app-private-key-file", "", "Path to the GitHub App private key (PEM)")
+	IndexCmd.Flags().StringVar(&providerName, "provider", provider.GitHub, "Forge to index from: github or gitlab")
+	IndexCmd.Flags().StringVar(&localPath, "local", "", "Index a git repository on disk instead of a forge")
+	IndexCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of release pairs to fetch in parallel (GitHub only)")
+	IndexCmd.Flags().IntVar(&retries, "retries", -1, "Times to retry a request that fails with a server or network error (default retry.attempts in the config, else 3)")
+	IndexCmd.Flags().BoolVar(&useGraphQL, "graphql", false, "Fetch releases, commits and PRs through the GitHub GraphQL API (needs a token)")
+	IndexCmd.Flags().BoolVar(&useTags, "tags", false, "Index git tags as releases even if the repository publishes GitHub Releases")
+	IndexCmd.Flags().BoolVar(&precomputeChangelogs, "precompute-changelogs", false, "Generate and store a changelog for every release")
+	IndexCmd.Flags().BoolVar(&noPatches, "no-patches", false, "Do not store patches, except those of dependency manifests, lockfiles, vendored version files and submodules")
+	IndexCmd.Flags().BoolVar(&resumeIndex, "resume", false, "Continue an interrupted index of the given or default repository")
+	IndexCmd.MarkFlagsRequiredTogether("app-id", "app-installation-id", "app-private-key-file")
+	IndexCmd.Flags().BoolVar(&cancelIndex, "cancel", false, "Stop a running index of the given or default repository")
+	IndexCmd.Flags().BoolVar(&estimateIndex, "estimate", false, "List releases and estimate the API requests and time of the index, without fetching any release pair")
+	IndexCmd.Flags().StringVar(&fromTag, "from-tag", "", "Oldest release to index")
+	IndexCmd.Flags().StringVar(&toTag, "to-tag", "", "Newest release to index")
+	IndexCmd.Flags().StringVar(&sinceDate, "since", "", "Only index releases published on or after this date (YYYY-MM-DD)")
+	IndexCmd.MarkFlagsMutuallyExclusive("cancel", "resume", "local")
+	IndexCmd.MarkFlagsMutuallyExclusive("cancel", "resume", "estimate")
+	IndexCmd.MarkFlagsMutuallyExclusive("resume", "from-tag")
+	IndexCmd.MarkFlagsMutuallyExclusive("resume", "to-tag")
+	IndexCmd.MarkFlagsMutuallyExclusive("resume", "since")
+}
+
+// indexRange returns the part of the release history selected by
+// --from-tag, --to-tag and --since.
+func indexRange() provider.Range {
+	r := provider.Range{FromTag: fromTag, ToTag: toTag}
+	if sinceDate != "" {
+		t, err := time.Parse("2006-01-02", sinceDate)
+		if err != nil {
+			log.Fatalf("Invalid --since %q: expected a date like 2024-01-01", sinceDate)
+		}
+		r.Since = t
+	}
+	return r
+}
+
+// indexStore applies --no-patches to the store an index writes to. Patches
+// of dependency manifests, lockfiles, the version files of vendored
+// dependencies, .gitmodules and submodules are kept, as dependency changes
+// are read from them.
+func indexStore(db cache.Store) cache.Store {
+	if !noPatches {
+		return db
+	}
+	isVersionFile := vendored.IsVersionFile(vendoredDeps())
+	return cache.WithoutPatches(db, func(fc *cache.FileChange) bool {
+		return deps.IsManifest(fc.Filename) || deps.IsLockfile(fc.Filename) || isVersionFile(fc.Filename) ||
+			fc.Filename == submodule.File || submodule.IsGitlink(*fc)
+	})
+}
+
+// indexTarget returns the repository named by <owner> <repo>, or the default.
+func indexTarget(args []string) (string, string) {
+	if len(args) == 2 {
+		loadConfig()
+		return args[0], args[1]
+	}
+	return defaultRepo()
+}
+
+func runResume(args []string) {
+	owner, repo := indexTarget(args)
+
+	db := openDB()
+	defer db.Close()
+
+	name, location, err := db.GetRepositoryProvider(owner, repo)
+	if err != nil {
+		slog.Warn("Failed to look up repository provider", "err", err)
+	}
+
+	fetcher := configureFetcher(newProviderFetcher(owner, repo, name, location), db)
+	resumer, ok := fetcher.(provider.Resumer)
+	if !ok {
+		log.Fatalf("Resuming is not supported for %s repositories; run index again", name)
+	}
+
+	fmt.Printf("Resuming index of %s/%s...\n", owner, repo)
+	err = runIndexJob(db, "resume", owner, repo, fetcher, func() error { return resumer.Resume(indexStore(db)) })
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Indexing cancelled; run 'ordiff index --resume' again to continue.")
+		return
+	}
+	if err != nil && !provider.Partial(err) {
+		log.Fatalf("Failed to resume: %v", err)
+	}
+
+	if precomputeChangelogs {
+		n, err := changelog.Precompute(db, owner, repo)
+		if err != nil {
+			slog.Warn("Failed to precompute changelogs", "err", err)
+		}
+		fmt.Printf("Precomputed %d changelogs\n", n)
+	}
+
+	if err != nil {
+		exitIncomplete(err, fetcher)
+	}
+	fmt.Println("Indexing complete!")
+}
+
+// exitIncomplete lists the release pairs an index gave up on after retrying
+// and exits with an error. The pairs that were fetched stay cached.
+func exitIncomplete(err error, fetcher provider.Fetcher) {
+	var pe *provider.PairErrors
+	errors.As(err, &pe)
+	fmt.Printf("%d of %d release pairs could not be fetched:\n", len(pe.Failed), pe.Total)
+	for _, f := range pe.Failed {
+		fmt.Printf("  %s → %s: %v\n", f.From, f.To, f.Err)
+	}
+	if _, ok := fetcher.(provider.Resumer); ok {
+		fmt.Println("The other pairs are cached. Run 'ordiff index --resume' to fetch the missing ones.")
+	} else {
+		fmt.Println("The other pairs are cached. Run index again to fetch the missing ones.")
+	}
+	os.Exit(1)
+}
+
+// runIndexJob runs an index of a repository, recorded in the index_jobs
+// table, with its process ID in the repository's lock file, where
+// 'ordiff index --cancel' finds it. Backends that support it stop cleanly on
+// the interrupt --cancel sends, as on Ctrl-C; the others are simply
+// terminated, and the job shows as interrupted in 'ordiff jobs'.
+func runIndexJob(db cache.Store, kind, owner, repo string, fetcher provider.Fetcher, index func() error) error {
+	host, _ := os.Hostname()
+	job := &cache.IndexJob{Owner: owner, Repo: repo, Kind: kind, Source: "cli", Host: host, PID: os.Getpid()}
+	if err := db.StartIndexJob(job); err != nil {
+		slog.Warn("Failed to record index job", "err", err)
+	}
+
+	lock := config.IndexLockPath(owner, repo)
+	err := os.MkdirAll(filepath.Dir(lock), 0o755)
+	if err == nil {
+		err = os.WriteFile(lock, []byte(strconv.Itoa(os.Getpid())), 0o644)
+	}
+	if err != nil {
+		slog.Warn("Could not write index lock", "path", lock, "err", err)
+	}
+	defer os.Remove(lock)
+
+	if c, ok := fetcher.(provider.Cancellable); ok {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		c.SetContext(ctx)
+	}
+
+	err = index()
+	if job.ID != 0 {
+		state, msg := cache.JobCompleted, ""
+		if errors.Is(err, context.Canceled) {
+			state = cache.JobCancelled
+		} else if err != nil {
+			state, msg = cache.JobFailed, err.Error()
+		}
+		if err := db.EndIndexJob(job.ID, state, msg); err != nil {
+			slog.Warn("Failed to record index job", "err", err)
+		}
+	}
+	return err
+}
+
+// runCancel interrupts the 'ordiff index' of a repository recorded in its
+// lock file.
+func runCancel(args []string) {
+	owner, repo := indexTarget(args)
+
+	lock := config.IndexLockPath(owner, repo)
+	data, err := os.ReadFile(lock)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("No index of %s/%s is running", owner, repo)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", lock, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		log.Fatalf("Invalid lock file %s: %v", lock, err)
+	}
+
+	p, err := os.FindProcess(pid)
+	if err == nil {
+		err = p.Signal(os.Interrupt)
+	}
+	if errors.Is(err, os.ErrProcessDone) {
+		os.Remove(lock)
+		log.Fatalf("No index of %s/%s is running", owner, repo)
+	}
+	if err != nil {
+		log.Fatalf("Failed to cancel the index of %s/%s (pid %d): %v", owner, repo, pid, err)
+	}
+	fmt.Printf("Cancelling the index of %s/%s (pid %d); it stops once the release pairs in flight are saved.\n", owner, repo, pid)
+}
+
+// configureFetcher applies --graphql and --concurrency to backends that
+// support them, and lets them revalidate API responses cached in db.
+func configureFetcher(fetcher provider.Fetcher, db cache.Store) provider.Fetcher {
+	if useGraphQL {
+		if gh, ok := fetcher.(*github.Fetcher); ok {
+			fetcher = github.NewGraphQLFetcher(gh)
+		} else {
+			slog.Warn("--graphql is only supported for GitHub")
+		}
+	}
+
+	if useTags {
+		if t, ok := fetcher.(provider.TagReleaser); ok {
+			t.SetTagReleases(true)
+		} else {
+			slog.Warn("--tags is only supported for GitHub repositories")
+		}
+	}
+
+	if c, ok := fetcher.(provider.HTTPCached); ok {
+		c.SetHTTPCache(db)
+	}
+
+	if c, ok := fetcher.(provider.Concurrent); ok {
+		c.SetConcurrency(concurrency)
+	} else if concurrency > 1 {
+		slog.Warn("--concurrency is not supported by this provider")
+	}
+	return fetcher
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+	"time"
+
+	"ordiff/internal/cache"
+
+	"github.com/spf13/cobra"
+)
+
+// jobInterrupted is reported for a job still recorded as running whose
+// process is gone.
+const jobInterrupted = "interrupted"
+
+var jobsLimit int
+
+var JobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "List past and running index jobs",
+	Long: `Lists the index, update and resume runs recorded in the cache, newest first,
+whether started from the command line or through the MCP server: when they
+ran, how they ended and how many release pairs they fetched.
+
+A job that is still marked running on this machine but whose process has
+exited is shown as interrupted; 'ordiff index --resume' picks up where it
+stopped.
+
+Example:
+  ordiff jobs
+  ordiff jobs --repo ollama/ollama -n 5
+  ordiff jobs --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var owner, repo string
+		if repoFlag != "" {
+			owner, repo = defaultRepo()
+		} else {
+			loadConfig()
+		}
+
+		db := openDB()
+		defer db.Close()
+
+		jobs, err := db.GetIndexJobs(owner, repo, jobsLimit)
+		if err != nil {
+			log.Fatalf("Failed to get index jobs: %v", err)
+		}
+		host, _ := os.Hostname()
+		for i := range jobs {
+			if jobs[i].State == cache.JobRunning && jobs[i].Host == host && !processAlive(jobs[i].PID) {
+				jobs[i].State = jobInterrupted
+			}
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(jobs)
+			return
+		}
+
+		if len(jobs) == 0 {
+			fmt.Println("No index jobs recorded.")
+			return
+		}
+
+		fmt.Printf("\n=== Index jobs ===\n\n")
+		for _, j := range jobs {
+			took := "-"
+			if !j.EndedAt.IsZero() {
+				took = j.EndedAt.Sub(j.StartedAt).String()
+			} else if j.State == cache.JobRunning {
+				took = time.Since(j.StartedAt).Truncate(time.Second).String() + "…"
+			}
+			fmt.Printf("  #%-5d %-30s %-7s %-4s %-12s %s  %8s  %4d pairs\n",
+				j.ID, j.Owner+"/"+j.Repo, j.Kind, j.Source, j.State,
+				j.StartedAt.Local().Format("2006-01-02 15:04"), took, j.DonePairs)
+			if j.Error != "" {
+				fmt.Printf("         %s\n", j.Error)
+			}
+		}
+		fmt.Println()
+	},
+}
+
+// processAlive reports whether a process may still be running; only a
+// process known to have exited is reported dead.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return !errors.Is(p.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
+
+func init() {
+	addRepoFlag(JobsCmd)
+	JobsCmd.Flags().IntVarP(&jobsLimit, "limit", "n", 20, "Maximum number of jobs to list")
+	JobsCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
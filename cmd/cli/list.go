@@ -4,44 +4,61 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"sort"
 
 	"ordiff/internal/cache"
+	"ordiff/internal/provider"
+	"ordiff/internal/report"
+	"ordiff/internal/schema"
+	"ordiff/internal/semver"
+	"ordiff/internal/table"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
-var jsonOutput bool
+var (
+	jsonOutput    bool
+	showChangelog bool
+	listSort      string
+	listFormat    string
+	listVerify    bool
+)
 
 var ListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List cached releases",
 	Long: `Displays all releases that have been indexed for the default repository.
 
+Releases are listed newest first by publish date, or by semantic version
+with --sort version. --format csv or tsv prints a table for spreadsheets.
+--schema prints the JSON Schema of the --json output. --template renders the
+releases with a Go text/template file (see the README for its data).
+
+--verify reports whether each release tag is signed: whether it is an
+annotated tag, who tagged it, the kind of signature (gpg, ssh, x509 or
+sigstore) and whether GitHub verified it. Tags are fetched once and cached
+with their message, and fetched again when the release's commit no longer
+matches the cached tag's, as when a tag was moved. The JSON output carries
+them as Tag.
+
 Example:
-  ordiff list`,
+  ordiff list
+  ordiff list --sort version
+  ordiff list --changelog
+  ordiff list --verify
+  ordiff list --format csv > releases.csv
+  ordiff list --template releases.tmpl`,
 	Run: func(cmd *cobra.Command, args []string) {
-		viper.SetConfigName(".ordiff")
-		viper.SetConfigType("yaml")
-		viper.AddConfigPath(".")
-		if err := viper.ReadInConfig(); err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				log.Printf("Warning: could not read config: %v\n", err)
-			}
+		if showSchema {
+			printSchema(schema.List)
+			return
 		}
 
-		owner := viper.GetString("default_owner")
-		repo := viper.GetString("default_repo")
-
-		if owner == "" || repo == "" {
-			log.Fatal("No default repository. Run 'ordiff index <owner> <repo>' first.")
-		}
+		owner, repo := defaultRepo()
 
-		db, err := cache.NewDB("ordiff.db")
-		if err != nil {
-			log.Fatalf("Failed to open database: %v", err)
-		}
+		db := openDB()
 		defer db.Close()
 
 		releases, err := db.GetReleases(owner, repo)
@@ -49,6 +66,48 @@ Example:
 			log.Fatalf("Failed to get releases: %v", err)
 		}
 
+		tags, err := db.GetTags(owner, repo)
+		if err != nil {
+			log.Fatalf("Failed to get tags: %v", err)
+		}
+		if listVerify {
+			fetchReleaseTags(db, owner, repo, releases, tags)
+		}
+		for i := range releases {
+			releases[i].Tag = tags[releases[i].TagName]
+		}
+
+		switch listSort {
+		case "date":
+		case "version":
+			sort.SliceStable(releases, func(i, j int) bool {
+				return semver.CompareStrings(releases[i].TagName, releases[j].TagName) > 0
+			})
+		default:
+			log.Fatalf("Invalid --sort %q, expected date or version", listSort)
+		}
+
+		if templateFile != "" {
+			if listFormat != "text" || jsonOutput {
+				log.Fatal("--template cannot be combined with --format or --json")
+			}
+			executeTemplate(os.Stdout, listTemplate{Owner: owner, Repo: repo, Releases: releases})
+			return
+		}
+
+		switch listFormat {
+		case "text":
+		case "csv", "tsv":
+			if err := report.ReleasesCSV(report.NewCSVWriter(os.Stdout, listFormat == "tsv"), releases); err != nil {
+				log.Fatalf("Failed to write %s: %v", listFormat, err)
+			}
+			return
+		case "json":
+			jsonOutput = true
+		default:
+			log.Fatalf("Invalid --format %q, expected text, json, csv or tsv", listFormat)
+		}
+
 		if jsonOutput {
 			enc := json.NewEncoder(os.Stdout)
 			enc.SetIndent("", "  ")
@@ -56,13 +115,124 @@ Example:
 			return
 		}
 
+		if showChangelog {
+			for _, r := range releases {
+				md, ok, err := db.GetChangelog(owner, repo, r.TagName)
+				if err != nil {
+					log.Fatalf("Failed to get changelog: %v", err)
+				}
+				if !ok {
+					md = "## " + r.TagName + "\n\nNo precomputed changelog. Run 'ordiff index --precompute-changelogs'.\n"
+				}
+				fmt.Println(md)
+			}
+			return
+		}
+
 		fmt.Printf("Releases for %s/%s:\n\n", owner, repo)
+		if listVerify {
+			printTagVerification(releases)
+			return
+		}
+		t := table.New(table.Column{Header: "Tag", Truncate: table.TrimEnd}, table.Column{Header: "Published"})
 		for _, r := range releases {
-			fmt.Printf("  %-20s  %s\n", r.TagName, r.PublishedAt.Format("2006-01-02"))
+			t.Row(r.TagName, r.PublishedAt.Format("2006-01-02"))
 		}
+		printTable(t)
 	},
 }
 
+// listTemplate is the data a list --template is executed with.
+type listTemplate struct {
+	Owner    string
+	Repo     string
+	Releases []cache.Release
+}
+
 func init() {
+	addRepoFlag(ListCmd)
 	ListCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+	ListCmd.Flags().StringVarP(&listFormat, "format", "f", "text", "Output format: text, json, csv or tsv")
+	addSchemaFlag(ListCmd)
+	ListCmd.Flags().StringVar(&listSort, "sort", "date", "Order releases by date or version")
+	ListCmd.Flags().BoolVar(&showChangelog, "changelog", false, "Show the precomputed changelog of each release")
+	ListCmd.Flags().BoolVar(&listVerify, "verify", false, "Report whether release tags are annotated and signed")
+	addTemplateFlag(ListCmd)
+}
+
+// fetchReleaseTags fetches the tags of the releases missing from tags and
+// caches them, adding them to tags.
+func fetchReleaseTags(db cache.Store, owner, repo string, releases []cache.Release, tags map[string]*cache.Tag) {
+	if name, _, err := db.GetRepositoryProvider(owner, repo); err != nil {
+		slog.Warn("Failed to look up repository provider", "err", err)
+	} else if name != "" && name != provider.GitHub {
+		log.Fatalf("--verify is not supported for %s repositories", name)
+	}
+
+	var missing []string
+	for _, r := range releases {
+		// A tag that now points elsewhere than when it was fetched was
+		// moved, and is verified again.
+		if t := tags[r.TagName]; t == nil || r.CommitSHA != "" && t.CommitSHA != r.CommitSHA {
+			missing = append(missing, r.TagName)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	slog.Info("Fetching release tags", "count", len(missing))
+	fetcher := newFetcher(owner, repo)
+	var fetched []cache.Tag
+	failed := 0
+	for i, name := range missing {
+		t, err := fetcher.FetchTag(name)
+		if err != nil {
+			slog.Warn("Failed to fetch tag", "tag", name, "err", err)
+			delete(tags, name)
+			failed++
+			continue
+		}
+		if t == nil {
+			slog.Warn("Tag not found", "tag", name)
+			continue
+		}
+		fetched = append(fetched, *t)
+		tags[name] = t
+		if (i+1)%50 == 0 {
+			slog.Info("Fetched tags", "done", i+1, "total", len(missing))
+		}
+	}
+	if err := db.SaveTags(owner, repo, fetched); err != nil {
+		log.Fatalf("Failed to save tags: %v", err)
+	}
+	if failed > 0 {
+		slog.Warn("Some tags could not be fetched and are reported as not found", "failed", failed, "total", len(missing))
+	}
+}
+
+func printTagVerification(releases []cache.Release) {
+	t := table.New(table.Column{Header: "Tag", Truncate: table.TrimEnd}, table.Column{Header: "Published"}, table.Column{Header: "Signature"},
+		table.Column{Header: "Tagger", Truncate: table.TrimEnd})
+	signed, verified := 0, 0
+	for _, r := range releases {
+		status, tagger := "not found", ""
+		switch tag := r.Tag; {
+		case tag == nil:
+		case !tag.Annotated:
+			status = "lightweight"
+		case !tag.Signed():
+			status, tagger = "unsigned", tag.Tagger
+		default:
+			signed++
+			status, tagger = tag.Signature+", "+tag.Reason, tag.Tagger
+			if tag.Verified {
+				verified++
+				status = tag.Signature + ", verified"
+			}
+		}
+		t.Row(r.TagName, r.PublishedAt.Format("2006-01-02"), status, tagger)
+	}
+	printTable(t)
+	fmt.Printf("\n%d of %s signed, %d verified\n", signed, plural(len(releases), "release tag"), verified)
 }
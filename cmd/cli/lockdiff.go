@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"ordiff/internal/deps"
+	"ordiff/internal/github"
+
+	"github.com/spf13/cobra"
+)
+
+var LockdiffCmd = &cobra.Command{
+	Use:   "lockdiff <from> <to>",
+	Short: "Show dependency lockfile changes between two releases",
+	Long: `Parses the cached patches of changed lockfiles (go.sum, package-lock.json,
+Cargo.lock, poetry.lock) and lists the exact version transitions as
+added, removed, upgraded or downgraded packages.
+
+Example:
+  ordiff lockdiff v0.1.0 v0.2.0`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		fetcher := github.NewFetcher(owner, repo, nil)
+		result, err := fetcher.GetCompareData(db, resolveRef(db, owner, repo, args[0]), resolveRef(db, owner, repo, args[1]))
+		if err != nil {
+			log.Fatalf("Failed to compare: %v", err)
+		}
+		if err := fetcher.LoadPatches(db, result); err != nil {
+			log.Fatalf("Failed to load patches: %v", err)
+		}
+
+		diffs := deps.LockfileDiffs(result.Files)
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(diffs)
+			return
+		}
+
+		fmt.Printf("\n=== Lockfile changes %s → %s ===\n\n", result.FromRelease.TagName, result.ToRelease.TagName)
+		if len(diffs) == 0 {
+			fmt.Println("No lockfiles changed.")
+			return
+		}
+
+		for _, d := range diffs {
+			fmt.Printf("%s\n", d.File)
+			if d.NoPatch {
+				fmt.Println("  (patch not available, file too large for the compare API)")
+				fmt.Println()
+				continue
+			}
+			if len(d.Changes) == 0 {
+				fmt.Println("  No version changes (checksums or metadata only)")
+				fmt.Println()
+				continue
+			}
+
+			width := len("Package")
+			for _, c := range d.Changes {
+				width = max(width, len(c.Name))
+			}
+
+			fmt.Printf("  %-*s  %-16s  %-16s  %s\n", width, "Package", "From", "To", "Change")
+			for _, c := range d.Changes {
+				fmt.Printf("  %-*s  %-16s  %-16s  %s\n", width, c.Name, orDash(c.From), orDash(c.To), c.Kind)
+			}
+			fmt.Println()
+		}
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	addRepoFlag(LockdiffCmd)
+	LockdiffCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
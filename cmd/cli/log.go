@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var logAuthor string
+
+var LogCmd = &cobra.Command{
+	Use:   "log [<from> <to>]",
+	Short: "List commits between two releases",
+	Long: `Lists every cached commit between two releases. Authors are shown under the
+identities the mailmap merges them into, and --author matches part of
+those names or emails.
+
+Example:
+  ordiff log v0.1.0 v0.2.0
+  ordiff log --since v2.0.0 --author jane@example.com`,
+	Args: rangeArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from, to := resolveRange(db, owner, repo, args)
+
+		commits, err := db.SearchCommitsBetween(owner, repo, from, to, cache.CommitQuery{Author: logAuthor})
+		if err != nil {
+			log.Fatalf("Failed to get commits: %v", err)
+		}
+
+		printCommits(from, to, commits)
+	},
+}
+
+// printCommits prints commits one per line, or as JSON with --json.
+func printCommits(from, to string, commits []cache.Commit) {
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(commits)
+		return
+	}
+
+	fmt.Printf("\n=== %s → %s (%d commits) ===\n\n", from, to, len(commits))
+	for _, c := range commits {
+		sha := c.SHA
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		fmt.Printf("  %s  %s  %-20s  %s\n", sha, c.Date.Format("2006-01-02"), c.Author, report.Subject(c.Message))
+	}
+}
+
+func init() {
+	addRangeFlags(LogCmd)
+	LogCmd.Flags().StringVar(&logAuthor, "author", "", "Only show commits whose author name or email contains this")
+}
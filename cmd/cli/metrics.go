@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/metrics"
+	"ordiff/internal/table"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	metricsHook  string
+	metricsForce bool
+)
+
+var MetricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Measure releases with your own scripts, such as binary sizes or benchmarks",
+	Long: `Runs the hooks under metrics in the config once per release and keeps what
+they measure in the cache. 'ordiff compare' then shows how every metric
+changed between the two releases.
+
+A hook is a shell command run in dir, typically a checkout of the
+repository, with the release in ORDIFF_OWNER, ORDIFF_REPO, ORDIFF_TAG and
+ORDIFF_COMMIT. It prints one "<name> <value> [unit]" per line; go test
+-bench output is read as well, and other lines are ignored:
+
+  metrics:
+    - name: size
+      dir: ~/src/ollama
+      command: |
+        git checkout -q "$ORDIFF_COMMIT" && go build -o /tmp/bin . &&
+        echo "binary_size $(stat -c %s /tmp/bin) B"
+    - name: bench
+      dir: ~/src/ollama
+      command: git checkout -q "$ORDIFF_COMMIT" && go test -run '^$' -bench . ./server
+      timeout: 1h`,
+}
+
+var metricsCollectCmd = &cobra.Command{
+	Use:   "collect [tag...]",
+	Short: "Run the metrics hooks for releases",
+	Long: `Runs every metrics hook for the given releases, or for every cached release
+a hook has not measured yet. A failing run is reported and the others go on.
+
+Example:
+  ordiff metrics collect
+  ordiff metrics collect v0.6.0 v0.6.1 --hook size --force`,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+		hooks := metricsHooks()
+		if metricsHook != "" {
+			hooks = selectHook(hooks, metricsHook)
+		}
+		if len(hooks) == 0 {
+			log.Fatal("No metrics hooks configured. Add them under metrics in the config.")
+		}
+
+		db := openDB()
+		defer db.Close()
+
+		releases := cachedReleases(db, owner, repo)
+		if len(args) > 0 {
+			byTag := map[string]cache.Release{}
+			for _, r := range releases {
+				byTag[r.TagName] = r
+			}
+			var picked []cache.Release
+			for _, arg := range args {
+				r, ok := byTag[resolveRef(db, owner, repo, arg)]
+				if !ok {
+					log.Fatalf("Release %s is not cached for %s/%s", arg, owner, repo)
+				}
+				picked = append(picked, r)
+			}
+			releases = picked
+		}
+
+		ran, failed := 0, 0
+		for _, r := range releases {
+			have := map[string]bool{}
+			if !metricsForce {
+				existing, err := db.GetMetrics(owner, repo, r.TagName)
+				if err != nil {
+					log.Fatalf("Failed to read metrics: %v", err)
+				}
+				for _, m := range existing {
+					have[m.Hook] = true
+				}
+			}
+			for _, h := range hooks {
+				if have[h.Name] {
+					continue
+				}
+				slog.Info("Running metrics hook", "hook", h.Name, "tag", r.TagName)
+				start := time.Now()
+				measured, err := metrics.Run(context.Background(), h, &r)
+				if err != nil {
+					slog.Warn("Failed to run metrics hook", "hook", h.Name, "tag", r.TagName, "err", err)
+					failed++
+					continue
+				}
+				if err := db.SaveMetrics(owner, repo, r.TagName, h.Name, measured); err != nil {
+					log.Fatalf("Failed to save metrics: %v", err)
+				}
+				slog.Info("Collected metrics", "hook", h.Name, "tag", r.TagName, "metrics", len(measured), "took", time.Since(start).Round(time.Second))
+				ran++
+			}
+		}
+
+		fmt.Printf("Ran %s", plural(ran, "hook"))
+		if failed > 0 {
+			fmt.Printf(", %d failed", failed)
+		}
+		fmt.Println()
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+var metricsShowCmd = &cobra.Command{
+	Use:   "show <tag>",
+	Short: "Show the metrics collected for a release",
+	Long: `Lists what the metrics hooks measured for a cached release.
+
+Example:
+  ordiff metrics show v0.6.0
+  ordiff metrics show v0.6.0 --json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		tag := resolveRef(db, owner, repo, args[0])
+		list, err := db.GetMetrics(owner, repo, tag)
+		if err != nil {
+			log.Fatalf("Failed to read metrics: %v", err)
+		}
+
+		if jsonOutput {
+			if list == nil {
+				list = []cache.Metric{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(list)
+			return
+		}
+
+		fmt.Printf("\n=== Metrics of %s/%s %s ===\n\n", owner, repo, tag)
+		if len(list) == 0 {
+			fmt.Println("No metrics collected for this release. Run 'ordiff metrics collect' first.")
+			return
+		}
+		t := table.New(table.Column{Header: "Hook", Color: table.Dim}, table.Column{Header: "Metric"}, table.Column{Header: "Value", Right: true}, table.Column{Header: "Unit"})
+		for _, m := range list {
+			t.Row(m.Hook, m.Name, formatMetric(m.Value), m.Unit)
+		}
+		printTable(t)
+	},
+}
+
+// metricsHooks returns the metrics hooks from the config, with their
+// defaults filled in.
+func metricsHooks() []metrics.Hook {
+	var hooks []metrics.Hook
+	if err := viper.UnmarshalKey("metrics", &hooks); err != nil {
+		log.Fatalf("Invalid metrics in config: %v", err)
+	}
+	for i := range hooks {
+		if err := hooks[i].Validate(); err != nil {
+			log.Fatalf("Invalid metrics in config: %v", err)
+		}
+	}
+	return hooks
+}
+
+func selectHook(hooks []metrics.Hook, name string) []metrics.Hook {
+	for _, h := range hooks {
+		if h.Name == name {
+			return []metrics.Hook{h}
+		}
+	}
+	log.Fatalf("No metrics hook named %s in the config", name)
+	return nil
+}
+
+// metricChanges compares the metrics of two releases, or returns nil when
+// either has none.
+func metricChanges(db cache.Store, owner, repo, from, to string) []metrics.Delta {
+	fromMetrics, err := db.GetMetrics(owner, repo, from)
+	if err != nil {
+		log.Fatalf("Failed to read metrics: %v", err)
+	}
+	toMetrics, err := db.GetMetrics(owner, repo, to)
+	if err != nil {
+		log.Fatalf("Failed to read metrics: %v", err)
+	}
+	if len(fromMetrics) == 0 || len(toMetrics) == 0 {
+		return nil
+	}
+	return metrics.Compare(fromMetrics, toMetrics)
+}
+
+func printMetricChanges(deltas []metrics.Delta) {
+	fmt.Println("Metrics:")
+	t := table.New(table.Column{Truncate: table.TrimEnd}, table.Column{Right: true}, table.Column{Right: true, Signed: true}, table.Column{Color: table.Dim})
+	t.Indent = "  "
+	for _, d := range deltas {
+		name := d.Name
+		if d.Unit != "" {
+			name += " (" + d.Unit + ")"
+		}
+		switch {
+		case d.From == nil:
+			t.Row(name, formatMetric(*d.To), "new", d.Hook)
+		case d.To == nil:
+			t.Row(name, formatMetric(*d.From), "gone", d.Hook)
+		default:
+			change := fmt.Sprintf("%+.1f%%", d.Percent)
+			if d.Change == 0 {
+				change = "0%"
+			}
+			t.Row(name, formatMetric(*d.From)+" → "+formatMetric(*d.To), change, d.Hook)
+		}
+	}
+	printTable(t)
+}
+
+// formatMetric renders a value without trailing zeros, e.g. 1234 or 0.25.
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func init() {
+	metricsCollectCmd.Flags().StringVar(&metricsHook, "hook", "", "Only run the hook with this name")
+	metricsCollectCmd.Flags().BoolVar(&metricsForce, "force", false, "Run hooks again for releases they already measured")
+	metricsShowCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+	addRepoFlag(metricsCollectCmd)
+	addRepoFlag(metricsShowCmd)
+	MetricsCmd.AddCommand(metricsCollectCmd, metricsShowCmd)
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+
+	"ordiff/internal/github"
+	"ordiff/internal/notify"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	webhookURL     string
+	slackWebhook   string
+	discordWebhook string
+	notifyDryRun   bool
+)
+
+var NotifyCmd = &cobra.Command{
+	Use:   "notify <from> <to>",
+	Short: "Post a release summary to Slack, Discord or a webhook",
+	Long: `Posts the comparison between two releases to a channel: commit, PR and
+file counts, the most changed files, merged PRs and, when there are none,
+the latest commits. Makes release announcements one command in CI.
+
+Targets default to notify.slack_webhook, notify.discord_webhook and
+notify.webhook in the config. --dry-run prints the message instead.
+
+Example:
+  ordiff notify v1.2.0 v1.3.0 --slack-webhook "$SLACK_WEBHOOK"
+  ordiff notify v1.2.0 v1.3.0 --discord-webhook "$DISCORD_WEBHOOK"
+  ordiff notify v1.2.x v1.3.x --dry-run`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+		loadNotifyTargets()
+		if !notifyDryRun && webhookURL == "" && slackWebhook == "" && discordWebhook == "" {
+			log.Fatal("No notification target. Use --slack-webhook, --discord-webhook or --webhook, or set them under notify in the config.")
+		}
+
+		db := openDB()
+		defer db.Close()
+
+		from := resolveRef(db, owner, repo, args[0])
+		to := resolveRef(db, owner, repo, args[1])
+
+		result, err := newFetcher(owner, repo).Compare(db, from, to, false)
+		if err != nil {
+			log.Fatalf("Failed to compare: %v", err)
+		}
+
+		if notifyDryRun {
+			fmt.Println(notify.Summary(result))
+			return
+		}
+		if failed := announce(result); failed > 0 {
+			log.Fatalf("Failed to notify %d targets", failed)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(NotifyCmd)
+	addNotifyFlags(NotifyCmd)
+	NotifyCmd.Flags().BoolVar(&notifyDryRun, "dry-run", false, "Print the message instead of posting it")
+}
+
+func addNotifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&webhookURL, "webhook", "", "POST the comparison as a JSON event to this URL")
+	cmd.Flags().StringVar(&slackWebhook, "slack-webhook", "", "Post a summary to this Slack incoming webhook")
+	cmd.Flags().StringVar(&discordWebhook, "discord-webhook", "", "Post a summary to this Discord webhook")
+}
+
+// loadNotifyTargets fills in the targets not given as flags from the config.
+func loadNotifyTargets() {
+	if webhookURL == "" {
+		webhookURL = viper.GetString("notify.webhook")
+	}
+	if slackWebhook == "" {
+		slackWebhook = viper.GetString("notify.slack_webhook")
+	}
+	if discordWebhook == "" {
+		discordWebhook = viper.GetString("notify.discord_webhook")
+	}
+}
+
+// announce posts a comparison to every configured target and returns how
+// many of them failed.
+func announce(result *github.CompareResult) int {
+	failed := 0
+	if webhookURL != "" {
+		if err := notify.Webhook(webhookURL, notify.NewEvent(result)); err != nil {
+			slog.Warn("Failed to call webhook", "err", err)
+			failed++
+		}
+	}
+	if slackWebhook != "" {
+		if err := notify.Slack(slackWebhook, notify.Summary(result)); err != nil {
+			slog.Warn("Failed to post to Slack", "err", err)
+			failed++
+		}
+	}
+	if discordWebhook != "" {
+		if err := notify.Discord(discordWebhook, result); err != nil {
+			slog.Warn("Failed to post to Discord", "err", err)
+			failed++
+		}
+	}
+	return failed
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os"
+
+	"ordiff/internal/table"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// NoColor is set by the --no-color flag.
+var NoColor bool
+
+// useColor reports whether stdout gets ANSI colors: it is a terminal and
+// neither --no-color nor NO_COLOR is set.
+func useColor() bool {
+	return isTerminal(os.Stdout) && !NoColor && os.Getenv("NO_COLOR") == ""
+}
+
+// printTable renders a table on stdout, truncated to the terminal width
+// when stdout is one.
+func printTable(t *table.Table) {
+	o := table.Options{Color: useColor()}
+	if isTerminal(os.Stdout) {
+		if width, _, err := term.GetSize(os.Stdout.Fd()); err == nil {
+			o.Width = width
+		}
+	}
+	t.Render(os.Stdout, o)
+}
+
+// interactive reports whether the user can answer prompts: stdin and stderr
+// are terminals. Stdout may be piped.
+func interactive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stderr)
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/codeowners"
+
+	"github.com/spf13/cobra"
+)
+
+var ownerFiles bool
+
+type ownersReport struct {
+	From string `json:"from_release"`
+	To   string `json:"to_release"`
+	codeowners.Summary
+}
+
+var OwnersCmd = &cobra.Command{
+	Use:   "owners [<from> <to>]",
+	Short: "Summarize the changes between two releases per code owner",
+	Long: `Attributes the files changed between two releases to their owners in the
+repository's CODEOWNERS file, so you know whom to ping about an upgrade.
+
+The CODEOWNERS file of the default branch (.github/, the root or docs/) is
+cached by 'ordiff index' and 'ordiff update'. As on GitHub, the last
+matching rule decides; a file with several owners counts for each.
+
+Example:
+  ordiff owners v1.29.0 v1.30.0
+  ordiff owners --since v1.29.0 --files`,
+	Args: rangeArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		rules, source := codeownerRules(db, owner, repo)
+		if source == "" {
+			log.Fatalf("No CODEOWNERS cached for %s/%s. Run 'ordiff update' to fetch it.", owner, repo)
+		}
+
+		from, to := resolveRange(db, owner, repo, args)
+
+		result, err := newFetcher(owner, repo).Compare(db, from, to, false)
+		if err != nil {
+			log.Fatalf("Failed to compare: %v", err)
+		}
+
+		report := ownersReport{From: from, To: to, Summary: codeowners.Summarize(source, rules, result.Files)}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(report)
+			return
+		}
+
+		fmt.Printf("\n=== Owners %s → %s (%s) ===\n\n", from, to, source)
+		printOwners(report.Summary, ownerFiles)
+	},
+}
+
+func init() {
+	addRangeFlags(OwnersCmd)
+	OwnersCmd.Flags().BoolVar(&ownerFiles, "files", false, "List the changed files of every owner")
+}
+
+// codeownerRules returns the rules of the cached CODEOWNERS file of a
+// repository and the path it was read from, empty if none is cached.
+func codeownerRules(db cache.Store, owner, repo string) ([]codeowners.Rule, string) {
+	c, err := db.GetCodeowners(owner, repo)
+	if err == sql.ErrNoRows {
+		return nil, ""
+	}
+	if err != nil {
+		log.Fatalf("Failed to read CODEOWNERS: %v", err)
+	}
+	return codeowners.Parse(c.Content), c.Path
+}
+
+func printOwners(s codeowners.Summary, files bool) {
+	width := len("(unowned)")
+	for _, o := range s.Owners {
+		width = max(width, len(o.Owner))
+	}
+
+	row := func(name string, impact codeowners.Impact) {
+		fmt.Printf("  %-*s  %5d files  %+7d  %7d\n", width, name, len(impact.Files), impact.Additions, -impact.Deletions)
+		if files {
+			for _, f := range impact.Files {
+				fmt.Printf("      %s\n", f)
+			}
+		}
+	}
+	for _, o := range s.Owners {
+		row(o.Owner, o)
+	}
+	if len(s.Unowned.Files) > 0 {
+		row("(unowned)", s.Unowned)
+	}
+}
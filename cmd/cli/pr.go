@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"ordiff/internal/github"
+	"ordiff/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var PrCmd = &cobra.Command{
+	Use:   "pr <number>",
+	Short: "Show a pull request and the release it shipped in",
+	Long: `Shows a cached pull request: title, author, labels, body, the commits that
+reference it and the releases it first shipped in.
+
+A change backported to several release lines lists one release per line.
+For caches without linked commits the first release published after the
+merge is shown instead.
+
+Example:
+  ordiff pr 1234
+  ordiff pr 1234 --repo ollama/ollama --json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			log.Fatalf("Invalid pull request number %q", args[0])
+		}
+
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		d, err := github.NewFetcher(owner, repo, nil).PullRequestDetails(db, number)
+		if err != nil {
+			log.Fatalf("Failed to get pull request: %v", err)
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(d)
+			return
+		}
+
+		pr := d.PullRequest
+		if pr.Title != "" {
+			fmt.Printf("\n#%d %s (@%s)\n", pr.Number, pr.Title, pr.Author)
+		} else {
+			fmt.Printf("\n#%d (not indexed; run 'ordiff index' to fetch pull requests)\n", pr.Number)
+		}
+		if pr.URL != "" {
+			fmt.Printf("%s\n", pr.URL)
+		}
+		fmt.Println()
+		if pr.MergedAt != nil {
+			fmt.Printf("  Merged:      %s\n", pr.MergedAt.Format("2006-01-02"))
+		}
+		if len(pr.Labels) > 0 {
+			fmt.Printf("  Labels:      %s\n", strings.Join(pr.Labels, ", "))
+		}
+		switch {
+		case len(d.Releases) > 0:
+			fmt.Printf("  Shipped in:  %s\n", strings.Join(d.Releases, ", "))
+		case d.FirstRelease != "":
+			fmt.Printf("  Shipped in:  %s (by merge date)\n", d.FirstRelease)
+		default:
+			fmt.Printf("  Shipped in:  not released yet\n")
+		}
+
+		if body := strings.TrimSpace(pr.Body); body != "" {
+			fmt.Println()
+			for _, line := range strings.Split(body, "\n") {
+				fmt.Printf("  %s\n", strings.TrimRight(line, "\r"))
+			}
+		}
+
+		fmt.Printf("\nCommits (%d):\n", len(d.Commits))
+		for _, c := range d.Commits {
+			sha := c.SHA
+			if len(sha) > 7 {
+				sha = sha[:7]
+			}
+			fmt.Printf("  %s  %s  %-20s  %s\n", sha, c.Date.Format("2006-01-02"), c.Author, report.Subject(c.Message))
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(PrCmd)
+	PrCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
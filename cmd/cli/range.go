@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/semver"
+
+	"github.com/spf13/cobra"
+)
+
+var sinceTag string
+
+// resolveRange turns either explicit <from> <to> arguments or --since into a
+// release range. With --since the newest cached release is the upper bound.
+func resolveRange(db cache.Store, owner, repo string, args []string) (string, string) {
+	if sinceTag == "" {
+		if len(args) != 2 {
+			log.Fatal("Specify <from> <to> or --since <version>")
+		}
+		return resolveRef(db, owner, repo, args[0]), resolveRef(db, owner, repo, args[1])
+	}
+
+	if len(args) != 0 {
+		log.Fatal("--since cannot be combined with <from> <to>")
+	}
+
+	releases := cachedReleases(db, owner, repo)
+	return resolveSince(releases, sinceTag), releases[0].TagName
+}
+
+// resolveRef maps a version pattern such as v0.1.x to the newest cached
+// release it matches. Anything else is returned unchanged.
+func resolveRef(db cache.Store, owner, repo, ref string) string {
+	if !semver.IsPattern(ref) {
+		return ref
+	}
+
+	var newest string
+	for _, r := range cachedReleases(db, owner, repo) {
+		if semver.Match(ref, r.TagName) && (newest == "" || semver.CompareStrings(r.TagName, newest) > 0) {
+			newest = r.TagName
+		}
+	}
+	if newest == "" {
+		log.Fatalf("No cached release matches %s", ref)
+	}
+	return newest
+}
+
+// resolveSince picks the lower bound for --since. A cached tag is used as is,
+// a pattern resolves to the oldest release it matches, and any other version
+// to the newest release at or below it.
+func resolveSince(releases []cache.Release, since string) string {
+	for _, r := range releases {
+		if r.TagName == since {
+			return since
+		}
+	}
+
+	var best string
+	if semver.IsPattern(since) {
+		for _, r := range releases {
+			if semver.Match(since, r.TagName) && (best == "" || semver.CompareStrings(r.TagName, best) < 0) {
+				best = r.TagName
+			}
+		}
+	} else if v, ok := semver.Parse(since); ok {
+		for _, r := range releases {
+			rv, ok := semver.Parse(r.TagName)
+			if ok && semver.Compare(rv, v) <= 0 && (best == "" || semver.CompareStrings(r.TagName, best) > 0) {
+				best = r.TagName
+			}
+		}
+	}
+	if best == "" {
+		log.Fatalf("No cached release matches --since %s", since)
+	}
+	return best
+}
+
+func cachedReleases(db cache.Store, owner, repo string) []cache.Release {
+	releases, err := db.GetReleases(owner, repo)
+	if err != nil {
+		log.Fatalf("Failed to get releases: %v", err)
+	}
+	if len(releases) == 0 {
+		log.Fatal("No cached releases. Run 'ordiff index <owner> <repo>' first.")
+	}
+	return releases
+}
+
+func rangeArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 && len(args) != 2 {
+		return fmt.Errorf("accepts 0 or 2 arg(s), received %d", len(args))
+	}
+	return nil
+}
+
+func addRangeFlags(cmd *cobra.Command) {
+	addRepoFlag(cmd)
+	cmd.Flags().StringVar(&sinceTag, "since", "", "Use the range from this version (tag, v1.2.0 or v1.x) to the newest cached release")
+	cmd.Flags().StringVar(&sinceTag, "since-tag", "", "Alias for --since")
+	cmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
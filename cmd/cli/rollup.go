@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/config"
+	"ordiff/internal/filter"
+	"ordiff/internal/github"
+
+	"github.com/spf13/cobra"
+)
+
+var rollupTop int
+
+type rollupReport struct {
+	*github.Rollup
+	Contributors []contributor `json:"contributors"`
+}
+
+var RollupCmd = &cobra.Command{
+	Use:   "rollup [<from> <to>]",
+	Short: "Summarize every release between two releases in one report",
+	Long: `Aggregates the release pairs between two cached releases into one cumulative
+view for upgrading across many versions: each release along the way, the
+commits and PRs of the whole range without duplicates, the churn of every
+file summed over all pairs, and the contributors.
+
+Files added and removed again within the range are left out.
+
+Example:
+  ordiff rollup v0.4.0 v0.9.0
+  ordiff rollup v0.4.x v0.9.x --top 20
+  ordiff rollup --since v0.4.0 --json`,
+	Args: rangeArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from, to := resolveRange(db, owner, repo, args)
+
+		rollup, err := newFetcher(owner, repo).Rollup(db, from, to)
+		if err != nil {
+			log.Fatalf("Failed to roll up releases: %v", err)
+		}
+		report := rollupReport{Rollup: rollup, Contributors: rankContributors(rollup.Commits)}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(report)
+			return
+		}
+
+		fmt.Printf("\n=== Rollup %s → %s (%d releases) ===\n\n", from, to, len(rollup.Steps))
+		for _, s := range rollup.Steps {
+			fmt.Printf("  %-12s %s  %4d commits  %3d PRs  %4d files  +%d -%d\n",
+				s.To, s.PublishedAt.Format("2006-01-02"), s.Commits, s.PullRequests, s.Files, s.Additions, s.Deletions)
+		}
+
+		var additions, deletions int
+		for _, f := range rollup.Files {
+			additions += f.Additions
+			deletions += f.Deletions
+		}
+		fmt.Printf("\nTotal:\n")
+		fmt.Printf("  Commits:        %d\n", len(rollup.Commits))
+		fmt.Printf("  Pull requests:  %d\n", rollup.PrCount)
+		fmt.Printf("  Files changed:  %d (+%d -%d)\n", len(rollup.Files), additions, deletions)
+		fmt.Printf("  Contributors:   %d\n", len(report.Contributors))
+
+		files := append([]cache.FileChange(nil), rollup.Files...)
+		var ignored []cache.FileChange
+		if !showAllFiles {
+			files, ignored = filter.PartitionIgnored(files, config.IgnorePatterns())
+		}
+		if len(files) > 0 {
+			sort.SliceStable(files, func(i, j int) bool { return files[i].Changes > files[j].Changes })
+			fmt.Printf("\nMost changed files:\n")
+			for _, f := range files[:min(rollupTop, len(files))] {
+				fmt.Printf("  %-9s +%d -%d  %s%s\n", f.Status, f.Additions, f.Deletions, f.Filename, renamedFrom(f))
+			}
+			if len(ignored) > 0 {
+				fmt.Printf("  (%d vendored, generated or binary files hidden; use --all to show them)\n", len(ignored))
+			}
+		}
+
+		if len(report.Contributors) > 0 {
+			fmt.Printf("\nTop contributors:\n")
+			for _, c := range report.Contributors[:min(rollupTop, len(report.Contributors))] {
+				fmt.Printf("  %5d  %s\n", c.Commits, c.Name)
+			}
+		}
+	},
+}
+
+func init() {
+	addRangeFlags(RollupCmd)
+	RollupCmd.Flags().IntVar(&rollupTop, "top", 10, "Number of files and contributors to list")
+	RollupCmd.Flags().BoolVar(&showAllFiles, "all", false, "Include vendored, generated and binary files in the most changed files")
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"log"
+	"os"
+
+	"ordiff/internal/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var showSchema bool
+
+// printSchema prints the JSON Schema of a command's --json output for
+// --schema.
+func printSchema(name string) {
+	b, err := schema.Get(name)
+	if err != nil {
+		log.Fatalf("Failed to get schema: %v", err)
+	}
+	os.Stdout.Write(b)
+}
+
+func addSchemaFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&showSchema, "schema", false, "Print the JSON Schema of the --json output and exit")
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"testing"
+
+	"ordiff/internal/analyzer"
+	"ordiff/internal/cache"
+	"ordiff/internal/changelog"
+	"ordiff/internal/codeowners"
+	"ordiff/internal/deps"
+	"ordiff/internal/filter"
+	"ordiff/internal/github"
+	"ordiff/internal/issues"
+	"ordiff/internal/language"
+	"ordiff/internal/risk"
+	"ordiff/internal/schema"
+	"ordiff/internal/schema/schematest"
+
+	"github.com/spf13/viper"
+)
+
+// migrations is an analyzer that reports one finding per changed file.
+type migrations struct{}
+
+func (migrations) Name() string { return "migrations" }
+
+func (migrations) Analyze(in *analyzer.Input) ([]analyzer.Section, error) {
+	s := analyzer.Section{Name: "Changed files"}
+	for _, fc := range in.Result.Files {
+		s.Findings = append(s.Findings, analyzer.Finding{File: fc.Filename, Line: 1, Message: fc.Status})
+	}
+	return []analyzer.Section{s}, nil
+}
+
+func TestCompareJSONMatchesSchema(t *testing.T) {
+	db := schematest.NewStore(t)
+	fetcher := github.NewFetcher(schematest.Owner, schematest.Repo, nil)
+
+	result, err := fetcher.ComputeCompareData(db, schematest.From, schematest.To)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fetcher.LoadPatches(db, result); err != nil {
+		t.Fatal(err)
+	}
+	for _, fc := range result.Files {
+		if fc.Patch == "" {
+			t.Errorf("%s: patch not loaded", fc.Filename)
+		}
+		if fc.Filename == "config.toml" && fc.PreviousFilename != "config.yaml" {
+			t.Errorf("config.toml: previous filename %q, want config.yaml", fc.PreviousFilename)
+		}
+	}
+
+	closed, err := issues.Between(db, schematest.Owner, schematest.Repo, result.Commits, result.PullRequests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metricDiff := metricChanges(db, schematest.Owner, schematest.Repo, schematest.From, schematest.To)
+	if len(metricDiff) == 0 {
+		t.Error("no metric deltas")
+	}
+
+	demoteGenerated, compareByDir, compareNotes = true, true, true
+	viper.Set("components", []map[string]interface{}{{"name": "docs", "paths": []string{"*.md"}}})
+	defer func() {
+		demoteGenerated, compareByDir, compareNotes = false, false, false
+		viper.Set("components", nil)
+	}()
+
+	var generated []cache.FileChange
+	result.Files, generated = filter.PartitionGenerated(result.Files, generatedPatterns())
+	changed := append(append([]cache.FileChange{}, result.Files...), generated...)
+	owners := codeowners.Summarize(".github/CODEOWNERS", codeowners.Parse("go.mod @acme/core"), changed)
+	data := compareJSON(compareTemplate{
+		CompareResult:     result,
+		Generated:         generated,
+		Risk:              risk.Assess(riskConfig(), result.Commits, result.PullRequests, changed),
+		DependencyChanges: deps.ManifestDiffs(changed),
+		AssetChanges:      assetChanges(db, schematest.Owner, schematest.Repo, schematest.From, schematest.To),
+		Metrics:           metricDiff,
+		Analyzers:         analyzer.Run([]analyzer.Analyzer{migrations{}}, schematest.Owner, schematest.Repo, result),
+		ClosedIssues:      closed,
+		ReleaseNotes:      releaseNotesBetween(db, schematest.Owner, schematest.Repo, schematest.From, schematest.To),
+		Languages:         language.Breakdown(changed),
+		Directories:       directories(changed),
+	}, changed, &owners)
+
+	schematest.Validate(t, schema.Compare, data)
+}
+
+func TestListJSONMatchesSchema(t *testing.T) {
+	db := schematest.NewStore(t)
+
+	releases, err := db.GetReleases(schematest.Owner, schematest.Repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := db.GetTags(schematest.Owner, schematest.Repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range releases {
+		releases[i].Tag = tags[releases[i].TagName]
+	}
+
+	schematest.Validate(t, schema.List, releases)
+}
+
+func TestChangelogJSONMatchesSchema(t *testing.T) {
+	db := schematest.NewStore(t)
+
+	doc, err := changelog.NewDocument(db, schematest.Owner, schematest.Repo, schematest.From, schematest.To)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Breaking) == 0 {
+		t.Error("breaking change not reported")
+	}
+
+	schematest.Validate(t, schema.Changelog, doc)
+}
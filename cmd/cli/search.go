@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"ordiff/internal/cache"
+
+	"github.com/spf13/cobra"
+)
+
+var searchLimit int
+
+var SearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Full-text search over cached commits, PRs and release notes",
+	Long: `Searches the commit messages, pull request titles and bodies and release
+notes of a repository. Every word must match; end a word with * to match it
+as a prefix. Hits are ranked best first and show the releases they landed
+between.
+
+Example:
+  ordiff search "gpu memory"
+  ordiff search "cuda*" --repo ollama/ollama --limit 50 --json`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		query := strings.Join(args, " ")
+		hits, err := db.SearchRepo(owner, repo, query, searchLimit)
+		if err != nil {
+			log.Fatalf("Failed to search: %v", err)
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(hits)
+			return
+		}
+
+		fmt.Printf("\n=== %d results for %q in %s/%s ===\n\n", len(hits), query, owner, repo)
+		for _, h := range hits {
+			fmt.Printf("  %-7s %-9s %s  %s\n", h.Kind, hitLabel(h), hitRange(h), h.Title)
+			snippet := strings.Join(strings.Fields(h.Snippet), " ")
+			if snippet != "" && strings.NewReplacer("[", "", "]", "").Replace(snippet) != h.Title {
+				fmt.Printf("                    %s\n", snippet)
+			}
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(SearchCmd)
+	SearchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "Maximum number of results")
+	SearchCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
+
+// hitLabel is a short SHA, #number or release tag.
+func hitLabel(h cache.SearchHit) string {
+	switch h.Kind {
+	case cache.HitCommit:
+		if len(h.Ref) > 7 {
+			return h.Ref[:7]
+		}
+	case cache.HitPullRequest:
+		return "#" + h.Ref
+	}
+	return h.Ref
+}
+
+func hitRange(h cache.SearchHit) string {
+	switch {
+	case h.To == "":
+		return fmt.Sprintf("[after %s]", h.From)
+	case h.From == "":
+		return fmt.Sprintf("[up to %s]", h.To)
+	}
+	return fmt.Sprintf("[%s → %s]", h.From, h.To)
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ordiff/internal/api"
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/provider"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var serveAddr string
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the cache as a JSON HTTP API",
+	Long: `Starts an HTTP server so dashboards and CI jobs can query the cache without
+shelling out to the CLI.
+
+Endpoints:
+  GET  /repos                           cached repositories
+  GET  /repos/{owner}/{repo}/releases   cached releases, newest first
+  GET  /repos/{owner}/{repo}/churn      additions and deletions per release pair
+  GET  /repos/{owner}/{repo}/compare    ?from=&to= comparison
+  GET  /compare                         ?from=&to=[&repo=owner/name], default repository
+  POST /index                           {"owner": "...", "repo": "...", "provider": "github", "update": false},
+                                        indexes in the background as a job
+  GET  /index                           ?job=, status of that or of the last index job
+
+Compare responses carry an ETag header and honor If-None-Match. Index jobs
+are recorded in the job history shown by 'ordiff jobs'.
+
+The server listens on 127.0.0.1 unless --addr says otherwise. It has no
+authentication and POST /index spends the API quota of the configured
+token, so expose it on other interfaces only behind a proxy that
+authenticates requests.
+
+Example:
+  ordiff serve
+  ordiff serve --addr 127.0.0.1:9000`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+
+		db := openDB()
+		defer db.Close()
+
+		slog.Info("Serving ordiff API", "addr", serveAddr)
+		s := newAPIServer(db)
+		listenAndServe(serveAddr, s.Handler(), s)
+	},
+}
+
+// listenAndServe serves h on addr until SIGINT or SIGTERM. It then stops
+// accepting requests, cancels the background indexes of s and waits for
+// both to finish, so the cache is closed with nothing half written.
+func listenAndServe(addr string, h http.Handler, s *api.Server) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: h}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		log.Fatalf("Failed to serve: %v", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("Shutting down")
+	wait, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(wait); err != nil {
+		slog.Warn("Failed to shut down", "err", err)
+	}
+	if err := s.Shutdown(wait); err != nil {
+		slog.Warn("Failed to stop indexing", "err", err)
+	}
+}
+
+func newAPIServer(db cache.Store) *api.Server {
+	return &api.Server{
+		DB: db,
+		NewFetcher: func(owner, repo string) *github.Fetcher {
+			return newFetcher(owner, repo)
+		},
+		NewIndexer: func(owner, repo, name string) provider.Fetcher {
+			return newProviderFetcher(owner, repo, name, "")
+		},
+		DefaultOwner: viper.GetString("default_owner"),
+		DefaultRepo:  viper.GetString("default_repo"),
+	}
+}
+
+func init() {
+	ServeCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "Address to listen on; the API can start indexes, so listen on other interfaces only behind an authenticating proxy")
+}
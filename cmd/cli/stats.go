@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"ordiff/internal/changelog"
+	"ordiff/internal/github"
+
+	"github.com/spf13/cobra"
+)
+
+type releaseStats struct {
+	From         string `json:"from_release"`
+	To           string `json:"to_release"`
+	Commits      int    `json:"commits"`
+	PRs          int    `json:"prs"`
+	Contributors int    `json:"contributors"`
+	FilesChanged int    `json:"files_changed"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+
+	CommitTypes changelog.TypeStats `json:"commit_types"`
+}
+
+var StatsCmd = &cobra.Command{
+	Use:   "stats [<from> <to>]",
+	Short: "Show churn statistics between two releases",
+	Long: `Summarizes commits, PRs, contributors and line churn between two releases,
+and breaks the commits down by conventional-commit type (feat, fix, ...)
+and scope.
+
+Example:
+  ordiff stats v0.1.0 v0.2.0
+  ordiff stats --since v2.0.0`,
+	Args: rangeArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		from, to := resolveRange(db, owner, repo, args)
+
+		fetcher := github.NewFetcher(owner, repo, nil)
+		result, err := fetcher.GetCompareData(db, from, to)
+		if err != nil {
+			log.Fatalf("Failed to compare: %v", err)
+		}
+
+		stats := releaseStats{
+			From:         from,
+			To:           to,
+			Commits:      len(result.Commits),
+			PRs:          result.PrCount,
+			Contributors: len(rankContributors(result.Commits)),
+			FilesChanged: len(result.Files),
+			CommitTypes:  changelog.CommitTypes(result.Commits),
+		}
+		for _, f := range result.Files {
+			stats.Additions += f.Additions
+			stats.Deletions += f.Deletions
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(stats)
+			return
+		}
+
+		fmt.Printf("\n=== Stats %s → %s ===\n\n", from, to)
+		fmt.Printf("  Commits:       %d\n", stats.Commits)
+		fmt.Printf("  PRs:           %d\n", stats.PRs)
+		fmt.Printf("  Contributors:  %d\n", stats.Contributors)
+		fmt.Printf("  Files changed: %d\n", stats.FilesChanged)
+		fmt.Printf("  Lines:         +%d -%d\n", stats.Additions, stats.Deletions)
+		if stats.CommitTypes.Conventional > 0 {
+			fmt.Println()
+			printCommitTypes(stats.CommitTypes)
+		}
+	},
+}
+
+// printCommitTypes prints the conventional-commit breakdown of a range.
+func printCommitTypes(s changelog.TypeStats) {
+	fmt.Printf("Commit Types: %s", s)
+	if s.Breaking > 0 {
+		fmt.Printf(" (%d breaking)", s.Breaking)
+	}
+	fmt.Println()
+	if len(s.Scopes) == 0 {
+		return
+	}
+	scopes := make([]string, len(s.Scopes))
+	for i, sc := range s.Scopes {
+		scopes[i] = fmt.Sprintf("%s (%d)", sc.Scope, sc.Count)
+	}
+	fmt.Printf("Top Scopes:   %s\n", strings.Join(scopes, ", "))
+}
+
+func init() {
+	addRangeFlags(StatsCmd)
+}
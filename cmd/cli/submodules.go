@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/report"
+	"ordiff/internal/submodule"
+	"ordiff/internal/table"
+	"ordiff/internal/vendored"
+)
+
+// loadSubmodulePatches loads the patches that tell whether the files of a
+// comparison are submodules: those of .gitmodules and of every file small
+// enough to be a gitlink.
+func loadSubmodulePatches(db cache.Store, fetcher *github.Fetcher, result *github.CompareResult) {
+	candidates := map[string]bool{}
+	for _, fc := range result.Files {
+		if fc.Patch == "" && (fc.Filename == submodule.File || submodule.MaybeGitlink(fc)) {
+			candidates[fc.Filename] = true
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	if err := fetcher.LoadPatchesFor(db, result, func(filename string) bool { return candidates[filename] }); err != nil {
+		log.Fatalf("Failed to load patches: %v", err)
+	}
+}
+
+// submoduleChanges resolves the submodules moved in files, with the range
+// of those whose repository is cached. Their URLs come from the .gitmodules
+// changes in files, the vendored config, and otherwise .gitmodules at ref.
+func submoduleChanges(db cache.Store, fetcher *github.Fetcher, owner, ref string, files []cache.FileChange, vendoredDeps []vendored.Dependency) []submodule.Change {
+	var modules []submodule.Module
+	for _, fc := range files {
+		if fc.Filename == submodule.File {
+			modules = append(modules, submodule.ParsePatch(fc.Patch)...)
+		}
+	}
+	for _, d := range vendoredDeps {
+		modules = append(modules, submodule.Module{
+			Name: d.Name,
+			Path: strings.TrimSuffix(d.Path, "/"),
+			URL:  strings.TrimSuffix(report.BaseURL, "/") + "/" + d.Upstream,
+		})
+	}
+
+	changes := submodule.Changes(files, modules, owner)
+	for _, c := range changes {
+		if c.URL != "" {
+			continue
+		}
+		content, err := fetcher.FileContent(submodule.File, ref)
+		if err != nil {
+			slog.Warn("Failed to fetch .gitmodules", "ref", ref, "err", err)
+			break
+		}
+		changes = submodule.Changes(files, append(modules, submodule.Parse(content)...), owner)
+		break
+	}
+
+	for i, c := range changes {
+		r, err := submodule.Summarize(db, c)
+		if err != nil {
+			slog.Warn("Failed to read the submodule range", "path", c.Path, "err", err)
+			continue
+		}
+		changes[i].Range = r
+	}
+	return changes
+}
+
+func printSubmodules(changes []submodule.Change) {
+	fmt.Println("Submodules:")
+	for _, c := range changes {
+		name := c.Path
+		if c.Owner != "" {
+			name += " (" + c.Owner + "/" + c.Repo + ")"
+		}
+		switch c.Status {
+		case submodule.StatusAdded:
+			fmt.Printf("  %s: added at %s\n", name, shortVersion(c.To))
+		case submodule.StatusRemoved:
+			fmt.Printf("  %s: removed, was at %s\n", name, shortVersion(c.From))
+		default:
+			fmt.Printf("  %s: moved from %s to %s\n", name, shortVersion(c.From), shortVersion(c.To))
+		}
+
+		r := c.Range
+		if r == nil {
+			continue
+		}
+		span := ""
+		if r.FromRelease != "" || r.ToRelease != "" {
+			span = fmt.Sprintf(", releases %s → %s", orUnreleased(r.FromRelease), orUnreleased(r.ToRelease))
+		}
+		summary := plural(len(r.Commits), "commit")
+		if r.Backward {
+			summary += " undone by moving back"
+		}
+		fmt.Printf("    %s, %s%s\n", summary, plural(r.PullRequests, "PR"), span)
+		t := table.New(table.Column{Color: table.Yellow}, table.Column{Truncate: table.TrimEnd})
+		t.Indent = "      "
+		for i := len(r.Commits) - 1; i >= 0 && i >= len(r.Commits)-3; i-- {
+			subject, _, _ := strings.Cut(r.Commits[i].Message, "\n")
+			t.Row(r.Commits[i].SHA[:min(7, len(r.Commits[i].SHA))], subject)
+		}
+		printTable(t)
+		if len(r.Commits) > 3 {
+			fmt.Printf("      ... and %d more commits\n", len(r.Commits)-3)
+		}
+	}
+}
+
+// orUnreleased names a commit no cached release shipped yet.
+func orUnreleased(tag string) string {
+	if tag == "" {
+		return "unreleased"
+	}
+	return tag
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"ordiff/internal/tmpl"
+
+	"github.com/spf13/cobra"
+)
+
+var templateFile string
+
+// readTemplate returns the text of the --template file, or "" without one.
+func readTemplate() string {
+	if templateFile == "" {
+		return ""
+	}
+	b, err := os.ReadFile(templateFile)
+	if err != nil {
+		log.Fatalf("Failed to read template: %v", err)
+	}
+	return string(b)
+}
+
+// executeTemplate renders data with the --template file.
+func executeTemplate(w io.Writer, data interface{}) {
+	if err := tmpl.Execute(w, filepath.Base(templateFile), readTemplate(), data); err != nil {
+		log.Fatalf("Failed to render template: %v", err)
+	}
+}
+
+func addTemplateFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&templateFile, "template", "t", "", "Go template file for the output")
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"ordiff/internal/timeline"
+
+	"github.com/spf13/cobra"
+)
+
+var TimelineCmd = &cobra.Command{
+	Use:   "timeline",
+	Short: "Show the release history with cadence and churn",
+	Long: `Walks every cached release oldest first and shows the days since the
+previous release, its commits, contributors and line churn, followed by a
+sparkline of churn across all releases.
+
+Example:
+  ordiff timeline
+  ordiff timeline --repo ollama/ollama --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		entries, err := timeline.Build(db, owner, repo)
+		if err != nil {
+			log.Fatalf("Failed to build timeline: %v", err)
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(entries)
+			return
+		}
+
+		fmt.Printf("\n=== Timeline of %s/%s ===\n\n", owner, repo)
+		if len(entries) == 0 {
+			fmt.Println("No cached releases.")
+			return
+		}
+
+		width := len("Release")
+		for _, e := range entries {
+			width = max(width, len(e.Tag))
+		}
+
+		fmt.Printf("  %-*s  %-10s  %5s  %7s  %7s  %7s  %7s\n", width, "Release", "Date", "Days", "Commits", "Authors", "+Add", "-Del")
+		churn := make([]int, 0, len(entries))
+		var gaps []float64
+		for _, e := range entries {
+			if e.Previous == "" {
+				fmt.Printf("  %-*s  %-10s  %5s  %7s  %7s  %7s  %7s\n", width, e.Tag, e.Date.Format("2006-01-02"), "-", "-", "-", "-", "-")
+				continue
+			}
+			fmt.Printf("  %-*s  %-10s  %5.0f  %7d  %7d  %+7d  %7d\n", width, e.Tag, e.Date.Format("2006-01-02"),
+				e.Days, e.Commits, e.Contributors, e.Additions, -e.Deletions)
+			churn = append(churn, e.Churn())
+			gaps = append(gaps, e.Days)
+		}
+
+		if len(gaps) == 0 {
+			return
+		}
+		sort.Float64s(gaps)
+		span := entries[len(entries)-1].Date.Sub(entries[0].Date).Hours() / 24
+		fmt.Printf("\nChurn: %s\n", timeline.Sparkline(churn))
+		fmt.Printf("%d releases over %.0f days, median %.0f days between releases\n", len(entries), span, gaps[len(gaps)/2])
+	},
+}
+
+func init() {
+	addRepoFlag(TimelineCmd)
+	TimelineCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+}
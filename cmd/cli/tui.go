@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/tui"
+
+	"github.com/spf13/cobra"
+)
+
+var TuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse releases, commits and patches in the terminal",
+	Long: `Opens a keyboard-driven browser for the cache. The left pane lists the
+cached releases; press enter on two of them to compare them. The right pane
+then lists the commits or (with tab) the files between them, and enter on a
+file shows its cached patch with syntax highlighting. Esc goes back, q quits.
+
+Only cached data is shown, so it works offline.
+
+Example:
+  ordiff tui
+  ordiff tui --repo ollama/ollama`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		if err := tui.Run(db, owner, repo); err != nil {
+			log.Fatalf("Failed to run TUI: %v", err)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(TuiCmd)
+}
+
+// pickRange asks for two cached releases with the interactive picker and
+// returns them as from and to arguments.
+func pickRange(db cache.Store, owner, repo string) []string {
+	releases := cachedReleases(db, owner, repo)
+	if len(releases) < 2 {
+		log.Fatalf("Need at least two cached releases of %s/%s to compare", owner, repo)
+	}
+	from, to, err := tui.PickRange(releases)
+	if errors.Is(err, tui.ErrCancelled) {
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("Failed to run release picker: %v", err)
+	}
+	return []string{from, to}
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+
+	"ordiff/internal/changelog"
+	"ordiff/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+var UpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Fetch releases published since the last index",
+	Long: `Fetches only the releases newer than the latest cached one and fills in any
+release pairs that are still missing, instead of re-walking every release
+like index does. The repository is updated from the forge it was indexed
+from.
+
+Example:
+  ordiff update
+  ordiff update --repo ollama/ollama`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		fmt.Printf("Updating %s/%s...\n", owner, repo)
+
+		db := openDB()
+		defer db.Close()
+
+		providerName, location, err := db.GetRepositoryProvider(owner, repo)
+		if err != nil {
+			slog.Warn("Failed to look up repository provider", "err", err)
+		}
+
+		fetcher := configureFetcher(newProviderFetcher(owner, repo, providerName, location), db)
+		err = runIndexJob(db, "update", owner, repo, fetcher, func() error { return fetcher.Update(indexStore(db)) })
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("Update cancelled; the release pairs fetched so far are cached. Run 'ordiff index --resume' to continue.")
+			return
+		}
+		if err != nil && !provider.Partial(err) {
+			log.Fatalf("Failed to update: %v", err)
+		}
+		if providerName != "" {
+			if err := db.SaveRepository(owner, repo, providerName, location); err != nil {
+				slog.Warn("Failed to record repository", "err", err)
+			}
+		}
+
+		if precomputeChangelogs {
+			n, err := changelog.Precompute(db, owner, repo)
+			if err != nil {
+				slog.Warn("Failed to precompute changelogs", "err", err)
+			}
+			fmt.Printf("Precomputed %d changelogs\n", n)
+		}
+
+		if err != nil {
+			exitIncomplete(err, fetcher)
+		}
+		fmt.Println("Update complete!")
+	},
+}
+
+func init() {
+	addRepoFlag(UpdateCmd)
+	UpdateCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of release pairs to fetch in parallel (GitHub only)")
+	UpdateCmd.Flags().IntVar(&retries, "retries", -1, "Times to retry a request that fails with a server or network error (default retry.attempts in the config, else 3)")
+	UpdateCmd.Flags().BoolVar(&useGraphQL, "graphql", false, "Fetch through the GitHub GraphQL API (needs a token)")
+	UpdateCmd.Flags().BoolVar(&useTags, "tags", false, "Index new git tags as releases (for repositories indexed with --tags)")
+	UpdateCmd.Flags().BoolVar(&precomputeChangelogs, "precompute-changelogs", false, "Generate and store a changelog for every release")
+	UpdateCmd.Flags().BoolVar(&noPatches, "no-patches", false, "Do not store patches of the new release pairs, except those of dependency manifests, lockfiles, vendored version files and submodules")
+}
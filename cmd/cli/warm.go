@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+
+	"ordiff/internal/github"
+
+	"github.com/spf13/cobra"
+)
+
+var warmAllPairs bool
+
+var WarmCmd = &cobra.Command{
+	Use:   "warm",
+	Short: "Precompute and cache comparison results",
+	Long: `Computes the comparison for every pair of consecutive cached releases and
+stores the result so compare and API reads are served without re-running
+the cache queries. With --all-pairs every older → newer combination of
+releases is warmed. Cached comparisons are invalidated on re-index.
+
+Example:
+  ordiff warm
+  ordiff warm --all-pairs`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+
+		db := openDB()
+		defer db.Close()
+
+		var pairs [][2]string
+		if warmAllPairs {
+			releases, err := db.GetReleases(owner, repo)
+			if err != nil {
+				log.Fatalf("Failed to get releases: %v", err)
+			}
+			for i := len(releases) - 1; i > 0; i-- {
+				for j := i - 1; j >= 0; j-- {
+					pairs = append(pairs, [2]string{releases[i].TagName, releases[j].TagName})
+				}
+			}
+		} else {
+			var err error
+			pairs, err = consecutivePairs(db, owner, repo)
+			if err != nil {
+				log.Fatalf("Failed to get releases: %v", err)
+			}
+		}
+
+		fetcher := github.NewFetcher(owner, repo, nil)
+		warmed := 0
+		for _, p := range pairs {
+			if _, err := fetcher.WarmCompareData(db, p[0], p[1]); err != nil {
+				slog.Warn("Failed to warm pair", "from", p[0], "to", p[1], "err", err)
+				continue
+			}
+			warmed++
+		}
+
+		fmt.Printf("Warmed %d/%d comparisons for %s/%s\n", warmed, len(pairs), owner, repo)
+	},
+}
+
+func init() {
+	addRepoFlag(WarmCmd)
+	WarmCmd.Flags().BoolVar(&warmAllPairs, "all-pairs", false, "Warm every older → newer release combination")
+}
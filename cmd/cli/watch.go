@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/notify"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval time.Duration
+	watchOnce     bool
+)
+
+var WatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Poll for new releases, index them and send notifications",
+	Long: `Runs 'ordiff update' every --interval. For every new release, the
+comparison with the release before it is logged and posted to the configured
+notification targets:
+
+  --webhook URL          the comparison as JSON (commit, PR and file counts
+                         plus a text summary)
+  --slack-webhook URL    a Slack message from an incoming webhook
+  --discord-webhook URL  a Discord embed
+
+They default to notify.webhook, notify.slack_webhook and
+notify.discord_webhook in the config. The first poll of a repository that
+was never indexed only indexes it, without announcing its whole history.
+
+Example:
+  ordiff watch --interval 1h --slack-webhook https://hooks.slack.com/services/...
+  ordiff watch --repo ollama/ollama --webhook https://example.com/hook --once`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, repo := defaultRepo()
+		loadNotifyTargets()
+		if watchInterval < time.Minute && !watchOnce {
+			log.Fatal("--interval must be at least 1m")
+		}
+
+		db := openDB()
+		defer db.Close()
+
+		for {
+			watchOnceFor(db, owner, repo)
+			if watchOnce {
+				return
+			}
+			slog.Info("Next check", "at", time.Now().Add(watchInterval).Format("15:04:05"))
+			time.Sleep(watchInterval)
+		}
+	},
+}
+
+func init() {
+	addRepoFlag(WatchCmd)
+	WatchCmd.Flags().DurationVar(&watchInterval, "interval", time.Hour, "Time between polls")
+	WatchCmd.Flags().BoolVar(&watchOnce, "once", false, "Poll once and exit (for cron jobs)")
+	addNotifyFlags(WatchCmd)
+	WatchCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of release pairs to fetch in parallel (GitHub only)")
+	WatchCmd.Flags().IntVar(&retries, "retries", -1, "Times to retry a request that fails with a server or network error (default retry.attempts in the config, else 3)")
+}
+
+// watchOnceFor updates a repository and announces the releases that were
+// not cached before, oldest first.
+func watchOnceFor(db cache.Store, owner, repo string) {
+	before, err := db.GetReleases(owner, repo)
+	if err != nil {
+		slog.Warn("Failed to read cached releases", "err", err)
+		return
+	}
+	known := make(map[string]bool, len(before))
+	for _, r := range before {
+		known[r.TagName] = true
+	}
+
+	providerName, location, err := db.GetRepositoryProvider(owner, repo)
+	if err != nil {
+		slog.Warn("Failed to look up repository provider", "err", err)
+	}
+	fetcher := configureFetcher(newProviderFetcher(owner, repo, providerName, location), db)
+	if err := fetcher.Update(db); err != nil {
+		slog.Warn("Failed to update", "repo", owner+"/"+repo, "err", err)
+		return
+	}
+
+	after, err := db.GetReleases(owner, repo)
+	if err != nil {
+		slog.Warn("Failed to read cached releases", "err", err)
+		return
+	}
+	if len(before) == 0 {
+		slog.Info("Indexed releases, watching for new ones", "repo", owner+"/"+repo, "count", len(after))
+		return
+	}
+
+	compare := github.NewFetcher(owner, repo, nil)
+	announced := 0
+	for i := len(after) - 2; i >= 0; i-- {
+		if known[after[i].TagName] {
+			continue
+		}
+		result, err := compare.GetCompareData(db, after[i+1].TagName, after[i].TagName)
+		if err != nil {
+			slog.Warn("Failed to compare", "tag", after[i].TagName, "err", err)
+			continue
+		}
+		announced++
+		fmt.Printf("%s\n\n", notify.Summary(result))
+		announce(result)
+	}
+	if announced == 0 {
+		slog.Info("No new releases", "repo", owner+"/"+repo)
+	}
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"log/slog"
+
+	"ordiff/internal/web"
+
+	"github.com/spf13/cobra"
+)
+
+var webAddr string
+
+var WebCmd = &cobra.Command{
+	Use:   "web",
+	Short: "Serve the web dashboard",
+	Long: `Serves a single-page dashboard listing the indexed repositories and their
+releases, charting additions and deletions per release pair, and rendering
+comparisons with collapsible per-file diffs. The JSON API of 'ordiff serve'
+is mounted under /api/.
+
+Example:
+  ordiff web
+  ordiff web --addr 127.0.0.1:9000`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+
+		db := openDB()
+		defer db.Close()
+
+		slog.Info("Serving ordiff dashboard", "addr", webAddr)
+		s := newAPIServer(db)
+		listenAndServe(webAddr, web.Handler(s.Handler()), s)
+	},
+}
+
+func init() {
+	WebCmd.Flags().StringVar(&webAddr, "addr", "127.0.0.1:8080", "Address to listen on; the API can start indexes, so listen on other interfaces only behind an authenticating proxy")
+}
@@ -1,9 +1,19 @@
 package mcp
 
 import (
+	"log"
+
+	"ordiff/internal/config"
+	"ordiff/internal/logging"
+
 	"github.com/spf13/cobra"
 )
 
+var (
+	httpAddr string
+	logFile  string
+)
+
 var McpCmd = &cobra.Command{
 	Use:   "mcp",
 	Short: "Run ordiff as an MCP server",
@@ -12,9 +22,35 @@ var McpCmd = &cobra.Command{
 This is used by MCP clients like Claude Desktop or opencode to access
 ordiff's tools for comparing GitHub releases.
 
+With --http the server listens for JSON-RPC requests POSTed to /mcp on the
+given address instead, so remote clients and several agents can share one
+long-lived instance and its cache.
+
+Over stdio the log goes to a file, mcp.log in the ordiff cache directory by
+default, so nothing but the protocol reaches the client; over HTTP it goes
+to stderr. --log-file picks another file, or stderr with '-'.
+
 Example:
-  ordiff mcp`,
+  ordiff mcp
+  ordiff mcp --http :8080
+  ordiff mcp --log-file - --verbose`,
 	Run: func(cmd *cobra.Command, args []string) {
-		RunServer()
+		path := logFile
+		if path == "" && httpAddr == "" {
+			path = config.MCPLogPath()
+		}
+		if path != "" && path != "-" {
+			f, err := logging.SetupFile(path)
+			if err != nil {
+				log.Fatalf("Failed to open log file: %v", err)
+			}
+			defer f.Close()
+		}
+		RunServer(httpAddr)
 	},
 }
+
+func init() {
+	McpCmd.Flags().StringVar(&httpAddr, "http", "", "Serve over HTTP on this address instead of stdio")
+	McpCmd.Flags().StringVar(&logFile, "log-file", "", "Log to this file, or to stderr with '-' (default mcp.log in the cache directory over stdio, stderr over HTTP)")
+}
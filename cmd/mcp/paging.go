@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ordiff/internal/github"
+)
+
+const (
+	// maxPageSize caps the limit argument of the paged tools.
+	maxPageSize = 500
+	// maxMergedPRs caps the merged PRs listed by compare_releases and
+	// summarize_data; the rest are counted.
+	maxMergedPRs = 100
+)
+
+// pageCursor is what a next_cursor encodes: the position in the commits of
+// one comparison, so a cursor is not accidentally reused for another.
+type pageCursor struct {
+	Repo   string `json:"r"`
+	From   string `json:"f"`
+	To     string `json:"t"`
+	Offset int    `json:"o"`
+}
+
+func (c pageCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// commitPage is the window of commits a tool call returns.
+type commitPage struct {
+	Start, End int
+	Total      int
+	Next       string // cursor of the next page, empty on the last page
+}
+
+// pageCommits resolves the limit, offset and cursor arguments of a paged
+// tool against the total number of commits of from..to. A cursor takes
+// precedence over offset.
+func pageCommits(owner, repo, from, to string, limit, offset int, cursor string, defaultLimit, total int) (commitPage, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	limit = min(limit, maxPageSize)
+
+	if cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(cursor)
+		var c pageCursor
+		if err == nil {
+			err = json.Unmarshal(b, &c)
+		}
+		if err != nil {
+			return commitPage{}, fmt.Errorf("invalid cursor")
+		}
+		if c.Repo != owner+"/"+repo || c.From != from || c.To != to {
+			return commitPage{}, fmt.Errorf("cursor is for %s %s..%s, not %s/%s %s..%s", c.Repo, c.From, c.To, owner, repo, from, to)
+		}
+		offset = c.Offset
+	}
+	if offset < 0 {
+		return commitPage{}, fmt.Errorf("offset must not be negative")
+	}
+
+	p := commitPage{Start: min(offset, total), Total: total}
+	p.End = min(p.Start+limit, total)
+	if p.End < total {
+		p.Next = pageCursor{Repo: owner + "/" + repo, From: from, To: to, Offset: p.End}.String()
+	}
+	return p, nil
+}
+
+type commitsPage struct {
+	FromRelease string           `json:"from_release"`
+	ToRelease   string           `json:"to_release"`
+	Total       int              `json:"total"`
+	Offset      int              `json:"offset"`
+	Commits     []pageCommitInfo `json:"commits"`
+	NextCursor  string           `json:"next_cursor,omitempty"`
+}
+
+type pageCommitInfo struct {
+	SHA      string `json:"sha"`
+	Message  string `json:"message"`
+	Author   string `json:"author"`
+	Date     string `json:"date"`
+	URL      string `json:"url,omitempty"`
+	PrNumber *int   `json:"pr_number,omitempty"`
+}
+
+// formatCommitsPage lists the commits of a page in full, unlike the
+// abbreviated commits of compare_releases and summarize_data.
+func formatCommitsPage(r *github.CompareResult, page commitPage) commitsPage {
+	out := commitsPage{
+		FromRelease: r.FromRelease.TagName,
+		ToRelease:   r.ToRelease.TagName,
+		Total:       page.Total,
+		Offset:      page.Start,
+		Commits:     make([]pageCommitInfo, 0, page.End-page.Start),
+		NextCursor:  page.Next,
+	}
+	for _, c := range r.Commits[page.Start:page.End] {
+		out.Commits = append(out.Commits, pageCommitInfo{
+			SHA:      c.SHA,
+			Message:  c.Message,
+			Author:   c.Author,
+			Date:     c.Date.Format(time.RFC3339),
+			URL:      c.URL,
+			PrNumber: c.PrNumber,
+		})
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/config"
+	"ordiff/internal/filter"
+	"ordiff/internal/github"
+
+	"github.com/metoro-io/mcp-golang"
+)
+
+// mcpServer is the running server, so indexing jobs can publish the
+// resources of the releases they add.
+var mcpServer *mcp_golang.Server
+
+const (
+	jsonMIME     = "application/json"
+	markdownMIME = "text/markdown"
+)
+
+// resourceURI builds ordiff://owner/repo/<parts>, escaping each part so tags
+// containing slashes stay one path segment.
+func resourceURI(owner, repo string, parts ...string) string {
+	uri := "ordiff://" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+	for _, p := range parts {
+		uri += "/" + p
+	}
+	return uri
+}
+
+// registerResources publishes the releases of every cached repository and
+// the comparison of each adjacent release pair as MCP resources. The library
+// only serves resources registered under their exact URI, so pairs are
+// registered one by one rather than as a URI template; other comparisons go
+// through the compare_releases tool.
+func registerResources(server *mcp_golang.Server, db cache.Store) error {
+	repos, err := db.GetRepositories()
+	if err != nil {
+		return err
+	}
+	for _, r := range repos {
+		if err := registerRepoResources(server, db, r.Owner, r.Repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerRepoResources publishes the resources of one repository that are
+// not registered yet:
+//
+//	ordiff://owner/repo/releases                the list --json output
+//	ordiff://owner/repo/compare/<from>..<to>    the summarize_data output
+//	ordiff://owner/repo/changelog/<tag>         the precomputed changelog
+func registerRepoResources(server *mcp_golang.Server, db cache.Store, owner, repo string) error {
+	if server == nil {
+		return nil
+	}
+	releases, err := db.GetReleases(owner, repo)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	registerAs := func(uri, name, description, mime string, handler func() (*mcp_golang.ResourceResponse, error)) {
+		if server.CheckResourceRegistered(uri) {
+			return
+		}
+		// Registering while serving notifies the client, which may fail on
+		// transports without a session; the resource is registered anyway.
+		if err := server.RegisterResource(uri, name, description, mime, handler); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	register := func(uri, name, description string, handler func() (*mcp_golang.ResourceResponse, error)) {
+		registerAs(uri, name, description, jsonMIME, handler)
+	}
+
+	uri := resourceURI(owner, repo, "releases")
+	register(uri, owner+"/"+repo+" releases", "Cached releases of "+owner+"/"+repo+", newest first", func() (*mcp_golang.ResourceResponse, error) {
+		releases, err := db.GetReleases(owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(releases, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(uri, string(data), jsonMIME)), nil
+	})
+
+	// GetReleases is newest first.
+	for i := 0; i+1 < len(releases); i++ {
+		from, to := releases[i+1].TagName, releases[i].TagName
+		uri := resourceURI(owner, repo, "compare", url.PathEscape(from)+".."+url.PathEscape(to))
+		register(uri, owner+"/"+repo+" "+from+".."+to, "Commits, PRs and top files from "+from+" to "+to, func() (*mcp_golang.ResourceResponse, error) {
+			result, err := github.NewFetcher(owner, repo, nil).GetCompareData(db, from, to)
+			if err != nil {
+				return nil, err
+			}
+			var ignored []cache.FileChange
+			result.Files, ignored = filter.PartitionIgnored(result.Files, config.IgnorePatterns())
+			page, err := pageCommits(owner, repo, from, to, 0, 0, "", 20, len(result.Commits))
+			if err != nil {
+				return nil, err
+			}
+			return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(uri, formatSummaryData(result, page, len(ignored)), jsonMIME)), nil
+		})
+	}
+
+	// Only releases with a precomputed changelog get one; get_changelog
+	// reads those precomputed after the server started.
+	for _, r := range releases {
+		tag := r.TagName
+		if _, ok, err := db.GetChangelog(owner, repo, tag); err != nil || !ok {
+			continue
+		}
+		uri := resourceURI(owner, repo, "changelog", url.PathEscape(tag))
+		registerAs(uri, owner+"/"+repo+" "+tag+" changelog", "Changelog of "+tag, markdownMIME, func() (*mcp_golang.ResourceResponse, error) {
+			md, ok, err := db.GetChangelog(owner, repo, tag)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("no precomputed changelog for %s", tag)
+			}
+			return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(uri, md, markdownMIME)), nil
+		})
+	}
+	return firstErr
+}
@@ -0,0 +1,25 @@
+package mcp
+
+import (
+	"testing"
+
+	"ordiff/internal/github"
+	"ordiff/internal/schema"
+	"ordiff/internal/schema/schematest"
+)
+
+func TestSummaryDataMatchesSchema(t *testing.T) {
+	db := schematest.NewStore(t)
+	fetcher := github.NewFetcher(schematest.Owner, schematest.Repo, nil)
+
+	result, err := fetcher.ComputeCompareData(db, schematest.From, schematest.To)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := pageCommits(schematest.Owner, schematest.Repo, schematest.From, schematest.To, 1, 0, "", 20, len(result.Commits))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schematest.ValidateJSON(t, schema.Summary, []byte(formatSummaryData(result, page, 0)))
+}
@@ -1,30 +1,152 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
-	"sync"
+	"strings"
+	"syscall"
 
 	"ordiff/internal/cache"
+	"ordiff/internal/changelog"
+	"ordiff/internal/config"
+	"ordiff/internal/filter"
 	"ordiff/internal/github"
+	"ordiff/internal/issues"
+	"ordiff/internal/jobs"
+	"ordiff/internal/language"
+	"ordiff/internal/provider"
+	"ordiff/internal/report"
+	"ordiff/internal/risk"
+	"ordiff/internal/schema"
+	"ordiff/internal/timeline"
 
 	"github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport"
+	mcphttp "github.com/metoro-io/mcp-golang/transport/http"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
 	"github.com/spf13/viper"
 )
 
 type CompareArgs struct {
-	From string `json:"from" jsonschema:"required,description=The older release tag or commit SHA"`
-	To   string `json:"to" jsonschema:"required,description=The newer release tag or commit SHA"`
+	From    string   `json:"from" jsonschema:"required,description=The older release tag, branch or commit SHA"`
+	To      string   `json:"to" jsonschema:"required,description=The newer release tag, branch or commit SHA"`
+	Repo    string   `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+	Path    []string `json:"path,omitempty" jsonschema:"description=Only include files under these paths or matching these globs (e.g. 'server/' or '*.go')"`
+	Exclude []string `json:"exclude,omitempty" jsonschema:"description=Leave out files under these paths or matching these globs"`
+	Limit   int      `json:"limit,omitempty" jsonschema:"description=Number of commits to list (default 5 for compare_releases and 20 for summarize_data; at most 500)"`
+	Offset  int      `json:"offset,omitempty" jsonschema:"description=Number of commits to skip"`
+	Cursor  string   `json:"cursor,omitempty" jsonschema:"description=next_cursor of a previous call, to list the following commits"`
+	All     bool     `json:"all,omitempty" jsonschema:"description=Include vendored, generated and binary files, which are left out by default"`
 }
 
-type ListReleasesArgs struct{}
+type CommitsPageArgs struct {
+	From   string `json:"from" jsonschema:"required,description=The older release tag, branch or commit SHA"`
+	To     string `json:"to" jsonschema:"required,description=The newer release tag, branch or commit SHA"`
+	Repo   string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+	Limit  int    `json:"limit,omitempty" jsonschema:"description=Number of commits per page (default 100; at most 500)"`
+	Offset int    `json:"offset,omitempty" jsonschema:"description=Number of commits to skip"`
+	Cursor string `json:"cursor,omitempty" jsonschema:"description=next_cursor of the previous page"`
+}
+
+type ReleaseNotesArgs struct {
+	From string `json:"from" jsonschema:"required,description=The older release tag, branch or commit SHA"`
+	To   string `json:"to" jsonschema:"required,description=The newer release tag, branch or commit SHA"`
+	Repo string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type ClosedIssuesArgs struct {
+	From string `json:"from" jsonschema:"required,description=The older release tag, branch or commit SHA"`
+	To   string `json:"to" jsonschema:"required,description=The newer release tag, branch or commit SHA"`
+	Repo string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type OutputSchemaArgs struct {
+	Name string `json:"name" jsonschema:"required,description=Output to describe: compare, list, summary (summarize_data) or changelog"`
+}
+
+type FilePatchArgs struct {
+	From string `json:"from" jsonschema:"required,description=The older release tag, branch or commit SHA"`
+	To   string `json:"to" jsonschema:"required,description=The newer release tag, branch or commit SHA"`
+	Path string `json:"path" jsonschema:"required,description=File path, directory or glob (e.g. 'server/' or '*.go') to show patches for"`
+	Repo string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type CommitDetailsArgs struct {
+	SHA     string   `json:"sha" jsonschema:"required,description=Commit SHA, or a unique prefix of at least 7 characters"`
+	Repo    string   `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+	Path    []string `json:"path,omitempty" jsonschema:"description=Only include files under these paths or matching these globs"`
+	Exclude []string `json:"exclude,omitempty" jsonschema:"description=Leave out files under these paths or matching these globs"`
+}
+
+type PRDetailsArgs struct {
+	Number int    `json:"number" jsonschema:"required,description=Pull request number"`
+	Repo   string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type ContainingReleaseArgs struct {
+	Ref  string `json:"ref" jsonschema:"required,description=A commit SHA (at least 7 characters) or a pull request number such as #1234"`
+	Repo string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type SearchCommitsArgs struct {
+	From   string `json:"from" jsonschema:"required,description=The older cached release tag"`
+	To     string `json:"to" jsonschema:"required,description=The newer cached release tag"`
+	Author string `json:"author,omitempty" jsonschema:"description=Only commits whose author name or email contains this (case-insensitive)"`
+	Grep   string `json:"grep,omitempty" jsonschema:"description=Only commits whose message contains this (case-insensitive)"`
+	PR     int    `json:"pr,omitempty" jsonschema:"description=Only commits of this pull request number"`
+	Repo   string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type SearchArgs struct {
+	Query string `json:"query" jsonschema:"required,description=Words that must all appear (e.g. 'gpu memory'); end a word with * to match it as a prefix"`
+	Limit int    `json:"limit,omitempty" jsonschema:"description=Maximum number of hits (default 20)"`
+	Repo  string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type ChangelogArgs struct {
+	Tag  string `json:"tag" jsonschema:"required,description=The cached release tag"`
+	Repo string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type TimelineArgs struct {
+	Repo string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
+
+type ListReleasesArgs struct {
+	Repo string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+}
 
 type IndexArgs struct {
 	Owner string `json:"owner" jsonschema:"required,description=The GitHub repository owner (e.g., 'ollama')"`
 	Repo  string `json:"repo" jsonschema:"required,description=The GitHub repository name (e.g., 'ollama')"`
+
+	PrecomputeChangelogs bool `json:"precompute_changelogs,omitempty" jsonschema:"description=Generate and store a changelog for every release"`
+	Tags                 bool `json:"tags,omitempty" jsonschema:"description=Index git tags as releases even if the repository publishes GitHub Releases"`
+}
+
+type UpdateArgs struct {
+	Repo string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (defaults to the configured repository)"`
+
+	PrecomputeChangelogs bool `json:"precompute_changelogs,omitempty" jsonschema:"description=Generate and store a changelog for every release"`
+	Tags                 bool `json:"tags,omitempty" jsonschema:"description=Index git tags as releases even if the repository publishes GitHub Releases"`
+}
+
+type IndexStatusArgs struct {
+	JobID string `json:"job_id,omitempty" jsonschema:"description=Job ID returned by index_repo or update_repo"`
+	Repo  string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name (omit both to list every indexing job)"`
+}
+
+type CancelIndexArgs struct {
+	JobID string `json:"job_id,omitempty" jsonschema:"description=Job ID returned by index_repo or update_repo"`
+	Repo  string `json:"repo,omitempty" jsonschema:"description=Repository as owner/name, to cancel its running job"`
 }
 
 type ReleaseInfo struct {
@@ -34,53 +156,47 @@ type ReleaseInfo struct {
 	Commit string `json:"commit"`
 }
 
-type IndexStatus struct {
-	Owner     string `json:"owner"`
-	Repo      string `json:"repo"`
-	IsRunning bool   `json:"is_running"`
-	Progress  int    `json:"progress"`
-	Total     int    `json:"total"`
-	Message   string `json:"message"`
-	Error     string `json:"error,omitempty"`
-}
-
 var (
-	indexState struct {
-		mu     sync.RWMutex
-		status IndexStatus
-	}
-	dbInstance *cache.DB
+	dbInstance cache.Store
+	indexJobs  = jobs.NewManager()
 )
 
-func NewServer() *cache.DB {
-	viper.SetConfigName(".ordiff")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			log.Printf("Warning: could not read config: %v\n", err)
-		}
-	}
+func NewServer() cache.Store {
+	config.Load()
 
-	db, err := cache.NewDB("ordiff.db")
+	db, err := cache.OpenStore(config.Storage())
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
 
+	m, err := config.Mailmap()
+	if err != nil {
+		log.Fatalf("Failed to read mailmap: %v", err)
+	}
+	db.SetMailmap(m)
+
 	dbInstance = db
 	return db
 }
 
-func RunServer() {
-	done := make(chan struct{})
+// RunServer serves the ordiff tools over stdio, or over stateless HTTP at
+// /mcp when httpAddr is set. Every HTTP client shares the same cache.
+//
+// The server runs until SIGINT or SIGTERM, or until the stdio client closes
+// its end. It then cancels running index jobs and waits for them to record
+// their progress before closing the cache.
+func RunServer(httpAddr string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	db := NewServer()
 
-	go func() {
-		defer db.Close()
-		<-done
-	}()
-
-	server := mcp_golang.NewServer(stdio.NewStdioServerTransport())
+	var t transport.Transport = stdio.NewStdioServerTransportWithIO(stdin{os.Stdin, stop}, os.Stdout)
+	if httpAddr != "" {
+		t = mcphttp.NewHTTPTransport("/mcp").WithAddr(httpAddr)
+	}
+	tracked := &requestTracker{Transport: t}
+	server := mcp_golang.NewServer(tracked)
+	mcpServer = server
 
 	server.RegisterTool("index_repo", "Index a GitHub repository's releases and commits for caching", func(args IndexArgs) (*mcp_golang.ToolResponse, error) {
 		owner := args.Owner
@@ -90,60 +206,93 @@ func RunServer() {
 			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: owner and repo are required")), nil
 		}
 
-		indexState.mu.Lock()
-		if indexState.status.IsRunning {
-			indexState.mu.Unlock()
-			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Indexing already in progress for " + indexState.status.Owner + "/" + indexState.status.Repo + ". Use get_index_status to check progress.")), nil
-		}
-		indexState.status = IndexStatus{
-			Owner:     owner,
-			Repo:      repo,
-			IsRunning: true,
-			Progress:  0,
-			Total:     100,
-			Message:   "Starting indexing...",
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
 		}
-		indexState.mu.Unlock()
+		fetcher.SetTagReleases(args.Tags)
 
-		token := os.Getenv("GITHUB_TOKEN")
-		fetcher := github.NewFetcher(owner, repo, &token)
+		job, ok := indexJobs.Start(owner, repo, "Starting indexing...")
+		if !ok {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Indexing already in progress for " + owner + "/" + repo + " (job_id: " + job.ID() + "). Use get_index_status to check progress.")), nil
+		}
 
-		go runIndexingAsync(owner, repo, fetcher, db)
+		indexJobs.Go(func() { runIndexingAsync(job, owner, repo, fetcher, db, args.PrecomputeChangelogs, false) })
 
-		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Started indexing " + owner + "/" + repo + ". Use get_index_status to check progress.")), nil
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Started indexing " + owner + "/" + repo + " (job_id: " + job.ID() + "). Use get_index_status to check progress and cancel_index to stop.")), nil
 	})
 
-	server.RegisterTool("get_index_status", "Get the status of the current indexing operation", func(args struct{}) (*mcp_golang.ToolResponse, error) {
-		indexState.mu.RLock()
-		defer indexState.mu.RUnlock()
+	server.RegisterTool("update_repo", "Fetch releases published since a repository was last indexed and fill in missing release pairs", func(args UpdateArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
 
-		status := indexState.status
-		if !status.IsRunning && status.Message == "" {
-			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("No indexing in progress.")), nil
+		providerName, _, err := db.GetRepositoryProvider(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
+		if providerName != "" && providerName != provider.GitHub {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + owner + "/" + repo + " was indexed from " + providerName + "; run 'ordiff update' instead")), nil
 		}
 
-		output := "Indexing Status:\n"
-		output += "Owner: " + status.Owner + "\n"
-		output += "Repo: " + status.Repo + "\n"
-		output += "Status: " + map[bool]string{true: "Running", false: "Completed/Failed"}[status.IsRunning] + "\n"
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
+		fetcher.SetTagReleases(args.Tags)
 
-		if status.Total > 0 {
-			output += "Progress: " + strconv.Itoa(status.Progress) + "/" + strconv.Itoa(status.Total) + " (" + strconv.Itoa(status.Progress*100/status.Total) + "%)\n"
+		job, ok := indexJobs.Start(owner, repo, "Starting update...")
+		if !ok {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Indexing already in progress for " + owner + "/" + repo + " (job_id: " + job.ID() + "). Use get_index_status to check progress.")), nil
 		}
-		output += "Message: " + status.Message + "\n"
-		if status.Error != "" {
-			output += "Error: " + status.Error + "\n"
+
+		indexJobs.Go(func() { runIndexingAsync(job, owner, repo, fetcher, db, args.PrecomputeChangelogs, true) })
+
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Started updating " + owner + "/" + repo + " (job_id: " + job.ID() + "). Use get_index_status to check progress and cancel_index to stop.")), nil
+	})
+
+	server.RegisterTool("get_index_status", "Get the status of an indexing job by job_id or repository, or of every job when both are omitted", func(args IndexStatusArgs) (*mcp_golang.ToolResponse, error) {
+		var statuses []jobs.Status
+		if args.JobID != "" || args.Repo != "" {
+			job, err := findJob(args.JobID, args.Repo)
+			if err != nil {
+				return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+			}
+			statuses = append(statuses, job.Status())
+		} else {
+			statuses = indexJobs.List()
+		}
+		if len(statuses) == 0 {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("No indexing in progress.")), nil
 		}
 
-		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(output)), nil
+		var blocks []string
+		for _, status := range statuses {
+			blocks = append(blocks, formatIndexStatus(status))
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.Join(blocks, "\n"))), nil
 	})
 
-	server.RegisterTool("list_releases", "List all cached releases for the default repository", func(args ListReleasesArgs) (*mcp_golang.ToolResponse, error) {
-		owner := viper.GetString("default_owner")
-		repo := viper.GetString("default_repo")
+	server.RegisterTool("cancel_index", "Stop a running indexing job, keeping the release pairs fetched so far", func(args CancelIndexArgs) (*mcp_golang.ToolResponse, error) {
+		if args.JobID == "" && args.Repo == "" {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: job_id or repo is required")), nil
+		}
+		job, err := findJob(args.JobID, args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+		status := job.Status()
+		if !job.Cancel() {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Job " + status.ID + " for " + status.Owner + "/" + status.Repo + " is not running (" + status.State + ").")), nil
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Cancelling job " + status.ID + " for " + status.Owner + "/" + status.Repo + ". Use get_index_status to confirm it stopped.")), nil
+	})
 
-		if owner == "" || repo == "" {
-			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("No default repository configured. Run 'ordiff index <owner> <repo>' first.")), nil
+	server.RegisterTool("list_releases", "List all cached releases for the default repository", func(args ListReleasesArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
 		}
 
 		releases, err := db.GetReleases(owner, repo)
@@ -172,22 +321,37 @@ func RunServer() {
 		from := args.From
 		to := args.To
 
-		owner := viper.GetString("default_owner")
-		repo := viper.GetString("default_repo")
-
-		if owner == "" || repo == "" {
-			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("No default repository configured. Run 'ordiff index <owner> <repo>' first.")), nil
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
 		}
 
-		token := os.Getenv("GITHUB_TOKEN")
-		fetcher := github.NewFetcher(owner, repo, &token)
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-		result, err := fetcher.GetCompareData(db, from, to)
+		result, err := fetcher.Compare(db, from, to, false)
 		if err != nil {
 			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to compare: " + err.Error())), nil
 		}
+		result.Files = filter.Paths(result.Files, args.Path, args.Exclude)
+		var ignored []cache.FileChange
+		if !args.All {
+			result.Files, ignored = filter.PartitionIgnored(result.Files, config.IgnorePatterns())
+		}
+
+		page, err := pageCommits(owner, repo, from, to, args.Limit, args.Offset, args.Cursor, 5, len(result.Commits))
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
+
+		riskCfg, err := config.Risk()
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-		output := formatCompareResult(result)
+		output := formatCompareResult(result, page, risk.Assess(riskCfg, result.Commits, result.PullRequests, result.Files), len(ignored))
 		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(output)), nil
 	})
 
@@ -195,133 +359,444 @@ func RunServer() {
 		from := args.From
 		to := args.To
 
-		owner := viper.GetString("default_owner")
-		repo := viper.GetString("default_repo")
-
-		if owner == "" || repo == "" {
-			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("No default repository configured. Run 'ordiff index <owner> <repo>' first.")), nil
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
 		}
 
-		token := os.Getenv("GITHUB_TOKEN")
-		fetcher := github.NewFetcher(owner, repo, &token)
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-		result, err := fetcher.GetCompareData(db, from, to)
+		result, err := fetcher.Compare(db, from, to, false)
 		if err != nil {
 			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to summarize: " + err.Error())), nil
 		}
+		result.Files = filter.Paths(result.Files, args.Path, args.Exclude)
+		var ignored []cache.FileChange
+		if !args.All {
+			result.Files, ignored = filter.PartitionIgnored(result.Files, config.IgnorePatterns())
+		}
+
+		page, err := pageCommits(owner, repo, from, to, args.Limit, args.Offset, args.Cursor, 20, len(result.Commits))
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-		output := formatSummaryData(result)
+		output := formatSummaryData(result, page, len(ignored))
 		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(output)), nil
 	})
 
-	log.Println("Starting ordiff MCP server...")
-	if err := server.Serve(); err != nil {
-		log.Printf("Server error: %v\n", err)
-		os.Exit(1)
-	}
-	<-done
-}
+	server.RegisterTool("get_commits_page", "Page through every commit between two releases, oldest first, following next_cursor until it is absent (JSON)", func(args CommitsPageArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
 
-func updateIndexProgress(progress, total int, message string) {
-	indexState.mu.Lock()
-	indexState.status.Progress = progress
-	indexState.status.Total = total
-	indexState.status.Message = message
-	indexState.mu.Unlock()
-}
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-func setIndexError(err string) {
-	indexState.mu.Lock()
-	indexState.status.IsRunning = false
-	indexState.status.Error = err
-	indexState.mu.Unlock()
-}
+		result, err := fetcher.Compare(db, args.From, args.To, false)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to compare: " + err.Error())), nil
+		}
 
-func finishIndexing(success bool, message string) {
-	indexState.mu.Lock()
-	indexState.status.IsRunning = false
-	indexState.status.Progress = indexState.status.Total
-	indexState.status.Message = message
-	if !success {
-		indexState.status.Error = message
-	}
-	indexState.mu.Unlock()
-}
+		page, err := pageCommits(owner, repo, args.From, args.To, args.Limit, args.Offset, args.Cursor, 100, len(result.Commits))
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-func runIndexingAsync(owner, repo string, fetcher *github.Fetcher, db *cache.DB) {
-	updateIndexProgress(0, 100, "Fetching releases...")
+		data, _ := json.MarshalIndent(formatCommitsPage(result, page), "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
 
-	releases, err := fetcher.FetchAllReleasesForIndexing(func(current, total int) {
-		updateIndexProgress(current, total, "Fetching releases...")
+	server.RegisterTool("get_output_schema", "Get the versioned JSON Schema of ordiff's JSON output: compare, list, summary (summarize_data) or changelog", func(args OutputSchemaArgs) (*mcp_golang.ToolResponse, error) {
+		b, err := schema.Get(args.Name)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(b))), nil
 	})
-	if err != nil {
-		setIndexError("Failed to fetch releases: " + err.Error())
-		return
-	}
 
-	updateIndexProgress(20, 100, "Saving releases to cache...")
-	for i, r := range releases {
-		if err := db.SaveRelease(r); err != nil {
-			log.Printf("Warning: failed to save release %s: %v\n", r.TagName, err)
+	server.RegisterTool("draft_release_notes", "Group the commits and merged PRs between two releases into breaking changes, features, fixes, dependencies, docs and other, as JSON", func(args ReleaseNotesArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
 		}
-		updateIndexProgress(20+(i*10/len(releases)), 100, "Saving releases...")
-	}
 
-	updateIndexProgress(30, 100, "Fetching commits and files for missing release pairs...")
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-	totalPairs := len(releases) - 1
-	processed := 0
-	skipped := 0
+		result, err := fetcher.Compare(db, args.From, args.To, false)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to compare: " + err.Error())), nil
+		}
+
+		notes := changelog.DraftReleaseNotes(result.FromRelease.TagName, result.ToRelease.TagName, result.Commits, result.PullRequests)
+		data, _ := json.MarshalIndent(notes, "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
+
+	server.RegisterTool("list_closed_issues", "List the issues closed between two releases, found through the closing keywords (fixes #12) of the merged PRs and commits in between, as JSON", func(args ClosedIssuesArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
 
-	for i := 0; i < totalPairs; i++ {
-		from := releases[i+1]
-		to := releases[i]
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-		alreadyCached, _ := db.HasFileChangesCached(owner, repo, from.TagName, to.TagName)
-		if alreadyCached {
-			skipped++
-			log.Printf("Skipping %s -> %s (already cached)\n", from.TagName, to.TagName)
-			continue
+		result, err := fetcher.Compare(db, args.From, args.To, false)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to compare: " + err.Error())), nil
 		}
 
-		processed++
-		pendingPairs := totalPairs - skipped - processed
-		updateIndexProgress(30+(processed*70/(processed+pendingPairs+1)), 100, "Processing "+from.TagName+" -> "+to.TagName+" ("+strconv.Itoa(processed)+" processed, "+strconv.Itoa(skipped)+" skipped)")
+		closed, err := issues.Between(db, owner, repo, result.Commits, result.PullRequests)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to read closed issues: " + err.Error())), nil
+		}
+		data, _ := json.MarshalIndent(closed, "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
 
-		commits, err := fetcher.FetchCommitsForIndexing(from.CommitSHA, to.CommitSHA, func(current, total int) {})
+	server.RegisterTool("get_file_patch", "Get the unified diff of files changed between two releases", func(args FilePatchArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
 		if err != nil {
-			log.Printf("Warning: failed to fetch commits: %v\n", err)
-			continue
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+		if args.Path == "" {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("path is required")), nil
 		}
 
-		for _, c := range commits {
-			if err := db.SaveCommit(c); err != nil {
-				log.Printf("Warning: failed to save commit: %v\n", err)
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
+
+		result, err := fetcher.Compare(db, args.From, args.To, false)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to compare: " + err.Error())), nil
+		}
+		if err := fetcher.LoadPatches(db, result); err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to load patches: " + err.Error())), nil
+		}
+
+		var sb strings.Builder
+		for _, fc := range result.Files {
+			if filter.MatchPath(args.Path, fc.Filename) {
+				report.UnifiedDiff(&sb, fc, false)
 			}
 		}
+		if sb.Len() == 0 {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(fmt.Sprintf("No changes to %s between %s and %s", args.Path, args.From, args.To))), nil
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(sb.String())), nil
+	})
+
+	server.RegisterTool("get_commit_details", "Get a commit's full message, author, linked PR and the patch of every file it changed, fetched from GitHub once if not cached (JSON)", func(args CommitDetailsArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+		if args.SHA == "" {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("sha is required")), nil
+		}
+
+		fetcher, err := newFetcher(owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Error: " + err.Error())), nil
+		}
 
-		files, err := fetcher.FetchFileChangesForIndexing(from.CommitSHA, to.CommitSHA)
+		details, err := fetcher.CommitDetails(db, args.SHA)
 		if err != nil {
-			log.Printf("Warning: failed to fetch files: %v\n", err)
-			continue
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to get commit: " + err.Error())), nil
 		}
+		details.Files = filter.Paths(details.Files, args.Path, args.Exclude)
+		if details.Files == nil {
+			details.Files = []cache.FileChange{}
+		}
+
+		data, _ := json.MarshalIndent(details, "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
 
-		for _, fc := range files {
-			fc.FromRelease = from.TagName
-			fc.ToRelease = to.TagName
-			if err := db.SaveFileChange(fc); err != nil {
-				log.Printf("Warning: failed to save file change: %v\n", err)
+	server.RegisterTool("get_pr_details", "Get a cached pull request's title, body, labels and commits, and the releases it first shipped in (JSON)", func(args PRDetailsArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+		if args.Number <= 0 {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("number is required")), nil
+		}
+
+		details, err := github.NewFetcher(owner, repo, nil).PullRequestDetails(db, args.Number)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to get pull request: " + err.Error())), nil
+		}
+
+		data, _ := json.MarshalIndent(details, "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
+
+	server.RegisterTool("find_containing_release", "Find the earliest cached release whose range includes a commit or pull request (JSON)", func(args ContainingReleaseArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+		if args.Ref == "" {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("ref is required")), nil
+		}
+
+		c, err := github.NewFetcher(owner, repo, nil).ContainingRelease(db, args.Ref)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to find release: " + err.Error())), nil
+		}
+
+		data, _ := json.MarshalIndent(c, "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
+
+	server.RegisterTool("get_changelog", "Get the changelog precomputed for a release when it was indexed with precompute_changelogs, as Markdown", func(args ChangelogArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+		if args.Tag == "" {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("tag is required")), nil
+		}
+
+		md, ok, err := db.GetChangelog(owner, repo, args.Tag)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to get changelog: " + err.Error())), nil
+		}
+		if !ok {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("No precomputed changelog for " + args.Tag + ". Index with precompute_changelogs or run 'ordiff index --precompute-changelogs'.")), nil
+		}
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(md)), nil
+	})
+
+	server.RegisterTool("get_release_timeline", "Get every cached release oldest first with the days since the previous release, commits, contributors and line churn, as JSON", func(args TimelineArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+
+		entries, err := timeline.Build(db, owner, repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to build timeline: " + err.Error())), nil
+		}
+
+		data, _ := json.MarshalIndent(entries, "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
+
+	server.RegisterTool("search_commits", "Find the commits between two cached releases by author, message text or pull request, as JSON", func(args SearchCommitsArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+
+		commits, err := db.SearchCommitsBetween(owner, repo, args.From, args.To, cache.CommitQuery{Author: args.Author, Grep: args.Grep, PR: args.PR})
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to search commits: " + err.Error())), nil
+		}
+
+		type commitInfo struct {
+			SHA      string `json:"sha"`
+			Message  string `json:"message"`
+			Author   string `json:"author"`
+			Email    string `json:"email,omitempty"`
+			Date     string `json:"date"`
+			PrNumber *int   `json:"pr_number,omitempty"`
+			URL      string `json:"url,omitempty"`
+		}
+		out := make([]commitInfo, len(commits))
+		for i, c := range commits {
+			out[i] = commitInfo{
+				SHA:      c.SHA,
+				Message:  c.Message,
+				Author:   c.Author,
+				Email:    c.AuthorEmail,
+				Date:     c.Date.Format("2006-01-02"),
+				PrNumber: c.PrNumber,
+				URL:      c.URL,
 			}
 		}
+
+		data, _ := json.MarshalIndent(out, "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
+
+	server.RegisterTool("search_repo_history", "Full-text search over cached commit messages, PR titles and bodies and release notes, returning ranked hits with the release range each falls in, as JSON", func(args SearchArgs) (*mcp_golang.ToolResponse, error) {
+		owner, repo, err := resolveRepo(args.Repo)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(err.Error())), nil
+		}
+		if args.Limit <= 0 {
+			args.Limit = 20
+		}
+
+		hits, err := db.SearchRepo(owner, repo, args.Query, args.Limit)
+		if err != nil {
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent("Failed to search: " + err.Error())), nil
+		}
+		if hits == nil {
+			hits = []cache.SearchHit{}
+		}
+
+		data, _ := json.MarshalIndent(hits, "", "  ")
+		return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(string(data))), nil
+	})
+
+	if err := registerResources(server, db); err != nil {
+		slog.Warn("Failed to register resources", "err", err)
+	}
+
+	if httpAddr != "" {
+		slog.Info("Starting ordiff MCP server", "addr", httpAddr+"/mcp")
+	} else {
+		slog.Info("Starting ordiff MCP server")
+	}
+	// Serve returns at once for stdio and only once closed for HTTP.
+	go func() {
+		if err := server.Serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+	<-ctx.Done()
+	stop()
+
+	slog.Info("Shutting down ordiff MCP server")
+	wait, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := tracked.Wait(wait); err != nil {
+		slog.Warn("Requests still running after shutdown timeout", "timeout", shutdownTimeout)
+	}
+	t.Close()
+	if err := indexJobs.Shutdown(wait); err != nil {
+		// The jobs may still be writing, so the cache is left open; SQLite
+		// rolls back their unfinished transaction on the next open.
+		slog.Warn("Index jobs did not stop within shutdown timeout", "timeout", shutdownTimeout)
+		return
+	}
+	if err := db.Close(); err != nil {
+		slog.Warn("Failed to close database", "err", err)
 	}
+}
 
-	viper.Set("default_owner", owner)
-	viper.Set("default_repo", repo)
-	if err := viper.SafeWriteConfigAs(".ordiff.yaml"); err != nil {
-		log.Printf("Warning: could not save config: %v\n", err)
+// resolveRepo parses an optional owner/name tool argument, falling back to
+// the configured default repository.
+func resolveRepo(arg string) (string, string, error) {
+	if arg != "" {
+		owner, repo, ok := strings.Cut(arg, "/")
+		if !ok || owner == "" || repo == "" {
+			return "", "", fmt.Errorf("invalid repo %q, expected owner/name", arg)
+		}
+		return owner, repo, nil
 	}
 
-	finishIndexing(true, "Indexed "+owner+"/"+repo+" - "+strconv.Itoa(processed)+" new, "+strconv.Itoa(skipped)+" already cached")
+	owner := viper.GetString("default_owner")
+	repo := viper.GetString("default_repo")
+	if owner == "" || repo == "" {
+		return "", "", errors.New("No default repository configured. Run 'ordiff index <owner> <repo>' first.")
+	}
+	return owner, repo, nil
+}
+
+func newFetcher(owner, repo string) (*github.Fetcher, error) {
+	creds, err := github.AppCredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := config.Retry()
+	if err != nil {
+		return nil, err
+	}
+
+	var fetcher *github.Fetcher
+	if creds != nil {
+		if fetcher, err = github.NewAppFetcher(owner, repo, *creds); err != nil {
+			return nil, err
+		}
+	} else {
+		token := config.GitHubToken()
+		fetcher = github.NewFetcher(owner, repo, &token)
+	}
+	fetcher.SetRetryPolicy(policy)
+	return fetcher, nil
+}
+
+// findJob looks up a job by ID, or else the latest job of a repository.
+func findJob(id, repoArg string) (*jobs.Job, error) {
+	if id != "" {
+		job, ok := indexJobs.Get(id)
+		if !ok {
+			return nil, fmt.Errorf("no indexing job %s", id)
+		}
+		return job, nil
+	}
+	owner, repo, err := resolveRepo(repoArg)
+	if err != nil {
+		return nil, err
+	}
+	job, ok := indexJobs.ForRepo(owner, repo)
+	if !ok {
+		return nil, fmt.Errorf("no indexing started for %s/%s", owner, repo)
+	}
+	return job, nil
+}
+
+func formatIndexStatus(status jobs.Status) string {
+	output := "Indexing Status:\n"
+	output += "Job: " + status.ID + "\n"
+	output += "Owner: " + status.Owner + "\n"
+	output += "Repo: " + status.Repo + "\n"
+	output += "Status: " + status.State + "\n"
+
+	if status.Total > 0 {
+		output += "Progress: " + strconv.Itoa(status.Progress) + "/" + strconv.Itoa(status.Total) + " (" + strconv.Itoa(status.Progress*100/status.Total) + "%)\n"
+	}
+	output += "Message: " + status.Message + "\n"
+	if status.Error != "" {
+		output += "Error: " + status.Error + "\n"
+	}
+	return output
+}
+
+// runIndexingAsync indexes a repository in the background through the
+// fetcher's IndexAll, or with incremental set its Update, which only fetches
+// releases newer than the cached ones.
+func runIndexingAsync(job *jobs.Job, owner, repo string, fetcher *github.Fetcher, db cache.Store, precomputeChangelogs, incremental bool) {
+	fetcher.SetStatusHook(job.SetMessage)
+	fetcher.SetHTTPCache(db)
+
+	jobs.RunIndex(job, db, jobs.Index{
+		Fetcher:  fetcher,
+		Provider: provider.GitHub,
+		Update:   incremental,
+		Source:   "mcp",
+		After: func() {
+			if precomputeChangelogs {
+				job.SetMessage("Precomputing changelogs...")
+				if _, err := changelog.Precompute(db, owner, repo); err != nil {
+					slog.Warn("Failed to precompute changelogs", "err", err)
+				}
+			}
+			if err := config.SaveDefaultRepo(owner, repo); err != nil {
+				slog.Warn("Could not save config", "err", err)
+			}
+			if err := registerRepoResources(mcpServer, db, owner, repo); err != nil {
+				slog.Warn("Failed to register resources", "err", err)
+			}
+		},
+	})
 }
 
 func formatReleases(releases []ReleaseInfo) string {
@@ -332,10 +807,28 @@ func formatReleases(releases []ReleaseInfo) string {
 	return output
 }
 
-func formatCompareResult(r *github.CompareResult) string {
+func formatCompareResult(r *github.CompareResult, page commitPage, score risk.Score, ignored int) string {
 	output := ""
 	output += "=== " + r.FromRelease.TagName + " -> " + r.ToRelease.TagName + " ===\n\n"
-	output += "Commits: " + strconv.Itoa(len(r.Commits)) + " | PRs: " + strconv.Itoa(r.PrCount) + " | Files: " + strconv.Itoa(len(r.Files)) + "\n\n"
+	output += "Commits: " + strconv.Itoa(len(r.Commits)) + " | PRs: " + strconv.Itoa(r.PrCount) + " | Files: " + strconv.Itoa(len(r.Files)+ignored) + "\n\n"
+
+	output += "Upgrade Risk: " + strconv.Itoa(score.Score) + "/100 (" + score.Level + ")\n"
+	for _, f := range score.Factors {
+		output += "  " + strconv.FormatFloat(f.Points, 'f', 1, 64) + "/" + strconv.FormatFloat(f.Max, 'f', 0, 64) + "  " + f.Detail + "\n"
+		for _, item := range f.Items[:min(len(f.Items), 10)] {
+			output += "    " + item + "\n"
+		}
+		if len(f.Items) > 10 {
+			output += "    ... and " + strconv.Itoa(len(f.Items)-10) + " more\n"
+		}
+	}
+	output += "\n"
+	if r.FileSource == github.FilesAggregated {
+		output += "Note: releases are not adjacent; file changes aggregated across intervening release pairs.\n\n"
+	}
+	if ignored > 0 {
+		output += "Note: " + strconv.Itoa(ignored) + " vendored, generated or binary files left out; pass all to include them.\n\n"
+	}
 
 	if len(r.Files) > 0 {
 		output += "Top Changed Files:\n"
@@ -345,16 +838,38 @@ func formatCompareResult(r *github.CompareResult) string {
 			if i >= 10 {
 				break
 			}
-			output += "  " + strconv.Itoa(f.Additions) + "  " + strconv.Itoa(f.Deletions) + "  " + f.Filename + "\n"
+			output += "  " + strconv.Itoa(f.Additions) + "  " + strconv.Itoa(f.Deletions) + "  " + f.Filename
+			if f.PreviousFilename != "" {
+				output += " (from " + f.PreviousFilename + ")"
+			}
+			output += "\n"
 		}
 		output += "\n"
 	}
 
-	output += "Recent Commits:\n"
-	for i, c := range r.Commits {
-		if i >= 5 {
-			break
+	if len(r.PullRequests) > 0 {
+		output += "Merged PRs:\n"
+		shown := *r
+		shown.PullRequests = r.PullRequests[:min(len(r.PullRequests), maxMergedPRs)]
+		labels, groups := shown.PullRequestsByLabel()
+		for _, label := range labels {
+			output += "  [" + label + "]\n"
+			for _, pr := range groups[label] {
+				output += "    #" + strconv.Itoa(pr.Number) + " " + pr.Title + " (@" + pr.Author + ")\n"
+			}
+		}
+		if len(r.PullRequests) > maxMergedPRs {
+			output += "  ... and " + strconv.Itoa(len(r.PullRequests)-maxMergedPRs) + " more merged PRs\n"
 		}
+		output += "\n"
+	}
+
+	if page.Start == 0 {
+		output += "Recent Commits:\n"
+	} else {
+		output += "Commits " + strconv.Itoa(page.Start+1) + "-" + strconv.Itoa(page.End) + " of " + strconv.Itoa(page.Total) + ":\n"
+	}
+	for _, c := range r.Commits[page.Start:page.End] {
 		msg := c.Message
 		if len(msg) > 60 {
 			msg = msg[:57] + "..."
@@ -366,20 +881,21 @@ func formatCompareResult(r *github.CompareResult) string {
 		output += "  " + sha + "  " + msg + "\n"
 	}
 
-	if len(r.Commits) > 5 {
-		output += "  ... and " + strconv.Itoa(len(r.Commits)-5) + " more commits\n"
+	if page.Next != "" {
+		output += "  ... and " + strconv.Itoa(page.Total-page.End) + " more commits (next_cursor: " + page.Next + ")\n"
 	}
 
 	return output
 }
 
-func formatSummaryData(r *github.CompareResult) string {
+func formatSummaryData(r *github.CompareResult, page commitPage, ignored int) string {
 	type FileInfo struct {
-		Name      string `json:"name"`
-		Additions int    `json:"additions"`
-		Deletions int    `json:"deletions"`
-		Changes   int    `json:"changes"`
-		Status    string `json:"status"`
+		Name         string `json:"name"`
+		PreviousName string `json:"previous_name,omitempty"`
+		Additions    int    `json:"additions"`
+		Deletions    int    `json:"deletions"`
+		Changes      int    `json:"changes"`
+		Status       string `json:"status"`
 	}
 
 	type CommitInfo struct {
@@ -390,14 +906,29 @@ func formatSummaryData(r *github.CompareResult) string {
 		PrNumber *int   `json:"pr_number,omitempty"`
 	}
 
+	type PRInfo struct {
+		Number int      `json:"number"`
+		Title  string   `json:"title"`
+		Author string   `json:"author"`
+		Labels []string `json:"labels,omitempty"`
+	}
+
 	type SummaryData struct {
-		FromRelease  string       `json:"from_release"`
-		ToRelease    string       `json:"to_release"`
-		CommitCount  int          `json:"commit_count"`
-		PrCount      int          `json:"pr_count"`
-		FilesChanged int          `json:"files_changed"`
-		TopFiles     []FileInfo   `json:"top_files"`
-		Commits      []CommitInfo `json:"commits"`
+		FromRelease  string              `json:"from_release"`
+		ToRelease    string              `json:"to_release"`
+		CommitCount  int                 `json:"commit_count"`
+		PrCount      int                 `json:"pr_count"`
+		FilesChanged int                 `json:"files_changed"`
+		FilesIgnored int                 `json:"files_ignored,omitempty"`
+		Breaking     []changelog.Notice  `json:"breaking_changes"`
+		TopFiles     []FileInfo          `json:"top_files"`
+		Languages    []language.Stat     `json:"languages"`
+		Commits      []CommitInfo        `json:"commits"`
+		CommitOffset int                 `json:"commits_offset,omitempty"`
+		NextCursor   string              `json:"next_cursor,omitempty"`
+		MergedPRs    []PRInfo            `json:"merged_prs,omitempty"`
+		PRsOmitted   int                 `json:"merged_prs_omitted,omitempty"`
+		CommitTypes  changelog.TypeStats `json:"commit_types"`
 	}
 
 	maxFiles := len(r.Files)
@@ -408,21 +939,18 @@ func formatSummaryData(r *github.CompareResult) string {
 	for i := 0; i < maxFiles; i++ {
 		f := r.Files[i]
 		files[i] = FileInfo{
-			Name:      f.Filename,
-			Additions: f.Additions,
-			Deletions: f.Deletions,
-			Changes:   f.Changes,
-			Status:    f.Status,
+			Name:         f.Filename,
+			PreviousName: f.PreviousFilename,
+			Additions:    f.Additions,
+			Deletions:    f.Deletions,
+			Changes:      f.Changes,
+			Status:       f.Status,
 		}
 	}
 
-	maxCommits := len(r.Commits)
-	if maxCommits > 20 {
-		maxCommits = 20
-	}
-	commits := make([]CommitInfo, maxCommits)
-	for i := 0; i < maxCommits; i++ {
-		c := r.Commits[i]
+	commits := make([]CommitInfo, page.End-page.Start)
+	for i := range commits {
+		c := r.Commits[page.Start+i]
 		sha := c.SHA
 		if len(sha) > 7 {
 			sha = sha[:7]
@@ -436,14 +964,32 @@ func formatSummaryData(r *github.CompareResult) string {
 		}
 	}
 
+	prs := make([]PRInfo, min(len(r.PullRequests), maxMergedPRs))
+	for i, pr := range r.PullRequests[:len(prs)] {
+		prs[i] = PRInfo{
+			Number: pr.Number,
+			Title:  pr.Title,
+			Author: pr.Author,
+			Labels: pr.Labels,
+		}
+	}
+
 	summary := SummaryData{
 		FromRelease:  r.FromRelease.TagName,
 		ToRelease:    r.ToRelease.TagName,
 		CommitCount:  len(r.Commits),
 		PrCount:      r.PrCount,
-		FilesChanged: len(r.Files),
+		FilesChanged: len(r.Files) + ignored,
+		FilesIgnored: ignored,
+		Breaking:     changelog.Notices(r.Commits, r.PullRequests),
 		TopFiles:     files,
+		Languages:    language.Breakdown(r.Files),
 		Commits:      commits,
+		CommitOffset: page.Start,
+		NextCursor:   page.Next,
+		MergedPRs:    prs,
+		PRsOmitted:   len(r.PullRequests) - len(prs),
+		CommitTypes:  changelog.CommitTypes(r.Commits),
 	}
 
 	b, err := json.Marshal(summary)
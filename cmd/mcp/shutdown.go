@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// shutdownTimeout bounds how long a shutdown waits for requests being
+// answered and for cancelled index jobs.
+const shutdownTimeout = 30 * time.Second
+
+// stdin calls eof once the client closes the server's stdin, which is how
+// stdio MCP clients end a session.
+type stdin struct {
+	io.Reader
+	eof func()
+}
+
+func (r stdin) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		r.eof()
+	}
+	return n, err
+}
+
+// requestTracker counts the requests that have not been answered yet, as
+// mcp-golang handles each on a goroutine of its own, so a shutdown can let
+// them finish.
+type requestTracker struct {
+	transport.Transport
+	pending sync.WaitGroup
+}
+
+func (t *requestTracker) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.Transport.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+			t.pending.Add(1)
+		}
+		handler(ctx, message)
+	})
+}
+
+func (t *requestTracker) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	err := t.Transport.Send(ctx, message)
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType || message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+		t.pending.Done()
+	}
+	return err
+}
+
+// Wait returns once every request received has been answered, or when ctx
+// is done.
+func (t *requestTracker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,113 @@
+// Package analyzer runs custom checks over a comparison, such as license
+// headers on new files or migrations that lack a rollback. Analyzers are
+// compiled in with Register or, without forking ordiff, configured as
+// external executables that read the comparison as JSON on stdin and write
+// their sections as JSON on stdout.
+package analyzer
+
+import (
+	"sort"
+	"sync"
+
+	"ordiff/internal/github"
+)
+
+// ProtocolVersion is the version of the Input and Output JSON exchanged
+// with external analyzers.
+const ProtocolVersion = 1
+
+// Analyzer checks a comparison and reports what it found as sections.
+type Analyzer interface {
+	Name() string
+	Analyze(in *Input) ([]Section, error)
+}
+
+// Input is what an analyzer is given: the comparison of two releases, with
+// the patches of its files loaded where the cache or forge has them.
+type Input struct {
+	Version int                   `json:"version"`
+	Owner   string                `json:"owner"`
+	Repo    string                `json:"repo"`
+	Result  *github.CompareResult `json:"result"`
+}
+
+// Section is a named part of an analyzer's report: free text, findings
+// tied to files, or both.
+type Section struct {
+	Name     string    `json:"name"`
+	Text     string    `json:"text,omitempty"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Severities of a Finding.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Finding is one thing an analyzer found. File and Line are optional, and
+// Severity is info when not given.
+type Finding struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Report is the outcome of one analyzer: its sections, or the error that
+// stopped it.
+type Report struct {
+	Analyzer string    `json:"analyzer"`
+	Sections []Section `json:"sections"`
+	Error    string    `json:"error,omitempty"`
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Analyzer{}
+)
+
+// Register makes an analyzer run with every comparison, replacing one of
+// the same name.
+func Register(a Analyzer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[a.Name()] = a
+}
+
+// Registered returns the registered analyzers by name.
+func Registered() []Analyzer {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	list := make([]Analyzer, 0, len(registry))
+	for _, a := range registry {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// Run runs analyzers over a comparison in order. A failing analyzer does
+// not stop the others; its report carries the error instead.
+func Run(analyzers []Analyzer, owner, repo string, result *github.CompareResult) []Report {
+	in := &Input{Version: ProtocolVersion, Owner: owner, Repo: repo, Result: result}
+	reports := []Report{}
+	for _, a := range analyzers {
+		r := Report{Analyzer: a.Name(), Sections: []Section{}}
+		sections, err := a.Analyze(in)
+		if err != nil {
+			r.Error = err.Error()
+		}
+		for _, s := range sections {
+			for i := range s.Findings {
+				if s.Findings[i].Severity == "" {
+					s.Findings[i].Severity = SeverityInfo
+				}
+			}
+			r.Sections = append(r.Sections, s)
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"ordiff/internal/command"
+)
+
+// DefaultTimeout bounds an external analyzer without a timeout of its own.
+const DefaultTimeout = 2 * time.Minute
+
+// External is an analyzers entry of the config: an executable that reads
+// an Input on stdin and writes an Output on stdout. A non-zero exit fails
+// the analyzer, with the last line of stderr as the reason.
+type External struct {
+	Label   string        `mapstructure:"name" json:"name"`
+	Command string        `mapstructure:"command" json:"command"`
+	Args    []string      `mapstructure:"args" json:"args,omitempty"`
+	Dir     string        `mapstructure:"dir" json:"dir,omitempty"`
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout,omitempty"`
+}
+
+// Output is what an external analyzer writes.
+type Output struct {
+	Sections []Section `json:"sections"`
+}
+
+// Validate fills in the defaults of e and checks the rest.
+func (e *External) Validate() error {
+	if e.Command == "" {
+		return fmt.Errorf("every analyzer needs a command")
+	}
+	if e.Label == "" {
+		e.Label = strings.TrimSuffix(e.Command[strings.LastIndexAny(e.Command, `/\`)+1:], ".exe")
+	}
+	if e.Timeout <= 0 {
+		e.Timeout = DefaultTimeout
+	}
+	return nil
+}
+
+func (e *External) Name() string {
+	return e.Label
+}
+
+func (e *External) Analyze(in *Input) ([]Section, error) {
+	input, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := command.Command{
+		Path:    e.Command,
+		Args:    e.Args,
+		Dir:     e.Dir,
+		Env:     []string{fmt.Sprintf("ORDIFF_ANALYZER_PROTOCOL=%d", ProtocolVersion)},
+		Stdin:   bytes.NewReader(input),
+		Timeout: e.Timeout,
+	}.Output(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var o Output
+	if err := json.Unmarshal(out, &o); err != nil {
+		return nil, fmt.Errorf("invalid output: %w", err)
+	}
+	for _, s := range o.Sections {
+		if s.Name == "" {
+			return nil, fmt.Errorf("invalid output: every section needs a name")
+		}
+		for _, f := range s.Findings {
+			switch f.Severity {
+			case "", SeverityInfo, SeverityWarning, SeverityError:
+			default:
+				return nil, fmt.Errorf("invalid output: unknown severity %q", f.Severity)
+			}
+		}
+	}
+	return o.Sections, nil
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/jobs"
+	"ordiff/internal/provider"
+)
+
+// Server exposes the cache as a JSON HTTP API.
+type Server struct {
+	DB cache.Store
+
+	// NewFetcher creates the GitHub fetcher used for comparisons.
+	NewFetcher func(owner, repo string) *github.Fetcher
+
+	// NewIndexer creates the fetcher that indexes a repository of a
+	// provider, github or gitlab.
+	NewIndexer func(owner, repo, provider string) provider.Fetcher
+
+	// DefaultOwner and DefaultRepo are used by /compare when no repo
+	// parameter is given.
+	DefaultOwner string
+	DefaultRepo  string
+
+	mu   sync.Mutex
+	jobs *jobs.Manager
+	last *jobs.Job
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler returns the routes of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos", s.handleRepos)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/releases", s.handleReleases)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/churn", s.handleChurn)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/compare", s.handleCompare)
+	mux.HandleFunc("GET /compare", s.handleCompare)
+	mux.HandleFunc("POST /index", s.handleIndex)
+	mux.HandleFunc("GET /index", s.handleIndexStatus)
+	return mux
+}
+
+func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	repos, err := s.DB.GetRepositories()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if repos == nil {
+		repos = []cache.Repository{}
+	}
+	writeJSON(w, http.StatusOK, repos)
+}
+
+func (s *Server) handleChurn(w http.ResponseWriter, r *http.Request) {
+	churn, err := s.DB.GetPairChurn(r.PathValue("owner"), r.PathValue("repo"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if churn == nil {
+		churn = []cache.PairChurn{}
+	}
+	writeJSON(w, http.StatusOK, churn)
+}
+
+func (s *Server) handleReleases(w http.ResponseWriter, r *http.Request) {
+	releases, err := s.DB.GetReleases(r.PathValue("owner"), r.PathValue("repo"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if releases == nil {
+		releases = []cache.Release{}
+	}
+	writeJSON(w, http.StatusOK, releases)
+}
+
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	owner, repo, ok := s.repoFromRequest(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "repo must be given as owner/name")
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+
+	fetcher := s.NewFetcher(owner, repo)
+	fetcher.SetContext(r.Context())
+	result, err := fetcher.Compare(s.DB, from, to, false)
+	if err != nil {
+		writeError(w, compareStatus(err), err.Error())
+		return
+	}
+
+	etag := result.ETag()
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if r.URL.Query().Get("patches") != "false" {
+		if err := fetcher.LoadPatches(s.DB, result); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	w.Header().Set("ETag", etag)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// compareStatus is the status a failed comparison is answered with: 404 when
+// either ref is unknown, here or on GitHub, and 502 for any other upstream
+// failure.
+func compareStatus(err error) int {
+	if errors.Is(err, github.ErrReleaseNotCached) || github.StatusCode(err) == http.StatusNotFound {
+		return http.StatusNotFound
+	}
+	return http.StatusBadGateway
+}
+
+type indexRequest struct {
+	Owner    string `json:"owner"`
+	Repo     string `json:"repo"`
+	Provider string `json:"provider"`
+	Update   bool   `json:"update"`
+}
+
+// handleIndex starts indexing in the background as a job and returns 202
+// with its status. Each repository is indexed by one job at a time; asking
+// for one while it runs returns 409 with that job's status.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	var req indexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if req.Owner == "" || req.Repo == "" {
+		writeError(w, http.StatusBadRequest, "owner and repo are required")
+		return
+	}
+	switch req.Provider {
+	case "":
+		req.Provider = provider.GitHub
+	case provider.GitHub, provider.GitLab:
+	default:
+		writeError(w, http.StatusBadRequest, "provider must be github or gitlab")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, started := s.manager().Start(req.Owner, req.Repo, "Starting...")
+	if !started {
+		writeJSON(w, http.StatusConflict, job.Status())
+		return
+	}
+	s.last = job
+
+	fetcher := s.NewIndexer(req.Owner, req.Repo, req.Provider)
+	if c, ok := fetcher.(provider.HTTPCached); ok {
+		c.SetHTTPCache(s.DB)
+	}
+	s.jobs.Go(func() {
+		jobs.RunIndex(job, s.DB, jobs.Index{Fetcher: fetcher, Provider: req.Provider, Update: req.Update, Source: "api"})
+	})
+
+	writeJSON(w, http.StatusAccepted, job.Status())
+}
+
+// handleIndexStatus returns the status of the job given by the job
+// parameter, or else of the last one started.
+func (s *Server) handleIndexStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job := s.last
+	if id := r.URL.Query().Get("job"); id != "" {
+		job, _ = s.manager().Get(id)
+	}
+	s.mu.Unlock()
+	if job == nil {
+		writeError(w, http.StatusNotFound, "no index job")
+		return
+	}
+	writeJSON(w, http.StatusOK, job.Status())
+}
+
+// manager returns the jobs of the server, creating it on first use. s.mu
+// must be held.
+func (s *Server) manager() *jobs.Manager {
+	if s.jobs == nil {
+		s.jobs = jobs.NewManager()
+	}
+	return s.jobs
+}
+
+// Shutdown cancels the running index jobs and waits until they have
+// recorded how far they got, or until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	m := s.manager()
+	s.mu.Unlock()
+	return m.Shutdown(ctx)
+}
+
+// repoFromRequest takes the repository from the path, the repo query
+// parameter, or the configured default, in that order.
+func (s *Server) repoFromRequest(r *http.Request) (string, string, bool) {
+	if owner := r.PathValue("owner"); owner != "" {
+		return owner, r.PathValue("repo"), true
+	}
+	if arg := r.URL.Query().Get("repo"); arg != "" {
+		owner, repo, ok := strings.Cut(arg, "/")
+		return owner, repo, ok && owner != "" && repo != ""
+	}
+	return s.DefaultOwner, s.DefaultRepo, s.DefaultOwner != "" && s.DefaultRepo != ""
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, errorResponse{Error: msg})
+}
@@ -0,0 +1,314 @@
+// Package apidiff compares the exported API of two versions of a Go module,
+// in the spirit of golang.org/x/exp/apidiff. It works on syntax alone, so no
+// dependencies need to be downloaded: a symbol is reported as changed when
+// its declaration reads differently.
+package apidiff
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// API maps package directories to their exported symbols and declarations.
+type API map[string]map[string]string
+
+const (
+	Removed = "removed"
+	Changed = "changed"
+	Added   = "added"
+)
+
+type Change struct {
+	Package string `json:"package"`
+	Symbol  string `json:"symbol"`
+	Kind    string `json:"kind"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+}
+
+// Incompatible reports whether the change can break importers.
+func (c Change) Incompatible() bool {
+	return c.Kind != Added
+}
+
+// IsAPIFile reports whether a repository path holds Go source that is part
+// of the public API: tests, examples, testdata, vendored and internal
+// packages are skipped.
+func IsAPIFile(name string) bool {
+	if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+		return false
+	}
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		switch {
+		case dir == "internal", dir == "testdata", dir == "vendor", strings.HasPrefix(dir, "."), strings.HasPrefix(dir, "_"):
+			return false
+		}
+	}
+	return true
+}
+
+// Extract parses Go files keyed by their path and collects the exported
+// declarations of every non-main package. Files that fail to parse are
+// skipped and reported in the returned list.
+func Extract(files map[string][]byte) (API, []string) {
+	api := API{}
+	var skipped []string
+	fset := token.NewFileSet()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !IsAPIFile(name) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, files[name], parser.SkipObjectResolution)
+		if err != nil {
+			skipped = append(skipped, name)
+			continue
+		}
+		if file.Name.Name == "main" {
+			continue
+		}
+
+		pkg := path.Dir(name)
+		symbols := api[pkg]
+		if symbols == nil {
+			symbols = map[string]string{}
+			api[pkg] = symbols
+		}
+		collect(fset, file, symbols)
+	}
+	return api, skipped
+}
+
+func collect(fset *token.FileSet, file *ast.File, symbols map[string]string) {
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !ast.IsExported(d.Name.Name) {
+				continue
+			}
+			name := d.Name.Name
+			decl := "func "
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				recv := receiverName(d.Recv.List[0].Type)
+				if !ast.IsExported(recv) {
+					continue
+				}
+				name = recv + "." + name
+				decl += "(" + render(fset, d.Recv.List[0].Type) + ") "
+			}
+			symbols[name] = decl + d.Name.Name + signature(fset, d.Type)
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if ast.IsExported(s.Name.Name) {
+						collectType(fset, s, symbols)
+					}
+				case *ast.ValueSpec:
+					collectValues(fset, d.Tok, s, symbols)
+				}
+			}
+		}
+	}
+}
+
+// collectType records a type. Exported struct fields are recorded as symbols
+// of their own so that adding a field is not reported as a change of the
+// whole struct.
+func collectType(fset *token.FileSet, s *ast.TypeSpec, symbols map[string]string) {
+	name := s.Name.Name
+	assign := ""
+	if s.Assign.IsValid() {
+		assign = "= "
+	}
+
+	st, ok := s.Type.(*ast.StructType)
+	if !ok {
+		symbols[name] = fmt.Sprintf("type %s%s %s%s", name, typeParams(fset, s), assign, render(fset, s.Type))
+		return
+	}
+
+	symbols[name] = fmt.Sprintf("type %s%s %sstruct", name, typeParams(fset, s), assign)
+	for _, field := range st.Fields.List {
+		typ := render(fset, field.Type)
+		if len(field.Names) == 0 {
+			embedded := receiverName(field.Type)
+			if ast.IsExported(embedded) {
+				symbols[name+"."+embedded] = "embedded " + typ
+			}
+			continue
+		}
+		for _, n := range field.Names {
+			if ast.IsExported(n.Name) {
+				symbols[name+"."+n.Name] = "field " + n.Name + " " + typ
+			}
+		}
+	}
+}
+
+func collectValues(fset *token.FileSet, tok token.Token, s *ast.ValueSpec, symbols map[string]string) {
+	for i, n := range s.Names {
+		if !ast.IsExported(n.Name) {
+			continue
+		}
+		decl := tok.String() + " " + n.Name
+		if s.Type != nil {
+			decl += " " + render(fset, s.Type)
+		}
+		// A constant's value is part of its API; a variable's initializer
+		// is not.
+		if tok == token.CONST && i < len(s.Values) {
+			decl += " = " + render(fset, s.Values[i])
+		}
+		symbols[n.Name] = decl
+	}
+}
+
+// signature renders a function's type parameters, parameters and results
+// without their names, since renaming a parameter does not change the API.
+func signature(fset *token.FileSet, ft *ast.FuncType) string {
+	sig := fieldTypes(fset, ft.TypeParams, "[", "]", true) + fieldTypes(fset, ft.Params, "(", ")", false)
+	if results := fieldTypes(fset, ft.Results, "(", ")", false); results != "" {
+		if ft.Results.NumFields() == 1 {
+			results = strings.TrimSuffix(strings.TrimPrefix(results, "("), ")")
+		}
+		sig += " " + results
+	}
+	return sig
+}
+
+func fieldTypes(fset *token.FileSet, fields *ast.FieldList, open, close string, keepNames bool) string {
+	if fields == nil {
+		return ""
+	}
+	var types []string
+	for _, f := range fields.List {
+		typ := render(fset, f.Type)
+		if len(f.Names) == 0 {
+			types = append(types, typ)
+			continue
+		}
+		for _, n := range f.Names {
+			if keepNames {
+				types = append(types, n.Name+" "+typ)
+			} else {
+				types = append(types, typ)
+			}
+		}
+	}
+	if len(types) == 0 && open == "[" {
+		return ""
+	}
+	return open + strings.Join(types, ", ") + close
+}
+
+func typeParams(fset *token.FileSet, s *ast.TypeSpec) string {
+	if s.TypeParams == nil {
+		return ""
+	}
+	var params []string
+	for _, f := range s.TypeParams.List {
+		var names []string
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		params = append(params, strings.Join(names, ", ")+" "+render(fset, f.Type))
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func render(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.RawFormat}
+	if err := cfg.Fprint(&buf, fset, stripComments(node)); err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	// Normalize layout, so that only the declaration itself is compared.
+	out := strings.Join(lines, "; ")
+	out = strings.ReplaceAll(out, "{; ", "{ ")
+	out = strings.ReplaceAll(out, "; }", " }")
+	out = strings.ReplaceAll(out, "interface{", "interface {")
+	return strings.ReplaceAll(out, "struct{", "struct {")
+}
+
+// stripComments drops field and method comments so that documentation edits
+// are not reported as API changes.
+func stripComments(node ast.Node) ast.Node {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if f, ok := n.(*ast.Field); ok {
+			f.Doc = nil
+			f.Comment = nil
+		}
+		return true
+	})
+	return node
+}
+
+// Compare lists the symbols removed, changed and added between two APIs,
+// sorted by package and symbol. A removed package reports every symbol it
+// had.
+func Compare(old, new API) []Change {
+	var changes []Change
+	for pkg, symbols := range old {
+		for sym, decl := range symbols {
+			now, ok := new[pkg][sym]
+			switch {
+			case !ok:
+				changes = append(changes, Change{Package: pkg, Symbol: sym, Kind: Removed, Old: decl})
+			case now != decl:
+				changes = append(changes, Change{Package: pkg, Symbol: sym, Kind: Changed, Old: decl, New: now})
+			}
+		}
+	}
+	for pkg, symbols := range new {
+		for sym, decl := range symbols {
+			if _, ok := old[pkg][sym]; !ok {
+				changes = append(changes, Change{Package: pkg, Symbol: sym, Kind: Added, New: decl})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Package != changes[j].Package {
+			return changes[i].Package < changes[j].Package
+		}
+		return changes[i].Symbol < changes[j].Symbol
+	})
+	return changes
+}
@@ -0,0 +1,146 @@
+// Package assets compares the files attached to releases, such as the
+// binaries and archives of every platform: how their sizes change between two
+// releases and how their downloads grow after each release.
+package assets
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+)
+
+const (
+	KindAdded   = "added"
+	KindRemoved = "removed"
+	KindChanged = "changed"
+)
+
+// Change is an asset of either release. Assets match across releases by
+// their name with the version taken out, so ordiff_1.2.0_linux_amd64.tar.gz
+// pairs with ordiff_1.3.0_linux_amd64.tar.gz under the Name
+// ordiff_{version}_linux_amd64.tar.gz.
+type Change struct {
+	Name     string `json:"name"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	FromSize int64  `json:"from_size"`
+	ToSize   int64  `json:"to_size"`
+	Delta    int64  `json:"delta"`
+	Kind     string `json:"kind"`
+}
+
+// Diff matches the assets of two releases and returns those added, removed
+// or changed in size, largest growth first, then added and removed ones by
+// name.
+func Diff(fromTag string, from []cache.ReleaseAsset, toTag string, to []cache.ReleaseAsset) []Change {
+	changes := map[string]*Change{}
+	var keys []string
+	for _, a := range from {
+		key := Key(a.Name, fromTag)
+		if _, ok := changes[key]; !ok {
+			keys = append(keys, key)
+			changes[key] = &Change{Name: key, From: a.Name, FromSize: a.Size, Kind: KindRemoved}
+		}
+	}
+	for _, a := range to {
+		key := Key(a.Name, toTag)
+		c, ok := changes[key]
+		switch {
+		case !ok:
+			keys = append(keys, key)
+			changes[key] = &Change{Name: key, To: a.Name, ToSize: a.Size, Kind: KindAdded}
+		case c.To == "":
+			c.To, c.ToSize, c.Kind = a.Name, a.Size, KindChanged
+		}
+	}
+
+	var diff []Change
+	for _, key := range keys {
+		c := changes[key]
+		c.Delta = c.ToSize - c.FromSize
+		if c.Kind == KindChanged && c.Delta == 0 {
+			continue
+		}
+		diff = append(diff, *c)
+	}
+
+	order := map[string]int{KindChanged: 0, KindAdded: 1, KindRemoved: 2}
+	sort.SliceStable(diff, func(i, j int) bool {
+		if diff[i].Kind != diff[j].Kind {
+			return order[diff[i].Kind] < order[diff[j].Kind]
+		}
+		if diff[i].Kind == KindChanged && diff[i].Delta != diff[j].Delta {
+			return diff[i].Delta > diff[j].Delta
+		}
+		return diff[i].Name < diff[j].Name
+	})
+	return diff
+}
+
+// Total sums the sizes of assets.
+func Total(assets []cache.ReleaseAsset) int64 {
+	var n int64
+	for _, a := range assets {
+		n += a.Size
+	}
+	return n
+}
+
+// Key replaces the version of tag in an asset name with {version}, trying
+// the tag itself and then the tag without its v prefix.
+func Key(name, tag string) string {
+	if tag == "" {
+		return name
+	}
+	if strings.Contains(name, tag) {
+		return strings.ReplaceAll(name, tag, "{version}")
+	}
+	if v := strings.TrimPrefix(tag, "v"); v != "" && strings.Contains(name, v) {
+		return strings.ReplaceAll(name, v, "{version}")
+	}
+	return name
+}
+
+// Point is the total download count of a release's assets at a time.
+type Point struct {
+	Time      time.Time `json:"time"`
+	Downloads int64     `json:"downloads"`
+}
+
+// Curve adds up the download history of a release's assets into its total
+// downloads at every time one of them was recorded. keep selects the assets
+// to count; nil counts all of them.
+func Curve(history []cache.DownloadSnapshot, keep func(asset string) bool) []Point {
+	counts := map[string]int64{}
+	var total int64
+	var points []Point
+	for _, s := range history {
+		if keep != nil && !keep(s.Asset) {
+			continue
+		}
+		total += s.DownloadCount - counts[s.Asset]
+		counts[s.Asset] = s.DownloadCount
+		if n := len(points); n > 0 && points[n-1].Time.Equal(s.RecordedAt) {
+			points[n-1].Downloads = total
+			continue
+		}
+		points = append(points, Point{Time: s.RecordedAt, Downloads: total})
+	}
+	return points
+}
+
+// At returns the downloads of a curve at t, the last point at or before it,
+// and false if the curve starts after t.
+func At(curve []Point, t time.Time) (int64, bool) {
+	var n int64
+	found := false
+	for _, p := range curve {
+		if p.Time.After(t) {
+			break
+		}
+		n, found = p.Downloads, true
+	}
+	return n, found
+}
@@ -0,0 +1,256 @@
+// Package backport finds the commits of a range that also landed in another
+// release line, as the original of a cherry-pick, a cherry-pick of it, or a
+// commit with the same patch, so work already shipped elsewhere is not
+// counted twice.
+package backport
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+)
+
+// How a copy was matched.
+const (
+	MatchCherryPick = "cherry-pick"
+	MatchPatchID    = "patch-id"
+)
+
+// Backport is a commit of a range with a copy outside it. Releases are those
+// that shipped the copy, oldest first, and ShippedIn is the first of them
+// when it was published before the range's newer release.
+type Backport struct {
+	SHA       string   `json:"sha"`
+	Subject   string   `json:"subject"`
+	Copy      string   `json:"copy"`
+	Match     string   `json:"match"`
+	Releases  []string `json:"releases"`
+	ShippedIn string   `json:"shipped_in,omitempty"`
+}
+
+// FilesFunc returns the file changes of a commit with their patches, nil
+// when they are not available.
+type FilesFunc func(sha string) ([]cache.FileChange, error)
+
+var cherryPickTrailer = regexp.MustCompile(`\(cherry picked from commit ([0-9a-f]{7,40})\)`)
+
+// Sources returns the commits a message says it was cherry-picked from, as
+// git cherry-pick -x records them.
+func Sources(message string) []string {
+	var shas []string
+	for _, m := range cherryPickTrailer.FindAllStringSubmatch(message, -1) {
+		shas = append(shas, m[1])
+	}
+	return shas
+}
+
+// PatchID hashes the lines a change adds and removes, ignoring whitespace,
+// hunk positions and context, like git patch-id. It returns "" when no file
+// has a patch.
+func PatchID(files []cache.FileChange) string {
+	files = append([]cache.FileChange{}, files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Filename < files[j].Filename })
+
+	h := sha1.New()
+	lines := 0
+	for _, fc := range files {
+		if fc.Patch == "" {
+			continue
+		}
+		h.Write([]byte(fc.Filename + "\n"))
+		for _, line := range strings.Split(fc.Patch, "\n") {
+			if line == "" || (line[0] != '+' && line[0] != '-') {
+				continue
+			}
+			h.Write([]byte(line[:1] + strings.Join(strings.Fields(line[1:]), "") + "\n"))
+			lines++
+		}
+	}
+	if lines == 0 {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var (
+	subjectPrefix = regexp.MustCompile(`(?i)^(\[[^\]]*\]\s*|(backport|cherry[- ]pick)[^:]*:\s*)+`)
+	subjectPR     = regexp.MustCompile(`\s*\(#\d+\)$`)
+)
+
+// subjectKey is the first line of a message without the markers backports
+// add to it, which commits must share to be compared by patch.
+func subjectKey(message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	subject = subjectPR.ReplaceAllString(subjectPrefix.ReplaceAllString(strings.TrimSpace(subject), ""), "")
+	return strings.ToLower(subject)
+}
+
+// Find lists the commits of a range that have a copy among the other cached
+// commits of the repository, in range order. Cherry-pick trailers link a
+// commit to its original, to its own cherry-picks, and to the other
+// cherry-picks of the same original. Commits without one are compared by
+// patch with the cached commits of the same subject, when files returns
+// patches for both. published is when the range's newer release came out.
+func Find(db cache.Store, owner, repo string, commits []cache.Commit, published time.Time, files FilesFunc) ([]Backport, error) {
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	all, err := db.GetCommits(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	inRange := map[string]bool{}
+	for _, c := range commits {
+		inRange[c.SHA] = true
+	}
+
+	// picks maps an original to the commits cherry-picked from it.
+	picks := map[string][]string{}
+	origins := map[string][]string{}
+	bySubject := map[string][]string{}
+	for _, c := range all {
+		for _, src := range Sources(c.Message) {
+			if full := resolve(all, src); full != "" {
+				picks[full] = append(picks[full], c.SHA)
+				origins[c.SHA] = append(origins[c.SHA], full)
+			}
+		}
+		if key := subjectKey(c.Message); key != "" && !inRange[c.SHA] {
+			bySubject[key] = append(bySubject[key], c.SHA)
+		}
+	}
+
+	dates := map[string]time.Time{}
+	releases, err := db.GetReleases(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range releases {
+		dates[r.TagName] = r.PublishedAt
+	}
+
+	var found []Backport
+	for _, c := range commits {
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		copies := map[string]string{}
+		for _, o := range origins[c.SHA] {
+			copies[o] = MatchCherryPick
+			for _, sibling := range picks[o] {
+				copies[sibling] = MatchCherryPick
+			}
+		}
+		for _, p := range picks[c.SHA] {
+			copies[p] = MatchCherryPick
+		}
+		delete(copies, c.SHA)
+		for sha := range copies {
+			if inRange[sha] {
+				delete(copies, sha)
+			}
+		}
+
+		if len(copies) == 0 && files != nil {
+			if candidates := bySubject[subjectKey(c.Message)]; len(candidates) > 0 {
+				if copies, err = samePatch(c.SHA, candidates, files); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		shas := make([]string, 0, len(copies))
+		for sha := range copies {
+			shas = append(shas, sha)
+		}
+		sort.Strings(shas)
+		for _, sha := range shas {
+			b := Backport{SHA: c.SHA, Subject: subject, Copy: sha, Match: copies[sha], Releases: []string{}}
+			tags, err := db.ReleasesContaining(owner, repo, []string{sha})
+			if err != nil {
+				return nil, err
+			}
+			b.Releases = append(b.Releases, tags...)
+			if len(tags) > 0 && dates[tags[0]].Before(published) {
+				b.ShippedIn = tags[0]
+			}
+			found = append(found, b)
+		}
+	}
+	return found, nil
+}
+
+// resolve expands a possibly abbreviated SHA to a cached commit, or returns
+// "" when none or several match.
+func resolve(all []cache.Commit, sha string) string {
+	if len(sha) == 40 {
+		for _, c := range all {
+			if c.SHA == sha {
+				return sha
+			}
+		}
+		return ""
+	}
+	match := ""
+	for _, c := range all {
+		if strings.HasPrefix(c.SHA, sha) {
+			if match != "" {
+				return ""
+			}
+			match = c.SHA
+		}
+	}
+	return match
+}
+
+// maxCandidates bounds the commits of the same subject compared by patch,
+// as their patches may have to be fetched.
+const maxCandidates = 10
+
+// samePatch returns the candidates whose patch matches the commit's.
+func samePatch(sha string, candidates []string, files FilesFunc) (map[string]string, error) {
+	own, err := files(sha)
+	if err != nil {
+		return nil, err
+	}
+	id := PatchID(own)
+	copies := map[string]string{}
+	if id == "" {
+		return copies, nil
+	}
+	for _, candidate := range candidates[:min(maxCandidates, len(candidates))] {
+		theirs, err := files(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if PatchID(theirs) == id {
+			copies[candidate] = MatchPatchID
+		}
+	}
+	return copies, nil
+}
+
+// Shipped returns the releases that already shipped copies of the
+// backports, in order of first appearance, and how many commits of the range
+// those account for.
+func Shipped(backports []Backport) (releases []string, commits int) {
+	seenRelease := map[string]bool{}
+	seenCommit := map[string]bool{}
+	for _, b := range backports {
+		if b.ShippedIn == "" {
+			continue
+		}
+		if !seenCommit[b.SHA] {
+			seenCommit[b.SHA] = true
+			commits++
+		}
+		if !seenRelease[b.ShippedIn] {
+			seenRelease[b.ShippedIn] = true
+			releases = append(releases, b.ShippedIn)
+		}
+	}
+	return releases, commits
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"database/sql"
+	"time"
+)
+
+// releaseAssets stores the files attached to each release, as they were when
+// the release was last listed.
+func releaseAssets(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS release_assets (
+		owner TEXT,
+		repo TEXT,
+		tag_name TEXT,
+		name TEXT,
+		size BIGINT,
+		download_count BIGINT,
+		content_type TEXT,
+		digest TEXT,
+		url TEXT,
+		fetched_at TEXT,
+		PRIMARY KEY (owner, repo, tag_name, name)
+	);
+	`)
+	return err
+}
+
+// assetDownloads keeps the download count of every asset each time it
+// changed between two listings of its release.
+func assetDownloads(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS asset_downloads (
+		owner TEXT,
+		repo TEXT,
+		tag_name TEXT,
+		name TEXT,
+		download_count BIGINT,
+		recorded_at TEXT,
+		PRIMARY KEY (owner, repo, tag_name, name, recorded_at)
+	);
+	`)
+	return err
+}
+
+// ReleaseAsset is a file attached to a release, such as a binary or an
+// archive. Digest is "algorithm:hex", e.g. "sha256:…", when the forge
+// reports one.
+type ReleaseAsset struct {
+	Name          string `json:"name"`
+	Size          int64  `json:"size"`
+	DownloadCount int64  `json:"download_count"`
+	ContentType   string `json:"content_type"`
+	Digest        string `json:"digest"`
+	URL           string `json:"url"`
+}
+
+// DownloadSnapshot is the download count of an asset when a listing of its
+// release found it changed.
+type DownloadSnapshot struct {
+	Asset         string    `json:"asset"`
+	DownloadCount int64     `json:"download_count"`
+	RecordedAt    time.Time `json:"recorded_at"`
+}
+
+// SaveReleaseAssets replaces the cached assets of a release without
+// touching the release itself.
+func (d *DB) SaveReleaseAssets(r *Release) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		return d.saveReleaseAssets(tx, r)
+	})
+}
+
+// saveReleaseAssets replaces the cached assets of a release and records the
+// download counts that changed since the last listing.
+func (d *DB) saveReleaseAssets(tx *sql.Tx, r *Release) error {
+	if _, err := tx.Exec(d.rebind(`DELETE FROM release_assets WHERE owner = ? AND repo = ? AND tag_name = ?`), r.Owner, r.Repo, r.TagName); err != nil {
+		return err
+	}
+	fetchedAt := time.Now().UTC().Format(time.RFC3339)
+	for _, a := range r.Assets {
+		if _, err := tx.Exec(d.rebind(`
+			INSERT OR REPLACE INTO release_assets (owner, repo, tag_name, name, size, download_count, content_type, digest, url, fetched_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`), r.Owner, r.Repo, r.TagName, a.Name, a.Size, a.DownloadCount, a.ContentType, a.Digest, a.URL, fetchedAt); err != nil {
+			return err
+		}
+
+		var last int64
+		err := tx.QueryRow(d.rebind(`
+			SELECT download_count FROM asset_downloads
+			WHERE owner = ? AND repo = ? AND tag_name = ? AND name = ?
+			ORDER BY recorded_at DESC LIMIT 1
+		`), r.Owner, r.Repo, r.TagName, a.Name).Scan(&last)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == nil && last == a.DownloadCount {
+			continue
+		}
+		if _, err := tx.Exec(d.rebind(`
+			INSERT OR REPLACE INTO asset_downloads (owner, repo, tag_name, name, download_count, recorded_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`), r.Owner, r.Repo, r.TagName, a.Name, a.DownloadCount, fetchedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDownloadHistory returns the recorded download counts of the assets of a
+// release, oldest first.
+func (d *DB) GetDownloadHistory(owner, repo, tag string) ([]DownloadSnapshot, error) {
+	rows, err := d.query(`
+		SELECT name, download_count, recorded_at
+		FROM asset_downloads
+		WHERE owner = ? AND repo = ? AND tag_name = ?
+		ORDER BY recorded_at, name
+	`, owner, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []DownloadSnapshot
+	for rows.Next() {
+		var s DownloadSnapshot
+		var recordedAt string
+		if err := rows.Scan(&s.Asset, &s.DownloadCount, &recordedAt); err != nil {
+			return nil, err
+		}
+		s.RecordedAt, _ = time.Parse(time.RFC3339, recordedAt)
+		history = append(history, s)
+	}
+	return history, rows.Err()
+}
+
+// GetReleaseAssets returns the cached assets of a release ordered by name,
+// nil if it has none or they were never listed.
+func (d *DB) GetReleaseAssets(owner, repo, tag string) ([]ReleaseAsset, error) {
+	rows, err := d.query(`
+		SELECT name, size, download_count, content_type, digest, url
+		FROM release_assets
+		WHERE owner = ? AND repo = ? AND tag_name = ?
+		ORDER BY name
+	`, owner, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assets []ReleaseAsset
+	for rows.Next() {
+		var a ReleaseAsset
+		if err := rows.Scan(&a.Name, &a.Size, &a.DownloadCount, &a.ContentType, &a.Digest, &a.URL); err != nil {
+			return nil, err
+		}
+		assets = append(assets, a)
+	}
+	return assets, rows.Err()
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"database/sql"
+
+	"ordiff/internal/conventional"
+)
+
+// breakingFlags marks the commits and pull requests that announce a breaking
+// change or a deprecation, detected from their message or title and body
+// when they are saved, and marks those already cached.
+func breakingFlags(tx *sql.Tx) error {
+	for _, table := range []string{"commits", "pull_requests"} {
+		for _, column := range []string{"breaking", "deprecated"} {
+			if err := ensureColumn(tx, table, column, "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+		}
+	}
+	if err := markBreaking(tx, "",
+		`SELECT owner, repo, sha, message FROM commits WHERE (owner, repo, sha) > (?, ?, ?) ORDER BY owner, repo, sha LIMIT 500`,
+		`UPDATE commits SET breaking = ?, deprecated = ? WHERE owner = ? AND repo = ? AND sha = ?`); err != nil {
+		return err
+	}
+	return markBreaking(tx, -1,
+		`SELECT owner, repo, number, COALESCE(title, '') || char(10) || COALESCE(body, '') FROM pull_requests WHERE (owner, repo, number) > (?, ?, ?) ORDER BY owner, repo, number LIMIT 500`,
+		`UPDATE pull_requests SET breaking = ?, deprecated = ? WHERE owner = ? AND repo = ? AND number = ?`)
+}
+
+func postgresBreakingFlags(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	ALTER TABLE commits ADD COLUMN IF NOT EXISTS breaking INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE commits ADD COLUMN IF NOT EXISTS deprecated INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS breaking INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS deprecated INTEGER NOT NULL DEFAULT 0;
+	`); err != nil {
+		return err
+	}
+	if err := markBreaking(tx, "",
+		`SELECT owner, repo, sha, message FROM commits WHERE (owner, repo, sha) > ($1, $2, $3) ORDER BY owner, repo, sha LIMIT 500`,
+		`UPDATE commits SET breaking = $1, deprecated = $2 WHERE owner = $3 AND repo = $4 AND sha = $5`); err != nil {
+		return err
+	}
+	return markBreaking(tx, -1,
+		`SELECT owner, repo, number, COALESCE(title, '') || chr(10) || COALESCE(body, '') FROM pull_requests WHERE (owner, repo, number) > ($1, $2, $3) ORDER BY owner, repo, number LIMIT 500`,
+		`UPDATE pull_requests SET breaking = $1, deprecated = $2 WHERE owner = $3 AND repo = $4 AND number = $5`)
+}
+
+// markBreaking walks a table in batches by key, starting after first, and
+// sets the flags of the rows whose text announces a breaking change or a
+// deprecation.
+func markBreaking(tx *sql.Tx, first interface{}, selectBatch, update string) error {
+	type row struct {
+		owner, repo string
+		key         interface{}
+		text        string
+	}
+
+	last := row{key: first}
+	for {
+		rows, err := tx.Query(selectBatch, last.owner, last.repo, last.key)
+		if err != nil {
+			return err
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.owner, &r.repo, &r.key, &r.text); err != nil {
+				rows.Close()
+				return err
+			}
+			if b, ok := r.key.([]byte); ok {
+				r.key = string(b)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, r := range batch {
+			breaking, deprecated := flag(conventional.Breaking(r.text)), flag(conventional.Deprecation(r.text))
+			if breaking == 0 && deprecated == 0 {
+				continue
+			}
+			if _, err := tx.Exec(update, breaking, deprecated, r.owner, r.repo, r.key); err != nil {
+				return err
+			}
+		}
+		last = batch[len(batch)-1]
+	}
+}
+
+// flag stores a boolean as the INTEGER 0 or 1 the cache uses for them.
+func flag(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"database/sql"
+	"time"
+)
+
+// codeowners stores the CODEOWNERS file of each repository's default branch.
+func codeowners(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS codeowners (
+		owner TEXT,
+		repo TEXT,
+		path TEXT,
+		content TEXT,
+		fetched_at TEXT,
+		PRIMARY KEY (owner, repo)
+	);
+	`)
+	return err
+}
+
+// Codeowners is a cached CODEOWNERS file.
+type Codeowners struct {
+	Path      string
+	Content   string
+	FetchedAt time.Time
+}
+
+// SaveCodeowners replaces the cached CODEOWNERS file of a repository.
+func (d *DB) SaveCodeowners(owner, repo, path, content string) error {
+	_, err := d.exec(`
+		INSERT OR REPLACE INTO codeowners (owner, repo, path, content, fetched_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, owner, repo, path, content, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// GetCodeowners returns the cached CODEOWNERS file of a repository, or
+// sql.ErrNoRows if none was found when it was last indexed.
+func (d *DB) GetCodeowners(owner, repo string) (*Codeowners, error) {
+	var c Codeowners
+	var fetchedAt string
+	err := d.queryRow(`
+		SELECT path, content, fetched_at FROM codeowners
+		WHERE owner = ? AND repo = ?
+	`, owner, repo).Scan(&c.Path, &c.Content, &fetchedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAt)
+	return &c, nil
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// commitFiles stores the file changes of single commits, fetched on demand
+// when an agent drills into a commit; release pairs only keep the combined
+// changes of all their commits.
+func commitFiles(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS commit_files (
+		owner TEXT,
+		repo TEXT,
+		sha TEXT,
+		filename TEXT,
+		additions INTEGER,
+		deletions INTEGER,
+		changes INTEGER,
+		status TEXT,
+		patch TEXT,
+		PRIMARY KEY (owner, repo, sha, filename)
+	);
+	`)
+	return err
+}
+
+// GetCommit returns a cached commit by its full SHA or a unique prefix of at
+// least 7 characters. It returns sql.ErrNoRows if no commit matches.
+func (d *DB) GetCommit(owner, repo, sha string) (*Commit, error) {
+	if len(sha) < 7 {
+		return nil, fmt.Errorf("commit SHA %q is too short, need at least 7 characters", sha)
+	}
+
+	rows, err := d.query(`
+		SELECT sha, message, author, author_email, date, url, pr_number, breaking, deprecated
+		FROM commits
+		WHERE owner = ? AND repo = ? AND substr(sha, 1, ?) = ?
+		LIMIT 2
+	`, owner, repo, len(sha), sha)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []Commit
+	for rows.Next() {
+		c := Commit{Owner: owner, Repo: repo}
+		var date string
+		if err := rows.Scan(&c.SHA, &c.Message, &c.Author, &c.AuthorEmail, &date, &c.URL, &c.PrNumber, &c.Breaking, &c.Deprecated); err != nil {
+			return nil, err
+		}
+		c.Author, c.AuthorEmail = d.mailmap.Resolve(c.Author, c.AuthorEmail)
+		c.Date, _ = time.Parse(time.RFC3339, date)
+		commits = append(commits, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(commits) {
+	case 0:
+		return nil, sql.ErrNoRows
+	case 1:
+		return &commits[0], nil
+	default:
+		return nil, fmt.Errorf("commit SHA %q is ambiguous", sha)
+	}
+}
+
+// FirstPairContaining returns the release pair, by the publish date of its
+// newer release, whose indexed commits first include any of the SHAs. It
+// returns sql.ErrNoRows if no indexed pair contains them.
+func (d *DB) FirstPairContaining(owner, repo string, shas []string) (from, to, sha string, err error) {
+	if len(shas) == 0 {
+		return "", "", "", sql.ErrNoRows
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(shas)), ", ")
+	args := []interface{}{owner, repo}
+	for _, s := range shas {
+		args = append(args, s)
+	}
+
+	err = d.queryRow(`
+		SELECT l.from_release, l.to_release, l.sha FROM pair_commits l
+		JOIN releases r ON r.owner = l.owner AND r.repo = l.repo AND r.tag_name = l.to_release
+		WHERE l.owner = ? AND l.repo = ? AND l.sha IN (`+placeholders+`)
+		ORDER BY r.published_at ASC LIMIT 1
+	`, args...).Scan(&from, &to, &sha)
+	return from, to, sha, err
+}
+
+// SaveCommitFiles replaces the cached file changes of a commit.
+func (d *DB) SaveCommitFiles(owner, repo, sha string, files []FileChange) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(d.rebind(`DELETE FROM commit_files WHERE owner = ? AND repo = ? AND sha = ?`), owner, repo, sha); err != nil {
+			return err
+		}
+		for _, fc := range files {
+			if _, err := tx.Exec(d.rebind(`
+				INSERT INTO commit_files (owner, repo, sha, filename, previous_filename, additions, deletions, changes, status, patch)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`), owner, repo, sha, fc.Filename, fc.PreviousFilename, fc.Additions, fc.Deletions, fc.Changes, fc.Status, fc.Patch); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetCommitFiles returns the cached file changes of a commit, nil if they
+// were never fetched.
+func (d *DB) GetCommitFiles(owner, repo, sha string) ([]FileChange, error) {
+	rows, err := d.query(`
+		SELECT filename, previous_filename, additions, deletions, changes, status, patch
+		FROM commit_files
+		WHERE owner = ? AND repo = ? AND sha = ?
+		ORDER BY filename
+	`, owner, repo, sha)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []FileChange
+	for rows.Next() {
+		fc := FileChange{Owner: owner, Repo: repo}
+		if err := rows.Scan(&fc.Filename, &fc.PreviousFilename, &fc.Additions, &fc.Deletions, &fc.Changes, &fc.Status, &fc.Patch); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fc)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"database/sql"
+
+	"ordiff/internal/conventional"
+)
+
+// commitTypes adds the conventional-commit type and scope of every commit,
+// parsed from its message when it is saved, and fills them in for the
+// commits already cached.
+func commitTypes(tx *sql.Tx) error {
+	for _, column := range []string{"commit_type", "commit_scope"} {
+		if err := ensureColumn(tx, "commits", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return parseCommitTypes(tx,
+		`SELECT owner, repo, sha, message FROM commits WHERE (owner, repo, sha) > (?, ?, ?) ORDER BY owner, repo, sha LIMIT 500`,
+		`UPDATE commits SET commit_type = ?, commit_scope = ? WHERE owner = ? AND repo = ? AND sha = ?`)
+}
+
+func postgresCommitTypes(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	ALTER TABLE commits ADD COLUMN IF NOT EXISTS commit_type TEXT NOT NULL DEFAULT '';
+	ALTER TABLE commits ADD COLUMN IF NOT EXISTS commit_scope TEXT NOT NULL DEFAULT '';
+	`); err != nil {
+		return err
+	}
+	return parseCommitTypes(tx,
+		`SELECT owner, repo, sha, message FROM commits WHERE (owner, repo, sha) > ($1, $2, $3) ORDER BY owner, repo, sha LIMIT 500`,
+		`UPDATE commits SET commit_type = $1, commit_scope = $2 WHERE owner = $3 AND repo = $4 AND sha = $5`)
+}
+
+// parseCommitTypes walks the commits in batches by key and records the type
+// and scope of those that follow the convention.
+func parseCommitTypes(tx *sql.Tx, selectBatch, update string) error {
+	type row struct {
+		owner, repo, sha string
+		message          string
+	}
+
+	var last row
+	for {
+		rows, err := tx.Query(selectBatch, last.owner, last.repo, last.sha)
+		if err != nil {
+			return err
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.owner, &r.repo, &r.sha, &r.message); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, r := range batch {
+			m := conventional.Parse(r.message)
+			if m.Type == "" {
+				continue
+			}
+			if _, err := tx.Exec(update, m.Type, m.Scope, r.owner, r.repo, r.sha); err != nil {
+				return err
+			}
+		}
+		last = batch[len(batch)-1]
+	}
+}
@@ -1,15 +1,28 @@
 package cache
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"ordiff/internal/mailmap"
+	"ordiff/internal/version"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type DB struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect
+	mailmap *mailmap.Mailmap
+	writes  chan func() // SQLite writes, run one at a time by the writer
+	closed  chan struct{}
+	once    sync.Once // closes closed
 }
 
 type Release struct {
@@ -20,6 +33,8 @@ type Release struct {
 	Body        string
 	Owner       string
 	Repo        string
+	Assets      []ReleaseAsset // nil if not listed, which keeps the cached ones
+	Tag         *Tag           `json:",omitempty"` // nil unless loaded with GetTags
 }
 
 type Commit struct {
@@ -32,156 +47,183 @@ type Commit struct {
 	Owner       string
 	Repo        string
 	PrNumber    *int
+	// Breaking and Deprecated are set when the message announces a
+	// breaking change or a deprecation.
+	Breaking   bool
+	Deprecated bool
+	// Parents are the SHAs of the commit's parents, nil when they were not
+	// recorded, as for commits cached before data version 3.
+	Parents []string `json:",omitempty"`
 }
 
 type PullRequest struct {
-	Number   int
-	Title    string
-	Body     string
-	State    string
-	MergedAt *time.Time
-	Author   string
-	URL      string
-	Owner    string
-	Repo     string
+	Number         int
+	Title          string
+	Body           string
+	State          string
+	MergedAt       *time.Time
+	Author         string
+	URL            string
+	Owner          string
+	Repo           string
+	Labels         []string
+	MergeCommitSHA string
+	// Breaking and Deprecated are set when the title or body announces a
+	// breaking change or a deprecation.
+	Breaking   bool
+	Deprecated bool
 }
 
 type FileChange struct {
-	Filename    string
-	Additions   int
-	Deletions   int
-	Changes     int
-	Status      string
-	Patch       string
-	Owner       string
-	Repo        string
-	FromRelease string
-	ToRelease   string
+	Filename         string
+	PreviousFilename string
+	Additions        int
+	Deletions        int
+	Changes          int
+	Status           string
+	Patch            string
+	Owner            string
+	Repo             string
+	FromRelease      string
+	ToRelease        string
 }
 
+// NewDB opens the cache and brings its schema up to date.
 func NewDB(path string) (*DB, error) {
-	db, err := sql.Open("sqlite3", path)
+	d, err := Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open db: %w", err)
+		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping db: %w", err)
+	if _, err := d.Migrate(); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
-	if err := initSchema(db); err != nil {
-		return nil, fmt.Errorf("failed to init schema: %w", err)
-	}
+	return d, nil
+}
+
+// SetMailmap makes queries return commit authors under their canonical
+// identities. The cache itself keeps the identities as committed.
+func (d *DB) SetMailmap(m *mailmap.Mailmap) {
+	d.mailmap = m
+}
 
-	return &DB{db: db}, nil
+// ResolveAuthors maps the authors of commits that did not come from a query,
+// such as stored or live comparisons, to their canonical identities.
+func (d *DB) ResolveAuthors(commits []Commit) {
+	for i := range commits {
+		commits[i].Author, commits[i].AuthorEmail = d.mailmap.Resolve(commits[i].Author, commits[i].AuthorEmail)
+	}
 }
 
+// Close stops the writer and closes the database. Writes after Close fail
+// with ErrClosed.
 func (d *DB) Close() error {
-	return d.db.Close()
-}
-
-func initSchema(db *sql.DB) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS releases (
-		tag_name TEXT PRIMARY KEY,
-		name TEXT,
-		published_at TEXT,
-		commit_sha TEXT,
-		body TEXT,
-		owner TEXT,
-		repo TEXT
-	);
-
-	CREATE TABLE IF NOT EXISTS commits (
-		sha TEXT PRIMARY KEY,
-		message TEXT,
-		author TEXT,
-		author_email TEXT,
-		date TEXT,
-		url TEXT,
-		owner TEXT,
-		repo TEXT,
-		pr_number INTEGER
-	);
-
-	CREATE TABLE IF NOT EXISTS pull_requests (
-		number INTEGER,
-		title TEXT,
-		body TEXT,
-		state TEXT,
-		merged_at TEXT,
-		author TEXT,
-		url TEXT,
-		owner TEXT,
-		repo TEXT,
-		PRIMARY KEY (owner, repo, number)
-	);
-
-	CREATE TABLE IF NOT EXISTS file_changes (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		filename TEXT,
-		additions INTEGER,
-		deletions INTEGER,
-		changes INTEGER,
-		status TEXT,
-		patch TEXT,
-		owner TEXT,
-		repo TEXT,
-		from_release TEXT,
-		to_release TEXT
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_commits_owner_repo ON commits(owner, repo);
-	CREATE INDEX IF NOT EXISTS idx_prs_owner_repo ON pull_requests(owner, repo);
-	CREATE INDEX IF NOT EXISTS idx_files_release ON file_changes(owner, repo, from_release, to_release);
-	`
-
-	_, err := db.Exec(schema)
+	var err error
+	d.once.Do(func() {
+		if d.closed != nil {
+			close(d.closed)
+		}
+		err = d.db.Close()
+	})
 	return err
 }
 
+const insertRelease = `
+	INSERT OR REPLACE INTO releases (tag_name, name, published_at, commit_sha, body, owner, repo, ordiff_version, data_version)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
 func (d *DB) SaveRelease(r *Release) error {
-	_, err := d.db.Exec(`
-		INSERT OR REPLACE INTO releases (tag_name, name, published_at, commit_sha, body, owner, repo)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, r.TagName, r.Name, r.PublishedAt.Format(time.RFC3339), r.CommitSHA, r.Body, r.Owner, r.Repo)
-	return err
+	args := []interface{}{r.TagName, r.Name, r.PublishedAt.UTC().Format(time.RFC3339), r.CommitSHA, r.Body, r.Owner, r.Repo, version.Version, version.DataVersion}
+	if r.Assets == nil {
+		_, err := d.exec(insertRelease, args...)
+		return err
+	}
+	return d.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(d.rebind(insertRelease), args...); err != nil {
+			return err
+		}
+		return d.saveReleaseAssets(tx, r)
+	})
 }
 
 func (d *DB) SaveCommit(c *Commit) error {
-	var prNum interface{}
-	if c.PrNumber != nil {
-		prNum = *c.PrNumber
-	}
-	_, err := d.db.Exec(`
-		INSERT OR REPLACE INTO commits (sha, message, author, author_email, date, url, owner, repo, pr_number)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, c.SHA, c.Message, c.Author, c.AuthorEmail, c.Date.Format(time.RFC3339), c.URL, c.Owner, c.Repo, prNum)
+	_, err := d.exec(insertCommit, commitArgs(c)...)
 	return err
 }
 
 func (d *DB) SavePullRequest(pr *PullRequest) error {
-	var mergedAt interface{}
-	if pr.MergedAt != nil {
-		mergedAt = pr.MergedAt.Format(time.RFC3339)
-	}
-	_, err := d.db.Exec(`
-		INSERT OR REPLACE INTO pull_requests (number, title, body, state, merged_at, author, url, owner, repo)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, pr.Number, pr.Title, pr.Body, pr.State, mergedAt, pr.Author, pr.URL, pr.Owner, pr.Repo)
+	args, err := pullRequestArgs(pr)
+	if err != nil {
+		return err
+	}
+	_, err = d.exec(insertPullRequest, args...)
 	return err
 }
 
-func (d *DB) SaveFileChange(fc *FileChange) error {
-	_, err := d.db.Exec(`
-		INSERT INTO file_changes (filename, additions, deletions, changes, status, patch, owner, repo, from_release, to_release)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, fc.Filename, fc.Additions, fc.Deletions, fc.Changes, fc.Status, fc.Patch, fc.Owner, fc.Repo, fc.FromRelease, fc.ToRelease)
-	return err
+// GetPullRequests returns the cached pull requests with the given numbers,
+// ordered by merge time. Numbers that were never indexed are skipped.
+func (d *DB) GetPullRequests(owner, repo string, numbers []int) ([]PullRequest, error) {
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(numbers)), ", ")
+	args := []interface{}{owner, repo}
+	for _, n := range numbers {
+		args = append(args, n)
+	}
+
+	rows, err := d.query(`
+		SELECT number, title, body, state, merged_at, author, url, labels, merge_commit_sha, breaking, deprecated
+		FROM pull_requests
+		WHERE owner = ? AND repo = ? AND number IN (`+placeholders+`)
+		ORDER BY merged_at ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prs []PullRequest
+	for rows.Next() {
+		var pr PullRequest
+		var mergedAt, labels, mergeSHA sql.NullString
+		if err := rows.Scan(&pr.Number, &pr.Title, &pr.Body, &pr.State, &mergedAt, &pr.Author, &pr.URL, &labels, &mergeSHA, &pr.Breaking, &pr.Deprecated); err != nil {
+			return nil, err
+		}
+		if mergedAt.Valid {
+			t, _ := time.Parse(time.RFC3339, mergedAt.String)
+			pr.MergedAt = &t
+		}
+		if labels.Valid {
+			json.Unmarshal([]byte(labels.String), &pr.Labels)
+		}
+		pr.MergeCommitSHA = mergeSHA.String
+		pr.Owner = owner
+		pr.Repo = repo
+		prs = append(prs, pr)
+	}
+	return prs, rows.Err()
+}
+
+// LatestPullRequestMerge returns when the most recently merged cached pull
+// request was merged, or the zero time if none are cached.
+func (d *DB) LatestPullRequestMerge(owner, repo string) (time.Time, error) {
+	var mergedAt sql.NullString
+	err := d.queryRow(`
+		SELECT MAX(merged_at) FROM pull_requests WHERE owner = ? AND repo = ?
+	`, owner, repo).Scan(&mergedAt)
+	if err != nil || !mergedAt.Valid {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, mergedAt.String)
 }
 
 func (d *DB) GetReleases(owner, repo string) ([]Release, error) {
-	rows, err := d.db.Query(`
+	rows, err := d.query(`
 		SELECT tag_name, name, published_at, commit_sha, body
 		FROM releases
 		WHERE owner = ? AND repo = ?
@@ -208,9 +250,14 @@ func (d *DB) GetReleases(owner, repo string) ([]Release, error) {
 }
 
 func (d *DB) GetRelease(owner, repo, tag string) (*Release, error) {
+	return d.GetReleaseContext(context.Background(), owner, repo, tag)
+}
+
+// GetReleaseContext is GetRelease, stopping when ctx is cancelled.
+func (d *DB) GetReleaseContext(ctx context.Context, owner, repo, tag string) (*Release, error) {
 	var r Release
 	var publishedAt string
-	err := d.db.QueryRow(`
+	err := d.queryRowContext(ctx, `
 		SELECT tag_name, name, published_at, commit_sha, body
 		FROM releases
 		WHERE owner = ? AND repo = ? AND tag_name = ?
@@ -224,19 +271,171 @@ func (d *DB) GetRelease(owner, repo, tag string) (*Release, error) {
 	return &r, nil
 }
 
+// linkedDataVersion is the first data version whose release pairs have their
+// commits recorded in pair_commits.
+const linkedDataVersion = 2
+
+// pairsInRange selects the indexed release pairs between two releases. Its
+// parameters are the from tag, the to tag, the owner and the repo.
+const pairsInRange = `
+	SELECT p.from_release, p.to_release, COALESCE(p.data_version, 0) AS data_version
+	FROM release_pairs p
+	JOIN releases a ON a.owner = p.owner AND a.repo = p.repo AND a.tag_name = p.from_release
+	JOIN releases b ON b.owner = p.owner AND b.repo = p.repo AND b.tag_name = p.to_release
+	JOIN releases rf ON rf.owner = p.owner AND rf.repo = p.repo AND rf.tag_name = ?
+	JOIN releases rt ON rt.owner = p.owner AND rt.repo = p.repo AND rt.tag_name = ?
+	WHERE p.owner = ? AND p.repo = ?
+	AND a.published_at >= rf.published_at AND b.published_at <= rt.published_at`
+
+// linkedCommits selects the SHAs linked to the pairs of pairsInRange.
+const linkedCommits = `
+	SELECT l.sha FROM pair_commits l
+	JOIN (` + pairsInRange + `) p ON p.from_release = l.from_release AND p.to_release = l.to_release
+	WHERE l.owner = ? AND l.repo = ?`
+
+// commitsLinked reports whether every release pair between two releases was
+// indexed with linked commits. Older caches fall back to matching commits to
+// releases by date, which misplaces backports and commits with skewed dates.
+func (d *DB) commitsLinked(ctx context.Context, owner, repo, fromTag, toTag string) (bool, error) {
+	var pairs int
+	var oldest sql.NullInt64
+	err := d.queryRowContext(ctx, `SELECT COUNT(*), MIN(data_version) FROM (`+pairsInRange+`) p`,
+		fromTag, toTag, owner, repo).Scan(&pairs, &oldest)
+	if err != nil {
+		return false, err
+	}
+	return pairs > 0 && oldest.Int64 >= linkedDataVersion, nil
+}
+
+// LinkPairCommits records which commits a release pair contains, replacing
+// any links from an earlier index of the pair.
+func (d *DB) LinkPairCommits(owner, repo, fromRelease, toRelease string, commits []*Commit) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(d.rebind(deletePairCommits), owner, repo, fromRelease, toRelease); err != nil {
+			return err
+		}
+		for _, c := range commits {
+			if _, err := tx.Exec(d.rebind(insertPairCommit), owner, repo, fromRelease, toRelease, c.SHA); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetCommitsBetween returns the commits between two releases, oldest first:
+// those the to release's commit reaches in the cached commit graph and the
+// from release's does not, which holds across release branches and
+// backports. Caches indexed before parents were recorded fall back to the
+// commits linked to every indexed release pair in between, or before pairs
+// were linked, the commits dated between the releases.
 func (d *DB) GetCommitsBetween(owner, repo, fromTag, toTag string) ([]Commit, error) {
-	rows, err := d.db.Query(`
-		SELECT c.sha, c.message, c.author, c.author_email, c.date, c.url, c.pr_number
-		FROM commits c
-		JOIN releases r1 ON c.date >= r1.published_at
-		JOIN releases r2 ON c.date <= r2.published_at
-		WHERE c.owner = ? AND c.repo = ?
-		AND r1.tag_name = ? AND r2.tag_name = ?
-		ORDER BY c.date ASC
-	`, owner, repo, fromTag, toTag)
+	return d.SearchCommitsBetween(owner, repo, fromTag, toTag, CommitQuery{})
+}
+
+// CommitQuery narrows SearchCommitsBetween. Empty fields match everything.
+type CommitQuery struct {
+	// Author matches part of the author name or email, ignoring case, after
+	// the mailmap has been applied.
+	Author string
+	// Grep matches part of the commit message, ignoring case.
+	Grep string
+	// PR matches commits associated with a pull request number.
+	PR int
+	// Type matches the conventional-commit type, such as feat or fix.
+	Type string
+	// Scope matches the conventional-commit scope.
+	Scope string
+}
+
+// SearchCommitsBetween returns the commits between two releases that match
+// a query, oldest first.
+func (d *DB) SearchCommitsBetween(owner, repo, fromTag, toTag string, q CommitQuery) ([]Commit, error) {
+	r, err := d.CommitRange(owner, repo, fromTag, toTag)
 	if err != nil {
 		return nil, err
 	}
+	return d.SearchCommitsIn(r, q)
+}
+
+// SearchCommitsIn returns the commits of a range that match a query, oldest
+// first.
+func (d *DB) SearchCommitsIn(r *Range, q CommitQuery) ([]Commit, error) {
+	return d.SearchCommitsInContext(context.Background(), r, q)
+}
+
+// SearchCommitsInContext is SearchCommitsIn, stopping when ctx is cancelled.
+func (d *DB) SearchCommitsInContext(ctx context.Context, r *Range, q CommitQuery) ([]Commit, error) {
+	owner, repo := r.Owner, r.Repo
+
+	var where string
+	var filterArgs []interface{}
+	if q.Grep != "" {
+		where += ` AND instr(lower(c.message), lower(CAST(? AS TEXT))) > 0`
+		filterArgs = append(filterArgs, q.Grep)
+	}
+	if q.PR != 0 {
+		where += ` AND c.pr_number = ?`
+		filterArgs = append(filterArgs, q.PR)
+	}
+	if q.Type != "" {
+		where += ` AND c.commit_type = lower(CAST(? AS TEXT))`
+		filterArgs = append(filterArgs, q.Type)
+	}
+	if q.Scope != "" {
+		where += ` AND lower(c.commit_scope) = lower(CAST(? AS TEXT))`
+		filterArgs = append(filterArgs, q.Scope)
+	}
+
+	const selectCommits = `
+		SELECT c.sha, c.message, c.author, c.author_email, c.date, c.url, c.pr_number, c.breaking, c.deprecated
+		FROM commits c`
+
+	if r.graphed {
+		var commits []Commit
+		for _, batch := range shaBatches(r.shas) {
+			placeholders, args := inArgs(owner, repo, batch)
+			rows, err := d.queryContext(ctx, selectCommits+`
+				WHERE c.owner = ? AND c.repo = ? AND c.sha IN (`+placeholders+`)`+where,
+				append(args, filterArgs...)...)
+			if err != nil {
+				return nil, err
+			}
+			found, err := d.scanCommits(rows, owner, repo, q)
+			if err != nil {
+				return nil, err
+			}
+			commits = append(commits, found...)
+		}
+		sort.SliceStable(commits, func(i, j int) bool { return commits[i].Date.Before(commits[j].Date) })
+		return commits, nil
+	}
+
+	var rows *sql.Rows
+	var err error
+	if r.linked {
+		rows, err = d.queryContext(ctx, selectCommits+`
+			WHERE c.owner = ? AND c.repo = ? AND c.sha IN (`+linkedCommits+`)`+where+`
+			ORDER BY c.date ASC
+		`, append([]interface{}{owner, repo, r.From, r.To, owner, repo, owner, repo}, filterArgs...)...)
+	} else {
+		rows, err = d.queryContext(ctx, selectCommits+`
+			JOIN releases r1 ON r1.owner = c.owner AND r1.repo = c.repo AND c.date >= r1.published_at
+			JOIN releases r2 ON r2.owner = c.owner AND r2.repo = c.repo AND c.date <= r2.published_at
+			WHERE c.owner = ? AND c.repo = ?
+			AND r1.tag_name = ? AND r2.tag_name = ?`+where+`
+			ORDER BY c.date ASC
+		`, append([]interface{}{owner, repo, r.From, r.To}, filterArgs...)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.scanCommits(rows, owner, repo, q)
+}
+
+// scanCommits reads and closes the rows of a commit search, leaving out the
+// commits whose author does not match the query.
+func (d *DB) scanCommits(rows *sql.Rows, owner, repo string, q CommitQuery) ([]Commit, error) {
 	defer rows.Close()
 
 	var commits []Commit
@@ -244,21 +443,37 @@ func (d *DB) GetCommitsBetween(owner, repo, fromTag, toTag string) ([]Commit, er
 		var c Commit
 		var prNum *int
 		var date string
-		if err := rows.Scan(&c.SHA, &c.Message, &c.Author, &c.AuthorEmail, &date, &c.URL, &prNum); err != nil {
+		if err := rows.Scan(&c.SHA, &c.Message, &c.Author, &c.AuthorEmail, &date, &c.URL, &prNum, &c.Breaking, &c.Deprecated); err != nil {
 			return nil, err
 		}
 		c.PrNumber = prNum
+		c.Author, c.AuthorEmail = d.mailmap.Resolve(c.Author, c.AuthorEmail)
 		c.Owner = owner
 		c.Repo = repo
 		c.Date, _ = time.Parse(time.RFC3339, date)
+		if q.Author != "" && !matchesAuthor(c, q.Author) {
+			continue
+		}
 		commits = append(commits, c)
 	}
 	return commits, rows.Err()
 }
 
+func matchesAuthor(c Commit, author string) bool {
+	author = strings.ToLower(author)
+	return strings.Contains(strings.ToLower(c.Author), author) || strings.Contains(strings.ToLower(c.AuthorEmail), author)
+}
+
+// GetFileChanges returns the file changes of a release pair without their
+// patches; GetPatches reads those.
 func (d *DB) GetFileChanges(owner, repo, fromTag, toTag string) ([]FileChange, error) {
-	rows, err := d.db.Query(`
-		SELECT filename, additions, deletions, changes, status, patch
+	return d.GetFileChangesContext(context.Background(), owner, repo, fromTag, toTag)
+}
+
+// GetFileChangesContext is GetFileChanges, stopping when ctx is cancelled.
+func (d *DB) GetFileChangesContext(ctx context.Context, owner, repo, fromTag, toTag string) ([]FileChange, error) {
+	rows, err := d.queryContext(ctx, `
+		SELECT filename, previous_filename, additions, deletions, changes, status
 		FROM file_changes
 		WHERE owner = ? AND repo = ? AND from_release = ? AND to_release = ?
 	`, owner, repo, fromTag, toTag)
@@ -270,7 +485,7 @@ func (d *DB) GetFileChanges(owner, repo, fromTag, toTag string) ([]FileChange, e
 	var changes []FileChange
 	for rows.Next() {
 		var fc FileChange
-		if err := rows.Scan(&fc.Filename, &fc.Additions, &fc.Deletions, &fc.Changes, &fc.Status, &fc.Patch); err != nil {
+		if err := rows.Scan(&fc.Filename, &fc.PreviousFilename, &fc.Additions, &fc.Deletions, &fc.Changes, &fc.Status); err != nil {
 			return nil, err
 		}
 		fc.Owner = owner
@@ -283,12 +498,41 @@ func (d *DB) GetFileChanges(owner, repo, fromTag, toTag string) ([]FileChange, e
 }
 
 func (d *DB) PrCountBetween(owner, repo, fromTag, toTag string) (int, error) {
+	r, err := d.CommitRange(owner, repo, fromTag, toTag)
+	if err != nil {
+		return 0, err
+	}
+	return d.PrCountIn(r)
+}
+
+// PrCountIn counts the pull requests of the commits of a range.
+func (d *DB) PrCountIn(r *Range) (int, error) {
+	return d.PrCountInContext(context.Background(), r)
+}
+
+// PrCountInContext is PrCountIn, stopping when ctx is cancelled.
+func (d *DB) PrCountInContext(ctx context.Context, r *Range) (int, error) {
+	if r.graphed {
+		return d.countGraphedPRs(ctx, r)
+	}
+
+	owner, repo, fromTag, toTag := r.Owner, r.Repo, r.From, r.To
 	var count int
-	err := d.db.QueryRow(`
+	if r.linked {
+		err := d.queryRowContext(ctx, `
+			SELECT COUNT(DISTINCT c.pr_number)
+			FROM commits c
+			WHERE c.owner = ? AND c.repo = ? AND c.pr_number IS NOT NULL
+			AND c.sha IN (`+linkedCommits+`)
+		`, owner, repo, fromTag, toTag, owner, repo, owner, repo).Scan(&count)
+		return count, err
+	}
+
+	err := d.queryRowContext(ctx, `
 		SELECT COUNT(DISTINCT c.pr_number)
 		FROM commits c
-		JOIN releases r1 ON c.date >= r1.published_at
-		JOIN releases r2 ON c.date <= r2.published_at
+		JOIN releases r1 ON r1.owner = c.owner AND r1.repo = c.repo AND c.date >= r1.published_at
+		JOIN releases r2 ON r2.owner = c.owner AND r2.repo = c.repo AND c.date <= r2.published_at
 		WHERE c.owner = ? AND c.repo = ? AND c.pr_number IS NOT NULL
 		AND r1.tag_name = ? AND r2.tag_name = ?
 	`, owner, repo, fromTag, toTag).Scan(&count)
@@ -297,7 +541,7 @@ func (d *DB) PrCountBetween(owner, repo, fromTag, toTag string) (int, error) {
 
 func (d *DB) HasFileChangesCached(owner, repo, fromRelease, toRelease string) (bool, error) {
 	var count int
-	err := d.db.QueryRow(`
+	err := d.queryRow(`
 		SELECT COUNT(*) FROM file_changes
 		WHERE owner = ? AND repo = ? AND from_release = ? AND to_release = ?
 	`, owner, repo, fromRelease, toRelease).Scan(&count)
@@ -309,9 +553,432 @@ func (d *DB) HasFileChangesCached(owner, repo, fromRelease, toRelease string) (b
 
 func (d *DB) GetReleasePairCount(owner, repo string) (int, error) {
 	var count int
-	err := d.db.QueryRow(`
+	err := d.queryRow(`
 		SELECT COUNT(*) FROM file_changes
 		WHERE owner = ? AND repo = ?
 	`, owner, repo).Scan(&count)
 	return count, err
 }
+
+// compareCacheVersion stamps precomputed comparisons with the data version
+// they were computed under, so those of an older ordiff are not served.
+func compareCacheVersion(tx *sql.Tx) error {
+	return ensureColumn(tx, "compare_cache", "data_version", "INTEGER NOT NULL DEFAULT 0")
+}
+
+func postgresCompareCacheVersion(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE compare_cache ADD COLUMN IF NOT EXISTS data_version INTEGER NOT NULL DEFAULT 0`)
+	return err
+}
+
+// utcDates rewrites the release, commit and merge dates stored with a time
+// zone offset, as local repositories record them, in UTC. Dates are
+// compared and sorted as strings, which only orders them in time when they
+// share a zone.
+func utcDates(tx *sql.Tx) error {
+	for _, col := range [][2]string{{"releases", "published_at"}, {"commits", "date"}, {"pull_requests", "merged_at"}} {
+		if _, err := tx.Exec(fmt.Sprintf(`
+			UPDATE %[1]s SET %[2]s = strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', %[2]s)
+			WHERE %[2]s NOT LIKE '%%Z' AND strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', %[2]s) IS NOT NULL
+		`, col[0], col[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func postgresUTCDates(tx *sql.Tx) error {
+	for _, col := range [][2]string{{"releases", "published_at"}, {"commits", "date"}, {"pull_requests", "merged_at"}} {
+		if _, err := tx.Exec(fmt.Sprintf(`
+			UPDATE %[1]s SET %[2]s = to_char(%[2]s::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+			WHERE %[2]s NOT LIKE '%%Z' AND %[2]s <> ''
+		`, col[0], col[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DB) SaveCompareCache(owner, repo, fromRelease, toRelease, data string) error {
+	_, err := d.exec(`
+		INSERT OR REPLACE INTO compare_cache (owner, repo, from_release, to_release, data, created_at, data_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, owner, repo, fromRelease, toRelease, data, time.Now().UTC().Format(time.RFC3339), version.DataVersion)
+	return err
+}
+
+// GetCompareCache returns a precomputed comparison. One saved under another
+// data version is reported as missing.
+func (d *DB) GetCompareCache(owner, repo, fromRelease, toRelease string) (string, bool, error) {
+	return d.GetCompareCacheContext(context.Background(), owner, repo, fromRelease, toRelease)
+}
+
+// GetCompareCacheContext is GetCompareCache, stopping when ctx is cancelled.
+func (d *DB) GetCompareCacheContext(ctx context.Context, owner, repo, fromRelease, toRelease string) (string, bool, error) {
+	var data string
+	err := d.queryRowContext(ctx, `
+		SELECT data FROM compare_cache
+		WHERE owner = ? AND repo = ? AND from_release = ? AND to_release = ? AND data_version = ?
+	`, owner, repo, fromRelease, toRelease, version.DataVersion).Scan(&data)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return data, true, nil
+}
+
+func (d *DB) ClearCompareCache(owner, repo string) error {
+	_, err := d.exec(`DELETE FROM compare_cache WHERE owner = ? AND repo = ?`, owner, repo)
+	return err
+}
+
+func (d *DB) SaveChangelog(owner, repo, tag, markdown string) error {
+	_, err := d.exec(`
+		INSERT OR REPLACE INTO release_changelogs (owner, repo, tag_name, markdown, generated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, owner, repo, tag, markdown, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func (d *DB) GetChangelog(owner, repo, tag string) (string, bool, error) {
+	var markdown string
+	err := d.queryRow(`
+		SELECT markdown FROM release_changelogs
+		WHERE owner = ? AND repo = ? AND tag_name = ?
+	`, owner, repo, tag).Scan(&markdown)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return markdown, true, nil
+}
+
+// StampReleasePair records that a release pair was indexed by the running
+// ordiff version.
+func (d *DB) StampReleasePair(owner, repo, fromRelease, toRelease string) error {
+	_, err := d.exec(stampReleasePair, stampArgs(owner, repo, fromRelease, toRelease)...)
+	return err
+}
+
+type StaleData struct {
+	Releases          int
+	Pairs             int
+	OldestDataVersion int
+}
+
+// StaleDataBefore counts cached releases and pairs stamped with a data
+// version older than minVersion. Rows cached before stamping existed count
+// as version 0.
+func (d *DB) StaleDataBefore(owner, repo string, minVersion int) (StaleData, error) {
+	var s StaleData
+	var oldestRelease, oldestPair sql.NullInt64
+	err := d.queryRow(`
+		SELECT COUNT(*), MIN(COALESCE(data_version, 0)) FROM releases
+		WHERE owner = ? AND repo = ? AND COALESCE(data_version, 0) < ?
+	`, owner, repo, minVersion).Scan(&s.Releases, &oldestRelease)
+	if err != nil {
+		return s, err
+	}
+
+	err = d.queryRow(`
+		SELECT COUNT(*), MIN(COALESCE(data_version, 0)) FROM release_pairs
+		WHERE owner = ? AND repo = ? AND COALESCE(data_version, 0) < ?
+	`, owner, repo, minVersion).Scan(&s.Pairs, &oldestPair)
+	if err != nil {
+		return s, err
+	}
+
+	s.OldestDataVersion = minVersion
+	if oldestRelease.Valid {
+		s.OldestDataVersion = min(s.OldestDataVersion, int(oldestRelease.Int64))
+	}
+	if oldestPair.Valid {
+		s.OldestDataVersion = min(s.OldestDataVersion, int(oldestPair.Int64))
+	}
+	return s, nil
+}
+
+// PairDataVersion returns the data version a release pair was indexed with,
+// or 0 when the pair predates stamping.
+func (d *DB) PairDataVersion(owner, repo, fromRelease, toRelease string) (int, error) {
+	var v sql.NullInt64
+	err := d.queryRow(`
+		SELECT data_version FROM release_pairs
+		WHERE owner = ? AND repo = ? AND from_release = ? AND to_release = ?
+	`, owner, repo, fromRelease, toRelease).Scan(&v)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+// IsPairStale reports whether a cached pair was indexed before a known data
+// fix and should be fetched again.
+func (d *DB) IsPairStale(owner, repo, fromRelease, toRelease string) bool {
+	minVersion := version.StaleBefore()
+	if minVersion == 0 {
+		return false
+	}
+	v, err := d.PairDataVersion(owner, repo, fromRelease, toRelease)
+	return err == nil && v < minVersion
+}
+
+func (d *DB) DeleteFileChanges(owner, repo, fromRelease, toRelease string) error {
+	if _, err := d.exec(`
+		DELETE FROM file_patches WHERE file_change_id IN (
+			SELECT id FROM file_changes
+			WHERE owner = ? AND repo = ? AND from_release = ? AND to_release = ?
+		)
+	`, owner, repo, fromRelease, toRelease); err != nil {
+		return err
+	}
+	_, err := d.exec(`
+		DELETE FROM file_changes
+		WHERE owner = ? AND repo = ? AND from_release = ? AND to_release = ?
+	`, owner, repo, fromRelease, toRelease)
+	return err
+}
+
+// SaveRepository records where a repository was indexed from. location is
+// the working copy path for local repositories and empty otherwise.
+func (d *DB) SaveRepository(owner, repo, provider, location string) error {
+	_, err := d.exec(`
+		INSERT OR REPLACE INTO repositories (owner, repo, provider, location, indexed_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, owner, repo, provider, location, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// GetRepositoryProvider returns the forge a repository was indexed from and
+// its location, or empty strings if it is unknown.
+func (d *DB) GetRepositoryProvider(owner, repo string) (string, string, error) {
+	var provider string
+	var location sql.NullString
+	err := d.queryRow(`
+		SELECT provider, location FROM repositories WHERE owner = ? AND repo = ?
+	`, owner, repo).Scan(&provider, &location)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	return provider, location.String, err
+}
+
+type Repository struct {
+	Owner    string
+	Repo     string
+	Provider string
+	Releases int
+}
+
+// GetRepositories lists every repository with cached releases.
+func (d *DB) GetRepositories() ([]Repository, error) {
+	rows, err := d.query(`
+		SELECT r.owner, r.repo, COALESCE(p.provider, ''), COUNT(*)
+		FROM releases r
+		LEFT JOIN repositories p ON p.owner = r.owner AND p.repo = r.repo
+		GROUP BY r.owner, r.repo, p.provider
+		ORDER BY r.owner, r.repo
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repos []Repository
+	for rows.Next() {
+		var r Repository
+		if err := rows.Scan(&r.Owner, &r.Repo, &r.Provider, &r.Releases); err != nil {
+			return nil, err
+		}
+		repos = append(repos, r)
+	}
+	return repos, rows.Err()
+}
+
+type PairChurn struct {
+	FromRelease string
+	ToRelease   string
+	Files       int
+	Additions   int
+	Deletions   int
+}
+
+// GetPairChurn sums the cached file changes of every indexed release pair.
+func (d *DB) GetPairChurn(owner, repo string) ([]PairChurn, error) {
+	rows, err := d.query(`
+		SELECT f.from_release, f.to_release, COUNT(*), SUM(f.additions), SUM(f.deletions)
+		FROM file_changes f
+		JOIN releases r ON r.owner = f.owner AND r.repo = f.repo AND r.tag_name = f.to_release
+		WHERE f.owner = ? AND f.repo = ?
+		GROUP BY f.from_release, f.to_release
+		ORDER BY MAX(r.published_at) ASC
+	`, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var churn []PairChurn
+	for rows.Next() {
+		var c PairChurn
+		if err := rows.Scan(&c.FromRelease, &c.ToRelease, &c.Files, &c.Additions, &c.Deletions); err != nil {
+			return nil, err
+		}
+		churn = append(churn, c)
+	}
+	return churn, rows.Err()
+}
+
+// GetCommits returns every cached commit of a repository, oldest first.
+func (d *DB) GetCommits(owner, repo string) ([]Commit, error) {
+	rows, err := d.query(`
+		SELECT sha, message, author, author_email, date, url, pr_number, breaking, deprecated
+		FROM commits
+		WHERE owner = ? AND repo = ?
+		ORDER BY date ASC
+	`, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []Commit
+	for rows.Next() {
+		c := Commit{Owner: owner, Repo: repo}
+		var date string
+		if err := rows.Scan(&c.SHA, &c.Message, &c.Author, &c.AuthorEmail, &date, &c.URL, &c.PrNumber, &c.Breaking, &c.Deprecated); err != nil {
+			return nil, err
+		}
+		c.Author, c.AuthorEmail = d.mailmap.Resolve(c.Author, c.AuthorEmail)
+		c.Date, _ = time.Parse(time.RFC3339, date)
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
+
+// GetAllFileChanges returns the file changes of every cached release pair.
+// Patches are left out unless withPatch is set, as they dominate the size.
+func (d *DB) GetAllFileChanges(owner, repo string, withPatch bool) ([]FileChange, error) {
+	patch, join := "NULL", ""
+	if withPatch {
+		patch, join = "p.patch", "LEFT JOIN file_patches p ON p.file_change_id = f.id"
+	}
+	rows, err := d.query(`
+		SELECT f.from_release, f.to_release, f.filename, f.previous_filename, f.additions, f.deletions, f.changes, f.status, `+patch+`
+		FROM file_changes f `+join+`
+		WHERE f.owner = ? AND f.repo = ?
+		ORDER BY f.id ASC
+	`, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []FileChange
+	for rows.Next() {
+		fc := FileChange{Owner: owner, Repo: repo}
+		var data []byte
+		if err := rows.Scan(&fc.FromRelease, &fc.ToRelease, &fc.Filename, &fc.PreviousFilename, &fc.Additions, &fc.Deletions, &fc.Changes, &fc.Status, &data); err != nil {
+			return nil, err
+		}
+		if data != nil {
+			if fc.Patch, err = decompressPatch(data); err != nil {
+				return nil, err
+			}
+		}
+		changes = append(changes, fc)
+	}
+	return changes, rows.Err()
+}
+
+// IndexRun records the progress of the latest index of a repository. A run
+// that never finished was interrupted and can be resumed.
+type IndexRun struct {
+	StartedAt  time.Time
+	UpdatedAt  time.Time
+	TotalPairs int
+	DonePairs  int
+	Finished   bool
+}
+
+func (d *DB) StartIndexRun(owner, repo string, totalPairs int) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := d.exec(`
+		INSERT OR REPLACE INTO index_runs (owner, repo, started_at, updated_at, total_pairs, done_pairs, finished)
+		VALUES (?, ?, ?, ?, ?, 0, 0)
+	`, owner, repo, now, now, totalPairs)
+	return err
+}
+
+func (d *DB) AdvanceIndexRun(owner, repo string) error {
+	_, err := d.exec(`
+		UPDATE index_runs SET done_pairs = done_pairs + 1, updated_at = ?
+		WHERE owner = ? AND repo = ?
+	`, time.Now().UTC().Format(time.RFC3339), owner, repo)
+	return err
+}
+
+func (d *DB) FinishIndexRun(owner, repo string) error {
+	_, err := d.exec(`
+		UPDATE index_runs SET finished = 1, updated_at = ?
+		WHERE owner = ? AND repo = ?
+	`, time.Now().UTC().Format(time.RFC3339), owner, repo)
+	return err
+}
+
+// GetIndexRun returns the latest index run of a repository, or nil if it was
+// never indexed by a version that tracks runs.
+func (d *DB) GetIndexRun(owner, repo string) (*IndexRun, error) {
+	var run IndexRun
+	var startedAt, updatedAt string
+	err := d.queryRow(`
+		SELECT started_at, updated_at, total_pairs, done_pairs, finished
+		FROM index_runs WHERE owner = ? AND repo = ?
+	`, owner, repo).Scan(&startedAt, &updatedAt, &run.TotalPairs, &run.DonePairs, &run.Finished)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	run.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+	run.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return &run, nil
+}
+
+// HTTPResponse is a cached API response together with the validators needed
+// to revalidate it with a conditional request.
+type HTTPResponse struct {
+	URL          string
+	ETag         string
+	LastModified string
+	Link         string
+	Body         []byte
+}
+
+func (d *DB) SaveHTTPResponse(r *HTTPResponse) error {
+	_, err := d.exec(`
+		INSERT OR REPLACE INTO http_cache (url, etag, last_modified, link, body, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, r.URL, r.ETag, r.LastModified, r.Link, r.Body, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// GetHTTPResponse returns the cached response for url, or nil if there is none.
+func (d *DB) GetHTTPResponse(url string) (*HTTPResponse, error) {
+	r := HTTPResponse{URL: url}
+	err := d.queryRow(`
+		SELECT etag, last_modified, link, body FROM http_cache WHERE url = ?
+	`, url).Scan(&r.ETag, &r.LastModified, &r.Link, &r.Body)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
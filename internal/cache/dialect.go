@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// dialect is the SQL flavor of the database behind a DB. Queries are written
+// for SQLite and rewritten for PostgreSQL by rebind.
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+// primaryKeys lists the conflict targets of the tables written with INSERT OR
+// REPLACE, which PostgreSQL spells as an upsert on the primary key.
+var primaryKeys = map[string][]string{
+	"releases":           {"owner", "repo", "tag_name"},
+	"release_assets":     {"owner", "repo", "tag_name", "name"},
+	"release_tags":       {"owner", "repo", "tag_name"},
+	"release_metrics":    {"owner", "repo", "tag_name", "hook", "name", "unit"},
+	"asset_downloads":    {"owner", "repo", "tag_name", "name", "recorded_at"},
+	"commits":            {"owner", "repo", "sha"},
+	"pull_requests":      {"owner", "repo", "number"},
+	"issues":             {"owner", "repo", "number"},
+	"milestones":         {"owner", "repo", "number"},
+	"compare_cache":      {"owner", "repo", "from_release", "to_release"},
+	"release_changelogs": {"owner", "repo", "tag_name"},
+	"release_pairs":      {"owner", "repo", "from_release", "to_release"},
+	"repositories":       {"owner", "repo"},
+	"index_runs":         {"owner", "repo"},
+	"http_cache":         {"url"},
+	"search_state":       {"owner", "repo"},
+	"codeowners":         {"owner", "repo"},
+}
+
+var (
+	insertOrReplaceRe = regexp.MustCompile(`(?s)INSERT OR REPLACE INTO (\w+) \(([^)]*)\)(.*)`)
+	insertOrIgnoreRe  = regexp.MustCompile(`(?s)INSERT OR IGNORE INTO (.*)`)
+
+	rebound sync.Map // query -> PostgreSQL query
+)
+
+// rebind rewrites a SQLite query for the database's dialect: upserts,
+// instr and ? placeholders become their PostgreSQL forms.
+func (d *DB) rebind(query string) string {
+	if d.dialect != dialectPostgres {
+		return query
+	}
+	if q, ok := rebound.Load(query); ok {
+		return q.(string)
+	}
+
+	q := query
+	if m := insertOrReplaceRe.FindStringSubmatch(q); m != nil {
+		table, columns, rest := m[1], m[2], strings.TrimRight(m[3], " \t\n")
+		key := primaryKeys[table]
+		var updates []string
+		for _, c := range strings.Split(columns, ",") {
+			c = strings.TrimSpace(c)
+			if !contains(key, c) {
+				updates = append(updates, c+" = EXCLUDED."+c)
+			}
+		}
+		q = "INSERT INTO " + table + " (" + columns + ")" + rest + "\n\t\tON CONFLICT (" + strings.Join(key, ", ") + ") DO UPDATE SET " + strings.Join(updates, ", ")
+	} else if m := insertOrIgnoreRe.FindStringSubmatch(q); m != nil {
+		q = "INSERT INTO " + strings.TrimRight(m[1], " \t\n") + "\n\t\tON CONFLICT DO NOTHING"
+	}
+	q = strings.ReplaceAll(q, "instr(", "strpos(")
+
+	var sb strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	q = sb.String()
+
+	rebound.Store(query, q)
+	return q
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DB) exec(query string, args ...interface{}) (res sql.Result, err error) {
+	err = d.write(func() error {
+		res, err = d.db.Exec(d.rebind(query), args...)
+		return err
+	})
+	return res, err
+}
+
+func (d *DB) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.queryContext(context.Background(), query, args...)
+}
+
+func (d *DB) queryRow(query string, args ...interface{}) *sql.Row {
+	return d.queryRowContext(context.Background(), query, args...)
+}
+
+func (d *DB) queryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, d.rebind(query), args...)
+}
+
+func (d *DB) queryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(ctx, d.rebind(query), args...)
+}
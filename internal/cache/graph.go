@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+)
+
+// commitParents records the parents of every commit, so ranges between
+// releases follow the commit graph instead of publish dates. Commits cached
+// before leave it NULL, and a root commit has it empty.
+func commitParents(tx *sql.Tx) error {
+	return ensureColumn(tx, "commits", "parents", "TEXT")
+}
+
+func postgresCommitParents(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE commits ADD COLUMN IF NOT EXISTS parents TEXT`)
+	return err
+}
+
+// graph is the cached commit graph of a repository. A SHA maps to nil
+// parents when the commit is cached without them, and is missing when the
+// commit is not cached at all.
+type graph struct {
+	parents map[string][]string
+	dates   map[string]time.Time
+}
+
+func (d *DB) commitGraph(ctx context.Context, owner, repo string) (*graph, error) {
+	rows, err := d.queryContext(ctx, `
+		SELECT sha, parents, date FROM commits WHERE owner = ? AND repo = ?
+	`, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	g := &graph{parents: map[string][]string{}, dates: map[string]time.Time{}}
+	for rows.Next() {
+		var sha, date string
+		var parents sql.NullString
+		if err := rows.Scan(&sha, &parents, &date); err != nil {
+			return nil, err
+		}
+		g.parents[sha] = nil
+		if parents.Valid {
+			g.parents[sha] = append([]string{}, strings.Fields(parents.String)...)
+		}
+		g.dates[sha], _ = time.Parse(time.RFC3339, date)
+	}
+	return g, rows.Err()
+}
+
+// ancestors walks the graph from sha, which is included, skipping the
+// commits in stop and their ancestors. complete is false when a commit on
+// the way was cached without its parents, so the walk may have stopped
+// short; commits missing from the cache end the walk silently, as they
+// precede what was indexed.
+func (g *graph) ancestors(sha string, stop map[string]bool) (seen map[string]bool, complete bool) {
+	seen = map[string]bool{}
+	complete = true
+	stack := []string{sha}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[c] || stop[c] {
+			continue
+		}
+		seen[c] = true
+		parents, cached := g.parents[c]
+		if cached && parents == nil {
+			complete = false
+		}
+		stack = append(stack, parents...)
+	}
+	return seen, complete
+}
+
+// releaseCommits returns the commits two releases are tagged at.
+func (d *DB) releaseCommits(ctx context.Context, owner, repo, fromTag, toTag string) (string, string, error) {
+	from, err := d.GetReleaseContext(ctx, owner, repo, fromTag)
+	if err != nil {
+		return "", "", err
+	}
+	to, err := d.GetReleaseContext(ctx, owner, repo, toTag)
+	if err != nil {
+		return "", "", err
+	}
+	return from.CommitSHA, to.CommitSHA, nil
+}
+
+// Range is the commits between two releases, resolved once so the queries
+// of one comparison share the commit graph instead of each loading it.
+type Range struct {
+	Owner, Repo, From, To string
+
+	fromSHA, toSHA string
+	g              *graph   // nil when the releases are not resolved to commits
+	shas           []string // the commits of the range, when graphed
+	graphed        bool     // the graph holds the whole range
+	linked         bool     // every pair in between was indexed with linked commits
+}
+
+// CommitRange resolves the commits between two releases.
+func (d *DB) CommitRange(owner, repo, fromTag, toTag string) (*Range, error) {
+	ranges, err := d.CommitRanges(owner, repo, [][2]string{{fromTag, toTag}})
+	if err != nil {
+		return nil, err
+	}
+	return ranges[0], nil
+}
+
+// CommitRanges resolves the commits between several pairs of releases of a
+// repository, loading its commit graph at most once.
+//
+// A range follows the graph when it can: the SHAs reachable from the to
+// release's commit but not from the from release's, as git log from..to
+// would. The graph cannot tell when the releases are not resolved to
+// commits, or the range holds commits cached before their parents were
+// recorded; the range then falls back to linked commits or publish dates.
+func (d *DB) CommitRanges(owner, repo string, pairs [][2]string) ([]*Range, error) {
+	return d.CommitRangesContext(context.Background(), owner, repo, pairs)
+}
+
+// CommitRangesContext is CommitRanges, stopping when ctx is cancelled.
+func (d *DB) CommitRangesContext(ctx context.Context, owner, repo string, pairs [][2]string) ([]*Range, error) {
+	var g *graph
+	ranges := make([]*Range, 0, len(pairs))
+	for _, p := range pairs {
+		r := &Range{Owner: owner, Repo: repo, From: p[0], To: p[1]}
+		fromSHA, toSHA, err := d.releaseCommits(ctx, owner, repo, p[0], p[1])
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == nil && fromSHA != "" && toSHA != "" {
+			if g == nil {
+				if g, err = d.commitGraph(ctx, owner, repo); err != nil {
+					return nil, err
+				}
+			}
+			r.fromSHA, r.toSHA, r.g = fromSHA, toSHA, g
+			if g.parents[toSHA] != nil {
+				before, _ := g.ancestors(fromSHA, nil)
+				shas, complete := g.ancestors(toSHA, before)
+				r.graphed = complete
+				for sha := range shas {
+					r.shas = append(r.shas, sha)
+				}
+				sort.Strings(r.shas)
+			}
+		}
+		if !r.graphed {
+			if r.linked, err = d.commitsLinked(ctx, owner, repo, p[0], p[1]); err != nil {
+				return nil, err
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// MergeBase returns the newest cached commit that both releases contain,
+// which is the from release's own commit when the to release descends from
+// it. It returns "" when the cached graph cannot tell.
+func (r *Range) MergeBase() string {
+	g := r.g
+	if g == nil || g.parents[r.toSHA] == nil {
+		return ""
+	}
+
+	toAncestors, complete := g.ancestors(r.toSHA, nil)
+	if toAncestors[r.fromSHA] {
+		return r.fromSHA
+	}
+	fromAncestors, fromComplete := g.ancestors(r.fromSHA, nil)
+	if !complete || !fromComplete {
+		return ""
+	}
+	base := ""
+	for sha := range fromAncestors {
+		date, cached := g.dates[sha]
+		if !toAncestors[sha] || !cached {
+			continue
+		}
+		if base == "" || date.After(g.dates[base]) || (date.Equal(g.dates[base]) && sha > base) {
+			base = sha
+		}
+	}
+	return base
+}
+
+// MergeBase returns the newest cached commit that both releases contain; see
+// Range.MergeBase.
+func (d *DB) MergeBase(owner, repo, fromTag, toTag string) (string, error) {
+	r, err := d.CommitRange(owner, repo, fromTag, toTag)
+	if err != nil {
+		return "", err
+	}
+	return r.MergeBase(), nil
+}
+
+// shaBatch bounds the SHAs bound into one IN list, well under the variable
+// limits of SQLite and Postgres.
+const shaBatch = 500
+
+// shaBatches splits SHAs into lists of at most shaBatch.
+func shaBatches(shas []string) [][]string {
+	var batches [][]string
+	for len(shas) > shaBatch {
+		batches = append(batches, shas[:shaBatch])
+		shas = shas[shaBatch:]
+	}
+	if len(shas) > 0 {
+		batches = append(batches, shas)
+	}
+	return batches
+}
+
+// inArgs returns the placeholders of an IN list and the arguments of a query
+// on a repository's rows with those SHAs.
+func inArgs(owner, repo string, shas []string) (string, []interface{}) {
+	args := []interface{}{owner, repo}
+	for _, sha := range shas {
+		args = append(args, sha)
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(shas)), ", "), args
+}
+
+// countGraphedPRs counts the pull requests of the commits in a graph range.
+func (d *DB) countGraphedPRs(ctx context.Context, r *Range) (int, error) {
+	prs := map[int]bool{}
+	for _, batch := range shaBatches(r.shas) {
+		placeholders, args := inArgs(r.Owner, r.Repo, batch)
+		rows, err := d.queryContext(ctx, `
+			SELECT DISTINCT pr_number FROM commits
+			WHERE owner = ? AND repo = ? AND pr_number IS NOT NULL AND sha IN (`+placeholders+`)
+		`, args...)
+		if err != nil {
+			return 0, err
+		}
+		for rows.Next() {
+			var number int
+			if err := rows.Scan(&number); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			prs[number] = true
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(prs), nil
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// closedIssues stores the closed issues of each repository, which compare
+// links to the release that closed them.
+func closedIssues(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS issues (
+		owner TEXT,
+		repo TEXT,
+		number INTEGER,
+		title TEXT,
+		state_reason TEXT,
+		closed_at TEXT,
+		author TEXT,
+		url TEXT,
+		labels TEXT,
+		PRIMARY KEY (owner, repo, number)
+	);
+	`)
+	return err
+}
+
+// Issue is a closed issue. StateReason is "completed" or "not_planned" when
+// the forge reports why it was closed.
+type Issue struct {
+	Number      int       `json:"number"`
+	Title       string    `json:"title"`
+	StateReason string    `json:"state_reason"`
+	ClosedAt    time.Time `json:"closed_at"`
+	Author      string    `json:"author"`
+	URL         string    `json:"url"`
+	Labels      []string  `json:"labels"`
+	Owner       string    `json:"-"`
+	Repo        string    `json:"-"`
+}
+
+func (d *DB) SaveIssue(i *Issue) error {
+	labels, err := json.Marshal(i.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = d.exec(`
+		INSERT OR REPLACE INTO issues (owner, repo, number, title, state_reason, closed_at, author, url, labels)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, i.Owner, i.Repo, i.Number, i.Title, i.StateReason, i.ClosedAt.UTC().Format(time.RFC3339), i.Author, i.URL, string(labels))
+	return err
+}
+
+// GetIssues returns the cached closed issues with the given numbers, ordered
+// by when they were closed. Numbers that are not cached, such as pull
+// requests or open issues, are skipped.
+func (d *DB) GetIssues(owner, repo string, numbers []int) ([]Issue, error) {
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(numbers)), ", ")
+	args := []interface{}{owner, repo}
+	for _, n := range numbers {
+		args = append(args, n)
+	}
+
+	rows, err := d.query(`
+		SELECT number, title, state_reason, closed_at, author, url, labels
+		FROM issues
+		WHERE owner = ? AND repo = ? AND number IN (`+placeholders+`)
+		ORDER BY closed_at ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []Issue
+	for rows.Next() {
+		var i Issue
+		var closedAt, labels string
+		if err := rows.Scan(&i.Number, &i.Title, &i.StateReason, &closedAt, &i.Author, &i.URL, &labels); err != nil {
+			return nil, err
+		}
+		i.ClosedAt, _ = time.Parse(time.RFC3339, closedAt)
+		json.Unmarshal([]byte(labels), &i.Labels)
+		i.Owner = owner
+		i.Repo = repo
+		issues = append(issues, i)
+	}
+	return issues, rows.Err()
+}
+
+// LatestIssueClose returns when the most recently closed cached issue was
+// closed, or the zero time if none are cached.
+func (d *DB) LatestIssueClose(owner, repo string) (time.Time, error) {
+	var closedAt sql.NullString
+	err := d.queryRow(`
+		SELECT MAX(closed_at) FROM issues WHERE owner = ? AND repo = ?
+	`, owner, repo).Scan(&closedAt)
+	if err != nil || !closedAt.Valid {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, closedAt.String)
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Index job states. A job still Running after its process died was
+// interrupted.
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// IndexJob is one recorded run of index, update or resume, by the CLI or the
+// MCP server. DonePairs counts the release pairs stamped since the job
+// started, so it is accurate even for a job that never ended.
+type IndexJob struct {
+	ID        int64     `json:"id"`
+	Owner     string    `json:"owner"`
+	Repo      string    `json:"repo"`
+	Kind      string    `json:"kind"`
+	Source    string    `json:"source"`
+	State     string    `json:"state"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	DonePairs int       `json:"done_pairs"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func indexJobs(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS index_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		owner TEXT,
+		repo TEXT,
+		kind TEXT,
+		source TEXT,
+		state TEXT,
+		host TEXT,
+		pid INTEGER,
+		started_at TEXT,
+		ended_at TEXT,
+		done_pairs INTEGER,
+		error TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_index_jobs_repo ON index_jobs(owner, repo);
+	`)
+	return err
+}
+
+func postgresIndexJobs(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS index_jobs (
+		id BIGSERIAL PRIMARY KEY,
+		owner TEXT,
+		repo TEXT,
+		kind TEXT,
+		source TEXT,
+		state TEXT,
+		host TEXT,
+		pid INTEGER,
+		started_at TEXT,
+		ended_at TEXT,
+		done_pairs INTEGER,
+		error TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_index_jobs_repo ON index_jobs(owner, repo);
+	`)
+	return err
+}
+
+// jobPairs counts the release pairs of a job's repository stamped since it
+// started.
+const jobPairs = `(SELECT COUNT(*) FROM release_pairs p
+	WHERE p.owner = index_jobs.owner AND p.repo = index_jobs.repo AND p.indexed_at >= index_jobs.started_at)`
+
+// StartIndexJob records a running job and sets its ID and start time.
+func (d *DB) StartIndexJob(j *IndexJob) error {
+	j.State = JobRunning
+	j.StartedAt = time.Now().UTC().Truncate(time.Second)
+	return d.write(func() error {
+		return d.queryRow(`
+			INSERT INTO index_jobs (owner, repo, kind, source, state, host, pid, started_at, ended_at, done_pairs, error)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, '', 0, '')
+			RETURNING id
+		`, j.Owner, j.Repo, j.Kind, j.Source, j.State, j.Host, j.PID, j.StartedAt.Format(time.RFC3339)).Scan(&j.ID)
+	})
+}
+
+// EndIndexJob records how a job ended, with errMsg for a failed one.
+func (d *DB) EndIndexJob(id int64, state, errMsg string) error {
+	_, err := d.exec(`
+		UPDATE index_jobs SET state = ?, error = ?, ended_at = ?, done_pairs = `+jobPairs+`
+		WHERE id = ?
+	`, state, errMsg, time.Now().UTC().Format(time.RFC3339), id)
+	return err
+}
+
+// GetIndexJobs returns the latest jobs, newest first, of one repository or
+// of all of them when owner is empty.
+func (d *DB) GetIndexJobs(owner, repo string, limit int) ([]IndexJob, error) {
+	rows, err := d.query(`
+		SELECT id, owner, repo, kind, source, state, host, pid, started_at, ended_at,
+			CASE WHEN state = 'running' THEN `+jobPairs+` ELSE done_pairs END,
+			error
+		FROM index_jobs
+		WHERE (? = '' OR owner = ? AND repo = ?)
+		ORDER BY id DESC
+		LIMIT ?
+	`, owner, owner, repo, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []IndexJob
+	for rows.Next() {
+		var j IndexJob
+		var startedAt, endedAt string
+		if err := rows.Scan(&j.ID, &j.Owner, &j.Repo, &j.Kind, &j.Source, &j.State, &j.Host, &j.PID,
+			&startedAt, &endedAt, &j.DonePairs, &j.Error); err != nil {
+			return nil, err
+		}
+		j.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		j.EndedAt, _ = time.Parse(time.RFC3339, endedAt)
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
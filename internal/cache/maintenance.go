@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// repoTables are the tables keyed by owner and repo, in the order stats
+// reports them.
+var repoTables = []string{
+	"releases",
+	"release_assets",
+	"release_tags",
+	"release_metrics",
+	"asset_downloads",
+	"commits",
+	"pull_requests",
+	"issues",
+	"milestones",
+	"milestone_items",
+	"file_changes",
+	"file_patches",
+	"commit_files",
+	"codeowners",
+	"compare_cache",
+	"release_changelogs",
+	"release_pairs",
+	"pair_commits",
+	"index_runs",
+	"index_jobs",
+	"repositories",
+	"search_index",
+	"search_state",
+}
+
+type TableCount struct {
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+}
+
+type RepoStats struct {
+	Owner string       `json:"owner"`
+	Repo  string       `json:"repo"`
+	Rows  []TableCount `json:"rows"`
+	// HTTPResponses counts the cached GitHub API responses of the repository.
+	HTTPResponses int `json:"http_responses"`
+}
+
+type Stats struct {
+	SizeBytes     int64       `json:"size_bytes"`
+	FreeBytes     int64       `json:"free_bytes"`
+	SchemaVersion int         `json:"schema_version"`
+	HTTPResponses int         `json:"http_responses"`
+	Repos         []RepoStats `json:"repos"`
+}
+
+// httpCacheMatch selects the cached API responses of one repository. instr
+// is used over LIKE because owner and repo names may contain underscores.
+const httpCacheMatch = `instr(url, '/repos/' || CAST(? AS TEXT) || '/' || CAST(? AS TEXT) || '/') > 0`
+
+// Stats reports the size of the cache file and the rows cached per
+// repository. FreeBytes is the space a vacuum would give back.
+func (d *DB) Stats() (*Stats, error) {
+	var s Stats
+	if d.dialect == dialectPostgres {
+		// The whole database; PostgreSQL does not report reclaimable space.
+		if err := d.queryRow(`SELECT pg_database_size(current_database())`).Scan(&s.SizeBytes); err != nil {
+			return nil, err
+		}
+	} else {
+		var pageSize, pages, free int64
+		if err := d.queryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+			return nil, err
+		}
+		if err := d.queryRow(`PRAGMA page_count`).Scan(&pages); err != nil {
+			return nil, err
+		}
+		if err := d.queryRow(`PRAGMA freelist_count`).Scan(&free); err != nil {
+			return nil, err
+		}
+		s.SizeBytes = pages * pageSize
+		s.FreeBytes = free * pageSize
+	}
+
+	var err error
+	if s.SchemaVersion, err = d.SchemaVersion(); err != nil {
+		return nil, err
+	}
+	if err := d.queryRow(`SELECT COUNT(*) FROM http_cache`).Scan(&s.HTTPResponses); err != nil {
+		return nil, err
+	}
+
+	selects := make([]string, len(repoTables))
+	for i, table := range repoTables {
+		selects[i] = "SELECT owner, repo FROM " + table
+	}
+	rows, err := d.query(strings.Join(selects, " UNION ") + " ORDER BY owner, repo")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var r RepoStats
+		if err := rows.Scan(&r.Owner, &r.Repo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.Repos = append(s.Repos, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range s.Repos {
+		r := &s.Repos[i]
+		for _, table := range repoTables {
+			var n int
+			if err := d.queryRow(`SELECT COUNT(*) FROM `+table+` WHERE owner = ? AND repo = ?`, r.Owner, r.Repo).Scan(&n); err != nil {
+				return nil, fmt.Errorf("failed to count %s: %w", table, err)
+			}
+			r.Rows = append(r.Rows, TableCount{Table: table, Rows: n})
+		}
+		if err := d.queryRow(`SELECT COUNT(*) FROM http_cache WHERE `+httpCacheMatch, r.Owner, r.Repo).Scan(&r.HTTPResponses); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+// Purge deletes everything cached for a repository and returns the number of
+// rows removed. The file does not shrink until Vacuum is run.
+func (d *DB) Purge(owner, repo string) (int64, error) {
+	var removed int64
+	err := d.inTx(func(tx *sql.Tx) error {
+		for _, table := range repoTables {
+			res, err := tx.Exec(d.rebind(`DELETE FROM `+table+` WHERE owner = ? AND repo = ?`), owner, repo)
+			if err != nil {
+				return fmt.Errorf("failed to purge %s: %w", table, err)
+			}
+			n, _ := res.RowsAffected()
+			removed += n
+		}
+		res, err := tx.Exec(d.rebind(`DELETE FROM http_cache WHERE `+httpCacheMatch), owner, repo)
+		if err != nil {
+			return fmt.Errorf("failed to purge http_cache: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// Vacuum rebuilds the cache file, returning the space freed by purges and
+// re-indexing to the file system.
+func (d *DB) Vacuum() error {
+	_, err := d.exec(`VACUUM`)
+	return err
+}
+
+// IntegrityCheck runs SQLite's integrity check over the cache file and
+// returns the problems it finds, none for a healthy cache. PostgreSQL keeps
+// its own pages consistent, so there it only checks that the database
+// answers.
+func (d *DB) IntegrityCheck() ([]string, error) {
+	if d.dialect == dialectPostgres {
+		return nil, d.db.Ping()
+	}
+	rows, err := d.query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	return problems, rows.Err()
+}
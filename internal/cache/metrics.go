@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"database/sql"
+	"time"
+)
+
+// releaseMetrics stores what the metrics hooks measured for each release,
+// such as binary sizes or benchmark timings.
+func releaseMetrics(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS release_metrics (
+		owner TEXT,
+		repo TEXT,
+		tag_name TEXT,
+		hook TEXT,
+		name TEXT,
+		unit TEXT,
+		value DOUBLE PRECISION,
+		collected_at TEXT,
+		PRIMARY KEY (owner, repo, tag_name, hook, name, unit)
+	);
+	`)
+	return err
+}
+
+// Metric is a value a metrics hook measured for a release. Unit is free
+// text, such as B or ns/op, and may be empty.
+type Metric struct {
+	Hook        string    `json:"hook"`
+	Name        string    `json:"name"`
+	Unit        string    `json:"unit,omitempty"`
+	Value       float64   `json:"value"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// SaveMetrics stores what a hook measured for a release, replacing its
+// earlier run.
+func (d *DB) SaveMetrics(owner, repo, tag, hook string, metrics []Metric) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(d.rebind(`DELETE FROM release_metrics WHERE owner = ? AND repo = ? AND tag_name = ? AND hook = ?`), owner, repo, tag, hook); err != nil {
+			return err
+		}
+		for _, m := range metrics {
+			if _, err := tx.Exec(d.rebind(`
+				INSERT OR REPLACE INTO release_metrics (owner, repo, tag_name, hook, name, unit, value, collected_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			`), owner, repo, tag, hook, m.Name, m.Unit, m.Value, m.CollectedAt.UTC().Format(time.RFC3339)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetMetrics returns the metrics of a release, by hook and name.
+func (d *DB) GetMetrics(owner, repo, tag string) ([]Metric, error) {
+	rows, err := d.query(`
+		SELECT hook, name, unit, value, collected_at
+		FROM release_metrics
+		WHERE owner = ? AND repo = ? AND tag_name = ?
+		ORDER BY hook, name, unit
+	`, owner, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []Metric
+	for rows.Next() {
+		var m Metric
+		var collectedAt string
+		if err := rows.Scan(&m.Hook, &m.Name, &m.Unit, &m.Value, &collectedAt); err != nil {
+			return nil, err
+		}
+		m.CollectedAt, _ = time.Parse(time.RFC3339, collectedAt)
+		metrics = append(metrics, m)
+	}
+	return metrics, rows.Err()
+}
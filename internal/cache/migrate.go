@@ -0,0 +1,392 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Migration is one step of the cache schema. Migrations run in order, each
+// in its own transaction, and the versions applied so far are recorded in the
+// schema_version table. Never edit a released migration; append a new one.
+type Migration struct {
+	Version     int
+	Description string
+	up          func(tx *sql.Tx) error
+}
+
+var migrations = []Migration{
+	{1, "baseline schema", baselineSchema},
+	{2, "link commits to release pairs", linkPairCommits},
+	{3, "full-text search index", searchIndex},
+	{4, "index job history", indexJobs},
+	{5, "per-commit file changes", commitFiles},
+	{6, "CODEOWNERS files", codeowners},
+	{7, "rename sources", renameSources},
+	{8, "compressed patches", filePatches},
+	{9, "release assets", releaseAssets},
+	{10, "asset download history", assetDownloads},
+	{11, "closed issues", closedIssues},
+	{12, "milestones", milestones},
+	{13, "conventional commit types", commitTypes},
+	{14, "breaking change flags", breakingFlags},
+	{15, "commit parents", commitParents},
+	{16, "release tags", releaseTags},
+	{17, "release metrics", releaseMetrics},
+	{18, "compare cache data version", compareCacheVersion},
+	{19, "UTC dates", utcDates},
+}
+
+// migrations returns the migrations for the database's dialect.
+func (d *DB) migrations() []Migration {
+	if d.dialect == dialectPostgres {
+		return postgresMigrations
+	}
+	return migrations
+}
+
+// LatestSchemaVersion is the schema version this build of ordiff expects.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Open opens the cache without migrating it, for inspecting the schema
+// version. Use NewDB for everything else.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite3", sqliteDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping db: %w", err)
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		description TEXT,
+		applied_at TEXT
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_version: %w", err)
+	}
+
+	d := &DB{db: db}
+	d.startWriter()
+	return d, nil
+}
+
+// SchemaVersion returns the highest migration applied to the cache, 0 for a
+// cache that predates versioning or is empty.
+func (d *DB) SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := d.db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// PendingMigrations returns the migrations not applied yet, oldest first.
+func (d *DB) PendingMigrations() ([]Migration, error) {
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > LatestSchemaVersion() {
+		return nil, fmt.Errorf("cache schema version %d is newer than this ordiff supports (%d); upgrade ordiff", current, LatestSchemaVersion())
+	}
+
+	var pending []Migration
+	for _, m := range d.migrations() {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies every pending migration and returns the ones it applied.
+// It stops at the first failure; migrations applied before it stay applied.
+func (d *DB) Migrate() ([]Migration, error) {
+	pending, err := d.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		ok, err := d.apply(m)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// apply runs a migration unless another process applied it since the
+// pending ones were listed, as happens when two commands open a new cache
+// at once.
+func (d *DB) apply(m Migration) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var done int
+	if err := tx.QueryRow(d.rebind(`SELECT COUNT(*) FROM schema_version WHERE version = ?`), m.Version).Scan(&done); err != nil {
+		return false, err
+	}
+	if done > 0 {
+		return false, nil
+	}
+
+	if err := m.up(tx); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(d.rebind(`INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)`),
+		m.Version, m.Description, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// baselineSchema is the schema as it stood before versioning. Caches created
+// by older releases are brought up to it in place, so every statement must
+// tolerate the tables already existing.
+func baselineSchema(tx *sql.Tx) error {
+	// Caches created before multi-repository support keyed releases and
+	// commits globally; rebuild them with repository-scoped keys.
+	if err := ensurePrimaryKey(tx, "releases", []string{"owner", "repo", "tag_name"}); err != nil {
+		return err
+	}
+	if err := ensurePrimaryKey(tx, "commits", []string{"owner", "repo", "sha"}); err != nil {
+		return err
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS releases (
+		tag_name TEXT,
+		name TEXT,
+		published_at TEXT,
+		commit_sha TEXT,
+		body TEXT,
+		owner TEXT,
+		repo TEXT,
+		PRIMARY KEY (owner, repo, tag_name)
+	);
+
+	CREATE TABLE IF NOT EXISTS commits (
+		sha TEXT,
+		message TEXT,
+		author TEXT,
+		author_email TEXT,
+		date TEXT,
+		url TEXT,
+		owner TEXT,
+		repo TEXT,
+		pr_number INTEGER,
+		PRIMARY KEY (owner, repo, sha)
+	);
+
+	CREATE TABLE IF NOT EXISTS pull_requests (
+		number INTEGER,
+		title TEXT,
+		body TEXT,
+		state TEXT,
+		merged_at TEXT,
+		author TEXT,
+		url TEXT,
+		owner TEXT,
+		repo TEXT,
+		PRIMARY KEY (owner, repo, number)
+	);
+
+	CREATE TABLE IF NOT EXISTS file_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		filename TEXT,
+		additions INTEGER,
+		deletions INTEGER,
+		changes INTEGER,
+		status TEXT,
+		patch TEXT,
+		owner TEXT,
+		repo TEXT,
+		from_release TEXT,
+		to_release TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS compare_cache (
+		owner TEXT,
+		repo TEXT,
+		from_release TEXT,
+		to_release TEXT,
+		data TEXT,
+		created_at TEXT,
+		PRIMARY KEY (owner, repo, from_release, to_release)
+	);
+
+	CREATE TABLE IF NOT EXISTS release_changelogs (
+		owner TEXT,
+		repo TEXT,
+		tag_name TEXT,
+		markdown TEXT,
+		generated_at TEXT,
+		PRIMARY KEY (owner, repo, tag_name)
+	);
+
+	CREATE TABLE IF NOT EXISTS release_pairs (
+		owner TEXT,
+		repo TEXT,
+		from_release TEXT,
+		to_release TEXT,
+		ordiff_version TEXT,
+		data_version INTEGER,
+		indexed_at TEXT,
+		PRIMARY KEY (owner, repo, from_release, to_release)
+	);
+
+	CREATE TABLE IF NOT EXISTS repositories (
+		owner TEXT,
+		repo TEXT,
+		provider TEXT,
+		location TEXT,
+		indexed_at TEXT,
+		PRIMARY KEY (owner, repo)
+	);
+
+	CREATE TABLE IF NOT EXISTS index_runs (
+		owner TEXT,
+		repo TEXT,
+		started_at TEXT,
+		updated_at TEXT,
+		total_pairs INTEGER,
+		done_pairs INTEGER,
+		finished INTEGER,
+		PRIMARY KEY (owner, repo)
+	);
+
+	CREATE TABLE IF NOT EXISTS http_cache (
+		url TEXT PRIMARY KEY,
+		etag TEXT,
+		last_modified TEXT,
+		link TEXT,
+		body BLOB,
+		fetched_at TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_commits_owner_repo ON commits(owner, repo);
+	CREATE INDEX IF NOT EXISTS idx_prs_owner_repo ON pull_requests(owner, repo);
+	CREATE INDEX IF NOT EXISTS idx_files_release ON file_changes(owner, repo, from_release, to_release);
+	`
+
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	if err := ensureColumn(tx, "pull_requests", "labels", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "pull_requests", "merge_commit_sha", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "repositories", "location", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "releases", "ordiff_version", "TEXT"); err != nil {
+		return err
+	}
+	return ensureColumn(tx, "releases", "data_version", "INTEGER")
+}
+
+// linkPairCommits adds the commits of every release pair as the indexer
+// found them, replacing the date-based join for pairs indexed from data
+// version 2 on.
+func linkPairCommits(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS pair_commits (
+		owner TEXT,
+		repo TEXT,
+		from_release TEXT,
+		to_release TEXT,
+		sha TEXT,
+		PRIMARY KEY (owner, repo, from_release, to_release, sha)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_pair_commits_sha ON pair_commits(owner, repo, sha);
+	`)
+	return err
+}
+
+func ensurePrimaryKey(tx *sql.Tx, table string, key []string) error {
+	rows, err := tx.Query(`SELECT name, type, pk FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var columns, defs []string
+	pkCount := 0
+	for rows.Next() {
+		var name, typ string
+		var pk int
+		if err := rows.Scan(&name, &typ, &pk); err != nil {
+			return err
+		}
+		columns = append(columns, name)
+		defs = append(defs, name+" "+typ)
+		if pk > 0 {
+			pkCount++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if len(columns) == 0 || pkCount == len(key) {
+		return nil
+	}
+
+	cols := strings.Join(columns, ", ")
+	stmts := []string{
+		fmt.Sprintf("CREATE TABLE %s_rekey (%s, PRIMARY KEY (%s))", table, strings.Join(defs, ", "), strings.Join(key, ", ")),
+		fmt.Sprintf("INSERT OR REPLACE INTO %s_rekey (%s) SELECT %s FROM %s", table, cols, cols, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s_rekey RENAME TO %s", table, table),
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func ensureColumn(tx *sql.Tx, table, column, typ string) error {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, typ))
+	return err
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"database/sql"
+	"time"
+)
+
+// milestones stores the milestones that compare --milestone checked, with
+// the issues and pull requests assigned to them when they were fetched.
+func milestones(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS milestones (
+		owner TEXT,
+		repo TEXT,
+		number INTEGER,
+		title TEXT,
+		state TEXT,
+		due_on TEXT,
+		url TEXT,
+		fetched_at TEXT,
+		PRIMARY KEY (owner, repo, number)
+	);
+
+	CREATE TABLE IF NOT EXISTS milestone_items (
+		owner TEXT,
+		repo TEXT,
+		milestone INTEGER,
+		number INTEGER,
+		title TEXT,
+		pull_request INTEGER,
+		state TEXT,
+		state_reason TEXT,
+		closed_at TEXT,
+		merged_at TEXT,
+		url TEXT,
+		PRIMARY KEY (owner, repo, milestone, number)
+	);
+	`)
+	return err
+}
+
+// Milestone is a milestone and the issues and pull requests assigned to it.
+type Milestone struct {
+	Number    int             `json:"number"`
+	Title     string          `json:"title"`
+	State     string          `json:"state"`
+	DueOn     *time.Time      `json:"due_on,omitempty"`
+	URL       string          `json:"url"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Items     []MilestoneItem `json:"-"`
+	Owner     string          `json:"-"`
+	Repo      string          `json:"-"`
+}
+
+// MilestoneItem is an issue or pull request assigned to a milestone.
+// MergedAt is set for merged pull requests.
+type MilestoneItem struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	PullRequest bool       `json:"pull_request"`
+	State       string     `json:"state"`
+	StateReason string     `json:"state_reason,omitempty"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+	MergedAt    *time.Time `json:"merged_at,omitempty"`
+	URL         string     `json:"url"`
+}
+
+// SaveMilestone replaces a cached milestone and its items.
+func (d *DB) SaveMilestone(m *Milestone) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(d.rebind(`
+			INSERT OR REPLACE INTO milestones (owner, repo, number, title, state, due_on, url, fetched_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`), m.Owner, m.Repo, m.Number, m.Title, m.State, formatTime(m.DueOn), m.URL, m.FetchedAt.UTC().Format(time.RFC3339)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(d.rebind(`DELETE FROM milestone_items WHERE owner = ? AND repo = ? AND milestone = ?`), m.Owner, m.Repo, m.Number); err != nil {
+			return err
+		}
+		for _, i := range m.Items {
+			pr := 0
+			if i.PullRequest {
+				pr = 1
+			}
+			if _, err := tx.Exec(d.rebind(`
+				INSERT INTO milestone_items (owner, repo, milestone, number, title, pull_request, state, state_reason, closed_at, merged_at, url)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`), m.Owner, m.Repo, m.Number, i.Number, i.Title, pr, i.State, i.StateReason, formatTime(i.ClosedAt), formatTime(i.MergedAt), i.URL); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetMilestone returns a cached milestone by title with its items, or
+// sql.ErrNoRows if it was never fetched.
+func (d *DB) GetMilestone(owner, repo, title string) (*Milestone, error) {
+	m := Milestone{Owner: owner, Repo: repo}
+	var dueOn sql.NullString
+	var fetchedAt string
+	err := d.queryRow(`
+		SELECT number, title, state, due_on, url, fetched_at FROM milestones
+		WHERE owner = ? AND repo = ? AND title = ?
+	`, owner, repo, title).Scan(&m.Number, &m.Title, &m.State, &dueOn, &m.URL, &fetchedAt)
+	if err != nil {
+		return nil, err
+	}
+	m.DueOn = parseTime(dueOn)
+	m.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAt)
+
+	rows, err := d.query(`
+		SELECT number, title, pull_request, state, state_reason, closed_at, merged_at, url
+		FROM milestone_items
+		WHERE owner = ? AND repo = ? AND milestone = ?
+		ORDER BY number
+	`, owner, repo, m.Number)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i MilestoneItem
+		var closedAt, mergedAt sql.NullString
+		if err := rows.Scan(&i.Number, &i.Title, &i.PullRequest, &i.State, &i.StateReason, &closedAt, &mergedAt, &i.URL); err != nil {
+			return nil, err
+		}
+		i.ClosedAt = parseTime(closedAt)
+		i.MergedAt = parseTime(mergedAt)
+		m.Items = append(m.Items, i)
+	}
+	return &m, rows.Err()
+}
+
+// formatTime stores an optional time as RFC 3339, NULL when unset.
+func formatTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseTime(s sql.NullString) *time.Time {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"ordiff/internal/conventional"
+	"ordiff/internal/version"
+)
+
+// Statements shared by the single-row savers and SavePair.
+const (
+	insertCommit = `
+		INSERT OR REPLACE INTO commits (sha, message, author, author_email, date, url, owner, repo, pr_number, commit_type, commit_scope, breaking, deprecated, parents)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertPullRequest = `
+		INSERT OR REPLACE INTO pull_requests (number, title, body, state, merged_at, author, url, owner, repo, labels, merge_commit_sha, breaking, deprecated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertFileChange = `
+		INSERT INTO file_changes (filename, previous_filename, additions, deletions, changes, status, patch, owner, repo, from_release, to_release)
+		VALUES (?, ?, ?, ?, ?, ?, '', ?, ?, ?, ?)
+		RETURNING id`
+	insertFilePatch = `
+		INSERT INTO file_patches (file_change_id, owner, repo, patch) VALUES (?, ?, ?, ?)`
+	deletePairCommits = `
+		DELETE FROM pair_commits
+		WHERE owner = ? AND repo = ? AND from_release = ? AND to_release = ?`
+	insertPairCommit = `
+		INSERT OR IGNORE INTO pair_commits (owner, repo, from_release, to_release, sha)
+		VALUES (?, ?, ?, ?, ?)`
+	stampReleasePair = `
+		INSERT OR REPLACE INTO release_pairs (owner, repo, from_release, to_release, ordiff_version, data_version, indexed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+)
+
+func commitArgs(c *Commit) []interface{} {
+	var prNum interface{}
+	if c.PrNumber != nil {
+		prNum = *c.PrNumber
+	}
+	var parents interface{}
+	if c.Parents != nil {
+		parents = strings.Join(c.Parents, " ")
+	}
+	m := conventional.Parse(c.Message)
+	return []interface{}{c.SHA, c.Message, c.Author, c.AuthorEmail, c.Date.UTC().Format(time.RFC3339), c.URL, c.Owner, c.Repo, prNum, m.Type, m.Scope,
+		flag(conventional.Breaking(c.Message)), flag(conventional.Deprecation(c.Message)), parents}
+}
+
+func pullRequestArgs(pr *PullRequest) ([]interface{}, error) {
+	var mergedAt interface{}
+	if pr.MergedAt != nil {
+		mergedAt = pr.MergedAt.UTC().Format(time.RFC3339)
+	}
+	labels, err := json.Marshal(pr.Labels)
+	if err != nil {
+		return nil, err
+	}
+	text := pr.Title + "\n" + pr.Body
+	return []interface{}{pr.Number, pr.Title, pr.Body, pr.State, mergedAt, pr.Author, pr.URL, pr.Owner, pr.Repo, string(labels), pr.MergeCommitSHA,
+		flag(conventional.Breaking(text)), flag(conventional.Deprecation(text))}, nil
+}
+
+func fileChangeArgs(fc *FileChange) []interface{} {
+	return []interface{}{fc.Filename, fc.PreviousFilename, fc.Additions, fc.Deletions, fc.Changes, fc.Status, fc.Owner, fc.Repo, fc.FromRelease, fc.ToRelease}
+}
+
+func stampArgs(owner, repo, fromRelease, toRelease string) []interface{} {
+	return []interface{}{owner, repo, fromRelease, toRelease, version.Version, version.DataVersion, time.Now().UTC().Format(time.RFC3339)}
+}
+
+// PairData is what an index fetched for one release pair.
+type PairData struct {
+	Owner, Repo            string
+	FromRelease, ToRelease string
+	Commits                []*Commit
+	PullRequests           []*PullRequest
+	Files                  []*FileChange
+}
+
+// SavePair stores the commits, pull requests and file changes of a release
+// pair, links its commits and stamps it, in one transaction with prepared
+// statements instead of committing every row on its own. Nothing is saved
+// if any row fails, so the pair is fetched again by the next index.
+func (d *DB) SavePair(p *PairData) error {
+	return d.SavePairContext(context.Background(), p)
+}
+
+// SavePairContext is SavePair, rolling the pair back when ctx is cancelled.
+func (d *DB) SavePairContext(ctx context.Context, p *PairData) error {
+	return d.inTxContext(ctx, func(tx *sql.Tx) error {
+		var stmts []*sql.Stmt
+		defer func() {
+			for _, s := range stmts {
+				s.Close()
+			}
+		}()
+		prepare := func(query string) (*sql.Stmt, error) {
+			s, err := tx.PrepareContext(ctx, d.rebind(query))
+			if err == nil {
+				stmts = append(stmts, s)
+			}
+			return s, err
+		}
+
+		commit, err := prepare(insertCommit)
+		if err != nil {
+			return err
+		}
+		for _, c := range p.Commits {
+			if _, err := commit.ExecContext(ctx, commitArgs(c)...); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, d.rebind(deletePairCommits), p.Owner, p.Repo, p.FromRelease, p.ToRelease); err != nil {
+			return err
+		}
+		link, err := prepare(insertPairCommit)
+		if err != nil {
+			return err
+		}
+		for _, c := range p.Commits {
+			if _, err := link.ExecContext(ctx, p.Owner, p.Repo, p.FromRelease, p.ToRelease, c.SHA); err != nil {
+				return err
+			}
+		}
+
+		pull, err := prepare(insertPullRequest)
+		if err != nil {
+			return err
+		}
+		for _, pr := range p.PullRequests {
+			args, err := pullRequestArgs(pr)
+			if err != nil {
+				return err
+			}
+			if _, err := pull.ExecContext(ctx, args...); err != nil {
+				return err
+			}
+		}
+
+		file, err := prepare(insertFileChange)
+		if err != nil {
+			return err
+		}
+		patch, err := prepare(insertFilePatch)
+		if err != nil {
+			return err
+		}
+		for _, fc := range p.Files {
+			var id int64
+			if err := file.QueryRowContext(ctx, fileChangeArgs(fc)...).Scan(&id); err != nil {
+				return err
+			}
+			if fc.Patch == "" {
+				continue
+			}
+			if _, err := patch.ExecContext(ctx, id, fc.Owner, fc.Repo, compressPatch(fc.Patch)); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, d.rebind(stampReleasePair), stampArgs(p.Owner, p.Repo, p.FromRelease, p.ToRelease)...); err != nil {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Patches dominate the size of the cache, so they are kept zstd-compressed
+// in a table of their own and only read when a diff is asked for.
+var (
+	patchEncoder, _ = zstd.NewWriter(nil)
+	patchDecoder, _ = zstd.NewReader(nil)
+)
+
+func compressPatch(patch string) []byte {
+	return patchEncoder.EncodeAll([]byte(patch), nil)
+}
+
+func decompressPatch(data []byte) (string, error) {
+	b, err := patchDecoder.DecodeAll(data, nil)
+	return string(b), err
+}
+
+// filePatches moves the patches of file_changes into compressed blobs keyed
+// by the file change. Cached comparisons embed patches, so they are dropped
+// and rebuilt on demand.
+func filePatches(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS file_patches (
+		file_change_id INTEGER PRIMARY KEY,
+		owner TEXT,
+		repo TEXT,
+		patch BLOB
+	);
+	`); err != nil {
+		return err
+	}
+	return movePatches(tx,
+		`SELECT id, owner, repo, patch FROM file_changes WHERE patch != '' AND id > ? ORDER BY id LIMIT 500`,
+		`INSERT INTO file_patches (file_change_id, owner, repo, patch) VALUES (?, ?, ?, ?)`)
+}
+
+func postgresFilePatches(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS file_patches (
+		file_change_id BIGINT PRIMARY KEY,
+		owner TEXT,
+		repo TEXT,
+		patch BYTEA
+	);
+	`); err != nil {
+		return err
+	}
+	return movePatches(tx,
+		`SELECT id, owner, repo, patch FROM file_changes WHERE patch != '' AND id > $1 ORDER BY id LIMIT 500`,
+		`INSERT INTO file_patches (file_change_id, owner, repo, patch) VALUES ($1, $2, $3, $4)`)
+}
+
+// movePatches compresses the patches of file_changes in batches, so neither
+// the whole table nor an open cursor is held while inserting.
+func movePatches(tx *sql.Tx, selectBatch, insert string) error {
+	type row struct {
+		id          int64
+		owner, repo string
+		patch       string
+	}
+
+	var last int64
+	for {
+		rows, err := tx.Query(selectBatch, last)
+		if err != nil {
+			return err
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.owner, &r.repo, &r.patch); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, r := range batch {
+			if _, err := tx.Exec(insert, r.id, r.owner, r.repo, compressPatch(r.patch)); err != nil {
+				return err
+			}
+		}
+		last = batch[len(batch)-1].id
+	}
+
+	_, err := tx.Exec(`
+	UPDATE file_changes SET patch = '' WHERE patch != '';
+	DELETE FROM compare_cache;
+	`)
+	return err
+}
+
+// SaveFileChange caches the change to one file in a release pair, with its
+// patch compressed separately.
+func (d *DB) SaveFileChange(fc *FileChange) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		var id int64
+		if err := tx.QueryRow(d.rebind(insertFileChange), fileChangeArgs(fc)...).Scan(&id); err != nil {
+			return err
+		}
+		if fc.Patch != "" {
+			if _, err := tx.Exec(d.rebind(insertFilePatch), id, fc.Owner, fc.Repo, compressPatch(fc.Patch)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetPatches returns the patches of a release pair by filename, of the given
+// files or of all of them when filenames is nil. Files without a stored
+// patch, such as binaries or those indexed with --no-patches, are missing.
+func (d *DB) GetPatches(owner, repo, fromTag, toTag string, filenames []string) (map[string]string, error) {
+	return d.GetPatchesContext(context.Background(), owner, repo, fromTag, toTag, filenames)
+}
+
+// GetPatchesContext is GetPatches, stopping when ctx is cancelled.
+func (d *DB) GetPatchesContext(ctx context.Context, owner, repo, fromTag, toTag string, filenames []string) (map[string]string, error) {
+	if filenames != nil && len(filenames) == 0 {
+		return map[string]string{}, nil
+	}
+
+	query := `
+		SELECT f.filename, p.patch
+		FROM file_changes f
+		JOIN file_patches p ON p.file_change_id = f.id
+		WHERE f.owner = ? AND f.repo = ? AND f.from_release = ? AND f.to_release = ?`
+	args := []interface{}{owner, repo, fromTag, toTag}
+	if filenames != nil {
+		query += ` AND f.filename IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(filenames)), ", ") + `)`
+		for _, name := range filenames {
+			args = append(args, name)
+		}
+	}
+
+	rows, err := d.queryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	patches := map[string]string{}
+	for rows.Next() {
+		var name string
+		var data []byte
+		if err := rows.Scan(&name, &data); err != nil {
+			return nil, err
+		}
+		if patches[name], err = decompressPatch(data); err != nil {
+			return nil, err
+		}
+	}
+	return patches, rows.Err()
+}
+
+// WithoutPatches wraps a store so that file changes are saved without their
+// patches, except for the files keep matches, for indexing with --no-patches.
+func WithoutPatches(s Store, keep func(fc *FileChange) bool) Store {
+	return patchlessStore{s, keep}
+}
+
+type patchlessStore struct {
+	Store
+	keep func(fc *FileChange) bool
+}
+
+func (s patchlessStore) SaveFileChange(fc *FileChange) error {
+	return s.Store.SaveFileChange(s.strip(fc))
+}
+
+func (s patchlessStore) SavePair(p *PairData) error {
+	return s.Store.SavePair(s.stripPair(p))
+}
+
+func (s patchlessStore) SavePairContext(ctx context.Context, p *PairData) error {
+	return s.Store.SavePairContext(ctx, s.stripPair(p))
+}
+
+func (s patchlessStore) stripPair(p *PairData) *PairData {
+	stripped := *p
+	stripped.Files = make([]*FileChange, len(p.Files))
+	for i, fc := range p.Files {
+		stripped.Files[i] = s.strip(fc)
+	}
+	return &stripped
+}
+
+func (s patchlessStore) strip(fc *FileChange) *FileChange {
+	if s.keep != nil && s.keep(fc) {
+		return fc
+	}
+	c := *fc
+	c.Patch = ""
+	return &c
+}
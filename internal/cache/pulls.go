@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// GetPullRequestCommits returns the cached commits of a pull request, oldest
+// first: those whose message references it, and its merge commit.
+func (d *DB) GetPullRequestCommits(owner, repo string, number int, mergeCommitSHA string) ([]Commit, error) {
+	rows, err := d.query(`
+		SELECT sha, message, author, author_email, date, url, pr_number, breaking, deprecated
+		FROM commits
+		WHERE owner = ? AND repo = ? AND (pr_number = ? OR sha = ?)
+		ORDER BY date ASC
+	`, owner, repo, number, mergeCommitSHA)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []Commit
+	for rows.Next() {
+		c := Commit{Owner: owner, Repo: repo}
+		var date string
+		if err := rows.Scan(&c.SHA, &c.Message, &c.Author, &c.AuthorEmail, &date, &c.URL, &c.PrNumber, &c.Breaking, &c.Deprecated); err != nil {
+			return nil, err
+		}
+		c.Author, c.AuthorEmail = d.mailmap.Resolve(c.Author, c.AuthorEmail)
+		c.Date, _ = time.Parse(time.RFC3339, date)
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
+
+// ReleasesContaining returns the releases whose indexed pair contains any of
+// the commits, oldest first. A change backported to several release lines
+// ships in one release of each.
+func (d *DB) ReleasesContaining(owner, repo string, shas []string) ([]string, error) {
+	if len(shas) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(shas)), ", ")
+	args := []interface{}{owner, repo}
+	for _, sha := range shas {
+		args = append(args, sha)
+	}
+
+	rows, err := d.query(`
+		SELECT r.tag_name FROM releases r
+		WHERE r.owner = ? AND r.repo = ? AND r.tag_name IN (
+			SELECT l.to_release FROM pair_commits l
+			WHERE l.owner = r.owner AND l.repo = r.repo AND l.sha IN (`+placeholders+`)
+		)
+		ORDER BY r.published_at ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
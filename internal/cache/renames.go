@@ -0,0 +1,89 @@
+package cache
+
+import "database/sql"
+
+// renameSources records the path a renamed file had before, so its history
+// can be followed across moves.
+func renameSources(tx *sql.Tx) error {
+	for _, table := range []string{"file_changes", "commit_files"} {
+		if err := ensureColumn(tx, table, "previous_filename", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func postgresRenameSources(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	ALTER TABLE file_changes ADD COLUMN IF NOT EXISTS previous_filename TEXT NOT NULL DEFAULT '';
+	ALTER TABLE commit_files ADD COLUMN IF NOT EXISTS previous_filename TEXT NOT NULL DEFAULT '';
+	`)
+	return err
+}
+
+// maxRenames bounds how many moves GetFileHistory follows.
+const maxRenames = 100
+
+// GetFileHistory returns every cached change to filename, one per release
+// pair, oldest pair first. Renames are followed: the changes made under a
+// file's previous paths before it was moved are included.
+func (d *DB) GetFileHistory(owner, repo, filename string) ([]FileChange, error) {
+	var history []FileChange
+	name, before := filename, ""
+	for i := 0; i < maxRenames; i++ {
+		changes, published, err := d.fileHistory(owner, repo, name, before)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only the changes since the file last moved here belong to it.
+		moved := -1
+		for j := len(changes) - 1; j >= 0; j-- {
+			if changes[j].Status == "renamed" && changes[j].PreviousFilename != "" && changes[j].PreviousFilename != name {
+				moved = j
+				break
+			}
+		}
+		if moved == -1 {
+			return append(changes, history...), nil
+		}
+		history = append(changes[moved:], history...)
+		name, before = changes[moved].PreviousFilename, published[moved]
+	}
+	return history, nil
+}
+
+// fileHistory lists the changes to one path, with the publish date of each
+// pair's newer release, optionally only those published before a date.
+func (d *DB) fileHistory(owner, repo, filename, before string) ([]FileChange, []string, error) {
+	query := `
+		SELECT f.filename, f.previous_filename, f.additions, f.deletions, f.changes, f.status, f.from_release, f.to_release, r.published_at
+		FROM file_changes f
+		JOIN releases r ON r.owner = f.owner AND r.repo = f.repo AND r.tag_name = f.to_release
+		WHERE f.owner = ? AND f.repo = ? AND f.filename = ?`
+	args := []interface{}{owner, repo, filename}
+	if before != "" {
+		query += ` AND r.published_at < ?`
+		args = append(args, before)
+	}
+	rows, err := d.query(query+`
+		ORDER BY r.published_at ASC
+	`, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var changes []FileChange
+	var published []string
+	for rows.Next() {
+		fc := FileChange{Owner: owner, Repo: repo}
+		var p string
+		if err := rows.Scan(&fc.Filename, &fc.PreviousFilename, &fc.Additions, &fc.Deletions, &fc.Changes, &fc.Status, &fc.FromRelease, &fc.ToRelease, &p); err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, fc)
+		published = append(published, p)
+	}
+	return changes, published, rows.Err()
+}
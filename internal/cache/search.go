@@ -0,0 +1,314 @@
+package cache
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of search hits.
+const (
+	HitCommit      = "commit"
+	HitPullRequest = "pr"
+	HitRelease     = "release"
+)
+
+// SearchHit is one match of SearchRepo. From and To are the releases the hit
+// falls between: the release pair a commit or PR landed in, or the release
+// before a release and the release itself. From is empty before the first
+// release and To after the last one.
+type SearchHit struct {
+	Kind    string    `json:"kind"`
+	Ref     string    `json:"ref"`
+	Title   string    `json:"title"`
+	Snippet string    `json:"snippet"`
+	URL     string    `json:"url,omitempty"`
+	Date    time.Time `json:"date"`
+	From    string    `json:"from_release,omitempty"`
+	To      string    `json:"to_release,omitempty"`
+	Score   float64   `json:"score"`
+}
+
+// searchIndex creates the full-text index over commit messages, PR titles
+// and bodies and release notes. FTS5 needs go-sqlite3 built with the
+// sqlite_fts5 tag; other builds get FTS4, which ranks hits in Go instead.
+func searchIndex(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+		owner UNINDEXED, repo UNINDEXED, kind UNINDEXED, ref UNINDEXED, date UNINDEXED, url UNINDEXED,
+		title, body
+	)`)
+	if err != nil && strings.Contains(err.Error(), "no such module") {
+		_, err = tx.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts4(
+			owner, repo, kind, ref, date, url, title, body,
+			notindexed=owner, notindexed=repo, notindexed=kind, notindexed=ref, notindexed=date, notindexed=url
+		)`)
+	}
+	if err != nil {
+		return err
+	}
+
+	// search_state records what the index was built from, so it is rebuilt
+	// only after the cached data changes.
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS search_state (
+		owner TEXT,
+		repo TEXT,
+		fingerprint TEXT,
+		PRIMARY KEY (owner, repo)
+	)`)
+	return err
+}
+
+// searchFingerprint changes whenever commits, PRs or releases of a repository
+// are added or replaced: INSERT OR REPLACE always moves a row to a new rowid,
+// and every PostgreSQL update gives the row a new xmin.
+func (d *DB) searchFingerprint(owner, repo string) (string, error) {
+	rowid := "rowid"
+	if d.dialect == dialectPostgres {
+		rowid = "xmin::text::bigint"
+	}
+	var parts []string
+	for _, table := range []string{"commits", "pull_requests", "releases"} {
+		var n, last int64
+		if err := d.queryRow(`SELECT COUNT(*), COALESCE(MAX(`+rowid+`), 0) FROM `+table+` WHERE owner = ? AND repo = ?`,
+			owner, repo).Scan(&n, &last); err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%d:%d", n, last))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// RefreshSearchIndex rebuilds the search index of a repository if its cached
+// data changed since the last build.
+func (d *DB) RefreshSearchIndex(owner, repo string) error {
+	fingerprint, err := d.searchFingerprint(owner, repo)
+	if err != nil {
+		return err
+	}
+	var built string
+	err = d.queryRow(`SELECT fingerprint FROM search_state WHERE owner = ? AND repo = ?`, owner, repo).Scan(&built)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if built == fingerprint {
+		return nil
+	}
+
+	return d.inTx(func(tx *sql.Tx) error {
+		statements := []string{
+			`DELETE FROM search_index WHERE owner = ? AND repo = ?`,
+			// Commits: the subject is the title, the rest of the message the body.
+			`INSERT INTO search_index (owner, repo, kind, ref, date, url, title, body)
+			SELECT owner, repo, 'commit', sha, date, COALESCE(url, ''),
+				CASE WHEN instr(message, char(10)) > 0 THEN substr(message, 1, instr(message, char(10)) - 1) ELSE message END,
+				CASE WHEN instr(message, char(10)) > 0 THEN substr(message, instr(message, char(10)) + 1) ELSE '' END
+			FROM commits WHERE owner = ? AND repo = ?`,
+			`INSERT INTO search_index (owner, repo, kind, ref, date, url, title, body)
+			SELECT owner, repo, 'pr', CAST(number AS TEXT), merged_at, COALESCE(url, ''), COALESCE(title, ''), COALESCE(body, '')
+			FROM pull_requests WHERE owner = ? AND repo = ?`,
+			`INSERT INTO search_index (owner, repo, kind, ref, date, url, title, body)
+			SELECT owner, repo, 'release', tag_name, published_at, '', COALESCE(NULLIF(name, ''), tag_name), COALESCE(body, '')
+			FROM releases WHERE owner = ? AND repo = ?`,
+		}
+		if d.dialect == dialectPostgres {
+			statements = postgresSearchIndex
+		}
+		for _, stmt := range statements {
+			if _, err := tx.Exec(d.rebind(stmt), owner, repo); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(d.rebind(`INSERT OR REPLACE INTO search_state (owner, repo, fingerprint) VALUES (?, ?, ?)`),
+			owner, repo, fingerprint); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// searchQuery turns free text into a query both FTS versions accept: every
+// word must appear, and words are quoted so punctuation is not parsed as
+// query syntax. A trailing * keeps its prefix meaning.
+func searchQuery(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.Trim(strings.ReplaceAll(word, `"`, ""), "*")
+		if word == "" {
+			continue
+		}
+		term := `"` + word + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+func (d *DB) searchUsesFTS5() (bool, error) {
+	var ddl string
+	if err := d.queryRow(`SELECT sql FROM sqlite_master WHERE name = 'search_index'`).Scan(&ddl); err != nil {
+		return false, err
+	}
+	return strings.Contains(strings.ToLower(ddl), "using fts5"), nil
+}
+
+// SearchRepo returns the commits, PRs and releases of a repository matching
+// every word of text, best match first. Titles weigh twice as much as
+// bodies. The index is refreshed first if the cache changed.
+func (d *DB) SearchRepo(owner, repo, text string, limit int) ([]SearchHit, error) {
+	query := searchQuery(text)
+	if query == "" {
+		return nil, fmt.Errorf("empty search query")
+	}
+	if err := d.RefreshSearchIndex(owner, repo); err != nil {
+		return nil, fmt.Errorf("failed to refresh search index: %w", err)
+	}
+	if d.dialect == dialectPostgres {
+		hits, err := d.searchPostgres(owner, repo, text, limit)
+		if err != nil {
+			return nil, err
+		}
+		return hits, d.placeHits(owner, repo, hits)
+	}
+	fts5, err := d.searchUsesFTS5()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows *sql.Rows
+	if fts5 {
+		rows, err = d.query(`
+			SELECT kind, ref, date, url, title,
+				snippet(search_index, -1, '[', ']', '…', 16),
+				-bm25(search_index, 0, 0, 0, 0, 0, 0, 2.0, 1.0)
+			FROM search_index
+			WHERE search_index MATCH ? AND owner = ? AND repo = ?
+			ORDER BY 7 DESC
+			LIMIT ?
+		`, query, owner, repo, limit)
+	} else {
+		rows, err = d.query(`
+			SELECT kind, ref, date, url, title,
+				snippet(search_index, '[', ']', '…', -1, 16),
+				matchinfo(search_index, 'pcnx')
+			FROM search_index
+			WHERE search_index MATCH ? AND owner = ? AND repo = ?
+		`, query, owner, repo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var h SearchHit
+		var date sql.NullString
+		var rank interface{}
+		if err := rows.Scan(&h.Kind, &h.Ref, &date, &h.URL, &h.Title, &h.Snippet, &rank); err != nil {
+			return nil, err
+		}
+		h.Date, _ = time.Parse(time.RFC3339, date.String)
+		switch r := rank.(type) {
+		case float64:
+			h.Score = r
+		case []byte:
+			h.Score = matchinfoScore(r)
+		}
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !fts5 {
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+		if len(hits) > limit {
+			hits = hits[:limit]
+		}
+	}
+	return hits, d.placeHits(owner, repo, hits)
+}
+
+// matchinfoScore ranks an FTS4 row from matchinfo 'pcnx' like bm25 without
+// length normalization: the hits of each phrase in the title and body,
+// weighted by how rare the phrase is across the index.
+func matchinfoScore(info []byte) float64 {
+	at := func(i int) float64 {
+		return float64(binary.NativeEndian.Uint32(info[i*4:]))
+	}
+	if len(info) < 12 {
+		return 0
+	}
+	phrases, columns, rows := int(at(0)), int(at(1)), at(2)
+	weights := map[int]float64{6: 2.0, 7: 1.0} // title, body
+
+	var score float64
+	for p := 0; p < phrases; p++ {
+		for c, weight := range weights {
+			if c >= columns {
+				continue
+			}
+			i := 3 + 3*(p*columns+c)
+			if (i+3)*4 > len(info) {
+				continue
+			}
+			hitsHere, docs := at(i), at(i+2)
+			if hitsHere == 0 {
+				continue
+			}
+			idf := math.Log((rows-docs+0.5)/(docs+0.5) + 1)
+			score += weight * idf * hitsHere / (hitsHere + 1.2)
+		}
+	}
+	return score
+}
+
+// placeHits fills in the release range of each hit: for commits the release
+// pair they were indexed under, otherwise the releases around the hit's date.
+func (d *DB) placeHits(owner, repo string, hits []SearchHit) error {
+	releases, err := d.GetReleases(owner, repo)
+	if err != nil {
+		return err
+	}
+	// GetReleases is newest first; walk oldest first.
+	sort.SliceStable(releases, func(i, j int) bool { return releases[i].PublishedAt.Before(releases[j].PublishedAt) })
+
+	for i := range hits {
+		h := &hits[i]
+		if h.Kind == HitCommit {
+			err := d.queryRow(`
+				SELECT l.from_release, l.to_release FROM pair_commits l
+				JOIN releases r ON r.owner = l.owner AND r.repo = l.repo AND r.tag_name = l.to_release
+				WHERE l.owner = ? AND l.repo = ? AND l.sha = ?
+				ORDER BY r.published_at ASC LIMIT 1
+			`, owner, repo, h.Ref).Scan(&h.From, &h.To)
+			if err == nil {
+				continue
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		for j, r := range releases {
+			if h.Kind == HitRelease && r.TagName == h.Ref || h.Kind != HitRelease && !r.PublishedAt.Before(h.Date) {
+				h.To = r.TagName
+				if j > 0 {
+					h.From = releases[j-1].TagName
+				}
+				break
+			}
+		}
+		if h.To == "" && len(releases) > 0 {
+			h.From = releases[len(releases)-1].TagName
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ordiff/internal/mailmap"
+)
+
+// Store is everything ordiff keeps between runs. *DB, the SQLite cache, is
+// the default implementation; other backends register themselves with
+// RegisterBackend and are selected with storage.backend in the config.
+type Store interface {
+	Close() error
+
+	// Releases, commits, pull requests and file changes.
+	SaveRelease(r *Release) error
+	GetReleases(owner, repo string) ([]Release, error)
+	GetRelease(owner, repo, tag string) (*Release, error)
+	SaveReleaseAssets(r *Release) error
+	GetReleaseAssets(owner, repo, tag string) ([]ReleaseAsset, error)
+	SaveTags(owner, repo string, tags []Tag) error
+	GetTags(owner, repo string) (map[string]*Tag, error)
+	SaveMetrics(owner, repo, tag, hook string, metrics []Metric) error
+	GetMetrics(owner, repo, tag string) ([]Metric, error)
+	GetDownloadHistory(owner, repo, tag string) ([]DownloadSnapshot, error)
+	SaveCommit(c *Commit) error
+	GetCommits(owner, repo string) ([]Commit, error)
+	GetCommit(owner, repo, sha string) (*Commit, error)
+	FirstPairContaining(owner, repo string, shas []string) (from, to, sha string, err error)
+	SaveCommitFiles(owner, repo, sha string, files []FileChange) error
+	GetCommitFiles(owner, repo, sha string) ([]FileChange, error)
+	GetCommitsBetween(owner, repo, fromTag, toTag string) ([]Commit, error)
+	SearchCommitsBetween(owner, repo, fromTag, toTag string, q CommitQuery) ([]Commit, error)
+	CommitRanges(owner, repo string, pairs [][2]string) ([]*Range, error)
+	SearchCommitsIn(r *Range, q CommitQuery) ([]Commit, error)
+	PrCountIn(r *Range) (int, error)
+	LinkPairCommits(owner, repo, fromRelease, toRelease string, commits []*Commit) error
+	SavePullRequest(pr *PullRequest) error
+	GetPullRequests(owner, repo string, numbers []int) ([]PullRequest, error)
+	GetPullRequestCommits(owner, repo string, number int, mergeCommitSHA string) ([]Commit, error)
+	ReleasesContaining(owner, repo string, shas []string) ([]string, error)
+	SaveCodeowners(owner, repo, path, content string) error
+	GetCodeowners(owner, repo string) (*Codeowners, error)
+	LatestPullRequestMerge(owner, repo string) (time.Time, error)
+	SaveIssue(i *Issue) error
+	GetIssues(owner, repo string, numbers []int) ([]Issue, error)
+	LatestIssueClose(owner, repo string) (time.Time, error)
+	SaveMilestone(m *Milestone) error
+	GetMilestone(owner, repo, title string) (*Milestone, error)
+	PrCountBetween(owner, repo, fromTag, toTag string) (int, error)
+	MergeBase(owner, repo, fromTag, toTag string) (string, error)
+	SaveFileChange(fc *FileChange) error
+	SavePair(p *PairData) error
+	GetFileChanges(owner, repo, fromTag, toTag string) ([]FileChange, error)
+	GetPatches(owner, repo, fromTag, toTag string, filenames []string) (map[string]string, error)
+	GetAllFileChanges(owner, repo string, withPatch bool) ([]FileChange, error)
+	GetFileHistory(owner, repo, filename string) ([]FileChange, error)
+	HasFileChangesCached(owner, repo, fromRelease, toRelease string) (bool, error)
+	DeleteFileChanges(owner, repo, fromRelease, toRelease string) error
+	GetPairChurn(owner, repo string) ([]PairChurn, error)
+
+	// Release pairs and the data version they were indexed with.
+	GetReleasePairCount(owner, repo string) (int, error)
+	StampReleasePair(owner, repo, fromRelease, toRelease string) error
+	StaleDataBefore(owner, repo string, minVersion int) (StaleData, error)
+	PairDataVersion(owner, repo, fromRelease, toRelease string) (int, error)
+	IsPairStale(owner, repo, fromRelease, toRelease string) bool
+
+	// Variants of the index write path and the compare read path that stop
+	// when ctx is cancelled. The plain methods use context.Background().
+	SavePairContext(ctx context.Context, p *PairData) error
+	GetReleaseContext(ctx context.Context, owner, repo, tag string) (*Release, error)
+	CommitRangesContext(ctx context.Context, owner, repo string, pairs [][2]string) ([]*Range, error)
+	SearchCommitsInContext(ctx context.Context, r *Range, q CommitQuery) ([]Commit, error)
+	PrCountInContext(ctx context.Context, r *Range) (int, error)
+	GetFileChangesContext(ctx context.Context, owner, repo, fromTag, toTag string) ([]FileChange, error)
+	GetPatchesContext(ctx context.Context, owner, repo, fromTag, toTag string, filenames []string) (map[string]string, error)
+	GetCompareCacheContext(ctx context.Context, owner, repo, fromRelease, toRelease string) (string, bool, error)
+
+	// Precomputed comparisons and changelogs.
+	SaveCompareCache(owner, repo, fromRelease, toRelease, data string) error
+	GetCompareCache(owner, repo, fromRelease, toRelease string) (string, bool, error)
+	ClearCompareCache(owner, repo string) error
+	SaveChangelog(owner, repo, tag, markdown string) error
+	GetChangelog(owner, repo, tag string) (string, bool, error)
+
+	// Repositories and indexing progress.
+	SaveRepository(owner, repo, provider, location string) error
+	GetRepositoryProvider(owner, repo string) (string, string, error)
+	GetRepositories() ([]Repository, error)
+	StartIndexRun(owner, repo string, totalPairs int) error
+	AdvanceIndexRun(owner, repo string) error
+	FinishIndexRun(owner, repo string) error
+	GetIndexRun(owner, repo string) (*IndexRun, error)
+	StartIndexJob(j *IndexJob) error
+	EndIndexJob(id int64, state, errMsg string) error
+	GetIndexJobs(owner, repo string, limit int) ([]IndexJob, error)
+
+	// Conditional-request cache of API responses.
+	SaveHTTPResponse(r *HTTPResponse) error
+	GetHTTPResponse(url string) (*HTTPResponse, error)
+
+	// Author identities.
+	SetMailmap(m *mailmap.Mailmap)
+	ResolveAuthors(commits []Commit)
+
+	// Full-text search.
+	RefreshSearchIndex(owner, repo string) error
+	SearchRepo(owner, repo, text string, limit int) ([]SearchHit, error)
+
+	// Maintenance.
+	Stats() (*Stats, error)
+	Purge(owner, repo string) (int64, error)
+	Vacuum() error
+}
+
+var _ Store = (*DB)(nil)
+
+// Built-in backends.
+const (
+	BackendSQLite = "sqlite"
+	BackendMemory = "memory"
+)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]func(dsn string) (Store, error){
+		BackendSQLite: func(dsn string) (Store, error) { return NewDB(dsn) },
+		BackendMemory: func(string) (Store, error) { return NewMemoryDB() },
+	}
+)
+
+// RegisterBackend makes a storage backend available to OpenStore. open gets
+// the storage.dsn from the config, or the cache path for sqlite.
+func RegisterBackend(name string, open func(dsn string) (Store, error)) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[name] = open
+}
+
+// OpenStore opens a store with a registered backend. An empty name is sqlite.
+func OpenStore(backend, dsn string) (Store, error) {
+	if backend == "" {
+		backend = BackendSQLite
+	}
+
+	backendsMu.RLock()
+	open, ok := backends[backend]
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	backendsMu.RUnlock()
+
+	if !ok {
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown storage backend %q (available: %s)", backend, strings.Join(names, ", "))
+	}
+	return open(dsn)
+}
+
+var memoryDBs atomic.Int64
+
+// NewMemoryDB returns an empty cache that lives in memory until closed, for
+// tests and throwaway servers. It is the SQLite cache with nothing on disk.
+func NewMemoryDB() (*DB, error) {
+	// A named shared-cache database, so every connection of the pool sees
+	// the same data; a plain :memory: database is private to one connection.
+	name := fmt.Sprintf("file:ordiff-memory-%d?mode=memory&cache=shared&_busy_timeout=5000", memoryDBs.Add(1))
+	d, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	// The database disappears with its last connection, so connections must
+	// never be retired for age.
+	d.db.SetConnMaxIdleTime(0)
+	d.db.SetConnMaxLifetime(0)
+
+	if _, err := d.Migrate(); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return d, nil
+}
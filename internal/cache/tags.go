@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"database/sql"
+	"time"
+)
+
+// releaseTags stores the tag object behind each release: whether it is an
+// annotated tag, who tagged it and with what message, and its signature.
+func releaseTags(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS release_tags (
+		owner TEXT,
+		repo TEXT,
+		tag_name TEXT,
+		commit_sha TEXT,
+		annotated INTEGER NOT NULL DEFAULT 0,
+		tagger TEXT,
+		tagger_email TEXT,
+		tagged_at TEXT,
+		message TEXT,
+		signature TEXT,
+		verified INTEGER NOT NULL DEFAULT 0,
+		reason TEXT,
+		fetched_at TEXT,
+		PRIMARY KEY (owner, repo, tag_name)
+	);
+	`)
+	return err
+}
+
+// Tag is the git tag of a release. Lightweight tags point straight at the
+// commit and carry nothing else. Signature is the kind of signature an
+// annotated tag carries (gpg, ssh, x509 or sigstore), empty when unsigned,
+// and Reason is the forge's verdict on it, such as valid or unknown_key.
+type Tag struct {
+	Name        string     `json:"name"`
+	CommitSHA   string     `json:"commit_sha"`
+	Annotated   bool       `json:"annotated"`
+	Tagger      string     `json:"tagger,omitempty"`
+	TaggerEmail string     `json:"tagger_email,omitempty"`
+	TaggedAt    *time.Time `json:"tagged_at,omitempty"`
+	Message     string     `json:"message,omitempty"`
+	Signature   string     `json:"signature,omitempty"`
+	Verified    bool       `json:"verified"`
+	Reason      string     `json:"reason,omitempty"`
+	FetchedAt   time.Time  `json:"fetched_at"`
+}
+
+// Signed reports whether the tag carries a signature, verified or not.
+func (t *Tag) Signed() bool {
+	return t.Signature != ""
+}
+
+// SaveTags stores the tags of a repository's releases, replacing earlier
+// fetches of the same tags.
+func (d *DB) SaveTags(owner, repo string, tags []Tag) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		for _, t := range tags {
+			var taggedAt interface{}
+			if t.TaggedAt != nil {
+				taggedAt = t.TaggedAt.UTC().Format(time.RFC3339)
+			}
+			if _, err := tx.Exec(d.rebind(`
+				INSERT OR REPLACE INTO release_tags (owner, repo, tag_name, commit_sha, annotated, tagger, tagger_email, tagged_at, message, signature, verified, reason, fetched_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`), owner, repo, t.Name, t.CommitSHA, flag(t.Annotated), t.Tagger, t.TaggerEmail, taggedAt, t.Message, t.Signature, flag(t.Verified), t.Reason,
+				t.FetchedAt.UTC().Format(time.RFC3339)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetTags returns the cached tags of a repository's releases by name.
+func (d *DB) GetTags(owner, repo string) (map[string]*Tag, error) {
+	rows, err := d.query(`
+		SELECT tag_name, commit_sha, annotated, tagger, tagger_email, tagged_at, message, signature, verified, reason, fetched_at
+		FROM release_tags
+		WHERE owner = ? AND repo = ?
+	`, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := map[string]*Tag{}
+	for rows.Next() {
+		var t Tag
+		var taggedAt sql.NullString
+		var fetchedAt string
+		if err := rows.Scan(&t.Name, &t.CommitSHA, &t.Annotated, &t.Tagger, &t.TaggerEmail, &taggedAt, &t.Message, &t.Signature, &t.Verified, &t.Reason, &fetchedAt); err != nil {
+			return nil, err
+		}
+		if taggedAt.Valid {
+			at, _ := time.Parse(time.RFC3339, taggedAt.String)
+			t.TaggedAt = &at
+		}
+		t.FetchedAt, _ = time.Parse(time.RFC3339, fetchedAt)
+		tags[t.Name] = &t
+	}
+	return tags, rows.Err()
+}
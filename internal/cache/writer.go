@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// ErrClosed is returned by writes to a closed cache.
+var ErrClosed = errors.New("cache is closed")
+
+// sqliteParams are added to the path of a SQLite cache unless it sets them.
+// WAL journaling lets readers carry on while an index writes, and the busy
+// timeout makes a process wait for another's write instead of failing with
+// "database is locked". Immediate transactions take the write lock up
+// front, as a deferred one that later upgrades can fail without waiting.
+var sqliteParams = []string{
+	"_journal_mode=WAL",
+	"_busy_timeout=5000",
+	"_txlock=immediate",
+}
+
+// sqliteDSN adds sqliteParams to the path of a SQLite cache. In-memory
+// databases have no journal to switch.
+func sqliteDSN(path string) string {
+	var add []string
+	for _, p := range sqliteParams {
+		key := p[:strings.Index(p, "=")]
+		if strings.Contains(path, key+"=") || (key == "_journal_mode" && strings.Contains(path, "mode=memory")) {
+			continue
+		}
+		add = append(add, p)
+	}
+	if len(add) == 0 {
+		return path
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + strings.Join(add, "&")
+}
+
+// startWriter serializes the writes of a SQLite cache on one goroutine.
+// SQLite allows a single writer at a time, so writes from concurrent
+// goroutines, such as an MCP index and the tools it serves meanwhile, would
+// otherwise contend for the lock.
+func (d *DB) startWriter() {
+	d.writes = make(chan func())
+	d.closed = make(chan struct{})
+	go func() {
+		for {
+			select {
+			case fn := <-d.writes:
+				fn()
+			case <-d.closed:
+				return
+			}
+		}
+	}()
+}
+
+// write runs fn on the writer goroutine and waits for it. Postgres handles
+// concurrent writers itself, so there fn runs on the caller's goroutine.
+// fn must not call write again.
+func (d *DB) write(fn func() error) error {
+	return d.writeContext(context.Background(), fn)
+}
+
+// writeContext is write, giving up on waiting for the writer when ctx is
+// cancelled.
+func (d *DB) writeContext(ctx context.Context, fn func() error) error {
+	if d.writes == nil {
+		return fn()
+	}
+	errc := make(chan error, 1)
+	select {
+	case d.writes <- func() { errc <- fn() }:
+	case <-d.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-errc
+}
+
+// inTx runs fn in a write transaction, committed if fn succeeds.
+func (d *DB) inTx(fn func(tx *sql.Tx) error) error {
+	return d.inTxContext(context.Background(), fn)
+}
+
+// inTxContext is inTx with a transaction that is rolled back when ctx is
+// cancelled.
+func (d *DB) inTxContext(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return d.writeContext(ctx, func() error {
+		tx, err := d.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
@@ -0,0 +1,93 @@
+package changelog
+
+import (
+	"strings"
+)
+
+// NotesSection is a heading of a release's notes and the lines under it.
+// Notes without headings have a single section with an empty title.
+type NotesSection struct {
+	Title string   `json:"title"`
+	New   bool     `json:"new,omitempty"`
+	Lines []string `json:"lines"`
+}
+
+// NotesDiff is what a release's notes added over the release before it.
+type NotesDiff struct {
+	Tag      string         `json:"tag"`
+	Previous string         `json:"previous,omitempty"`
+	Sections []NotesSection `json:"sections"`
+}
+
+// ParseNotes splits Markdown release notes into sections at their headings.
+// Blank lines are dropped and list markers kept.
+func ParseNotes(body string) []NotesSection {
+	var sections []NotesSection
+	current := -1
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if title, ok := heading(line); ok {
+			sections = append(sections, NotesSection{Title: title})
+			current = len(sections) - 1
+			continue
+		}
+		if current < 0 {
+			sections = append(sections, NotesSection{})
+			current = 0
+		}
+		sections[current].Lines = append(sections[current].Lines, line)
+	}
+	return sections
+}
+
+// heading recognizes ATX headings (## Features) and lines that are bold and
+// nothing else (**Features**), which many projects use instead.
+func heading(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "#") {
+		title := strings.TrimLeft(trimmed, "#")
+		if title == "" || title[0] == ' ' {
+			return strings.TrimSpace(strings.TrimRight(title, "# ")), true
+		}
+	}
+	if len(trimmed) > 4 && strings.HasPrefix(trimmed, "**") && strings.HasSuffix(trimmed, "**") && !strings.Contains(trimmed[2:len(trimmed)-2], "**") {
+		return strings.TrimSuffix(strings.TrimSpace(trimmed[2:len(trimmed)-2]), ":"), true
+	}
+	return "", false
+}
+
+// DiffNotes returns the sections of cur with the lines that do not appear
+// anywhere in prev, so notes that repeat earlier entries only show what is
+// new. Sections whose title prev lacks are marked New; sections with nothing
+// new are left out.
+func DiffNotes(prev, cur string) []NotesSection {
+	seenLines := map[string]bool{}
+	seenTitles := map[string]bool{}
+	for _, s := range ParseNotes(prev) {
+		seenTitles[normalize(s.Title)] = true
+		for _, l := range s.Lines {
+			seenLines[normalize(l)] = true
+		}
+	}
+
+	var diff []NotesSection
+	for _, s := range ParseNotes(cur) {
+		added := NotesSection{Title: s.Title, New: s.Title != "" && !seenTitles[normalize(s.Title)]}
+		for _, l := range s.Lines {
+			if !seenLines[normalize(l)] {
+				added.Lines = append(added.Lines, l)
+			}
+		}
+		if len(added.Lines) > 0 {
+			diff = append(diff, added)
+		}
+	}
+	return diff
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,79 @@
+package changelog
+
+import (
+	"fmt"
+	"strings"
+
+	"ordiff/internal/cache"
+)
+
+// Kinds of Notice.
+const (
+	KindBreaking    = "breaking"
+	KindDeprecation = "deprecation"
+)
+
+// Notice is a breaking change or deprecation announced by a merged pull
+// request, or by a commit not merged through a cached one. Ref is the pull
+// request (#N) or the short commit SHA.
+type Notice struct {
+	Kind  string `json:"kind"`
+	Ref   string `json:"ref"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Notices lists the breaking changes of a release range, then its
+// deprecations, each in commit order. A pull request counts as breaking
+// when its title, body or any of its commits is marked breaking, and is
+// listed once.
+func Notices(commits []cache.Commit, prs []cache.PullRequest) []Notice {
+	byNumber := map[int]cache.PullRequest{}
+	for _, pr := range prs {
+		byNumber[pr.Number] = pr
+	}
+	for _, c := range commits {
+		if c.PrNumber == nil {
+			continue
+		}
+		if pr, ok := byNumber[*c.PrNumber]; ok {
+			pr.Breaking = pr.Breaking || c.Breaking
+			pr.Deprecated = pr.Deprecated || c.Deprecated
+			byNumber[pr.Number] = pr
+		}
+	}
+
+	var breaking, deprecated []Notice
+	add := func(n Notice, isBreaking, isDeprecated bool) {
+		switch {
+		case isBreaking:
+			n.Kind = KindBreaking
+			breaking = append(breaking, n)
+		case isDeprecated:
+			n.Kind = KindDeprecation
+			deprecated = append(deprecated, n)
+		}
+	}
+	seen := map[int]bool{}
+	addPR := func(pr cache.PullRequest) {
+		if !seen[pr.Number] {
+			seen[pr.Number] = true
+			add(Notice{Ref: fmt.Sprintf("#%d", pr.Number), Title: pr.Title, URL: pr.URL}, pr.Breaking, pr.Deprecated)
+		}
+	}
+
+	for _, c := range commits {
+		if c.PrNumber != nil {
+			if pr, ok := byNumber[*c.PrNumber]; ok {
+				addPR(pr)
+				continue
+			}
+		}
+		title, _, _ := strings.Cut(c.Message, "\n")
+		add(Notice{Ref: c.SHA[:min(7, len(c.SHA))], Title: title, URL: c.URL}, c.Breaking, c.Deprecated)
+	}
+	for _, pr := range prs {
+		addPR(byNumber[pr.Number])
+	}
+	return append(append(make([]Notice, 0, len(breaking)+len(deprecated)), breaking...), deprecated...)
+}
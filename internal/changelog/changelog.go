@@ -0,0 +1,135 @@
+package changelog
+
+import (
+	"fmt"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/conventional"
+)
+
+type Entry struct {
+	Type     string
+	Scope    string
+	Breaking bool
+	Subject  string
+	SHA      string
+	PrNumber *int
+}
+
+type Section struct {
+	Title   string
+	Entries []Entry
+}
+
+// Parse extracts the conventional-commit type, scope and breaking marker
+// from a commit message. Messages that don't follow the convention get an
+// empty type.
+func Parse(msg string) Entry {
+	m := conventional.Parse(msg)
+	return Entry{Type: m.Type, Scope: m.Scope, Breaking: m.Breaking, Subject: m.Subject}
+}
+
+var sectionOrder = []struct {
+	title string
+	types []string
+}{
+	{"Features", []string{"feat", "feature"}},
+	{"Bug Fixes", []string{"fix", "bugfix"}},
+	{"Performance", []string{"perf"}},
+	{"Refactoring", []string{"refactor"}},
+	{"Documentation", []string{"docs", "doc"}},
+	{"Tests", []string{"test", "tests"}},
+	{"Build & CI", []string{"build", "ci"}},
+	{"Chores", []string{"chore", "style", "revert"}},
+}
+
+// Group sorts commits into changelog sections. Breaking changes and
+// deprecations are listed in their own leading sections in addition to
+// their type section.
+func Group(commits []cache.Commit) []Section {
+	byType := map[string]int{}
+	sections := []Section{{Title: "⚠ Breaking Changes"}, {Title: "Deprecations"}}
+	for _, s := range sectionOrder {
+		for _, t := range s.types {
+			byType[t] = len(sections)
+		}
+		sections = append(sections, Section{Title: s.title})
+	}
+	other := len(sections)
+	sections = append(sections, Section{Title: "Other Changes"})
+
+	for _, c := range commits {
+		e := Parse(c.Message)
+		e.SHA = c.SHA
+		e.PrNumber = c.PrNumber
+
+		e.Breaking = e.Breaking || c.Breaking
+		if e.Breaking {
+			sections[0].Entries = append(sections[0].Entries, e)
+		} else if c.Deprecated {
+			sections[1].Entries = append(sections[1].Entries, e)
+		}
+		idx, ok := byType[e.Type]
+		if !ok {
+			idx = other
+		}
+		sections[idx].Entries = append(sections[idx].Entries, e)
+	}
+
+	var out []Section
+	for _, s := range sections {
+		if len(s.Entries) > 0 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func Markdown(tag string, commits []cache.Commit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n", tag)
+
+	sections := Group(commits)
+	if len(sections) == 0 {
+		b.WriteString("\nNo changes.\n")
+	}
+	for _, s := range sections {
+		fmt.Fprintf(&b, "\n### %s\n\n", s.Title)
+		for _, e := range s.Entries {
+			b.WriteString("- ")
+			if e.Scope != "" {
+				fmt.Fprintf(&b, "**%s:** ", e.Scope)
+			}
+			b.WriteString(e.Subject)
+			if len(e.SHA) >= 7 {
+				fmt.Fprintf(&b, " (%s)", e.SHA[:7])
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// Precompute regenerates and stores the changelog of every cached release
+// that has a predecessor.
+func Precompute(db cache.Store, owner, repo string) (int, error) {
+	releases, err := db.GetReleases(owner, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := 0; i < len(releases)-1; i++ {
+		from, to := releases[i+1], releases[i]
+		commits, err := db.GetCommitsBetween(owner, repo, from.TagName, to.TagName)
+		if err != nil {
+			return count, fmt.Errorf("failed to get commits for %s: %w", to.TagName, err)
+		}
+		if err := db.SaveChangelog(owner, repo, to.TagName, Markdown(to.TagName, commits)); err != nil {
+			return count, fmt.Errorf("failed to save changelog for %s: %w", to.TagName, err)
+		}
+		count++
+	}
+	return count, nil
+}
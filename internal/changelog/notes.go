@@ -0,0 +1,153 @@
+package changelog
+
+import (
+	"strings"
+
+	"ordiff/internal/cache"
+)
+
+// NoteItem is one line of release notes: a merged pull request, or a commit
+// that was not merged through one.
+type NoteItem struct {
+	Title    string   `json:"title"`
+	Scope    string   `json:"scope,omitempty"`
+	Breaking bool     `json:"breaking,omitempty"`
+	SHA      string   `json:"sha,omitempty"`
+	PrNumber *int     `json:"pr_number,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Author   string   `json:"author,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// ReleaseNotes groups the changes between two releases into the categories
+// release notes are usually written in. Breaking changes are also listed in
+// their regular category.
+type ReleaseNotes struct {
+	From         string     `json:"from"`
+	To           string     `json:"to"`
+	Breaking     []NoteItem `json:"breaking_changes"`
+	Features     []NoteItem `json:"features"`
+	Fixes        []NoteItem `json:"fixes"`
+	Dependencies []NoteItem `json:"dependencies"`
+	Docs         []NoteItem `json:"docs"`
+	Other        []NoteItem `json:"other"`
+}
+
+var labelCategories = map[string]string{
+	"feature":         "features",
+	"enhancement":     "features",
+	"feat":            "features",
+	"bug":             "fixes",
+	"bugfix":          "fixes",
+	"fix":             "fixes",
+	"dependencies":    "dependencies",
+	"deps":            "dependencies",
+	"documentation":   "docs",
+	"docs":            "docs",
+	"breaking":        "breaking",
+	"breaking change": "breaking",
+	"breaking-change": "breaking",
+}
+
+// DraftReleaseNotes categorizes commits and their merged pull requests. PR
+// labels decide the category when they match a known one; otherwise the
+// conventional-commit prefix of the PR title or commit message does. Commits
+// belonging to the same pull request are reported once.
+func DraftReleaseNotes(from, to string, commits []cache.Commit, prs []cache.PullRequest) *ReleaseNotes {
+	byNumber := map[int]cache.PullRequest{}
+	for _, pr := range prs {
+		byNumber[pr.Number] = pr
+	}
+
+	notes := &ReleaseNotes{
+		From:         from,
+		To:           to,
+		Breaking:     []NoteItem{},
+		Features:     []NoteItem{},
+		Fixes:        []NoteItem{},
+		Dependencies: []NoteItem{},
+		Docs:         []NoteItem{},
+		Other:        []NoteItem{},
+	}
+
+	seen := map[int]bool{}
+	for _, c := range commits {
+		var item NoteItem
+		var labels []string
+		entry := Parse(c.Message)
+
+		if pr, ok := prByCommit(c, byNumber); ok {
+			if seen[pr.Number] {
+				continue
+			}
+			seen[pr.Number] = true
+
+			fromTitle := Parse(pr.Title)
+			fromTitle.Breaking = fromTitle.Breaking || entry.Breaking || strings.Contains(pr.Body, "BREAKING CHANGE")
+			if fromTitle.Type == "" {
+				fromTitle.Type = entry.Type
+			}
+			entry = fromTitle
+			number := pr.Number
+			item = NoteItem{PrNumber: &number, URL: pr.URL, Author: pr.Author, Labels: pr.Labels}
+			labels = pr.Labels
+		} else {
+			item = NoteItem{PrNumber: c.PrNumber, URL: c.URL, Author: c.Author}
+		}
+		item.Title = entry.Subject
+		item.Scope = entry.Scope
+		item.SHA = c.SHA
+
+		item.Breaking = entry.Breaking
+		for _, l := range labels {
+			if labelCategories[strings.ToLower(l)] == "breaking" {
+				item.Breaking = true
+			}
+		}
+
+		if item.Breaking {
+			notes.Breaking = append(notes.Breaking, item)
+		}
+		switch categorize(entry, labels) {
+		case "features":
+			notes.Features = append(notes.Features, item)
+		case "fixes":
+			notes.Fixes = append(notes.Fixes, item)
+		case "dependencies":
+			notes.Dependencies = append(notes.Dependencies, item)
+		case "docs":
+			notes.Docs = append(notes.Docs, item)
+		default:
+			notes.Other = append(notes.Other, item)
+		}
+	}
+	return notes
+}
+
+func prByCommit(c cache.Commit, byNumber map[int]cache.PullRequest) (cache.PullRequest, bool) {
+	if c.PrNumber == nil {
+		return cache.PullRequest{}, false
+	}
+	pr, ok := byNumber[*c.PrNumber]
+	return pr, ok
+}
+
+func categorize(e Entry, labels []string) string {
+	for _, l := range labels {
+		if category, ok := labelCategories[strings.ToLower(l)]; ok && category != "breaking" {
+			return category
+		}
+	}
+
+	switch {
+	case e.Type == "feat" || e.Type == "feature":
+		return "features"
+	case e.Type == "fix" || e.Type == "bugfix":
+		return "fixes"
+	case e.Type == "deps" || e.Scope == "deps" || strings.HasPrefix(strings.ToLower(e.Subject), "bump "):
+		return "dependencies"
+	case e.Type == "docs" || e.Type == "doc":
+		return "docs"
+	}
+	return "other"
+}
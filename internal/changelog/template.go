@@ -0,0 +1,105 @@
+package changelog
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/tmpl"
+)
+
+// Document is the data passed to changelog templates.
+type Document struct {
+	Owner        string
+	Repo         string
+	From         string
+	To           string
+	Date         time.Time
+	Sections     []Section
+	PullRequests []cache.PullRequest
+	Breaking     []Notice
+}
+
+// DefaultTemplate renders a Keep a Changelog style document.
+const DefaultTemplate = `# Changelog
+
+All notable changes to {{.Owner}}/{{.Repo}} since {{.From}}.
+
+## [{{.To}}] - {{.Date.Format "2006-01-02"}}
+{{- range .Sections}}
+
+### {{.Title}}
+{{range .Entries}}
+- {{if .Scope}}**{{.Scope}}:** {{end}}{{.Subject}} ({{short .SHA}})
+{{- end}}
+{{- else}}
+
+No changes.
+{{- end}}
+`
+
+// NewDocument collects the cached commits and merged pull requests between
+// two releases.
+func NewDocument(db cache.Store, owner, repo, from, to string) (*Document, error) {
+	release, err := db.GetRelease(owner, repo, to)
+	if err != nil {
+		return nil, fmt.Errorf("release %s not found: %w", to, err)
+	}
+
+	commits, err := db.GetCommitsBetween(owner, repo, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+
+	seen := map[int]bool{}
+	var numbers []int
+	for _, c := range commits {
+		if c.PrNumber != nil && !seen[*c.PrNumber] {
+			seen[*c.PrNumber] = true
+			numbers = append(numbers, *c.PrNumber)
+		}
+	}
+	prs, err := db.GetPullRequests(owner, repo, numbers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull requests: %w", err)
+	}
+
+	// Commits merged through a breaking or deprecating pull request are
+	// listed as such even when their own message doesn't say so.
+	flagged := map[int]cache.PullRequest{}
+	for _, pr := range prs {
+		if pr.Breaking || pr.Deprecated {
+			flagged[pr.Number] = pr
+		}
+	}
+	for i, c := range commits {
+		if c.PrNumber == nil {
+			continue
+		}
+		if pr, ok := flagged[*c.PrNumber]; ok {
+			commits[i].Breaking = c.Breaking || pr.Breaking
+			commits[i].Deprecated = c.Deprecated || pr.Deprecated
+		}
+	}
+
+	return &Document{
+		Owner:        owner,
+		Repo:         repo,
+		From:         from,
+		To:           to,
+		Date:         release.PublishedAt,
+		Sections:     Group(commits),
+		PullRequests: prs,
+		Breaking:     Notices(commits, prs),
+	}, nil
+}
+
+// Render executes a changelog template against doc. An empty text uses
+// DefaultTemplate.
+func Render(w io.Writer, doc *Document, text string) error {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	return tmpl.Execute(w, "changelog", text, doc)
+}
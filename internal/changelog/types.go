@@ -0,0 +1,87 @@
+package changelog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/conventional"
+)
+
+// maxScopes bounds the scopes a TypeStats lists.
+const maxScopes = 10
+
+type TypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+type ScopeCount struct {
+	Scope string `json:"scope"`
+	Count int    `json:"count"`
+}
+
+// TypeStats breaks a set of commits down by conventional-commit type. Commits
+// that don't follow the convention count as "other".
+type TypeStats struct {
+	Commits      int          `json:"commits"`
+	Conventional int          `json:"conventional"`
+	Breaking     int          `json:"breaking"`
+	Types        []TypeCount  `json:"types"`
+	Scopes       []ScopeCount `json:"top_scopes"`
+}
+
+// CommitTypes counts the commits of every type, most frequent first, and
+// the most frequent scopes.
+func CommitTypes(commits []cache.Commit) TypeStats {
+	s := TypeStats{Commits: len(commits), Types: []TypeCount{}, Scopes: []ScopeCount{}}
+	types := map[string]int{}
+	scopes := map[string]int{}
+	for _, c := range commits {
+		m := conventional.Parse(c.Message)
+		if m.Breaking || c.Breaking {
+			s.Breaking++
+		}
+		if m.Type == "" {
+			types["other"]++
+			continue
+		}
+		s.Conventional++
+		types[m.Type]++
+		if m.Scope != "" {
+			scopes[m.Scope]++
+		}
+	}
+
+	for t, n := range types {
+		s.Types = append(s.Types, TypeCount{t, n})
+	}
+	sort.Slice(s.Types, func(i, j int) bool {
+		if s.Types[i].Count != s.Types[j].Count {
+			return s.Types[i].Count > s.Types[j].Count
+		}
+		return s.Types[i].Type < s.Types[j].Type
+	})
+	for scope, n := range scopes {
+		s.Scopes = append(s.Scopes, ScopeCount{scope, n})
+	}
+	sort.Slice(s.Scopes, func(i, j int) bool {
+		if s.Scopes[i].Count != s.Scopes[j].Count {
+			return s.Scopes[i].Count > s.Scopes[j].Count
+		}
+		return s.Scopes[i].Scope < s.Scopes[j].Scope
+	})
+	s.Scopes = s.Scopes[:min(maxScopes, len(s.Scopes))]
+	return s
+}
+
+// String summarizes the breakdown on one line, e.g. "12 feat, 30 fix,
+// 5 refactor, 8 other".
+func (s TypeStats) String() string {
+	parts := make([]string, len(s.Types))
+	for i, t := range s.Types {
+		parts[i] = fmt.Sprintf("%d %s", t.Count, t.Type)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,140 @@
+// Package codeowners parses CODEOWNERS files and attributes changed files to
+// the teams and people that own them.
+package codeowners
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/filter"
+)
+
+// Locations are where GitHub looks for a CODEOWNERS file, in order.
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule assigns the files matching a gitignore-style pattern to owners. A
+// rule without owners leaves its files unowned.
+type Rule struct {
+	Pattern string
+	Owners  []string
+}
+
+// Parse reads the rules of a CODEOWNERS file. Comments, blank lines and
+// GitLab section headers are skipped.
+func Parse(content string) []Rule {
+	var rules []Rule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, " #"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "[") || strings.HasPrefix(fields[0], "^[") {
+			continue
+		}
+		rules = append(rules, Rule{Pattern: strings.ReplaceAll(fields[0], `\#`, "#"), Owners: fields[1:]})
+	}
+	return rules
+}
+
+// Owners returns the owners of a file: those of the last matching rule, as
+// on GitHub.
+func Owners(rules []Rule, filename string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if match(rules[i].Pattern, filename) {
+			return rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// match applies gitignore semantics: a pattern matches a file or any
+// directory above it, patterns with a leading or inner slash are relative to
+// the repository root, and a trailing slash only matches directories.
+func match(pattern, filename string) bool {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "/")
+	if trimmed == "" {
+		return false
+	}
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(trimmed, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+
+	segments := strings.Split(filename, "/")
+	for n := 1; n <= len(segments); n++ {
+		if dirOnly && n == len(segments) {
+			break
+		}
+		candidate := strings.Join(segments[:n], "/")
+		switch {
+		case !anchored:
+			if ok, _ := path.Match(trimmed, segments[n-1]); ok {
+				return true
+			}
+		case !strings.Contains(trimmed, "/"):
+			if ok, _ := path.Match(trimmed, candidate); ok {
+				return true
+			}
+		case filter.Match(trimmed, candidate):
+			return true
+		}
+	}
+	return false
+}
+
+// Impact is what one owner is affected by in a range of file changes.
+type Impact struct {
+	Owner     string   `json:"owner,omitempty"`
+	Files     []string `json:"files"`
+	Additions int      `json:"additions"`
+	Deletions int      `json:"deletions"`
+}
+
+// Summary attributes a range of file changes to owners.
+type Summary struct {
+	Source  string   `json:"source"` // path of the CODEOWNERS file
+	Owners  []Impact `json:"owners"`
+	Unowned Impact   `json:"unowned"`
+}
+
+// Summarize groups file changes by owner, most churn first. A file with
+// several owners counts for each of them; files no rule assigns are
+// unowned.
+func Summarize(source string, rules []Rule, files []cache.FileChange) Summary {
+	unowned := Impact{Files: []string{}}
+	byOwner := map[string]*Impact{}
+	for _, f := range files {
+		fileOwners := Owners(rules, f.Filename)
+		if len(fileOwners) == 0 {
+			unowned.Files = append(unowned.Files, f.Filename)
+			unowned.Additions += f.Additions
+			unowned.Deletions += f.Deletions
+			continue
+		}
+		for _, o := range fileOwners {
+			impact, ok := byOwner[o]
+			if !ok {
+				impact = &Impact{Owner: o}
+				byOwner[o] = impact
+			}
+			impact.Files = append(impact.Files, f.Filename)
+			impact.Additions += f.Additions
+			impact.Deletions += f.Deletions
+		}
+	}
+
+	owners := make([]Impact, 0, len(byOwner))
+	for _, impact := range byOwner {
+		sort.Strings(impact.Files)
+		owners = append(owners, *impact)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		ci, cj := owners[i].Additions+owners[i].Deletions, owners[j].Additions+owners[j].Deletions
+		if ci != cj {
+			return ci > cj
+		}
+		return owners[i].Owner < owners[j].Owner
+	})
+	sort.Strings(unowned.Files)
+	return Summary{Source: source, Owners: owners, Unowned: unowned}
+}
@@ -0,0 +1,54 @@
+// Package command runs the external commands of the config, such as
+// analyzers and metrics hooks, with a time limit.
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command is an executable run with Args in Dir. Env is added to the
+// environment of ordiff, and a Timeout of 0 sets no limit.
+type Command struct {
+	Path    string
+	Args    []string
+	Dir     string
+	Env     []string
+	Stdin   io.Reader
+	Timeout time.Duration
+}
+
+// Output runs c, killing it once ctx is done or its timeout passes, and
+// returns what it wrote to stdout. A non-zero exit is reported with the last
+// line of stderr as the reason.
+func (c Command) Output(ctx context.Context) ([]byte, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = append(os.Environ(), c.Env...)
+	cmd.Stdin = c.Stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %s", c.Timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg[strings.LastIndex(msg, "\n")+1:])
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,285 @@
+// Package config locates the ordiff config file and cache database. Both
+// default to per-user XDG locations so ordiff behaves the same from any
+// directory; a .ordiff.yaml or ordiff.db in the working directory overrides
+// them for that project.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ordiff/internal/filter"
+	"ordiff/internal/mailmap"
+	"ordiff/internal/retry"
+	"ordiff/internal/risk"
+
+	"github.com/spf13/viper"
+)
+
+// File, DB and APIURL are set by the --config, --db and --api-url flags.
+var (
+	File   string
+	DB     string
+	APIURL string
+)
+
+const (
+	localConfig  = ".ordiff.yaml"
+	localDB      = "ordiff.db"
+	localMailmap = ".mailmap"
+)
+
+var loaded bool
+
+// Path returns the config file in use: --config, $ORDIFF_CONFIG, a
+// .ordiff.yaml in the working directory, or
+// $XDG_CONFIG_HOME/ordiff/config.yaml.
+func Path() string {
+	if File != "" {
+		return File
+	}
+	if env := os.Getenv("ORDIFF_CONFIG"); env != "" {
+		return env
+	}
+	if exists(localConfig) {
+		return localConfig
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return localConfig
+	}
+	return filepath.Join(dir, "ordiff", "config.yaml")
+}
+
+// DBPath returns the cache database in use: --db, $ORDIFF_DB, the db key of
+// the config, an ordiff.db in the working directory, or
+// $XDG_CACHE_HOME/ordiff/ordiff.db. The directory is created if needed.
+func DBPath() string {
+	Load()
+
+	path := DB
+	if path == "" {
+		path = os.Getenv("ORDIFF_DB")
+	}
+	if path == "" {
+		path = expandHome(viper.GetString("db"))
+	}
+	if path == "" && exists(localDB) {
+		path = localDB
+	}
+	if path == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return localDB
+		}
+		path = filepath.Join(dir, "ordiff", localDB)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		slog.Warn("Could not create cache directory", "err", err)
+	}
+	return path
+}
+
+// GitHubAPIURL returns the API URL of a GitHub Enterprise Server instance:
+// --api-url, the api_base_url key of the config, or $GITHUB_API_URL (set in
+// GitHub Actions). Empty means github.com.
+func GitHubAPIURL() string {
+	if APIURL != "" {
+		return APIURL
+	}
+	Load()
+	if u := viper.GetString("api_base_url"); u != "" {
+		return u
+	}
+	return os.Getenv("GITHUB_API_URL")
+}
+
+// MCPLogPath returns the file the MCP server logs to over stdio, whose
+// stdout carries the protocol.
+func MCPLogPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ordiff", "mcp.log")
+}
+
+// IndexLockPath returns the file in which a running 'ordiff index' of a
+// repository records its process ID, for 'ordiff index --cancel'.
+func IndexLockPath(owner, repo string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	name := strings.ReplaceAll(owner+"_"+repo, "/", "_")
+	return filepath.Join(dir, "ordiff", "index-"+name+".pid")
+}
+
+// Load reads the config file once. A missing file is not an error.
+func Load() {
+	if loaded {
+		return
+	}
+	loaded = true
+
+	viper.SetConfigFile(Path())
+	viper.SetConfigType("yaml")
+	if err := viper.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("Could not read config", "err", err)
+	}
+}
+
+// SaveDefaultRepo records the repository used when no --repo is given.
+func SaveDefaultRepo(owner, repo string) error {
+	Load()
+	viper.Set("default_owner", owner)
+	viper.Set("default_repo", repo)
+
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return viper.WriteConfigAs(path)
+}
+
+// Storage returns the storage backend from storage.backend, or db_driver,
+// in the config (sqlite by default) and what to open it with: the cache path
+// for sqlite; storage.dsn or the db setting (a connection URL) otherwise.
+func Storage() (string, string) {
+	Load()
+
+	backend := viper.GetString("storage.backend")
+	if backend == "" {
+		backend = viper.GetString("db_driver")
+	}
+	if backend == "" || backend == "sqlite" {
+		return "sqlite", DBPath()
+	}
+
+	dsn := viper.GetString("storage.dsn")
+	for _, v := range []string{DB, os.Getenv("ORDIFF_DB"), viper.GetString("db")} {
+		if dsn == "" {
+			dsn = v
+		}
+	}
+	return backend, dsn
+}
+
+// Mailmap returns the author identity mapping: the .mailmap file named by
+// the mailmap key of the config, or a .mailmap in the working directory,
+// plus the lines listed under mailmap_entries.
+func Mailmap() (*mailmap.Mailmap, error) {
+	Load()
+
+	m := mailmap.New()
+	path := expandHome(viper.GetString("mailmap"))
+	if path == "" && exists(localMailmap) {
+		path = localMailmap
+	}
+	if path != "" {
+		var err error
+		if m, err = mailmap.ParseFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, line := range viper.GetStringSlice("mailmap_entries") {
+		if err := m.Add(line); err != nil {
+			return nil, fmt.Errorf("mailmap_entries: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// IgnorePatterns returns the paths hidden from comparisons without --all:
+// the ignore_patterns key of the config, or filter.DefaultIgnorePatterns.
+func IgnorePatterns() []string {
+	Load()
+	if patterns := viper.GetStringSlice("ignore_patterns"); len(patterns) > 0 {
+		return patterns
+	}
+	return filter.DefaultIgnorePatterns
+}
+
+// Retry returns how API calls failing with a server error or a network
+// failure are retried: retry.Default with the keys set under retry in the
+// config replacing the defaults.
+func Retry() (retry.Policy, error) {
+	Load()
+
+	p := retry.Default
+	if viper.IsSet("retry.attempts") {
+		p.Attempts = viper.GetInt("retry.attempts")
+	}
+	if viper.IsSet("retry.base_delay") {
+		p.BaseDelay = viper.GetDuration("retry.base_delay")
+	}
+	if viper.IsSet("retry.max_delay") {
+		p.MaxDelay = viper.GetDuration("retry.max_delay")
+	}
+	if p.Attempts < 0 || p.BaseDelay < 0 || p.MaxDelay < p.BaseDelay {
+		return p, fmt.Errorf("retry: attempts and delays must not be negative, and max_delay must be at least base_delay")
+	}
+	return p, nil
+}
+
+// Risk returns the upgrade risk heuristics: risk.DefaultConfig with the
+// keys set under risk in the config replacing the defaults.
+func Risk() (risk.Config, error) {
+	Load()
+
+	cfg := risk.DefaultConfig()
+	var c risk.Config
+	if err := viper.UnmarshalKey("risk", &c); err != nil {
+		return cfg, fmt.Errorf("risk: %w", err)
+	}
+	if viper.IsSet("risk.core_paths") {
+		cfg.CorePaths = c.CorePaths
+	}
+	if viper.IsSet("risk.non_core_paths") {
+		cfg.NonCorePaths = c.NonCorePaths
+	}
+	if viper.IsSet("risk.breaking_keywords") {
+		cfg.BreakingKeywords = c.BreakingKeywords
+	}
+	for name, fc := range c.Factors {
+		f, ok := cfg.Factors[name]
+		if !ok {
+			return cfg, fmt.Errorf("risk: unknown factor %q", name)
+		}
+		if viper.IsSet("risk.factors." + name + ".weight") {
+			f.Weight = fc.Weight
+		}
+		if viper.IsSet("risk.factors." + name + ".saturation") {
+			f.Saturation = fc.Saturation
+		}
+		if f.Weight < 0 || f.Saturation <= 0 {
+			return cfg, fmt.Errorf("risk: factor %q needs a weight of at least 0 and a positive saturation", name)
+		}
+		cfg.Factors[name] = f
+	}
+	return cfg, nil
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
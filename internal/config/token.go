@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/zalando/go-keyring"
+)
+
+// Keyring entry holding a GitHub token, e.g. stored with
+// `secret-tool store --label ordiff service ordiff username github_token`.
+const (
+	keyringService = "ordiff"
+	keyringUser    = "github_token"
+)
+
+// Sources of the GitHub token, as reported by GitHubTokenSource.
+const (
+	TokenFromEnv     = "GITHUB_TOKEN"
+	TokenFromConfig  = "config"
+	TokenFromKeyring = "keyring"
+	TokenFromGh      = "gh CLI"
+)
+
+var githubToken = sync.OnceValues(lookupGitHubToken)
+
+// GitHubToken returns the token for GitHub API requests: $GITHUB_TOKEN, the
+// github_token key of the config, the ordiff entry in the system keyring, or
+// the token the gh CLI is logged in with, in that order. It is empty if none
+// is set, and GitHub then allows 60 requests an hour.
+func GitHubToken() string {
+	token, _ := githubToken()
+	return token
+}
+
+// GitHubTokenSource returns where GitHubToken found the token, one of the
+// TokenFrom constants, or "" without a token.
+func GitHubTokenSource() string {
+	_, source := githubToken()
+	return source
+}
+
+func lookupGitHubToken() (string, string) {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token, TokenFromEnv
+	}
+
+	Load()
+	if token := viper.GetString("github_token"); token != "" {
+		return token, TokenFromConfig
+	}
+
+	if token, err := keyring.Get(keyringService, keyringUser); err == nil && token != "" {
+		return strings.TrimSpace(token), TokenFromKeyring
+	}
+
+	if token := ghAuthToken(); token != "" {
+		return token, TokenFromGh
+	}
+	return "", ""
+}
+
+// ghAuthToken asks the gh CLI for its token for the GitHub host ordiff talks
+// to, if gh is installed and logged in there.
+func ghAuthToken() string {
+	path, err := exec.LookPath("gh")
+	if err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "auth", "token", "--hostname", githubHost()).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// githubHost returns the host of GitHubAPIURL as gh names it: github.com,
+// or the GitHub Enterprise Server host.
+func githubHost() string {
+	u, err := url.Parse(GitHubAPIURL())
+	if err != nil || u.Host == "" || u.Host == "api.github.com" {
+		return "github.com"
+	}
+	return u.Host
+}
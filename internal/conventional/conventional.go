@@ -0,0 +1,59 @@
+// Package conventional parses commit messages that follow the Conventional
+// Commits convention, such as "feat(api)!: drop v1 endpoints".
+package conventional
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Message is a parsed commit message. Messages that don't follow the
+// convention have an empty Type and their whole first line as Subject.
+type Message struct {
+	Type     string
+	Scope    string
+	Breaking bool
+	Subject  string
+}
+
+var headerRe = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// Parse extracts the type, scope and breaking marker from a commit message.
+// A BREAKING CHANGE footer also marks it breaking. Types are lower-cased.
+func Parse(msg string) Message {
+	subject := msg
+	body := ""
+	if i := strings.IndexByte(msg, '\n'); i != -1 {
+		subject = msg[:i]
+		body = msg[i+1:]
+	}
+	subject = strings.TrimSpace(subject)
+
+	m := Message{Subject: subject}
+	if h := headerRe.FindStringSubmatch(subject); h != nil {
+		m.Type = strings.ToLower(h[1])
+		m.Scope = h[2]
+		m.Breaking = h[3] == "!"
+		m.Subject = h[4]
+	}
+	if strings.Contains(body, "BREAKING CHANGE:") || strings.Contains(body, "BREAKING-CHANGE:") {
+		m.Breaking = true
+	}
+	return m
+}
+
+var deprecationRe = regexp.MustCompile(`(?im)^\s*(?:\w+(?:\([^)]*\))?!?:\s*)?deprecat(?:e[sd]?|ing|ion)\b`)
+
+// Breaking reports whether text, a commit message or a pull request title
+// and body, announces a breaking change: a "!" after the type, or a
+// BREAKING CHANGE note anywhere in it.
+func Breaking(text string) bool {
+	return Parse(text).Breaking || strings.Contains(text, "BREAKING CHANGE") || strings.Contains(text, "BREAKING-CHANGE")
+}
+
+// Deprecation reports whether a line of text starts by deprecating
+// something, after the type and scope if it has them: "deprecate --foo",
+// "feat(cli): deprecate --foo" or "Deprecated: use Bar instead".
+func Deprecation(text string) bool {
+	return deprecationRe.MatchString(text)
+}
@@ -0,0 +1,277 @@
+package deps
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/semver"
+)
+
+const (
+	KindAdded      = "added"
+	KindRemoved    = "removed"
+	KindUpgraded   = "upgraded"
+	KindDowngraded = "downgraded"
+)
+
+type Change struct {
+	Name string `json:"name"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	Kind string `json:"kind"`
+}
+
+type FileDiff struct {
+	File    string   `json:"file"`
+	Changes []Change `json:"changes"`
+	NoPatch bool     `json:"no_patch,omitempty"`
+}
+
+type versionSets struct {
+	removed map[string][]string
+	added   map[string][]string
+}
+
+var lockfileParsers = map[string]func(patch string) versionSets{
+	"go.sum":            parseGoSum,
+	"package-lock.json": parsePackageLock,
+	"Cargo.lock":        parseTOMLLock,
+	"poetry.lock":       parseTOMLLock,
+}
+
+func IsLockfile(filename string) bool {
+	_, ok := lockfileParsers[path.Base(filename)]
+	return ok
+}
+
+func LockfileDiffs(files []cache.FileChange) []FileDiff {
+	var diffs []FileDiff
+	for _, fc := range files {
+		parse, ok := lockfileParsers[path.Base(fc.Filename)]
+		if !ok {
+			continue
+		}
+		if fc.Patch == "" {
+			diffs = append(diffs, FileDiff{File: fc.Filename, NoPatch: true})
+			continue
+		}
+		diffs = append(diffs, FileDiff{File: fc.Filename, Changes: classify(parse(fc.Patch), compareBare)})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].File < diffs[j].File })
+	return diffs
+}
+
+// DiffVersions classifies the changes between two sets of installed package
+// versions, such as the OS packages of two container images, ordering
+// versions with compare.
+func DiffVersions(from, to map[string]string, compare func(a, b string) int) []Change {
+	vs := versionSets{removed: map[string][]string{}, added: map[string][]string{}}
+	for name, v := range from {
+		vs.removed[name] = []string{v}
+	}
+	for name, v := range to {
+		vs.added[name] = []string{v}
+	}
+	return classify(vs, compare)
+}
+
+func classify(vs versionSets, compare func(a, b string) int) []Change {
+	names := map[string]bool{}
+	for n := range vs.removed {
+		names[n] = true
+	}
+	for n := range vs.added {
+		names[n] = true
+	}
+
+	var changes []Change
+	for name := range names {
+		from := highest(vs.removed[name])
+		to := highest(vs.added[name])
+
+		c := Change{Name: name, From: from, To: to}
+		switch {
+		case from == "":
+			c.Kind = KindAdded
+		case to == "":
+			c.Kind = KindRemoved
+		case from == to:
+			continue
+		case compare(from, to) < 0:
+			c.Kind = KindUpgraded
+		default:
+			c.Kind = KindDowngraded
+		}
+		changes = append(changes, c)
+	}
+
+	order := map[string]int{KindAdded: 0, KindRemoved: 1, KindUpgraded: 2, KindDowngraded: 3}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return order[changes[i].Kind] < order[changes[j].Kind]
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// IsMajorBump reports whether an upgrade may break callers under semantic
+// versioning: a new major version, or a new minor version before 1.0.
+func IsMajorBump(c Change) bool {
+	if c.Kind != KindUpgraded {
+		return false
+	}
+	from, okFrom := semver.Parse(bareVersion(c.From))
+	to, okTo := semver.Parse(bareVersion(c.To))
+	if !okFrom || !okTo {
+		return false
+	}
+	if from.Major == 0 && to.Major == 0 {
+		return to.Minor > from.Minor
+	}
+	return to.Major > from.Major
+}
+
+func highest(versions []string) string {
+	best := ""
+	for _, v := range versions {
+		if best == "" || semver.CompareStrings(bareVersion(v), bareVersion(best)) > 0 {
+			best = v
+		}
+	}
+	return best
+}
+
+// compareBare orders versions as semver, ignoring range operators.
+func compareBare(a, b string) int {
+	return semver.CompareStrings(bareVersion(a), bareVersion(b))
+}
+
+// bareVersion strips range operators such as ^, ~ and >= so that manifest
+// constraints compare by the version they name.
+func bareVersion(v string) string {
+	return strings.TrimLeft(v, "^~<>=! ")
+}
+
+// record adds a version to the side of the diff its line is on. Context
+// lines are on both sides, so a module whose other version's lines did not
+// change is not reported as added or removed.
+func (vs versionSets) record(side byte, name, version string) {
+	if name == "" || version == "" {
+		return
+	}
+	if side == '-' || side == ' ' {
+		vs.removed[name] = append(vs.removed[name], version)
+	}
+	if side == '+' || side == ' ' {
+		vs.added[name] = append(vs.added[name], version)
+	}
+}
+
+func newVersionSets() versionSets {
+	return versionSets{removed: map[string][]string{}, added: map[string][]string{}}
+}
+
+// patchLines yields the side marker ('+', '-' or ' ') and content of every
+// line in a unified diff hunk, skipping hunk headers.
+func patchLines(patch string, fn func(side byte, line string)) {
+	for _, line := range strings.Split(patch, "\n") {
+		if line == "" || strings.HasPrefix(line, "@@") || strings.HasPrefix(line, `\`) {
+			continue
+		}
+		switch line[0] {
+		case '+', '-', ' ':
+			fn(line[0], line[1:])
+		}
+	}
+}
+
+func parseGoSum(patch string) versionSets {
+	vs := newVersionSets()
+	patchLines(patch, func(side byte, line string) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return
+		}
+		vs.record(side, fields[0], strings.TrimSuffix(fields[1], "/go.mod"))
+	})
+	return vs
+}
+
+var (
+	jsonKeyRe     = regexp.MustCompile(`^\s*"([^"]*)":\s*\{`)
+	jsonVersionRe = regexp.MustCompile(`^\s*"version":\s*"([^"]+)"`)
+)
+
+var packageLockSections = map[string]bool{
+	"packages":             true,
+	"dependencies":         true,
+	"devDependencies":      true,
+	"peerDependencies":     true,
+	"optionalDependencies": true,
+	"requires":             true,
+	"engines":              true,
+	"funding":              true,
+	"bin":                  true,
+}
+
+func parsePackageLock(patch string) versionSets {
+	vs := newVersionSets()
+	current := map[byte]string{}
+	patchLines(patch, func(side byte, line string) {
+		if m := jsonKeyRe.FindStringSubmatch(line); m != nil {
+			if packageLockSections[m[1]] {
+				return
+			}
+			name := m[1]
+			if i := strings.LastIndex(name, "node_modules/"); i != -1 {
+				name = name[i+len("node_modules/"):]
+			}
+			setCurrent(current, side, name)
+			return
+		}
+		if m := jsonVersionRe.FindStringSubmatch(line); m != nil {
+			vs.record(side, current[side], m[1])
+		}
+	})
+	return vs
+}
+
+var (
+	tomlNameRe    = regexp.MustCompile(`^name\s*=\s*"([^"]+)"`)
+	tomlVersionRe = regexp.MustCompile(`^version\s*=\s*"([^"]+)"`)
+)
+
+func parseTOMLLock(patch string) versionSets {
+	vs := newVersionSets()
+	current := map[byte]string{}
+	patchLines(patch, func(side byte, line string) {
+		line = strings.TrimSpace(line)
+		if line == "[[package]]" {
+			setCurrent(current, side, "")
+			return
+		}
+		if m := tomlNameRe.FindStringSubmatch(line); m != nil {
+			setCurrent(current, side, m[1])
+			return
+		}
+		if m := tomlVersionRe.FindStringSubmatch(line); m != nil {
+			vs.record(side, current[side], m[1])
+		}
+	})
+	return vs
+}
+
+// setCurrent tracks the package a version line belongs to. Context lines
+// apply to both sides of the diff.
+func setCurrent(current map[byte]string, side byte, name string) {
+	if side == ' ' {
+		current['-'] = name
+		current['+'] = name
+		return
+	}
+	current[side] = name
+}
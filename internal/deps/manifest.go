@@ -0,0 +1,144 @@
+package deps
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+)
+
+var manifestParsers = map[string]func(patch string) versionSets{
+	"go.mod":           parseGoMod,
+	"package.json":     parsePackageJSON,
+	"requirements.txt": parseRequirements,
+}
+
+func manifestParser(filename string) (func(patch string) versionSets, bool) {
+	base := path.Base(filename)
+	if strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt") {
+		base = "requirements.txt"
+	}
+	parse, ok := manifestParsers[base]
+	return parse, ok
+}
+
+func IsManifest(filename string) bool {
+	_, ok := manifestParser(filename)
+	return ok
+}
+
+// ManifestDiffs lists the declared dependencies added, removed or bumped in
+// changed go.mod, package.json and requirements*.txt files. Unlike
+// LockfileDiffs it reports the versions the project asks for, not the ones
+// that were resolved.
+func ManifestDiffs(files []cache.FileChange) []FileDiff {
+	var diffs []FileDiff
+	for _, fc := range files {
+		parse, ok := manifestParser(fc.Filename)
+		if !ok {
+			continue
+		}
+		if fc.Patch == "" {
+			diffs = append(diffs, FileDiff{File: fc.Filename, NoPatch: true})
+			continue
+		}
+		if changes := classify(parse(fc.Patch), compareBare); len(changes) > 0 {
+			diffs = append(diffs, FileDiff{File: fc.Filename, Changes: changes})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].File < diffs[j].File })
+	return diffs
+}
+
+func parseGoMod(patch string) versionSets {
+	vs := newVersionSets()
+	patchLines(patch, func(side byte, line string) {
+		if i := strings.Index(line, "//"); i != -1 {
+			line = line[:i]
+		}
+		if strings.Contains(line, "=>") {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "require" {
+			fields = fields[1:]
+		}
+		if len(fields) != 2 || !strings.Contains(fields[0], ".") || !strings.HasPrefix(fields[1], "v") {
+			return
+		}
+		vs.record(side, fields[0], fields[1])
+	})
+	return vs
+}
+
+var (
+	jsonSectionRe = regexp.MustCompile(`^\s*"([^"]*)":\s*\{`)
+	jsonEntryRe   = regexp.MustCompile(`^\s*"([^"]+)":\s*"([^"]*)",?\s*$`)
+	versionSpecRe = regexp.MustCompile(`^(?:[\^~<>=*]|\d|x\b|latest$|workspace:|npm:)`)
+)
+
+var packageJSONSections = map[string]bool{
+	"dependencies":         true,
+	"devDependencies":      true,
+	"peerDependencies":     true,
+	"optionalDependencies": true,
+}
+
+// parsePackageJSON tracks the object every line belongs to from the hunk's
+// context. When a hunk starts inside an object whose key is out of view, an
+// entry counts as a dependency if its value looks like a version range.
+func parsePackageJSON(patch string) versionSets {
+	vs := newVersionSets()
+	section := map[byte]string{}
+	patchLines(patch, func(side byte, line string) {
+		if m := jsonSectionRe.FindStringSubmatch(line); m != nil {
+			setCurrent(section, side, m[1])
+			return
+		}
+		if strings.TrimSpace(line) == "}" || strings.TrimSpace(line) == "}," {
+			setCurrent(section, side, "-")
+			return
+		}
+		m := jsonEntryRe.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		s := section[side]
+		if side == ' ' {
+			s = section['+']
+		}
+		switch {
+		case packageJSONSections[s]:
+		case s == "" && versionSpecRe.MatchString(m[2]) && m[1] != "version":
+		default:
+			return
+		}
+		vs.record(side, m[1], m[2])
+	})
+	return vs
+}
+
+var requirementRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[^\]]*\])?\s*((?:==|>=|<=|~=|!=|>|<)[^;#]*)?`)
+
+func parseRequirements(patch string) versionSets {
+	vs := newVersionSets()
+	patchLines(patch, func(side byte, line string) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			return
+		}
+		m := requirementRe.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		version := strings.ReplaceAll(strings.TrimSpace(m[3]), " ", "")
+		version = strings.TrimPrefix(version, "==")
+		if version == "" {
+			version = "*"
+		}
+		vs.record(side, strings.ToLower(m[1]), version)
+	})
+	return vs
+}
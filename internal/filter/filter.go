@@ -0,0 +1,275 @@
+package filter
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+)
+
+var DefaultGeneratedPatterns = []string{
+	"*.lock",
+	"go.sum",
+	"package-lock.json",
+	"pnpm-lock.yaml",
+	"*.min.js",
+	"*.min.css",
+	"*.pb.go",
+	"*_pb2.py",
+	"*_pb2_grpc.py",
+	"dist/",
+	"generated/",
+}
+
+// DefaultIgnorePatterns are the vendored and generated paths left out of
+// comparisons unless --all is given.
+var DefaultIgnorePatterns = append([]string{
+	"vendor/",
+	"node_modules/",
+	"third_party/",
+	"Godeps/",
+}, DefaultGeneratedPatterns...)
+
+var binaryExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".ico": true, ".webp": true, ".bmp": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".7z": true, ".jar": true,
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".a": true, ".o": true, ".wasm": true, ".bin": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp3": true, ".mp4": true, ".mov": true, ".wav": true, ".gguf": true, ".safetensors": true,
+}
+
+// Match reports whether filename matches a glob pattern. Patterns without a
+// slash match the base name at any depth, patterns ending in a slash match a
+// directory anywhere in the path, and everything else is matched against the
+// full path with path.Match semantics plus a "**" wildcard for any number of
+// directories.
+func Match(pattern, filename string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		segments := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+		segments = append(append([]string{"**"}, segments...), "*", "**")
+		return matchSegments(segments, strings.Split(filename, "/"))
+	}
+
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(filename))
+		return ok
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(filename, "/"))
+}
+
+// MatchPath reports whether filename is pattern itself, lies below the
+// directory pattern, or matches pattern as a glob.
+func MatchPath(pattern, filename string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if filename == pattern || strings.HasPrefix(filename, strings.TrimSuffix(pattern, "/")+"/") {
+		return true
+	}
+	return Match(pattern, filename)
+}
+
+func MatchAny(patterns []string, filename string) bool {
+	for _, p := range patterns {
+		if Match(p, filename) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		parts = parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// IsGenerated flags files that are likely machine generated, either by path
+// or because a modification is overwhelmingly one-sided and very large.
+func IsGenerated(fc cache.FileChange, patterns []string) bool {
+	if MatchAny(patterns, fc.Filename) {
+		return true
+	}
+
+	if fc.Status != "modified" || fc.Changes < 1000 {
+		return false
+	}
+	lo, hi := fc.Additions, fc.Deletions
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return hi >= 20*max(lo, 1)
+}
+
+func PartitionGenerated(files []cache.FileChange, patterns []string) ([]cache.FileChange, []cache.FileChange) {
+	var authored, generated []cache.FileChange
+	for _, f := range files {
+		if IsGenerated(f, patterns) {
+			generated = append(generated, f)
+		} else {
+			authored = append(authored, f)
+		}
+	}
+	return authored, generated
+}
+
+// IsBinary flags binary files: by extension, or a modification without a
+// patch or line counts, which is how GitHub reports binary diffs.
+func IsBinary(fc cache.FileChange) bool {
+	if binaryExtensions[strings.ToLower(path.Ext(fc.Filename))] {
+		return true
+	}
+	return fc.Status == "modified" && fc.Patch == "" && fc.Additions == 0 && fc.Deletions == 0
+}
+
+// PartitionIgnored splits off binary files and the files matching the
+// ignore patterns, which would otherwise crowd out authored changes.
+func PartitionIgnored(files []cache.FileChange, patterns []string) ([]cache.FileChange, []cache.FileChange) {
+	var kept, ignored []cache.FileChange
+	for _, f := range files {
+		if IsBinary(f) || MatchAny(patterns, f.Filename) {
+			ignored = append(ignored, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, ignored
+}
+
+// Paths keeps the files that match one of include (all files when include is
+// empty) and none of exclude. Patterns are matched with MatchPath.
+func Paths(files []cache.FileChange, include, exclude []string) []cache.FileChange {
+	if len(include) == 0 && len(exclude) == 0 {
+		return files
+	}
+
+	var kept []cache.FileChange
+	for _, f := range files {
+		if len(include) > 0 && !matchAnyPath(include, f.Filename) {
+			continue
+		}
+		if matchAnyPath(exclude, f.Filename) {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+func matchAnyPath(patterns []string, filename string) bool {
+	for _, p := range patterns {
+		if MatchPath(p, filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// Component is a named part of a monorepo, such as api/ or docs/, made of
+// the files matching any of its paths.
+type Component struct {
+	Name  string   `mapstructure:"name" json:"name"`
+	Paths []string `mapstructure:"paths" json:"paths"`
+}
+
+// OtherComponent collects the files no configured component matches.
+const OtherComponent = "other"
+
+type ComponentChanges struct {
+	Name      string             `json:"name"`
+	Files     []cache.FileChange `json:"files"`
+	Additions int                `json:"additions"`
+	Deletions int                `json:"deletions"`
+}
+
+// Dir returns the directory of filename cut to depth segments, with a
+// trailing slash, or "./" for files at the top level.
+func Dir(filename string, depth int) string {
+	d := path.Dir(filename)
+	if d == "." {
+		return "./"
+	}
+	segments := strings.Split(d, "/")
+	return strings.Join(segments[:min(depth, len(segments))], "/") + "/"
+}
+
+// DirChanges is the changes under one directory.
+type DirChanges struct {
+	Dir       string `json:"dir"`
+	Files     int    `json:"files"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// GroupByDir rolls files up into their directories cut to depth segments
+// (see Dir), most changed lines first.
+func GroupByDir(files []cache.FileChange, depth int) []DirChanges {
+	index := map[string]int{}
+	dirs := []DirChanges{}
+	for _, f := range files {
+		d := Dir(f.Filename, depth)
+		i, ok := index[d]
+		if !ok {
+			i = len(dirs)
+			index[d] = i
+			dirs = append(dirs, DirChanges{Dir: d})
+		}
+		dirs[i].Files++
+		dirs[i].Additions += f.Additions
+		dirs[i].Deletions += f.Deletions
+	}
+	sort.SliceStable(dirs, func(i, j int) bool {
+		a, b := dirs[i], dirs[j]
+		if a.Additions+a.Deletions != b.Additions+b.Deletions {
+			return a.Additions+a.Deletions > b.Additions+b.Deletions
+		}
+		return a.Dir < b.Dir
+	})
+	return dirs
+}
+
+// GroupByComponent assigns every file to the first component with a
+// matching path (see MatchPath), in configuration order. Every component is
+// returned, changed or not, followed by OtherComponent when files are left
+// over.
+func GroupByComponent(files []cache.FileChange, components []Component) []ComponentChanges {
+	groups := make([]ComponentChanges, len(components), len(components)+1)
+	for i, c := range components {
+		groups[i] = ComponentChanges{Name: c.Name, Files: []cache.FileChange{}}
+	}
+	other := ComponentChanges{Name: OtherComponent, Files: []cache.FileChange{}}
+
+	for _, f := range files {
+		g := &other
+		for i, c := range components {
+			if matchAnyPath(c.Paths, f.Filename) {
+				g = &groups[i]
+				break
+			}
+		}
+		g.Files = append(g.Files, f)
+		g.Additions += f.Additions
+		g.Deletions += f.Deletions
+	}
+
+	if len(other.Files) > 0 {
+		groups = append(groups, other)
+	}
+	return groups
+}
@@ -0,0 +1,65 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"ordiff/internal/provider"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// Access is what GitHub reports about the credentials requests are made
+// with. Scopes are those of a classic personal access token; GitHub reports
+// none for fine-grained and GitHub App tokens or anonymous requests, which
+// ScopesKnown tells apart from a classic token without scopes.
+type Access struct {
+	Quota       *provider.Quota
+	Scopes      []string
+	ScopesKnown bool
+}
+
+// CheckAccess asks the API for the rate limit, which shows whether it is
+// reachable and accepts the credentials without counting against the
+// quota. It is not retried.
+func (f *Fetcher) CheckAccess() (*Access, error) {
+	limits, resp, err := f.client.RateLimit.Get(f.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Access{}
+	if core := limits.GetCore(); core != nil {
+		a.Quota = &provider.Quota{Limit: core.Limit, Remaining: core.Remaining, Reset: core.Reset.Time}
+	}
+	if values, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; ok {
+		a.ScopesKnown = true
+		for _, v := range values {
+			for _, scope := range strings.Split(v, ",") {
+				if scope = strings.TrimSpace(scope); scope != "" {
+					a.Scopes = append(a.Scopes, scope)
+				}
+			}
+		}
+	}
+	return a, nil
+}
+
+// CheckRepository returns an error when the repository cannot be read with
+// the credentials, a 404 for private repositories the token has no access
+// to.
+func (f *Fetcher) CheckRepository() error {
+	_, _, err := f.client.Repositories.Get(f.ctx, f.owner, f.repo)
+	return err
+}
+
+// StatusCode returns the HTTP status of a failed API call, or 0 when it
+// failed without a response, such as on a network error.
+func StatusCode(err error) int {
+	var rest *github.ErrorResponse
+	if errors.As(err, &rest) && rest.Response != nil {
+		return rest.Response.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+type AppCredentials struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKey     []byte
+}
+
+// AppCredentialsFromEnv reads GitHub App credentials from ORDIFF_APP_ID,
+// ORDIFF_APP_INSTALLATION_ID and ORDIFF_APP_PRIVATE_KEY_FILE. It returns nil
+// when no app id is configured.
+func AppCredentialsFromEnv() (*AppCredentials, error) {
+	appID := os.Getenv("ORDIFF_APP_ID")
+	if appID == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseInt(appID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ORDIFF_APP_ID: %w", err)
+	}
+	installationID, err := strconv.ParseInt(os.Getenv("ORDIFF_APP_INSTALLATION_ID"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ORDIFF_APP_INSTALLATION_ID: %w", err)
+	}
+	key, err := os.ReadFile(os.Getenv("ORDIFF_APP_PRIVATE_KEY_FILE"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app private key: %w", err)
+	}
+
+	return &AppCredentials{AppID: id, InstallationID: installationID, PrivateKey: key}, nil
+}
+
+func NewAppFetcher(owner, repo string, creds AppCredentials) (*Fetcher, error) {
+	ts, err := newAppTokenSource(creds)
+	if err != nil {
+		return nil, err
+	}
+	return newFetcher(owner, repo, oauth2.NewClient(context.Background(), oauth2.ReuseTokenSource(nil, ts))), nil
+}
+
+type appTokenSource struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+}
+
+func newAppTokenSource(creds AppCredentials) (*appTokenSource, error) {
+	if creds.AppID == 0 || creds.InstallationID == 0 {
+		return nil, errors.New("app id and installation id are required")
+	}
+
+	key, err := parsePrivateKey(creds.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &appTokenSource{
+		appID:          creds.AppID,
+		installationID: creds.InstallationID,
+		key:            key,
+	}, nil
+}
+
+// Token exchanges a freshly minted app JWT for an installation token.
+// Wrapped in oauth2.ReuseTokenSource it is only called once the previous
+// installation token expires.
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.mintJWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	client := newClient(nil).WithAuthToken(jwt)
+	tok, _, err := client.Apps.CreateInstallationToken(context.Background(), s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token: %w", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: tok.GetToken(),
+		TokenType:   "token",
+		Expiry:      tok.GetExpiresAt().Time,
+	}, nil
+}
+
+func (s *appTokenSource) mintJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	// Backdate issuance to tolerate clock drift; GitHub caps expiry at 10 minutes.
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(s.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign app JWT: %w", err)
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("app private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse app private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("app private key is not an RSA key")
+	}
+	return key, nil
+}
@@ -0,0 +1,51 @@
+package github
+
+import (
+	"log/slog"
+	"net/http"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/codeowners"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// IndexCodeowners caches the CODEOWNERS file of the default branch, from the
+// first of codeowners.Locations that exists. A repository without one is
+// cached with an empty path, so a removed file does not linger.
+func (f *Fetcher) IndexCodeowners(db cache.Store) (string, error) {
+	for _, path := range codeowners.Locations {
+		var file *github.RepositoryContent
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			file, _, resp, err = f.client.Repositories.GetContents(f.ctx, f.owner, f.repo, path, nil)
+			return err
+		})
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if file == nil {
+			continue
+		}
+
+		content, err := file.GetContent()
+		if err != nil {
+			return "", err
+		}
+		return path, db.SaveCodeowners(f.owner, f.repo, path, content)
+	}
+	return "", db.SaveCodeowners(f.owner, f.repo, "", "")
+}
+
+func (f *Fetcher) indexCodeowners(db cache.Store) {
+	path, err := f.IndexCodeowners(db)
+	switch {
+	case err != nil:
+		slog.Warn("Failed to fetch CODEOWNERS", "err", err)
+	case path != "":
+		slog.Info("Cached CODEOWNERS", "path", path)
+	}
+}
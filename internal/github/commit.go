@@ -0,0 +1,131 @@
+package github
+
+import (
+	"database/sql"
+	"fmt"
+
+	"ordiff/internal/cache"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// CommitDetails is one commit with the pull request it was merged through
+// and the patch of every file it changed.
+type CommitDetails struct {
+	Commit      cache.Commit       `json:"commit"`
+	PullRequest *cache.PullRequest `json:"pull_request,omitempty"`
+	Files       []cache.FileChange `json:"files"`
+	Cached      bool               `json:"cached"`
+}
+
+// CommitDetails looks up a commit by its SHA or a unique prefix. The file
+// changes of cached commits come from the cache, or are fetched from the
+// GitHub commit API once and cached. Commits outside the cached history are
+// fetched on every call, so they do not leak into release pairs.
+func (f *Fetcher) CommitDetails(db cache.Store, sha string) (*CommitDetails, error) {
+	commit, err := db.GetCommit(f.owner, f.repo, sha)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	d := &CommitDetails{Cached: true}
+	if commit != nil {
+		d.Commit = *commit
+		if d.Files, err = db.GetCommitFiles(f.owner, f.repo, commit.SHA); err != nil {
+			return nil, err
+		}
+	}
+
+	if commit == nil || d.Files == nil {
+		fetched, files, err := f.fetchCommit(sha)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch commit %s: %w", sha, err)
+		}
+		d.Files = files
+		d.Cached = false
+		if commit == nil {
+			commits := []cache.Commit{*fetched}
+			db.ResolveAuthors(commits)
+			d.Commit = commits[0]
+		} else if err := db.SaveCommitFiles(f.owner, f.repo, commit.SHA, files); err != nil {
+			return nil, fmt.Errorf("failed to cache commit files: %w", err)
+		}
+	}
+
+	if d.Commit.PrNumber != nil {
+		prs, err := db.GetPullRequests(f.owner, f.repo, []int{*d.Commit.PrNumber})
+		if err != nil {
+			return nil, err
+		}
+		if len(prs) > 0 {
+			d.PullRequest = &prs[0]
+		}
+	}
+	return d, nil
+}
+
+// fetchCommit fetches a commit and all of its files, 300 per page.
+func (f *Fetcher) fetchCommit(sha string) (*cache.Commit, []cache.FileChange, error) {
+	var commit *cache.Commit
+	var files []cache.FileChange
+	page := 1
+
+	for {
+		var rc *github.RepositoryCommit
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			rc, resp, err = f.client.Repositories.GetCommit(f.ctx, f.owner, f.repo, sha, &github.ListOptions{
+				Page:    page,
+				PerPage: 300,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if commit == nil {
+			commit = &cache.Commit{
+				SHA:         rc.GetSHA(),
+				Message:     rc.GetCommit().GetMessage(),
+				Author:      rc.GetCommit().GetAuthor().GetName(),
+				AuthorEmail: rc.GetCommit().GetAuthor().GetEmail(),
+				Date:        rc.GetCommit().GetAuthor().GetDate().Time,
+				URL:         rc.GetHTMLURL(),
+				Owner:       f.owner,
+				Repo:        f.repo,
+				PrNumber:    f.extractPrNumber(rc.GetCommit().GetMessage()),
+				Parents:     parentSHAs(rc.Parents),
+			}
+		}
+		for _, file := range rc.Files {
+			files = append(files, cache.FileChange{
+				Filename:         file.GetFilename(),
+				PreviousFilename: file.GetPreviousFilename(),
+				Additions:        file.GetAdditions(),
+				Deletions:        file.GetDeletions(),
+				Changes:          file.GetChanges(),
+				Status:           file.GetStatus(),
+				Patch:            file.GetPatch(),
+				Owner:            f.owner,
+				Repo:             f.repo,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+	return commit, files, nil
+}
+
+// parentSHAs returns the SHAs of a commit's parents, empty rather than nil
+// for a root commit so the cache can tell it from one fetched without them.
+func parentSHAs(parents []*github.Commit) []string {
+	shas := make([]string, len(parents))
+	for i, p := range parents {
+		shas[i] = p.GetSHA()
+	}
+	return shas
+}
@@ -0,0 +1,235 @@
+package github
+
+import (
+	"fmt"
+
+	"ordiff/internal/cache"
+)
+
+// How the file list of a CompareResult was obtained.
+const (
+	FilesDirect     = "direct"
+	FilesAggregated = "aggregated"
+	FilesLive       = "live"
+	FilesNone       = "none"
+)
+
+// cachedFileChanges returns the file changes for a release pair. Only
+// adjacent pairs are indexed, so for non-adjacent releases the changes of
+// every intervening pair are merged instead. Patches are loaded for the
+// files withPatch matches, or for all of them when it is nil.
+func (f *Fetcher) cachedFileChanges(db cache.Store, fromTag, toTag string, withPatch func(filename string) bool) ([]cache.FileChange, string, error) {
+	files, err := f.pairFileChanges(db, fromTag, toTag, withPatch)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(files) > 0 {
+		return files, FilesDirect, nil
+	}
+
+	pairs, err := f.intermediatePairs(db, fromTag, toTag)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(pairs) < 2 {
+		return nil, FilesNone, nil
+	}
+
+	var perPair [][]cache.FileChange
+	for _, p := range pairs {
+		pairFiles, err := f.pairFileChanges(db, p[0], p[1], withPatch)
+		if err != nil {
+			return nil, "", err
+		}
+		perPair = append(perPair, pairFiles)
+	}
+
+	return aggregateFileChanges(perPair, fromTag, toTag), FilesAggregated, nil
+}
+
+// pairFileChanges returns the cached file changes of one release pair, with
+// the patches of the files withPatch matches, or of all when it is nil.
+func (f *Fetcher) pairFileChanges(db cache.Store, fromTag, toTag string, withPatch func(filename string) bool) ([]cache.FileChange, error) {
+	files, err := db.GetFileChangesContext(f.ctx, f.owner, f.repo, fromTag, toTag)
+	if err != nil || len(files) == 0 {
+		return files, err
+	}
+
+	var names []string
+	if withPatch != nil {
+		names = []string{}
+		for _, fc := range files {
+			if withPatch(fc.Filename) {
+				names = append(names, fc.Filename)
+			}
+		}
+	}
+	patches, err := db.GetPatchesContext(f.ctx, f.owner, f.repo, fromTag, toTag, names)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].Patch = patches[files[i].Filename]
+	}
+	return files, nil
+}
+
+// LoadPatches fills in the patches of a comparison of cached releases. They
+// dominate its size, so comparisons only carry those of dependency
+// manifests until a diff is asked for.
+func (f *Fetcher) LoadPatches(db cache.Store, r *CompareResult) error {
+	return f.LoadPatchesFor(db, r, nil)
+}
+
+// LoadPatchesFor is LoadPatches for the files match matches only.
+func (f *Fetcher) LoadPatchesFor(db cache.Store, r *CompareResult, match func(filename string) bool) error {
+	if r.FileSource != FilesDirect && r.FileSource != FilesAggregated {
+		return nil
+	}
+
+	files, _, err := f.cachedFileChanges(db, r.FromRelease.TagName, r.ToRelease.TagName, match)
+	if err != nil {
+		return err
+	}
+	patches := make(map[string]string, len(files))
+	for _, fc := range files {
+		patches[fc.Filename] = fc.Patch
+	}
+	for i := range r.Files {
+		if patch, ok := patches[r.Files[i].Filename]; ok && (match == nil || match(r.Files[i].Filename)) {
+			r.Files[i].Patch = patch
+		}
+	}
+	return nil
+}
+
+// intermediatePairs lists the adjacent release pairs between two releases,
+// oldest first.
+func (f *Fetcher) intermediatePairs(db cache.Store, fromTag, toTag string) ([][2]string, error) {
+	releases, err := db.GetReleases(f.owner, f.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	fromIdx, toIdx := -1, -1
+	for i, r := range releases {
+		switch r.TagName {
+		case fromTag:
+			fromIdx = i
+		case toTag:
+			toIdx = i
+		}
+	}
+	if fromIdx == -1 || toIdx == -1 || fromIdx <= toIdx {
+		return nil, nil
+	}
+
+	var pairs [][2]string
+	for i := fromIdx; i > toIdx; i-- {
+		pairs = append(pairs, [2]string{releases[i].TagName, releases[i-1].TagName})
+	}
+	return pairs, nil
+}
+
+// aggregateFileChanges combines the file changes of consecutive release
+// pairs, oldest first. A file renamed in a later pair carries the churn of
+// its earlier path.
+func aggregateFileChanges(perPair [][]cache.FileChange, fromTag, toTag string) []cache.FileChange {
+	byName := map[string]*cache.FileChange{}
+	var order []string
+
+	for _, files := range perPair {
+		for _, fc := range files {
+			if fc.Status == "renamed" {
+				renameAggregate(byName, order, fc.PreviousFilename, fc.Filename)
+			}
+
+			agg, ok := byName[fc.Filename]
+			if !ok {
+				c := fc
+				c.FromRelease = fromTag
+				c.ToRelease = toTag
+				byName[fc.Filename] = &c
+				order = append(order, fc.Filename)
+				continue
+			}
+
+			agg.Additions += fc.Additions
+			agg.Deletions += fc.Deletions
+			agg.Changes += fc.Changes
+			if fc.Patch != "" {
+				if agg.Patch != "" {
+					agg.Patch += "\n"
+				}
+				agg.Patch += fc.Patch
+			}
+
+			switch {
+			case fc.Status == "removed" && agg.Status == "added":
+				agg.Status = "transient"
+			case fc.Status == "removed":
+				agg.Status = "removed"
+				agg.PreviousFilename = ""
+			case agg.Status == "added" || agg.Status == "transient":
+				agg.Status = "added"
+			case agg.PreviousFilename != "":
+				agg.Status = "renamed"
+			default:
+				agg.Status = "modified"
+			}
+		}
+	}
+
+	changes := make([]cache.FileChange, 0, len(order))
+	for _, name := range order {
+		if byName[name].Status == "transient" {
+			continue
+		}
+		changes = append(changes, *byName[name])
+	}
+	return changes
+}
+
+// renameAggregate moves the changes aggregated for a file's previous path to
+// its new one, keeping the path it had before the range.
+func renameAggregate(byName map[string]*cache.FileChange, order []string, previous, filename string) {
+	agg, ok := byName[previous]
+	if !ok || previous == filename || byName[filename] != nil {
+		return
+	}
+
+	delete(byName, previous)
+	byName[filename] = agg
+	for i, name := range order {
+		if name == previous {
+			order[i] = filename
+			break
+		}
+	}
+
+	agg.Filename = filename
+	if agg.Status != "added" && agg.PreviousFilename == "" {
+		agg.PreviousFilename = previous
+	}
+	if agg.PreviousFilename == filename {
+		agg.PreviousFilename = ""
+	}
+}
+
+// FetchLiveFiles replaces the file list with a direct comparison fetched
+// from the GitHub compare API.
+func (f *Fetcher) FetchLiveFiles(r *CompareResult) error {
+	files, err := f.fetchFileChanges(r.FromRelease.CommitSHA, r.ToRelease.CommitSHA)
+	if err != nil {
+		return fmt.Errorf("failed to fetch live comparison: %w", err)
+	}
+
+	r.Files = make([]cache.FileChange, len(files))
+	for i, fc := range files {
+		fc.FromRelease = r.FromRelease.TagName
+		fc.ToRelease = r.ToRelease.TagName
+		r.Files[i] = *fc
+	}
+	r.FileSource = FilesLive
+	return nil
+}
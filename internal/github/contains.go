@@ -0,0 +1,127 @@
+package github
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+)
+
+// Containment is the earliest release that includes a commit or a pull
+// request. Release is empty if the change has not been released yet.
+type Containment struct {
+	Ref         string     `json:"ref"`
+	Kind        string     `json:"kind"` // "commit" or "pull_request"
+	SHA         string     `json:"sha,omitempty"`
+	PrNumber    *int       `json:"pr_number,omitempty"`
+	FromRelease string     `json:"from_release,omitempty"`
+	Release     string     `json:"release,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	ByDate      bool       `json:"by_date,omitempty"`
+}
+
+// ContainingRelease finds the earliest release whose range includes ref, a
+// commit SHA (or a unique prefix) or a pull request number such as "#1234".
+// The range comes from the indexed release pairs; when no pair contains the
+// change, the first release published after its date is used and ByDate is
+// set.
+func (f *Fetcher) ContainingRelease(db cache.Store, ref string) (*Containment, error) {
+	ref = strings.TrimSpace(ref)
+	c := &Containment{Ref: ref}
+
+	var shas []string
+	var date *time.Time
+	if number, ok := parsePrRef(ref); ok {
+		c.Kind = "pull_request"
+		c.PrNumber = &number
+
+		prs, err := db.GetPullRequests(f.owner, f.repo, []int{number})
+		if err != nil {
+			return nil, err
+		}
+		var mergeSHA string
+		if len(prs) > 0 {
+			mergeSHA = prs[0].MergeCommitSHA
+			date = prs[0].MergedAt
+		}
+		commits, err := db.GetPullRequestCommits(f.owner, f.repo, number, mergeSHA)
+		if err != nil {
+			return nil, err
+		}
+		if len(prs) == 0 && len(commits) == 0 {
+			return nil, fmt.Errorf("pull request #%d is not cached", number)
+		}
+		for _, commit := range commits {
+			shas = append(shas, commit.SHA)
+		}
+		if date == nil && len(commits) > 0 {
+			date = &commits[len(commits)-1].Date
+		}
+	} else {
+		c.Kind = "commit"
+
+		commit, err := db.GetCommit(f.owner, f.repo, ref)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("commit %s is not cached", ref)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.SHA = commit.SHA
+		c.PrNumber = commit.PrNumber
+		shas = []string{commit.SHA}
+		date = &commit.Date
+	}
+
+	from, to, sha, err := db.FirstPairContaining(f.owner, f.repo, shas)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		c.SHA, c.FromRelease, c.Release = sha, from, to
+		r, err := db.GetRelease(f.owner, f.repo, to)
+		if err != nil {
+			return nil, err
+		}
+		c.PublishedAt = &r.PublishedAt
+		return c, nil
+	}
+
+	if date == nil {
+		return c, nil
+	}
+	releases, err := db.GetReleases(f.owner, f.repo)
+	if err != nil {
+		return nil, err
+	}
+	// GetReleases is newest first.
+	for i := len(releases) - 1; i >= 0; i-- {
+		if !releases[i].PublishedAt.Before(*date) {
+			c.Release = releases[i].TagName
+			c.PublishedAt = &releases[i].PublishedAt
+			if i+1 < len(releases) {
+				c.FromRelease = releases[i+1].TagName
+			}
+			c.ByDate = true
+			break
+		}
+	}
+	return c, nil
+}
+
+// parsePrRef reports whether ref names a pull request: "#1234", or a number
+// too short to be a commit SHA prefix.
+func parsePrRef(ref string) (int, bool) {
+	digits := strings.TrimPrefix(ref, "#")
+	if digits == ref && len(ref) >= 7 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,25 @@
+package github
+
+import (
+	"net/http"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// FileContent returns the content of a file at a ref, or an empty string
+// when the file does not exist there.
+func (f *Fetcher) FileContent(path, ref string) (string, error) {
+	var file *github.RepositoryContent
+	var resp *github.Response
+	err := f.withRetry(func() (err error) {
+		file, _, resp, err = f.client.Repositories.GetContents(f.ctx, f.owner, f.repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+		return err
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil || file == nil {
+		return "", err
+	}
+	return file.GetContent()
+}
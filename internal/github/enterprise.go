@@ -0,0 +1,53 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// enterpriseURL is the REST API root of the GitHub Enterprise Server
+// instance set with UseEnterprise, or empty for github.com.
+var enterpriseURL string
+
+// UseEnterprise points every Fetcher created afterwards at a GitHub
+// Enterprise Server instance, given its API URL
+// (https://ghe.example.com/api/v3) or its web root. It returns the web root,
+// where releases and pull requests are linked. github.com URLs keep the
+// defaults.
+func UseEnterprise(apiURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(apiURL))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("expected a URL such as https://ghe.example.com/api/v3")
+	}
+	if u.Host == "github.com" || u.Host == "api.github.com" {
+		return "https://github.com", nil
+	}
+
+	// go-github appends /api/v3/ when the URL lacks it.
+	if _, err := github.NewClient(nil).WithEnterpriseURLs(u.String(), u.String()); err != nil {
+		return "", err
+	}
+	web := u.Scheme + "://" + u.Host
+	enterpriseURL = u.String()
+	GraphQLURL = web + "/api/graphql"
+	return web, nil
+}
+
+// newClient returns a GitHub API client over httpClient, for github.com or
+// the instance set with UseEnterprise.
+func newClient(httpClient *http.Client) *github.Client {
+	client := github.NewClient(httpClient)
+	if enterpriseURL != "" {
+		if c, err := client.WithEnterpriseURLs(enterpriseURL, enterpriseURL); err == nil {
+			client = c
+		}
+	}
+	return client
+}
@@ -2,12 +2,22 @@ package github
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"ordiff/internal/cache"
+	"ordiff/internal/deps"
+	"ordiff/internal/provider"
+	"ordiff/internal/retry"
 
 	"github.com/google/go-github/v81/github"
 	"golang.org/x/oauth2"
@@ -18,6 +28,25 @@ type Fetcher struct {
 	repo   string
 	client *github.Client
 	ctx    context.Context
+
+	concurrency  int
+	tagReleases  bool
+	releaseRange provider.Range
+
+	onStatus    func(msg string)
+	onProgress  func(done, total int)
+	retryPolicy retry.Policy
+
+	// Rate-limit state shared by concurrent workers.
+	mu            sync.Mutex
+	pausedUntil   time.Time
+	cooldownUntil time.Time
+	nextRequest   time.Time
+	rateRemaining int
+	rateReset     time.Time
+
+	httpCache     cache.Store
+	httpCacheHits atomic.Int64
 }
 
 func NewFetcher(owner, repo string, token *string) *Fetcher {
@@ -28,23 +57,61 @@ func NewFetcher(owner, repo string, token *string) *Fetcher {
 		)
 		httpClient = oauth2.NewClient(context.Background(), ts)
 	}
-	return &Fetcher{
-		owner:  owner,
-		repo:   repo,
-		client: github.NewClient(httpClient),
-		ctx:    context.Background(),
+	return newFetcher(owner, repo, httpClient)
+}
+
+func newFetcher(owner, repo string, httpClient *http.Client) *Fetcher {
+	f := &Fetcher{
+		owner:       owner,
+		repo:        repo,
+		ctx:         context.Background(),
+		retryPolicy: retry.Default,
+	}
+
+	client := &http.Client{}
+	if httpClient != nil {
+		*client = *httpClient
 	}
+	client.Transport = &rateLimitTransport{
+		base: &conditionalTransport{base: client.Transport, f: f},
+		f:    f,
+	}
+	f.client = newClient(client)
+	return f
+}
+
+// SetConcurrency sets how many release pairs are fetched in parallel while
+// indexing. Values below 1 mean one.
+func (f *Fetcher) SetConcurrency(n int) {
+	f.concurrency = n
+}
+
+// SetContext makes indexing stop when ctx is cancelled: requests in flight
+// are aborted, no further release pairs are started, and the index is left
+// unfinished so Resume can pick it up. Comparisons stop reading the cache.
+func (f *Fetcher) SetContext(ctx context.Context) {
+	f.ctx = ctx
+}
+
+// SetProgress registers a callback told how many of the release pairs being
+// indexed are done, fetched or failed, each time one is.
+func (f *Fetcher) SetProgress(fn func(done, total int)) {
+	f.onProgress = fn
 }
 
-func (f *Fetcher) IndexAll(db *cache.DB) error {
-	log.Printf("Fetching releases for %s/%s...\n", f.owner, f.repo)
+func (f *Fetcher) IndexAll(db cache.Store) error {
+	slog.Info("Fetching releases", "repo", f.owner+"/"+f.repo)
 
 	releases, err := f.fetchAllReleases()
 	if err != nil {
 		return fmt.Errorf("failed to fetch releases: %w", err)
 	}
 
-	log.Printf("Found %d releases, caching...\n", len(releases))
+	slog.Info("Found releases, caching", "count", len(releases))
+
+	// Cached comparisons are dropped once the new rows are written, so none
+	// computed from the old ones while indexing outlives the index.
+	defer f.invalidateComparisons(db)
 
 	for _, r := range releases {
 		if err := db.SaveRelease(r); err != nil {
@@ -53,11 +120,186 @@ func (f *Fetcher) IndexAll(db *cache.DB) error {
 	}
 
 	cachedPairs, _ := db.GetReleasePairCount(f.owner, f.repo)
-	log.Printf("Already cached %d file change records\n", cachedPairs)
+	slog.Info("Already cached file change records", "count", cachedPairs)
+
+	pairsErr := f.indexPairs(db, releases, false, f.fetchPair)
+	if pairsErr != nil && !provider.Partial(pairsErr) {
+		return pairsErr
+	}
+	f.indexPullRequests(db, time.Time{})
+	f.indexIssues(db, time.Time{})
+	f.indexCodeowners(db)
+	f.logHTTPCacheHits()
+	return pairsErr
+}
+
+// Update caches releases published since the newest cached one and fills in
+// any release pairs that are still missing, without re-listing every release.
+func (f *Fetcher) Update(db cache.Store) error {
+	slog.Info("Checking for new releases", "repo", f.owner+"/"+f.repo)
 
-	log.Printf("Fetching commits and files for missing release pairs...\n")
+	fresh, err := f.FetchNewReleases(db)
+	if err != nil {
+		return fmt.Errorf("failed to fetch releases: %w", err)
+	}
+
+	slog.Info("Found new releases, caching", "count", len(fresh))
+
+	for _, r := range fresh {
+		if err := db.SaveRelease(r); err != nil {
+			return fmt.Errorf("failed to save release %s: %w", r.TagName, err)
+		}
+	}
+
+	releases, err := CachedReleases(db, f.owner, f.repo)
+	if err != nil {
+		return fmt.Errorf("failed to read cached releases: %w", err)
+	}
+	if err := f.ResolveCachedReleases(db, releases); err != nil {
+		return err
+	}
+
+	pairsErr := f.indexPairs(db, releases, false, f.fetchPair)
+	if pairsErr != nil && !provider.Partial(pairsErr) {
+		return pairsErr
+	}
 
-	processed := 0
+	since, err := db.LatestPullRequestMerge(f.owner, f.repo)
+	if err != nil {
+		slog.Warn("Failed to read cached pull requests", "err", err)
+	}
+	f.indexPullRequests(db, since)
+
+	closed, err := db.LatestIssueClose(f.owner, f.repo)
+	if err != nil {
+		slog.Warn("Failed to read cached issues", "err", err)
+	}
+	f.indexIssues(db, closed)
+	f.indexCodeowners(db)
+	f.logHTTPCacheHits()
+	return pairsErr
+}
+
+func (f *Fetcher) indexPullRequests(db cache.Store, since time.Time) {
+	slog.Info("Fetching merged pull requests")
+	n, err := f.IndexPullRequests(db, since)
+	if err != nil {
+		slog.Warn("Failed to index pull requests", "err", err)
+	}
+	slog.Info("Cached merged pull requests", "count", n)
+	if n > 0 {
+		f.invalidateComparisons(db)
+	}
+}
+
+// invalidateComparisons drops the repository's cached comparisons after
+// releases, pairs, pull requests or issues they are computed from were
+// written.
+func (f *Fetcher) invalidateComparisons(db cache.Store) {
+	if err := db.ClearCompareCache(f.owner, f.repo); err != nil {
+		slog.Warn("Failed to invalidate compare cache", "err", err)
+	}
+}
+
+// Resume continues an index that was interrupted, without listing releases
+// again: the pairs of the releases cached by the aborted run that are still
+// missing are fetched.
+func (f *Fetcher) Resume(db cache.Store) error {
+	pairsErr := f.resume(db, f.fetchPair)
+	if pairsErr != nil && !provider.Partial(pairsErr) {
+		return pairsErr
+	}
+	f.indexPullRequests(db, time.Time{})
+	f.indexIssues(db, time.Time{})
+	return pairsErr
+}
+
+func (f *Fetcher) resume(db cache.Store, fetch func(from, to *cache.Release) (*pairData, error)) error {
+	run, err := db.GetIndexRun(f.owner, f.repo)
+	if err != nil {
+		return fmt.Errorf("failed to read index progress: %w", err)
+	}
+	if run == nil || run.Finished {
+		return fmt.Errorf("no interrupted index of %s/%s to resume", f.owner, f.repo)
+	}
+
+	slog.Info("Resuming index", "repo", f.owner+"/"+f.repo, "started", run.StartedAt.Local().Format("2006-01-02 15:04"),
+		"done", run.DonePairs, "total", run.TotalPairs)
+
+	releases, err := CachedReleases(db, f.owner, f.repo)
+	if err != nil {
+		return fmt.Errorf("failed to read cached releases: %w", err)
+	}
+	if err := f.ResolveCachedReleases(db, releases); err != nil {
+		return err
+	}
+
+	return f.indexPairs(db, releases, true, fetch)
+}
+
+// FetchNewReleases lists the releases that are not cached yet. GitHub returns
+// releases newest first, so paging stops once a page reaches a cached tag.
+// The assets of the cached releases listed on the way are saved, which
+// records their download counts.
+func (f *Fetcher) FetchNewReleases(db cache.Store) ([]*cache.Release, error) {
+	cached, err := db.GetReleases(f.owner, f.repo)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(cached))
+	for _, r := range cached {
+		known[r.TagName] = true
+	}
+
+	releases, err := f.fetchReleases(known)
+	if err != nil {
+		return nil, err
+	}
+
+	var fresh []*cache.Release
+	for _, r := range releases {
+		if !known[r.TagName] {
+			fresh = append(fresh, r)
+			continue
+		}
+		if r.Assets != nil {
+			if err := db.SaveReleaseAssets(r); err != nil {
+				slog.Warn("Failed to save release assets", "tag", r.TagName, "err", err)
+			}
+		}
+	}
+	return fresh, nil
+}
+
+// CachedReleases returns the cached releases of a repository, newest first,
+// in the form the indexing loops work with.
+func CachedReleases(db cache.Store, owner, repo string) ([]*cache.Release, error) {
+	cached, err := db.GetReleases(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	releases := make([]*cache.Release, len(cached))
+	for i := range cached {
+		releases[i] = &cached[i]
+	}
+	return releases, nil
+}
+
+// pairData is everything fetched for one release pair.
+type pairData struct {
+	commits []*cache.Commit
+	files   []*cache.FileChange
+	prs     []*cache.PullRequest
+}
+
+// indexPairs fetches every release pair that is not cached yet with fetch,
+// using up to f.concurrency workers. Database writes are serialized; SQLite
+// allows only one writer at a time. If the context is cancelled, the pairs
+// fetched so far are kept and the index run is left open for Resume.
+func (f *Fetcher) indexPairs(db cache.Store, releases []*cache.Release, resuming bool, fetch func(from, to *cache.Release) (*pairData, error)) error {
+	slog.Info("Fetching commits and files for missing release pairs")
+
+	var pending [][2]*cache.Release
 	skipped := 0
 	for i := 0; i < len(releases)-1; i++ {
 		from := releases[i+1]
@@ -65,58 +307,188 @@ func (f *Fetcher) IndexAll(db *cache.DB) error {
 
 		alreadyCached, err := db.HasFileChangesCached(f.owner, f.repo, from.TagName, to.TagName)
 		if err != nil {
-			log.Printf("    Warning: failed to check cache: %v\n", err)
+			slog.Warn("Failed to check cache", "err", err)
+		}
+		if alreadyCached && db.IsPairStale(f.owner, f.repo, from.TagName, to.TagName) {
+			slog.Info("Refreshing pair cached by an older ordiff", "from", from.TagName, "to", to.TagName)
+			if err := db.DeleteFileChanges(f.owner, f.repo, from.TagName, to.TagName); err != nil {
+				slog.Warn("Failed to clear stale file changes", "err", err)
+			}
+			alreadyCached = false
 		}
 		if alreadyCached {
 			skipped++
-			log.Printf("  Skipping %s → %s (already cached)\n", from.TagName, to.TagName)
+			slog.Debug("Skipping pair, already cached", "from", from.TagName, "to", to.TagName)
 			continue
 		}
+		pending = append(pending, [2]*cache.Release{from, to})
+	}
 
-		processed++
-		log.Printf("  Processing %s → %s (%d/%d, %d skipped)\n", from.TagName, to.TagName, processed, len(releases)-1-skipped, skipped)
+	if resuming {
+		slog.Info("Resuming", "pairs_left", len(pending))
+	} else if err := db.StartIndexRun(f.owner, f.repo, len(pending)); err != nil {
+		slog.Warn("Failed to record index progress", "err", err)
+	}
 
-		commits, err := f.fetchCommits(from.CommitSHA, to.CommitSHA)
-		if err != nil {
-			log.Printf("    Warning: failed to fetch commits: %v\n", err)
-			continue
+	workers := max(f.concurrency, 1)
+	jobs := make(chan [2]*cache.Release)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	processed, saved := 0, 0
+	var failed []provider.PairFailure
+	progress := func() {
+		if f.onProgress != nil {
+			f.onProgress(saved+len(failed), len(pending))
 		}
+	}
+	progress()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pair := range jobs {
+				from, to := pair[0], pair[1]
+
+				mu.Lock()
+				processed++
+				slog.Info("Processing pair", "from", from.TagName, "to", to.TagName, "done", processed, "total", len(pending), "skipped", skipped)
+				mu.Unlock()
+
+				data, err := fetch(from, to)
+				if err == nil {
+					mu.Lock()
+					err = f.savePair(db, from, to, data)
+					mu.Unlock()
+				}
+				if err != nil {
+					if f.ctx.Err() != nil {
+						continue
+					}
+					slog.Error("Failed to fetch pair", "from", from.TagName, "to", to.TagName, "err", err)
+					mu.Lock()
+					failed = append(failed, provider.PairFailure{From: from.TagName, To: to.TagName, Err: err})
+					progress()
+					mu.Unlock()
+					continue
+				}
 
-		for _, c := range commits {
-			if err := db.SaveCommit(c); err != nil {
-				log.Printf("    Warning: failed to save commit: %v\n", err)
+				mu.Lock()
+				saved++
+				progress()
+				if err := db.AdvanceIndexRun(f.owner, f.repo); err != nil {
+					slog.Warn("Failed to record index progress", "err", err)
+				}
+				mu.Unlock()
 			}
-		}
+		}()
+	}
 
-		files, err := f.fetchFileChanges(from.CommitSHA, to.CommitSHA)
-		if err != nil {
-			log.Printf("    Warning: failed to fetch files: %v\n", err)
-			continue
+	for _, pair := range pending {
+		if f.ctx.Err() != nil {
+			break
 		}
+		jobs <- pair
+	}
+	close(jobs)
+	wg.Wait()
+	if saved > 0 {
+		f.invalidateComparisons(db)
+	}
 
-		for _, fc := range files {
-			fc.FromRelease = from.TagName
-			fc.ToRelease = to.TagName
-			if err := db.SaveFileChange(fc); err != nil {
-				log.Printf("    Warning: failed to save file change: %v\n", err)
-			}
-		}
+	if err := f.ctx.Err(); err != nil {
+		slog.Warn("Indexing cancelled", "done", saved, "total", len(pending))
+		return err
+	}
+
+	// The run stays open, so --resume fetches just the pairs that failed.
+	if len(failed) > 0 {
+		slog.Warn("Indexing incomplete", "failed", len(failed), "total", len(pending))
+		return &provider.PairErrors{Failed: failed, Total: len(pending)}
+	}
+
+	if err := db.FinishIndexRun(f.owner, f.repo); err != nil {
+		slog.Warn("Failed to record index progress", "err", err)
+	}
+
+	slog.Info("Indexing complete", "processed", processed, "skipped", skipped)
+	return nil
+}
+
+// fetchPair fetches a release pair through the REST compare API.
+func (f *Fetcher) fetchPair(from, to *cache.Release) (*pairData, error) {
+	commits, err := f.fetchCommits(from.CommitSHA, to.CommitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commits: %w", err)
+	}
+
+	files, err := f.fetchFileChanges(from.CommitSHA, to.CommitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch files: %w", err)
+	}
 
-		log.Println("    Sleeping 100ms to avoid rate limits...")
+	return &pairData{commits: commits, files: files}, nil
+}
+
+func (f *Fetcher) savePair(db cache.Store, from, to *cache.Release, data *pairData) error {
+	for _, fc := range data.files {
+		fc.FromRelease = from.TagName
+		fc.ToRelease = to.TagName
 	}
 
-	log.Printf("Indexing complete! Processed %d pairs, skipped %d already cached\n", processed, skipped)
+	if err := db.SavePairContext(f.ctx, &cache.PairData{
+		Owner:        f.owner,
+		Repo:         f.repo,
+		FromRelease:  from.TagName,
+		ToRelease:    to.TagName,
+		Commits:      data.commits,
+		PullRequests: data.prs,
+		Files:        data.files,
+	}); err != nil {
+		return fmt.Errorf("failed to save release pair: %w", err)
+	}
 	return nil
 }
 
+// FetchReleases lists the repository's releases on GitHub without touching
+// the cache.
+func (f *Fetcher) FetchReleases() ([]*cache.Release, error) {
+	return f.fetchAllReleases()
+}
+
 func (f *Fetcher) fetchAllReleases() ([]*cache.Release, error) {
+	releases, err := f.fetchReleases(nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.releaseRange.Apply(releases)
+}
+
+// SetRange limits IndexAll to part of the release history.
+func (f *Fetcher) SetRange(r provider.Range) {
+	f.releaseRange = r
+}
+
+// fetchReleases pages through the release list, stopping after the first
+// page that contains a tag in known.
+func (f *Fetcher) fetchReleases(known map[string]bool) ([]*cache.Release, error) {
+	if f.tagReleases {
+		return f.fetchTagReleases(known)
+	}
+
 	var allReleases []*cache.Release
 	page := 1
+	reachedKnown := false
 
 	for {
-		releases, resp, err := f.client.Repositories.ListReleases(f.ctx, f.owner, f.repo, &github.ListOptions{
-			Page:    page,
-			PerPage: 100,
+		var releases []*github.RepositoryRelease
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			releases, resp, err = f.client.Repositories.ListReleases(f.ctx, f.owner, f.repo, &github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			})
+			return err
 		})
 		if err != nil {
 			return nil, err
@@ -136,19 +508,139 @@ func (f *Fetcher) fetchAllReleases() ([]*cache.Release, error) {
 				Body:        r.GetBody(),
 				Owner:       f.owner,
 				Repo:        f.repo,
+				Assets:      releaseAssets(r),
 			}
 			allReleases = append(allReleases, release)
+			if known[release.TagName] {
+				reachedKnown = true
+			}
 		}
 
-		if resp.NextPage == 0 {
+		if resp.NextPage == 0 || reachedKnown {
 			break
 		}
 		page = resp.NextPage
 	}
 
+	if len(allReleases) == 0 {
+		slog.Info("No GitHub releases found, indexing tags instead", "repo", f.owner+"/"+f.repo)
+		return f.fetchTagReleases(known)
+	}
+
+	var fresh []*cache.Release
+	for _, r := range allReleases {
+		if !known[r.TagName] {
+			fresh = append(fresh, r)
+		}
+	}
+	if err := f.resolveTagCommits(fresh); err != nil {
+		return nil, fmt.Errorf("failed to resolve tags: %w", err)
+	}
+
 	return allReleases, nil
 }
 
+// resolveTagCommits points releases at the commit their tag resolves to.
+// The release API only reports target_commitish, usually the branch the
+// release was cut from, which keeps moving after the release. The tag list
+// is paged until every release is found; /tags reports the commit annotated
+// tags point at, not the tag object. Releases whose tag is missing keep
+// target_commitish.
+func (f *Fetcher) resolveTagCommits(releases []*cache.Release) error {
+	want := make(map[string][]*cache.Release, len(releases))
+	for _, r := range releases {
+		want[r.TagName] = append(want[r.TagName], r)
+	}
+
+	page := 1
+	for len(want) > 0 {
+		var tags []*github.RepositoryTag
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			tags, resp, err = f.client.Repositories.ListTags(f.ctx, f.owner, f.repo, &github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, t := range tags {
+			for _, r := range want[t.GetName()] {
+				r.CommitSHA = t.GetCommit().GetSHA()
+			}
+			delete(want, t.GetName())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	for tag := range want {
+		slog.Warn("Tag not found, using the release target instead", "tag", tag)
+	}
+	return nil
+}
+
+// ResolveCachedReleases re-resolves cached releases that still point at a
+// branch rather than a commit, as releases cached before tags were resolved
+// do, and saves the corrected releases.
+func (f *Fetcher) ResolveCachedReleases(db cache.Store, releases []*cache.Release) error {
+	var unresolved []*cache.Release
+	for _, r := range releases {
+		if !isCommitSHA(r.CommitSHA) {
+			unresolved = append(unresolved, r)
+		}
+	}
+	if len(unresolved) == 0 {
+		return nil
+	}
+
+	slog.Info("Resolving the tags of cached releases", "count", len(unresolved))
+	if err := f.resolveTagCommits(unresolved); err != nil {
+		return fmt.Errorf("failed to resolve tags: %w", err)
+	}
+	for _, r := range unresolved {
+		if err := db.SaveRelease(r); err != nil {
+			return fmt.Errorf("failed to save release %s: %w", r.TagName, err)
+		}
+	}
+	return nil
+}
+
+// releaseAssets converts the assets of a listed release, never nil so a
+// release whose assets were all deleted clears the cached ones.
+func releaseAssets(r *github.RepositoryRelease) []cache.ReleaseAsset {
+	assets := make([]cache.ReleaseAsset, 0, len(r.Assets))
+	for _, a := range r.Assets {
+		assets = append(assets, cache.ReleaseAsset{
+			Name:          a.GetName(),
+			Size:          int64(a.GetSize()),
+			DownloadCount: int64(a.GetDownloadCount()),
+			ContentType:   a.GetContentType(),
+			Digest:        a.GetDigest(),
+			URL:           a.GetBrowserDownloadURL(),
+		})
+	}
+	return assets
+}
+
+func isCommitSHA(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
 func (f *Fetcher) fetchCommits(fromSHA, toSHA string) ([]*cache.Commit, error) {
 	if fromSHA == "" || toSHA == "" {
 		return []*cache.Commit{}, nil
@@ -158,9 +650,14 @@ func (f *Fetcher) fetchCommits(fromSHA, toSHA string) ([]*cache.Commit, error) {
 	page := 1
 
 	for {
-		commits, resp, err := f.client.Repositories.CompareCommits(f.ctx, f.owner, f.repo, fromSHA, toSHA, &github.ListOptions{
-			Page:    page,
-			PerPage: 100,
+		var commits *github.CommitsComparison
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			commits, resp, err = f.client.Repositories.CompareCommits(f.ctx, f.owner, f.repo, fromSHA, toSHA, &github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			})
+			return err
 		})
 		if err != nil {
 			return nil, err
@@ -176,6 +673,7 @@ func (f *Fetcher) fetchCommits(fromSHA, toSHA string) ([]*cache.Commit, error) {
 				URL:         c.GetHTMLURL(),
 				Owner:       f.owner,
 				Repo:        f.repo,
+				Parents:     parentSHAs(c.Parents),
 			}
 
 			prNum := f.extractPrNumber(c.GetCommit().GetMessage())
@@ -200,27 +698,41 @@ func (f *Fetcher) fetchFileChanges(fromSHA, toSHA string) ([]*cache.FileChange,
 		return []*cache.FileChange{}, nil
 	}
 
-	diff, _, err := f.client.Repositories.CompareCommits(f.ctx, f.owner, f.repo, fromSHA, toSHA, nil)
+	diff, err := f.fetchComparison(fromSHA, toSHA)
 	if err != nil {
 		return nil, err
 	}
+	return f.fileChanges(diff), nil
+}
 
+// fetchComparison fetches the first page of a comparison, which carries all
+// of its files and up to 250 of its commits.
+func (f *Fetcher) fetchComparison(fromSHA, toSHA string) (*github.CommitsComparison, error) {
+	var diff *github.CommitsComparison
+	err := f.withRetry(func() (err error) {
+		diff, _, err = f.client.Repositories.CompareCommits(f.ctx, f.owner, f.repo, fromSHA, toSHA, nil)
+		return err
+	})
+	return diff, err
+}
+
+func (f *Fetcher) fileChanges(diff *github.CommitsComparison) []*cache.FileChange {
 	var changes []*cache.FileChange
 	for _, file := range diff.Files {
 		change := &cache.FileChange{
-			Filename:  file.GetFilename(),
-			Additions: file.GetAdditions(),
-			Deletions: file.GetDeletions(),
-			Changes:   file.GetChanges(),
-			Status:    file.GetStatus(),
-			Patch:     file.GetPatch(),
-			Owner:     f.owner,
-			Repo:      f.repo,
+			Filename:         file.GetFilename(),
+			PreviousFilename: file.GetPreviousFilename(),
+			Additions:        file.GetAdditions(),
+			Deletions:        file.GetDeletions(),
+			Changes:          file.GetChanges(),
+			Status:           file.GetStatus(),
+			Patch:            file.GetPatch(),
+			Owner:            f.owner,
+			Repo:             f.repo,
 		}
 		changes = append(changes, change)
 	}
-
-	return changes, nil
+	return changes
 }
 
 func (f *Fetcher) extractPrNumber(msg string) *int {
@@ -247,175 +759,123 @@ func (f *Fetcher) extractPrNumber(msg string) *int {
 	return nil
 }
 
-func (f *Fetcher) GetCompareData(db *cache.DB, fromTag, toTag string) (*CompareResult, error) {
-	fromRelease, err := db.GetRelease(f.owner, f.repo, fromTag)
+// GetCompareData returns the comparison between two cached releases, served
+// from the precomputed compare cache when `ordiff warm` has populated it.
+func (f *Fetcher) GetCompareData(db cache.Store, fromTag, toTag string) (*CompareResult, error) {
+	data, ok, err := db.GetCompareCacheContext(f.ctx, f.owner, f.repo, fromTag, toTag)
+	if err != nil {
+		slog.Warn("Failed to read compare cache", "err", err)
+	}
+	if ok {
+		var result CompareResult
+		if err := json.Unmarshal([]byte(data), &result); err == nil {
+			db.ResolveAuthors(result.Commits)
+			return &result, nil
+		}
+	}
+	return f.ComputeCompareData(db, fromTag, toTag)
+}
+
+// WarmCompareData recomputes a comparison and stores it in the compare cache.
+func (f *Fetcher) WarmCompareData(db cache.Store, fromTag, toTag string) (*CompareResult, error) {
+	result, err := f.ComputeCompareData(db, fromTag, toTag)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SaveCompareCache(f.owner, f.repo, fromTag, toTag, string(data)); err != nil {
+		return nil, fmt.Errorf("failed to save compare cache: %w", err)
+	}
+	return result, nil
+}
+
+func (f *Fetcher) ComputeCompareData(db cache.Store, fromTag, toTag string) (*CompareResult, error) {
+	fromRelease, err := db.GetReleaseContext(f.ctx, f.owner, f.repo, fromTag)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%s: %w", fromTag, ErrReleaseNotCached)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("release %s not found: %w", fromTag, err)
 	}
 
-	toRelease, err := db.GetRelease(f.owner, f.repo, toTag)
+	toRelease, err := db.GetReleaseContext(f.ctx, f.owner, f.repo, toTag)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%s: %w", toTag, ErrReleaseNotCached)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("release %s not found: %w", toTag, err)
 	}
 
-	commits, err := db.GetCommitsBetween(f.owner, f.repo, fromTag, toTag)
+	ranges, err := db.CommitRangesContext(f.ctx, f.owner, f.repo, [][2]string{{fromTag, toTag}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve commits: %w", err)
+	}
+	commitRange := ranges[0]
+
+	commits, err := db.SearchCommitsInContext(f.ctx, commitRange, cache.CommitQuery{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commits: %w", err)
 	}
 
-	files, err := db.GetFileChanges(f.owner, f.repo, fromTag, toTag)
+	files, strategy, err := f.cachedFileChanges(db, fromTag, toTag, deps.IsManifest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get files: %w", err)
 	}
 
-	prCount, err := db.PrCountBetween(f.owner, f.repo, fromTag, toTag)
+	prCount, err := db.PrCountInContext(f.ctx, commitRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count PRs: %w", err)
 	}
 
-	return &CompareResult{
-		FromRelease: fromRelease,
-		ToRelease:   toRelease,
-		Commits:     commits,
-		Files:       files,
-		PrCount:     prCount,
-	}, nil
-}
-
-func (f *Fetcher) FetchAllReleasesForIndexing(onProgress func(current, total int)) ([]*cache.Release, error) {
-	var allReleases []*cache.Release
-	page := 1
-	totalPages := 0
-
-	for {
-		releases, resp, err := f.client.Repositories.ListReleases(f.ctx, f.owner, f.repo, &github.ListOptions{
-			Page:    page,
-			PerPage: 100,
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		for _, r := range releases {
-			commitSHA := ""
-			if r.TargetCommitish != nil && *r.TargetCommitish != "" {
-				commitSHA = *r.TargetCommitish
-			}
-
-			release := &cache.Release{
-				TagName:     r.GetTagName(),
-				Name:        r.GetName(),
-				PublishedAt: r.GetPublishedAt().Time,
-				CommitSHA:   commitSHA,
-				Body:        r.GetBody(),
-				Owner:       f.owner,
-				Repo:        f.repo,
-			}
-			allReleases = append(allReleases, release)
-		}
-
-		if resp.NextPage == 0 {
-			break
-		}
-		if totalPages == 0 && resp.NextPage > page {
-			totalPages = resp.NextPage
-		}
-		page = resp.NextPage
-
-		if onProgress != nil {
-			onProgress(page*100/totalPages, 100)
-		}
+	prs, err := f.mergedPullRequests(db, commits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull requests: %w", err)
 	}
 
-	return allReleases, nil
+	return &CompareResult{
+		FromRelease:  fromRelease,
+		ToRelease:    toRelease,
+		Commits:      commits,
+		Files:        files,
+		PrCount:      prCount,
+		PullRequests: prs,
+		FileSource:   strategy,
+		MergeBase:    commitRange.MergeBase(),
+	}, nil
 }
 
-func (f *Fetcher) FetchCommitsForIndexing(fromSHA, toSHA string, onProgress func(current, total int)) ([]*cache.Commit, error) {
-	if fromSHA == "" || toSHA == "" {
-		return []*cache.Commit{}, nil
-	}
-
-	var allCommits []*cache.Commit
-	page := 1
-	totalPages := 0
-
-	for {
-		commits, resp, err := f.client.Repositories.CompareCommits(f.ctx, f.owner, f.repo, fromSHA, toSHA, &github.ListOptions{
-			Page:    page,
-			PerPage: 100,
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		for _, c := range commits.Commits {
-			commit := &cache.Commit{
-				SHA:         c.GetSHA(),
-				Message:     c.GetCommit().GetMessage(),
-				Author:      c.GetCommit().GetAuthor().GetName(),
-				AuthorEmail: c.GetCommit().GetAuthor().GetEmail(),
-				Date:        c.GetCommit().GetAuthor().GetDate().Time,
-				URL:         c.GetHTMLURL(),
-				Owner:       f.owner,
-				Repo:        f.repo,
-			}
-
-			prNum := f.extractPrNumber(c.GetCommit().GetMessage())
-			if prNum != nil {
-				commit.PrNumber = prNum
-			}
-
-			allCommits = append(allCommits, commit)
-		}
-
-		if resp.NextPage == 0 {
-			break
-		}
-		if totalPages == 0 && resp.NextPage > page {
-			totalPages = resp.NextPage
-		}
-		page = resp.NextPage
-
-		if onProgress != nil {
-			onProgress(page*100/totalPages, 100)
-		}
-	}
-
-	return allCommits, nil
+type CompareResult struct {
+	FromRelease  *cache.Release
+	ToRelease    *cache.Release
+	Commits      []cache.Commit
+	Files        []cache.FileChange
+	PrCount      int
+	PullRequests []cache.PullRequest
+	FileSource   string
+	// MergeBase is the newest commit both releases contain, the from
+	// release's own commit unless the releases are on diverging branches,
+	// and empty when the cached commit graph cannot tell.
+	MergeBase string
 }
 
-func (f *Fetcher) FetchFileChangesForIndexing(fromSHA, toSHA string) ([]*cache.FileChange, error) {
-	if fromSHA == "" || toSHA == "" {
-		return []*cache.FileChange{}, nil
+// ETag returns a content hash of the comparison so consumers can detect
+// changes without diffing the whole payload. Patches are left out: they
+// follow from the files and the release commits, so the tag is the same
+// whether or not they were loaded.
+func (r *CompareResult) ETag() string {
+	bare := *r
+	bare.Files = make([]cache.FileChange, len(r.Files))
+	for i, fc := range r.Files {
+		fc.Patch = ""
+		bare.Files[i] = fc
 	}
-
-	diff, _, err := f.client.Repositories.CompareCommits(f.ctx, f.owner, f.repo, fromSHA, toSHA, nil)
+	b, err := json.Marshal(&bare)
 	if err != nil {
-		return nil, err
+		return ""
 	}
-
-	var changes []*cache.FileChange
-	for _, file := range diff.Files {
-		change := &cache.FileChange{
-			Filename:  file.GetFilename(),
-			Additions: file.GetAdditions(),
-			Deletions: file.GetDeletions(),
-			Changes:   file.GetChanges(),
-			Status:    file.GetStatus(),
-			Patch:     file.GetPatch(),
-			Owner:     f.owner,
-			Repo:      f.repo,
-		}
-		changes = append(changes, change)
-	}
-
-	return changes, nil
-}
-
-type CompareResult struct {
-	FromRelease *cache.Release
-	ToRelease   *cache.Release
-	Commits     []cache.Commit
-	Files       []cache.FileChange
-	PrCount     int
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
 }
@@ -0,0 +1,172 @@
+package github
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"ordiff/internal/cache"
+)
+
+// Divergence statuses, as the GitHub compare API reports them for the fork
+// against upstream.
+const (
+	DivergenceIdentical = "identical"
+	DivergenceAhead     = "ahead"
+	DivergenceBehind    = "behind"
+	DivergenceDiverged  = "diverged"
+)
+
+// RepoRef is one side of a comparison across repositories.
+type RepoRef struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Ref   string `json:"ref"`
+}
+
+func (r RepoRef) String() string {
+	return r.Owner + "/" + r.Repo + "@" + r.Ref
+}
+
+// Divergence is how a fork differs from upstream since the commit they
+// share last. Commits only on one side are listed oldest first. Diverged
+// files were changed on both sides since the merge base, so merging
+// upstream into the fork may conflict on them.
+type Divergence struct {
+	Upstream RepoRef `json:"upstream"`
+	Fork     RepoRef `json:"fork"`
+	Status   string  `json:"status"`
+	// AheadBy counts the commits only in the fork, BehindBy those only
+	// upstream.
+	AheadBy         int            `json:"ahead_by"`
+	BehindBy        int            `json:"behind_by"`
+	MergeBase       string         `json:"merge_base"`
+	MergeBaseDate   time.Time      `json:"merge_base_date"`
+	UpstreamDate    time.Time      `json:"upstream_date"`
+	ForkCommits     []cache.Commit `json:"fork_commits"`
+	UpstreamCommits []cache.Commit `json:"upstream_commits"`
+	DivergedFiles   []DivergedFile `json:"diverged_files"`
+	// ForkFiles and UpstreamFiles count the files changed on each side
+	// since the merge base, diverged ones included.
+	ForkFiles     int `json:"fork_files"`
+	UpstreamFiles int `json:"upstream_files"`
+}
+
+// DivergedFile is a file changed on both sides since the merge base.
+type DivergedFile struct {
+	Filename          string `json:"filename"`
+	ForkAdditions     int    `json:"fork_additions"`
+	ForkDeletions     int    `json:"fork_deletions"`
+	UpstreamAdditions int    `json:"upstream_additions"`
+	UpstreamDeletions int    `json:"upstream_deletions"`
+}
+
+// DefaultBranch returns the default branch of the fetcher's repository.
+func (f *Fetcher) DefaultBranch() (string, error) {
+	var branch string
+	err := f.withRetry(func() error {
+		r, _, err := f.client.Repositories.Get(f.ctx, f.owner, f.repo)
+		branch = r.GetDefaultBranch()
+		return err
+	})
+	return branch, err
+}
+
+// CountCommits returns how many commits head is ahead of base, or minus
+// how many it is behind when it moved back.
+func (f *Fetcher) CountCommits(base, head string) (int, error) {
+	c, err := f.fetchComparison(base, head)
+	if err != nil {
+		return 0, err
+	}
+	if c.GetStatus() == DivergenceBehind {
+		return -c.GetBehindBy(), nil
+	}
+	return c.GetAheadBy(), nil
+}
+
+// crossRef names a ref of another repository of the fork network in a
+// compare request. An owner has at most one repository per network, so the
+// owner is enough.
+func crossRef(r RepoRef) string {
+	return r.Owner + ":" + r.Ref
+}
+
+// Diverge compares a fork against upstream, two repositories of one fork
+// network: f fetches from upstream and fork from the fork. Each side is
+// compared against the other from its own repository, which gives the
+// commits and files unique to it. GitHub lists at most 300 files per side.
+func (f *Fetcher) Diverge(fork *Fetcher, upstreamRef, forkRef string) (*Divergence, error) {
+	d := &Divergence{
+		Upstream: RepoRef{f.owner, f.repo, upstreamRef},
+		Fork:     RepoRef{fork.owner, fork.repo, forkRef},
+	}
+
+	ahead, err := f.fetchComparison(upstreamRef, crossRef(d.Fork))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %w", d.Upstream, d.Fork, err)
+	}
+	d.Status = ahead.GetStatus()
+	d.AheadBy = ahead.GetAheadBy()
+	d.BehindBy = ahead.GetBehindBy()
+	d.MergeBase = ahead.GetMergeBaseCommit().GetSHA()
+	d.MergeBaseDate = ahead.GetMergeBaseCommit().GetCommit().GetCommitter().GetDate().Time
+	d.UpstreamDate = ahead.GetBaseCommit().GetCommit().GetCommitter().GetDate().Time
+
+	behind, err := fork.fetchComparison(forkRef, crossRef(d.Upstream))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %w", d.Fork, d.Upstream, err)
+	}
+
+	if d.ForkCommits, err = sideCommits(f, upstreamRef, crossRef(d.Fork), fork); err != nil {
+		return nil, err
+	}
+	if d.UpstreamCommits, err = sideCommits(fork, forkRef, crossRef(d.Upstream), f); err != nil {
+		return nil, err
+	}
+
+	forkFiles := f.fileChanges(ahead)
+	upstreamFiles := fork.fileChanges(behind)
+	d.ForkFiles, d.UpstreamFiles = len(forkFiles), len(upstreamFiles)
+
+	changed := map[string]*cache.FileChange{}
+	for _, fc := range upstreamFiles {
+		changed[fc.Filename] = fc
+	}
+	d.DivergedFiles = []DivergedFile{}
+	for _, fc := range forkFiles {
+		up, ok := changed[fc.Filename]
+		if !ok {
+			continue
+		}
+		d.DivergedFiles = append(d.DivergedFiles, DivergedFile{
+			Filename:          fc.Filename,
+			ForkAdditions:     fc.Additions,
+			ForkDeletions:     fc.Deletions,
+			UpstreamAdditions: up.Additions,
+			UpstreamDeletions: up.Deletions,
+		})
+	}
+	sort.Slice(d.DivergedFiles, func(i, j int) bool {
+		a, b := d.DivergedFiles[i], d.DivergedFiles[j]
+		return a.ForkAdditions+a.ForkDeletions+a.UpstreamAdditions+a.UpstreamDeletions >
+			b.ForkAdditions+b.ForkDeletions+b.UpstreamAdditions+b.UpstreamDeletions
+	})
+	return d, nil
+}
+
+// sideCommits lists the commits of head missing from base, fetched through
+// f, and attributes them to the repository of from, whose pull requests
+// their messages refer to.
+func sideCommits(f *Fetcher, base, head string, from *Fetcher) ([]cache.Commit, error) {
+	commits, err := f.fetchCommits(base, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the commits of %s/%s: %w", from.owner, from.repo, err)
+	}
+	out := make([]cache.Commit, 0, len(commits))
+	for _, c := range commits {
+		c.Owner, c.Repo = from.owner, from.repo
+		out = append(out, *c)
+	}
+	return out, nil
+}
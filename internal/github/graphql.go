@@ -0,0 +1,518 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// GraphQLURL is the GitHub GraphQL endpoint, changed by UseEnterprise.
+var GraphQLURL = "https://api.github.com/graphql"
+
+// GraphQLFetcher indexes through the GraphQL API. Releases and each pair's
+// commit history come back 100 per request together with the pull request
+// that introduced every commit, so no separate pull request listing is
+// needed. GraphQL has no diff API, so file changes still take one REST
+// compare call per pair. The GraphQL API requires a token.
+type GraphQLFetcher struct {
+	*Fetcher
+}
+
+func NewGraphQLFetcher(f *Fetcher) *GraphQLFetcher {
+	return &GraphQLFetcher{Fetcher: f}
+}
+
+func (g *GraphQLFetcher) IndexAll(db cache.Store) error {
+	slog.Info("Fetching releases via GraphQL", "repo", g.owner+"/"+g.repo)
+
+	releases, err := g.FetchReleases()
+	if err != nil {
+		return fmt.Errorf("failed to fetch releases: %w", err)
+	}
+
+	slog.Info("Found releases, caching", "count", len(releases))
+
+	defer g.invalidateComparisons(db)
+
+	for _, r := range releases {
+		if err := db.SaveRelease(r); err != nil {
+			return fmt.Errorf("failed to save release %s: %w", r.TagName, err)
+		}
+	}
+
+	if err := g.indexPairs(db, releases, false, g.fetchPair); err != nil {
+		return err
+	}
+	g.logHTTPCacheHits()
+	return nil
+}
+
+// Update re-lists releases, which is cheap over GraphQL, and fills in the
+// pairs that are not cached yet.
+func (g *GraphQLFetcher) Update(db cache.Store) error {
+	releases, err := g.FetchReleases()
+	if err != nil {
+		return fmt.Errorf("failed to fetch releases: %w", err)
+	}
+
+	for _, r := range releases {
+		if err := db.SaveRelease(r); err != nil {
+			return fmt.Errorf("failed to save release %s: %w", r.TagName, err)
+		}
+	}
+
+	if err := g.indexPairs(db, releases, false, g.fetchPair); err != nil {
+		return err
+	}
+	g.logHTTPCacheHits()
+	return nil
+}
+
+func (g *GraphQLFetcher) Resume(db cache.Store) error {
+	return g.resume(db, g.fetchPair)
+}
+
+const releasesQuery = `query($owner: String!, $repo: String!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    releases(first: 100, after: $cursor, orderBy: {field: CREATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        tagName
+        name
+        publishedAt
+        description
+        tagCommit { oid }
+        releaseAssets(first: 100) {
+          nodes { name size downloadCount contentType downloadUrl }
+        }
+      }
+    }
+  }
+}`
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// FetchReleases lists releases newest first. Unlike the REST API, the commit
+// of every release is the tagged commit rather than its target branch.
+func (g *GraphQLFetcher) FetchReleases() ([]*cache.Release, error) {
+	releases, err := g.listReleases()
+	if err != nil {
+		return nil, err
+	}
+	return g.releaseRange.Apply(releases)
+}
+
+func (g *GraphQLFetcher) listReleases() ([]*cache.Release, error) {
+	if g.tagReleases {
+		return g.fetchTagReleases()
+	}
+
+	var all []*cache.Release
+	var cursor *string
+
+	for {
+		var data struct {
+			Repository struct {
+				Releases struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						TagName     string    `json:"tagName"`
+						Name        string    `json:"name"`
+						PublishedAt time.Time `json:"publishedAt"`
+						Description string    `json:"description"`
+						TagCommit   *struct {
+							OID string `json:"oid"`
+						} `json:"tagCommit"`
+						ReleaseAssets struct {
+							Nodes []struct {
+								Name          string `json:"name"`
+								Size          int64  `json:"size"`
+								DownloadCount int64  `json:"downloadCount"`
+								ContentType   string `json:"contentType"`
+								DownloadURL   string `json:"downloadUrl"`
+							} `json:"nodes"`
+						} `json:"releaseAssets"`
+					} `json:"nodes"`
+				} `json:"releases"`
+			} `json:"repository"`
+		}
+		err := g.query(releasesQuery, map[string]interface{}{
+			"owner":  g.owner,
+			"repo":   g.repo,
+			"cursor": cursor,
+		}, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		releases := data.Repository.Releases
+		for _, n := range releases.Nodes {
+			r := &cache.Release{
+				TagName:     n.TagName,
+				Name:        n.Name,
+				PublishedAt: n.PublishedAt,
+				Body:        n.Description,
+				Owner:       g.owner,
+				Repo:        g.repo,
+			}
+			if n.TagCommit != nil {
+				r.CommitSHA = n.TagCommit.OID
+			}
+			r.Assets = make([]cache.ReleaseAsset, 0, len(n.ReleaseAssets.Nodes))
+			for _, a := range n.ReleaseAssets.Nodes {
+				r.Assets = append(r.Assets, cache.ReleaseAsset{
+					Name:          a.Name,
+					Size:          a.Size,
+					DownloadCount: a.DownloadCount,
+					ContentType:   a.ContentType,
+					URL:           a.DownloadURL,
+				})
+			}
+			all = append(all, r)
+		}
+
+		if !releases.PageInfo.HasNextPage {
+			break
+		}
+		cursor = &releases.PageInfo.EndCursor
+	}
+
+	if len(all) == 0 {
+		slog.Info("No GitHub releases found, indexing tags instead", "repo", g.owner+"/"+g.repo)
+		return g.fetchTagReleases()
+	}
+	return all, nil
+}
+
+const tagsQuery = `query($owner: String!, $repo: String!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    refs(refPrefix: "refs/tags/", first: 100, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        name
+        target {
+          ... on Commit { oid committedDate }
+          ... on Tag { target { ... on Commit { oid committedDate } } }
+        }
+      }
+    }
+  }
+}`
+
+type taggedCommit struct {
+	OID           string    `json:"oid"`
+	CommittedDate time.Time `json:"committedDate"`
+}
+
+// fetchTagReleases lists tags as releases, newest first, dated by their
+// commits. Unlike REST, one request covers 100 tags with their dates.
+// Annotated tags are resolved to the commit they point at.
+func (g *GraphQLFetcher) fetchTagReleases() ([]*cache.Release, error) {
+	var all []*cache.Release
+	var cursor *string
+
+	for {
+		var data struct {
+			Repository struct {
+				Refs struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						Name   string `json:"name"`
+						Target struct {
+							taggedCommit
+							Target *taggedCommit `json:"target"`
+						} `json:"target"`
+					} `json:"nodes"`
+				} `json:"refs"`
+			} `json:"repository"`
+		}
+		err := g.query(tagsQuery, map[string]interface{}{
+			"owner":  g.owner,
+			"repo":   g.repo,
+			"cursor": cursor,
+		}, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		refs := data.Repository.Refs
+		for _, n := range refs.Nodes {
+			commit := n.Target.taggedCommit
+			if n.Target.Target != nil {
+				commit = *n.Target.Target
+			}
+			if commit.OID == "" {
+				continue // a tag of a tree or blob
+			}
+			all = append(all, &cache.Release{
+				TagName:     n.Name,
+				PublishedAt: commit.CommittedDate,
+				CommitSHA:   commit.OID,
+				Owner:       g.owner,
+				Repo:        g.repo,
+			})
+		}
+
+		if !refs.PageInfo.HasNextPage {
+			break
+		}
+		cursor = &refs.PageInfo.EndCursor
+	}
+
+	sortNewestFirst(all)
+	return all, nil
+}
+
+const historyQuery = `query($owner: String!, $repo: String!, $oid: GitObjectID!, $since: GitTimestamp, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    object(oid: $oid) {
+      ... on Commit {
+        history(first: 100, after: $cursor, since: $since) {
+          pageInfo { hasNextPage endCursor }
+          nodes {
+            oid
+            message
+            url
+            author { name email date }
+            parents(first: 10) { nodes { oid } }
+            associatedPullRequests(first: 1) {
+              nodes {
+                number
+                title
+                body
+                mergedAt
+                url
+                author { login }
+                mergeCommit { oid }
+                labels(first: 20) { nodes { name } }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type historyNode struct {
+	OID     string `json:"oid"`
+	Message string `json:"message"`
+	URL     string `json:"url"`
+	Author  struct {
+		Name  string    `json:"name"`
+		Email string    `json:"email"`
+		Date  time.Time `json:"date"`
+	} `json:"author"`
+	Parents struct {
+		Nodes []struct {
+			OID string `json:"oid"`
+		} `json:"nodes"`
+	} `json:"parents"`
+	AssociatedPullRequests struct {
+		Nodes []struct {
+			Number   int        `json:"number"`
+			Title    string     `json:"title"`
+			Body     string     `json:"body"`
+			MergedAt *time.Time `json:"mergedAt"`
+			URL      string     `json:"url"`
+			Author   *struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			MergeCommit *struct {
+				OID string `json:"oid"`
+			} `json:"mergeCommit"`
+			Labels struct {
+				Nodes []struct {
+					Name string `json:"name"`
+				} `json:"nodes"`
+			} `json:"labels"`
+		} `json:"nodes"`
+	} `json:"associatedPullRequests"`
+}
+
+// fetchPair fetches the file changes over REST, then walks the history of
+// the newer release back to the older release's publish date. The walk can
+// reach commits the older release already contains, such as commits merged
+// from a branch, so when the compare response lists every commit of the
+// pair, only those are kept.
+func (g *GraphQLFetcher) fetchPair(from, to *cache.Release) (*pairData, error) {
+	if from.CommitSHA == "" || to.CommitSHA == "" {
+		return &pairData{}, nil
+	}
+
+	diff, err := g.fetchComparison(from.CommitSHA, to.CommitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch files: %w", err)
+	}
+	var inPair map[string]bool
+	if len(diff.Commits) >= diff.GetTotalCommits() {
+		inPair = make(map[string]bool, len(diff.Commits))
+		for _, c := range diff.Commits {
+			inPair[c.GetSHA()] = true
+		}
+	}
+
+	data := &pairData{files: g.fileChanges(diff)}
+	seenPRs := map[int]bool{}
+	var cursor *string
+
+	for {
+		var resp struct {
+			Repository struct {
+				Object *struct {
+					History struct {
+						PageInfo pageInfo      `json:"pageInfo"`
+						Nodes    []historyNode `json:"nodes"`
+					} `json:"history"`
+				} `json:"object"`
+			} `json:"repository"`
+		}
+		err := g.query(historyQuery, map[string]interface{}{
+			"owner":  g.owner,
+			"repo":   g.repo,
+			"oid":    to.CommitSHA,
+			"since":  from.PublishedAt.UTC().Format(time.RFC3339),
+			"cursor": cursor,
+		}, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch commits: %w", err)
+		}
+		if resp.Repository.Object == nil {
+			return nil, fmt.Errorf("failed to fetch commits: commit %s not found", to.CommitSHA)
+		}
+
+		history := resp.Repository.Object.History
+		for _, n := range history.Nodes {
+			if n.OID == from.CommitSHA || (inPair != nil && !inPair[n.OID]) {
+				continue
+			}
+			c := &cache.Commit{
+				SHA:         n.OID,
+				Message:     n.Message,
+				Author:      n.Author.Name,
+				AuthorEmail: n.Author.Email,
+				Date:        n.Author.Date,
+				URL:         n.URL,
+				Owner:       g.owner,
+				Repo:        g.repo,
+				PrNumber:    g.extractPrNumber(n.Message),
+				Parents:     make([]string, len(n.Parents.Nodes)),
+			}
+			for i, p := range n.Parents.Nodes {
+				c.Parents[i] = p.OID
+			}
+
+			for _, pr := range n.AssociatedPullRequests.Nodes {
+				if pr.MergedAt == nil {
+					continue
+				}
+				number := pr.Number
+				c.PrNumber = &number
+				if seenPRs[number] {
+					continue
+				}
+				seenPRs[number] = true
+
+				p := &cache.PullRequest{
+					Number:   number,
+					Title:    pr.Title,
+					Body:     pr.Body,
+					State:    "merged",
+					MergedAt: pr.MergedAt,
+					URL:      pr.URL,
+					Owner:    g.owner,
+					Repo:     g.repo,
+				}
+				if pr.Author != nil {
+					p.Author = pr.Author.Login
+				}
+				if pr.MergeCommit != nil {
+					p.MergeCommitSHA = pr.MergeCommit.OID
+				}
+				for _, l := range pr.Labels.Nodes {
+					p.Labels = append(p.Labels, l.Name)
+				}
+				data.prs = append(data.prs, p)
+			}
+
+			data.commits = append(data.commits, c)
+		}
+
+		if !history.PageInfo.HasNextPage {
+			break
+		}
+		cursor = &history.PageInfo.EndCursor
+	}
+
+	return data, nil
+}
+
+type graphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// query runs a GraphQL query, retrying on secondary rate limits like the
+// REST calls do.
+func (g *GraphQLFetcher) query(q string, vars map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": q, "variables": vars})
+	if err != nil {
+		return err
+	}
+
+	return g.withRetry(func() error {
+		req, err := http.NewRequestWithContext(g.ctx, http.MethodPost, GraphQLURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := g.client.Client().Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+			if retry := resp.Header.Get("Retry-After"); retry != "" {
+				secs, _ := strconv.Atoi(retry)
+				wait := time.Duration(secs) * time.Second
+				return &github.AbuseRateLimitError{Response: resp, Message: "graphql secondary rate limit", RetryAfter: &wait}
+			}
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("graphql API requires a token (set GITHUB_TOKEN or github_token, log in with gh, or use a GitHub App)")
+		}
+		if resp.StatusCode != http.StatusOK {
+			return &statusError{resp: resp, msg: "graphql request failed: " + resp.Status}
+		}
+
+		var result struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []graphQLError  `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("failed to decode graphql response: %w", err)
+		}
+		if len(result.Errors) > 0 {
+			msgs := make([]string, len(result.Errors))
+			for i, e := range result.Errors {
+				msgs[i] = e.Message
+			}
+			return fmt.Errorf("graphql: %s", strings.Join(msgs, "; "))
+		}
+		return json.Unmarshal(result.Data, out)
+	})
+}
@@ -0,0 +1,120 @@
+package github
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"ordiff/internal/cache"
+)
+
+// SetHTTPCache stores release, compare and pull request listings in db and
+// revalidates them with If-None-Match / If-Modified-Since. GitHub answers
+// unchanged resources with 304, which does not count against the rate limit,
+// so re-indexing an unchanged repository costs almost no quota.
+func (f *Fetcher) SetHTTPCache(db cache.Store) {
+	f.mu.Lock()
+	f.httpCache = db
+	f.mu.Unlock()
+}
+
+// HTTPCacheHits returns how many requests were answered from the HTTP cache.
+func (f *Fetcher) HTTPCacheHits() int64 {
+	return f.httpCacheHits.Load()
+}
+
+func (f *Fetcher) logHTTPCacheHits() {
+	if hits := f.HTTPCacheHits(); hits > 0 {
+		slog.Info("Requests answered from the HTTP cache", "count", hits)
+	}
+}
+
+// conditionalTransport sends cached validators with cacheable GET requests
+// and turns a 304 back into the stored 200 response.
+type conditionalTransport struct {
+	base http.RoundTripper
+	f    *Fetcher
+}
+
+func cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+	path := req.URL.Path
+	return strings.Contains(path, "/releases") || strings.Contains(path, "/compare/") || strings.HasSuffix(path, "/pulls")
+}
+
+func (t *conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t.f.mu.Lock()
+	db := t.f.httpCache
+	t.f.mu.Unlock()
+	if db == nil || !cacheable(req) {
+		return base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	cached, err := db.GetHTTPResponse(key)
+	if err != nil {
+		slog.Warn("Failed to read HTTP cache", "err", err)
+	}
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		t.f.httpCacheHits.Add(1)
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Header.Set("Content-Type", "application/json; charset=utf-8")
+		if cached.Link != "" {
+			resp.Header.Set("Link", cached.Link)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = db.SaveHTTPResponse(&cache.HTTPResponse{
+		URL:          key,
+		ETag:         etag,
+		LastModified: lastModified,
+		Link:         resp.Header.Get("Link"),
+		Body:         body,
+	})
+	if err != nil {
+		slog.Warn("Failed to write HTTP cache", "err", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,84 @@
+package github
+
+import (
+	"log/slog"
+	"time"
+
+	"ordiff/internal/cache"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// IndexIssues caches the repository's closed issues and returns how many
+// were saved. With since set only issues updated since then are listed.
+func (f *Fetcher) IndexIssues(db cache.Store, since time.Time) (int, error) {
+	saved := 0
+	page := 1
+
+	for {
+		var issues []*github.Issue
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			issues, resp, err = f.client.Issues.ListByRepo(f.ctx, f.owner, f.repo, &github.IssueListByRepoOptions{
+				State:     "closed",
+				Sort:      "updated",
+				Direction: "desc",
+				Since:     since,
+				ListOptions: github.ListOptions{
+					Page:    page,
+					PerPage: 100,
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return saved, err
+		}
+
+		for _, i := range issues {
+			// The issues API lists pull requests too.
+			if i.IsPullRequest() || i.ClosedAt == nil {
+				continue
+			}
+
+			var labels []string
+			for _, l := range i.Labels {
+				labels = append(labels, l.GetName())
+			}
+
+			if err := db.SaveIssue(&cache.Issue{
+				Number:      i.GetNumber(),
+				Title:       i.GetTitle(),
+				StateReason: i.GetStateReason(),
+				ClosedAt:    i.GetClosedAt().Time,
+				Author:      i.GetUser().GetLogin(),
+				URL:         i.GetHTMLURL(),
+				Labels:      labels,
+				Owner:       f.owner,
+				Repo:        f.repo,
+			}); err != nil {
+				return saved, err
+			}
+			saved++
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	return saved, nil
+}
+
+func (f *Fetcher) indexIssues(db cache.Store, since time.Time) {
+	slog.Info("Fetching closed issues")
+	n, err := f.IndexIssues(db, since)
+	if err != nil {
+		slog.Warn("Failed to index issues", "err", err)
+	}
+	slog.Info("Cached closed issues", "count", n)
+	if n > 0 {
+		f.invalidateComparisons(db)
+	}
+}
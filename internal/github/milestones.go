@@ -0,0 +1,102 @@
+package github
+
+import (
+	"fmt"
+	"time"
+
+	"ordiff/internal/cache"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// FetchMilestone looks up a milestone by title, lists the issues and pull
+// requests assigned to it and caches them.
+func (f *Fetcher) FetchMilestone(db cache.Store, title string) (*cache.Milestone, error) {
+	var milestone *github.Milestone
+	page := 1
+	for milestone == nil {
+		var list []*github.Milestone
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			list, resp, err = f.client.Issues.ListMilestones(f.ctx, f.owner, f.repo, &github.MilestoneListOptions{
+				State:       "all",
+				ListOptions: github.ListOptions{Page: page, PerPage: 100},
+			})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range list {
+			if m.GetTitle() == title {
+				milestone = m
+				break
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+	if milestone == nil {
+		return nil, fmt.Errorf("no milestone titled %q in %s/%s", title, f.owner, f.repo)
+	}
+
+	m := &cache.Milestone{
+		Number:    milestone.GetNumber(),
+		Title:     milestone.GetTitle(),
+		State:     milestone.GetState(),
+		URL:       milestone.GetHTMLURL(),
+		FetchedAt: time.Now(),
+		Owner:     f.owner,
+		Repo:      f.repo,
+	}
+	if milestone.DueOn != nil {
+		due := milestone.DueOn.Time
+		m.DueOn = &due
+	}
+
+	page = 1
+	for {
+		var items []*github.Issue
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			items, resp, err = f.client.Issues.ListByRepo(f.ctx, f.owner, f.repo, &github.IssueListByRepoOptions{
+				Milestone:   fmt.Sprint(m.Number),
+				State:       "all",
+				ListOptions: github.ListOptions{Page: page, PerPage: 100},
+			})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range items {
+			item := cache.MilestoneItem{
+				Number:      i.GetNumber(),
+				Title:       i.GetTitle(),
+				PullRequest: i.IsPullRequest(),
+				State:       i.GetState(),
+				StateReason: i.GetStateReason(),
+				URL:         i.GetHTMLURL(),
+			}
+			if i.ClosedAt != nil {
+				closed := i.ClosedAt.Time
+				item.ClosedAt = &closed
+			}
+			if i.PullRequestLinks != nil && i.PullRequestLinks.MergedAt != nil {
+				merged := i.PullRequestLinks.MergedAt.Time
+				item.MergedAt = &merged
+			}
+			m.Items = append(m.Items, item)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	return m, db.SaveMilestone(m)
+}
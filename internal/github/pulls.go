@@ -0,0 +1,188 @@
+package github
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"ordiff/internal/cache"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// IndexPullRequests caches the repository's merged pull requests and returns
+// how many were saved. Pull requests are listed most recently updated first,
+// so with since set paging stops at the first one not updated after it.
+func (f *Fetcher) IndexPullRequests(db cache.Store, since time.Time) (int, error) {
+	saved := 0
+	page := 1
+
+	for {
+		var prs []*github.PullRequest
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			prs, resp, err = f.client.PullRequests.List(f.ctx, f.owner, f.repo, &github.PullRequestListOptions{
+				State:     "closed",
+				Sort:      "updated",
+				Direction: "desc",
+				ListOptions: github.ListOptions{
+					Page:    page,
+					PerPage: 100,
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return saved, err
+		}
+
+		for _, pr := range prs {
+			if !since.IsZero() && pr.GetUpdatedAt().Before(since) {
+				return saved, nil
+			}
+			if pr.MergedAt == nil {
+				continue
+			}
+
+			mergedAt := pr.GetMergedAt().Time
+			var labels []string
+			for _, l := range pr.Labels {
+				labels = append(labels, l.GetName())
+			}
+
+			if err := db.SavePullRequest(&cache.PullRequest{
+				Number:         pr.GetNumber(),
+				Title:          pr.GetTitle(),
+				Body:           pr.GetBody(),
+				State:          "merged",
+				MergedAt:       &mergedAt,
+				Author:         pr.GetUser().GetLogin(),
+				URL:            pr.GetHTMLURL(),
+				Owner:          f.owner,
+				Repo:           f.repo,
+				Labels:         labels,
+				MergeCommitSHA: pr.GetMergeCommitSHA(),
+			}); err != nil {
+				return saved, err
+			}
+			saved++
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	return saved, nil
+}
+
+// mergedPullRequests looks up the cached pull requests referenced by a set
+// of commits.
+func (f *Fetcher) mergedPullRequests(db cache.Store, commits []cache.Commit) ([]cache.PullRequest, error) {
+	seen := map[int]bool{}
+	var numbers []int
+	for _, c := range commits {
+		if c.PrNumber != nil && !seen[*c.PrNumber] {
+			seen[*c.PrNumber] = true
+			numbers = append(numbers, *c.PrNumber)
+		}
+	}
+	return db.GetPullRequests(f.owner, f.repo, numbers)
+}
+
+// Unlabeled is the group for merged pull requests without labels.
+const Unlabeled = "unlabeled"
+
+// PullRequestsByLabel groups the merged pull requests by their first label.
+// Labels are returned sorted, with unlabeled pull requests last.
+func (r *CompareResult) PullRequestsByLabel() ([]string, map[string][]cache.PullRequest) {
+	groups := map[string][]cache.PullRequest{}
+	for _, pr := range r.PullRequests {
+		label := Unlabeled
+		if len(pr.Labels) > 0 {
+			label = pr.Labels[0]
+		}
+		groups[label] = append(groups[label], pr)
+	}
+
+	var labels []string
+	for label := range groups {
+		if label != Unlabeled {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	if _, ok := groups[Unlabeled]; ok {
+		labels = append(labels, Unlabeled)
+	}
+	return labels, groups
+}
+
+// PullRequestDetails is a merged pull request with its commits and the
+// releases it shipped in.
+type PullRequestDetails struct {
+	PullRequest  cache.PullRequest `json:"pull_request"`
+	Commits      []cache.Commit    `json:"commits"`
+	Releases     []string          `json:"releases"`
+	FirstRelease string            `json:"first_release,omitempty"`
+}
+
+// PullRequestDetails looks up a pull request in the cache. Releases lists
+// every release whose indexed pair contains one of its commits, so a
+// backported change names one release per release line. When no pair does,
+// FirstRelease falls back to the first release published after the merge.
+func (f *Fetcher) PullRequestDetails(db cache.Store, number int) (*PullRequestDetails, error) {
+	prs, err := db.GetPullRequests(f.owner, f.repo, []int{number})
+	if err != nil {
+		return nil, err
+	}
+	d := &PullRequestDetails{PullRequest: cache.PullRequest{Number: number, Owner: f.owner, Repo: f.repo}}
+	if len(prs) > 0 {
+		d.PullRequest = prs[0]
+	}
+
+	if d.Commits, err = db.GetPullRequestCommits(f.owner, f.repo, number, d.PullRequest.MergeCommitSHA); err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 && len(d.Commits) == 0 {
+		return nil, fmt.Errorf("pull request #%d is not cached", number)
+	}
+
+	if d.Commits == nil {
+		d.Commits = []cache.Commit{}
+	}
+
+	shas := make([]string, len(d.Commits))
+	for i, c := range d.Commits {
+		shas[i] = c.SHA
+	}
+	if d.Releases, err = db.ReleasesContaining(f.owner, f.repo, shas); err != nil {
+		return nil, err
+	}
+	if len(d.Releases) > 0 {
+		d.FirstRelease = d.Releases[0]
+		return d, nil
+	}
+	d.Releases = []string{}
+
+	merged := d.PullRequest.MergedAt
+	if merged == nil && len(d.Commits) > 0 {
+		merged = &d.Commits[len(d.Commits)-1].Date
+	}
+	if merged == nil {
+		return d, nil
+	}
+	releases, err := db.GetReleases(f.owner, f.repo)
+	if err != nil {
+		return nil, err
+	}
+	// GetReleases is newest first.
+	for i := len(releases) - 1; i >= 0; i-- {
+		if !releases[i].PublishedAt.Before(*merged) {
+			d.FirstRelease = releases[i].TagName
+			break
+		}
+	}
+	return d, nil
+}
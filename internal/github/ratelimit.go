@@ -0,0 +1,233 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ordiff/internal/provider"
+	"ordiff/internal/retry"
+
+	"github.com/google/go-github/v81/github"
+)
+
+const (
+	maxSecondaryRetries  = 5
+	defaultSecondaryWait = time.Minute
+	// After a secondary limit hit, requests are spaced out for a while since
+	// GitHub penalizes bursts even when quota remains.
+	secondaryCooldown = 10 * time.Minute
+	cooldownSpacing   = time.Second
+)
+
+// SetStatusHook registers a callback for human-readable status updates such
+// as rate-limit backoffs, so progress reporting can surface them.
+func (f *Fetcher) SetStatusHook(fn func(msg string)) {
+	f.onStatus = fn
+}
+
+func (f *Fetcher) status(msg string) {
+	slog.Warn(msg)
+	if f.onStatus != nil {
+		f.onStatus(msg)
+	}
+}
+
+// withRetry runs an API call, backing off and retrying when GitHub reports a
+// secondary (abuse) rate limit, or when the call fails with a server error
+// or a network failure as set by the retry policy. When the primary quota
+// runs out the call waits for the reset and is retried.
+func (f *Fetcher) withRetry(call func() error) error {
+	transient := 0
+	for attempt := 0; ; attempt++ {
+		if err := f.pace(); err != nil {
+			return err
+		}
+
+		err := call()
+
+		var primary *github.RateLimitError
+		if errors.As(err, &primary) {
+			f.waitForReset(primary.Rate.Reset.Time)
+			attempt--
+			continue
+		}
+
+		if resp, ok := transientFailure(err); ok && f.ctx.Err() == nil {
+			if transient >= f.retryPolicy.Attempts {
+				if transient == 0 {
+					return err
+				}
+				return fmt.Errorf("giving up after %d retries: %w", transient, err)
+			}
+			wait := f.retryPolicy.Delay(transient, resp)
+			transient++
+			f.status(fmt.Sprintf("Request failed (%v), retrying in %s (%d/%d)", err, wait.Round(time.Millisecond), transient, f.retryPolicy.Attempts))
+			if err := retry.Wait(f.ctx, wait); err != nil {
+				return err
+			}
+			attempt--
+			continue
+		}
+
+		var abuse *github.AbuseRateLimitError
+		if !errors.As(err, &abuse) {
+			return err
+		}
+		if attempt >= maxSecondaryRetries {
+			return fmt.Errorf("secondary rate limit persisted after %d retries: %w", attempt, err)
+		}
+
+		wait := abuse.GetRetryAfter()
+		if wait <= 0 {
+			wait = defaultSecondaryWait << attempt
+		}
+		f.status(fmt.Sprintf("Hit secondary rate limit, backing off %ds", int(wait.Seconds())))
+
+		// Pause every worker, not just this one, then space requests out.
+		f.mu.Lock()
+		now := time.Now()
+		if until := now.Add(wait); until.After(f.pausedUntil) {
+			f.pausedUntil = until
+		}
+		f.cooldownUntil = f.pausedUntil.Add(secondaryCooldown)
+		f.mu.Unlock()
+	}
+}
+
+// statusError is a failed response outside the REST client, such as one of
+// the GraphQL endpoint.
+type statusError struct {
+	resp *http.Response
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// transientFailure reports whether err is worth retrying, along with the
+// response that failed, if any, for its Retry-After header.
+func transientFailure(err error) (*http.Response, bool) {
+	var rest *github.ErrorResponse
+	if errors.As(err, &rest) && rest.Response != nil {
+		return rest.Response, retry.Status(rest.Response.StatusCode)
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.resp, retry.Status(status.resp.StatusCode)
+	}
+	return nil, retry.Network(err)
+}
+
+// SetRetryPolicy sets how API calls that fail with a server error or a
+// network failure are retried.
+func (f *Fetcher) SetRetryPolicy(p retry.Policy) {
+	f.retryPolicy = p
+}
+
+// waitForReset pauses every worker until the primary quota resets.
+func (f *Fetcher) waitForReset(reset time.Time) {
+	reset = reset.Add(time.Second)
+	if reset.Before(time.Now()) {
+		// GitHub still refused; don't spin on a stale reset time.
+		reset = time.Now().Add(defaultSecondaryWait)
+	}
+	f.status(fmt.Sprintf("Rate limit exhausted, waiting until %s", reset.Local().Format("15:04:05")))
+
+	f.mu.Lock()
+	if reset.After(f.pausedUntil) {
+		f.pausedUntil = reset
+	}
+	f.mu.Unlock()
+}
+
+// rateLimitTransport records the X-RateLimit headers of every response so
+// pace can stop before the quota is exhausted instead of after.
+type rateLimitTransport struct {
+	base http.RoundTripper
+	f    *Fetcher
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	remaining, errRemaining := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, errReset := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if errRemaining == nil && errReset == nil {
+		t.f.mu.Lock()
+		t.f.rateRemaining = remaining
+		t.f.rateReset = time.Unix(reset, 0)
+		t.f.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// pace blocks while a secondary rate limit backoff is in effect, and during
+// the cooldown after one lets at most one request start per cooldownSpacing
+// across all workers. Waiting ends early with an error if the fetcher's
+// context is cancelled.
+func (f *Fetcher) pace() error {
+	f.mu.Lock()
+	now := time.Now()
+	if reset := f.rateReset; f.rateRemaining == 0 && reset.After(now) && reset.After(f.pausedUntil) {
+		f.mu.Unlock()
+		f.waitForReset(reset)
+		f.mu.Lock()
+	}
+	start := now
+	if f.pausedUntil.After(start) {
+		start = f.pausedUntil
+	}
+	if start.Before(f.cooldownUntil) {
+		if f.nextRequest.After(start) {
+			start = f.nextRequest
+		}
+		f.nextRequest = start.Add(cooldownSpacing)
+	}
+	f.mu.Unlock()
+
+	if err := f.ctx.Err(); err != nil {
+		return err
+	}
+	if start.After(now) {
+		select {
+		case <-time.After(start.Sub(now)):
+		case <-f.ctx.Done():
+			return f.ctx.Err()
+		}
+	}
+	return nil
+}
+
+// CallsPerPair is the least number of requests fetching a release pair takes:
+// the comparison carrying its files and a page of its commits. Pairs of more
+// than 100 commits take a page more for every 100.
+func (f *Fetcher) CallsPerPair() int {
+	return 2
+}
+
+// RateLimit returns the core REST quota. Asking for it doesn't count
+// against it.
+func (f *Fetcher) RateLimit() (*provider.Quota, error) {
+	limits, _, err := f.client.RateLimit.Get(f.ctx)
+	if err != nil {
+		return nil, err
+	}
+	core := limits.GetCore()
+	if core == nil {
+		return nil, nil
+	}
+	return &provider.Quota{Limit: core.Limit, Remaining: core.Remaining, Reset: core.Reset.Time}, nil
+}
@@ -0,0 +1,79 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ordiff/internal/cache"
+)
+
+// ErrReleaseNotCached is returned by GetCompareData when either side of a
+// comparison is not a cached release tag.
+var ErrReleaseNotCached = errors.New("release not found in cache")
+
+// Compare compares two cached releases, falling back to CompareRefs when
+// either side is a branch, commit SHA or tag that was never indexed.
+func (f *Fetcher) Compare(db cache.Store, from, to string, save bool) (*CompareResult, error) {
+	result, err := f.GetCompareData(db, from, to)
+	if !errors.Is(err, ErrReleaseNotCached) {
+		return result, err
+	}
+	return f.CompareRefs(db, from, to, save)
+}
+
+// CompareRefs compares any two refs with the GitHub compare API. With save
+// set the result is stored in the compare cache, so later comparisons of the
+// same refs are served locally until the next index.
+func (f *Fetcher) CompareRefs(db cache.Store, base, head string, save bool) (*CompareResult, error) {
+	commits, err := f.fetchCommits(base, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", base, head, err)
+	}
+
+	files, err := f.fetchFileChanges(base, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", base, head, err)
+	}
+
+	result := &CompareResult{
+		FromRelease: &cache.Release{TagName: base, CommitSHA: base, Owner: f.owner, Repo: f.repo},
+		ToRelease:   &cache.Release{TagName: head, CommitSHA: head, Owner: f.owner, Repo: f.repo},
+		Commits:     make([]cache.Commit, 0, len(commits)),
+		Files:       make([]cache.FileChange, 0, len(files)),
+		FileSource:  FilesLive,
+	}
+
+	prs := map[int]bool{}
+	for _, c := range commits {
+		result.Commits = append(result.Commits, *c)
+		if c.PrNumber != nil {
+			prs[*c.PrNumber] = true
+		}
+	}
+	result.PrCount = len(prs)
+
+	result.PullRequests, err = f.mergedPullRequests(db, result.Commits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull requests: %w", err)
+	}
+
+	for _, fc := range files {
+		fc.FromRelease = base
+		fc.ToRelease = head
+		result.Files = append(result.Files, *fc)
+	}
+
+	if save {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.SaveCompareCache(f.owner, f.repo, base, head, string(data)); err != nil {
+			return nil, fmt.Errorf("failed to save compare cache: %w", err)
+		}
+	}
+
+	db.ResolveAuthors(result.Commits)
+	return result, nil
+}
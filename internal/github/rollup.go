@@ -0,0 +1,114 @@
+package github
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"ordiff/internal/cache"
+)
+
+// RollupStep summarizes one adjacent release pair of a Rollup.
+type RollupStep struct {
+	From         string    `json:"from_release"`
+	To           string    `json:"to_release"`
+	PublishedAt  time.Time `json:"published_at"`
+	Commits      int       `json:"commits"`
+	PullRequests int       `json:"pull_requests"`
+	Files        int       `json:"files_changed"`
+	Additions    int       `json:"additions"`
+	Deletions    int       `json:"deletions"`
+}
+
+// Rollup is the cumulative view of every release pair between two releases:
+// the commits of all pairs without duplicates and the file churn summed per
+// file, leaving out files added and removed again within the range.
+type Rollup struct {
+	FromRelease *cache.Release     `json:"from_release"`
+	ToRelease   *cache.Release     `json:"to_release"`
+	Steps       []RollupStep       `json:"steps"`
+	Commits     []cache.Commit     `json:"commits"`
+	PrCount     int                `json:"pull_requests"`
+	Files       []cache.FileChange `json:"files"`
+}
+
+// Rollup aggregates the cached data of the release pairs from fromTag to
+// toTag, oldest first. Both releases must be cached and fromTag older.
+func (f *Fetcher) Rollup(db cache.Store, fromTag, toTag string) (*Rollup, error) {
+	r := &Rollup{}
+	for _, t := range []struct {
+		tag string
+		dst **cache.Release
+	}{{fromTag, &r.FromRelease}, {toTag, &r.ToRelease}} {
+		release, err := db.GetRelease(f.owner, f.repo, t.tag)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", t.tag, ErrReleaseNotCached)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("release %s not found: %w", t.tag, err)
+		}
+		*t.dst = release
+	}
+
+	pairs, err := f.intermediatePairs(db, fromTag, toTag)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("%s is not an older release than %s", fromTag, toTag)
+	}
+
+	ranges, err := db.CommitRanges(f.owner, f.repo, pairs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve commits: %w", err)
+	}
+
+	seen := map[string]bool{}
+	prs := map[int]bool{}
+	var perPair [][]cache.FileChange
+	for i, p := range pairs {
+		to, err := db.GetRelease(f.owner, f.repo, p[1])
+		if err != nil {
+			return nil, fmt.Errorf("release %s not found: %w", p[1], err)
+		}
+		step := RollupStep{From: p[0], To: p[1], PublishedAt: to.PublishedAt}
+
+		commits, err := db.SearchCommitsIn(ranges[i], cache.CommitQuery{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commits: %w", err)
+		}
+		step.Commits = len(commits)
+		for _, c := range commits {
+			if seen[c.SHA] {
+				continue
+			}
+			seen[c.SHA] = true
+			r.Commits = append(r.Commits, c)
+			if c.PrNumber != nil {
+				prs[*c.PrNumber] = true
+			}
+		}
+
+		if step.PullRequests, err = db.PrCountIn(ranges[i]); err != nil {
+			return nil, fmt.Errorf("failed to count PRs: %w", err)
+		}
+
+		files, err := db.GetFileChanges(f.owner, f.repo, p[0], p[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get files: %w", err)
+		}
+		step.Files = len(files)
+		for _, fc := range files {
+			step.Additions += fc.Additions
+			step.Deletions += fc.Deletions
+		}
+		perPair = append(perPair, files)
+
+		r.Steps = append(r.Steps, step)
+	}
+
+	r.PrCount = len(prs)
+	r.Files = aggregateFileChanges(perPair, fromTag, toTag)
+	db.ResolveAuthors(r.Commits)
+	return r, nil
+}
@@ -0,0 +1,74 @@
+package github
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// SourceFiles downloads the tarball of a commit and returns the files for
+// which keep returns true, keyed by their path in the repository.
+func (f *Fetcher) SourceFiles(sha string, keep func(path string) bool) (map[string][]byte, error) {
+	var link string
+	err := f.withRetry(func() error {
+		u, _, err := f.client.Repositories.GetArchiveLink(f.ctx, f.owner, f.repo, github.Tarball, &github.RepositoryContentGetOptions{Ref: sha}, 3)
+		if err == nil {
+			link = u.String()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive link: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download archive: %s", resp.Status)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Entries are nested in an <owner>-<repo>-<sha>/ directory.
+		_, name, ok := strings.Cut(hdr.Name, "/")
+		if !ok || !keep(name) {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive: %w", name, err)
+		}
+		files[name] = data
+	}
+	return files, nil
+}
@@ -0,0 +1,94 @@
+package github
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// maxTagDepth bounds the chain of annotated tags pointing at tags followed
+// to reach a commit.
+const maxTagDepth = 5
+
+// FetchTag resolves a tag to the commit it points at, reading the tagger,
+// message and signature verification of annotated tags. It returns nil when
+// the tag does not exist.
+func (f *Fetcher) FetchTag(name string) (*cache.Tag, error) {
+	var ref *github.Reference
+	var resp *github.Response
+	err := f.withRetry(func() (err error) {
+		ref, resp, err = f.client.Git.GetRef(f.ctx, f.owner, f.repo, "tags/"+name)
+		return err
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &cache.Tag{Name: name, FetchedAt: time.Now().UTC()}
+	obj := ref.GetObject()
+	for depth := 0; obj.GetType() == "tag"; depth++ {
+		if depth == maxTagDepth {
+			return nil, fmt.Errorf("tag %s: more than %d nested tags", name, maxTagDepth)
+		}
+		var tag *github.Tag
+		sha := obj.GetSHA()
+		err := f.withRetry(func() (err error) {
+			tag, _, err = f.client.Git.GetTag(f.ctx, f.owner, f.repo, sha)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !t.Annotated {
+			t.Annotated = true
+			t.Tagger = tag.GetTagger().GetName()
+			t.TaggerEmail = tag.GetTagger().GetEmail()
+			if date := tag.GetTagger().GetDate(); !date.IsZero() {
+				at := date.UTC()
+				t.TaggedAt = &at
+			}
+			t.Message = strings.TrimSpace(tag.GetMessage())
+			v := tag.GetVerification()
+			t.Signature = signatureKind(v.GetSignature())
+			t.Verified = v.GetVerified()
+			t.Reason = v.GetReason()
+		}
+		obj = tag.GetObject()
+	}
+	if obj.GetType() == "commit" {
+		t.CommitSHA = obj.GetSHA()
+	}
+	return t, nil
+}
+
+// signatureKind names the kind of an ASCII-armored signature: gpg, ssh, or
+// for x509 signatures sigstore when the certificate was issued by Sigstore,
+// as gitsign's are, and x509 otherwise. It returns "" when there is none.
+func signatureKind(armored string) string {
+	switch {
+	case armored == "":
+		return ""
+	case strings.Contains(armored, "BEGIN PGP SIGNATURE"):
+		return "gpg"
+	case strings.Contains(armored, "BEGIN SSH SIGNATURE"):
+		return "ssh"
+	}
+	block, _ := pem.Decode([]byte(armored))
+	if block == nil {
+		return "unknown"
+	}
+	if bytes.Contains(block.Bytes, []byte("sigstore")) {
+		return "sigstore"
+	}
+	return "x509"
+}
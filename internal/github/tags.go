@@ -0,0 +1,83 @@
+package github
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"ordiff/internal/cache"
+
+	"github.com/google/go-github/v81/github"
+)
+
+// SetTagReleases makes the fetcher index git tags as releases even if the
+// repository publishes GitHub Releases. Repositories without any releases
+// fall back to their tags on their own.
+func (f *Fetcher) SetTagReleases(on bool) {
+	f.tagReleases = on
+}
+
+// fetchTagReleases lists the repository's tags as releases, newest first,
+// dated by the commit each tag points at. Tags in known are left out and
+// their commits not looked up.
+func (f *Fetcher) fetchTagReleases(known map[string]bool) ([]*cache.Release, error) {
+	var releases []*cache.Release
+	page := 1
+	for {
+		var tags []*github.RepositoryTag
+		var resp *github.Response
+		err := f.withRetry(func() (err error) {
+			tags, resp, err = f.client.Repositories.ListTags(f.ctx, f.owner, f.repo, &github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+
+		for _, t := range tags {
+			if known[t.GetName()] {
+				continue
+			}
+			releases = append(releases, &cache.Release{
+				TagName:   t.GetName(),
+				CommitSHA: t.GetCommit().GetSHA(),
+				Owner:     f.owner,
+				Repo:      f.repo,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	slog.Info("Dating tags by their commits", "count", len(releases))
+	for i, r := range releases {
+		var commit *github.Commit
+		err := f.withRetry(func() (err error) {
+			commit, _, err = f.client.Git.GetCommit(f.ctx, f.owner, f.repo, r.CommitSHA)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the commit of tag %s: %w", r.TagName, err)
+		}
+		r.PublishedAt = commit.GetCommitter().GetDate().Time
+		if (i+1)%50 == 0 {
+			slog.Info("Dated tags", "done", i+1, "total", len(releases))
+		}
+	}
+
+	sortNewestFirst(releases)
+	return releases, nil
+}
+
+// sortNewestFirst orders releases the way the release APIs return them.
+func sortNewestFirst(releases []*cache.Release) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].PublishedAt.After(releases[j].PublishedAt)
+	})
+}
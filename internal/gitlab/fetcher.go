@@ -0,0 +1,356 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/provider"
+	"ordiff/internal/retry"
+)
+
+const defaultBaseURL = "https://gitlab.com"
+
+type Fetcher struct {
+	owner   string
+	repo    string
+	baseURL string
+	token   string
+	client  *http.Client
+	ctx     context.Context
+
+	retryPolicy  retry.Policy
+	releaseRange provider.Range
+}
+
+// NewFetcher creates a GitLab fetcher for the project owner/repo, where owner
+// may be a nested group path. GITLAB_URL selects a self-managed instance.
+func NewFetcher(owner, repo, token string) *Fetcher {
+	baseURL := os.Getenv("GITLAB_URL")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return &Fetcher{
+		owner:   owner,
+		repo:    repo,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  http.DefaultClient,
+		ctx:     context.Background(),
+
+		retryPolicy: retry.Default,
+	}
+}
+
+// SetContext makes indexing stop when ctx is cancelled: requests in flight
+// are aborted and no further release pairs are started.
+func (f *Fetcher) SetContext(ctx context.Context) {
+	f.ctx = ctx
+}
+
+type release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ReleasedAt  time.Time `json:"released_at"`
+	Commit      struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+type commit struct {
+	ID           string    `json:"id"`
+	Message      string    `json:"message"`
+	AuthorName   string    `json:"author_name"`
+	AuthorEmail  string    `json:"author_email"`
+	AuthoredDate time.Time `json:"authored_date"`
+	WebURL       string    `json:"web_url"`
+	ParentIDs    []string  `json:"parent_ids"`
+}
+
+type diff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	Diff        string `json:"diff"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+type comparison struct {
+	Commits []commit `json:"commits"`
+	Diffs   []diff   `json:"diffs"`
+}
+
+func (f *Fetcher) IndexAll(db cache.Store) error {
+	slog.Info("Fetching releases from GitLab", "repo", f.owner+"/"+f.repo)
+
+	releases, err := f.FetchReleases()
+	if err != nil {
+		return fmt.Errorf("failed to fetch releases: %w", err)
+	}
+
+	slog.Info("Found releases, caching", "count", len(releases))
+
+	defer func() {
+		if err := db.ClearCompareCache(f.owner, f.repo); err != nil {
+			slog.Warn("Failed to invalidate compare cache", "err", err)
+		}
+	}()
+
+	for _, r := range releases {
+		if err := db.SaveRelease(r); err != nil {
+			return fmt.Errorf("failed to save release %s: %w", r.TagName, err)
+		}
+	}
+
+	processed := 0
+	skipped := 0
+	var failed []provider.PairFailure
+	for i := 0; i < len(releases)-1; i++ {
+		if err := f.ctx.Err(); err != nil {
+			slog.Warn("Indexing cancelled", "done", processed)
+			return err
+		}
+		from := releases[i+1]
+		to := releases[i]
+
+		alreadyCached, err := db.HasFileChangesCached(f.owner, f.repo, from.TagName, to.TagName)
+		if err != nil {
+			slog.Warn("Failed to check cache", "err", err)
+		}
+		if alreadyCached && db.IsPairStale(f.owner, f.repo, from.TagName, to.TagName) {
+			if err := db.DeleteFileChanges(f.owner, f.repo, from.TagName, to.TagName); err != nil {
+				slog.Warn("Failed to clear stale file changes", "err", err)
+			}
+			alreadyCached = false
+		}
+		if alreadyCached {
+			skipped++
+			slog.Debug("Skipping pair, already cached", "from", from.TagName, "to", to.TagName)
+			continue
+		}
+
+		processed++
+		slog.Info("Processing pair", "from", from.TagName, "to", to.TagName, "done", processed, "total", len(releases)-1-skipped, "skipped", skipped)
+
+		cmp, err := f.compare(from.CommitSHA, to.CommitSHA)
+		if err != nil {
+			if f.ctx.Err() != nil {
+				continue
+			}
+			slog.Error("Failed to fetch pair", "from", from.TagName, "to", to.TagName, "err", err)
+			failed = append(failed, provider.PairFailure{From: from.TagName, To: to.TagName, Err: fmt.Errorf("failed to compare: %w", err)})
+			continue
+		}
+
+		commits := make([]*cache.Commit, len(cmp.Commits))
+		for i, c := range cmp.Commits {
+			commits[i] = f.toCommit(c)
+		}
+
+		files := make([]*cache.FileChange, len(cmp.Diffs))
+		for i, d := range cmp.Diffs {
+			files[i] = f.toFileChange(d)
+			files[i].FromRelease = from.TagName
+			files[i].ToRelease = to.TagName
+		}
+
+		if err := db.SavePairContext(f.ctx, &cache.PairData{
+			Owner:       f.owner,
+			Repo:        f.repo,
+			FromRelease: from.TagName,
+			ToRelease:   to.TagName,
+			Commits:     commits,
+			Files:       files,
+		}); err != nil {
+			if f.ctx.Err() != nil {
+				continue
+			}
+			slog.Error("Failed to fetch pair", "from", from.TagName, "to", to.TagName, "err", err)
+			failed = append(failed, provider.PairFailure{From: from.TagName, To: to.TagName, Err: fmt.Errorf("failed to save release pair: %w", err)})
+		}
+	}
+
+	if len(failed) > 0 {
+		slog.Warn("Indexing incomplete", "failed", len(failed), "total", processed)
+		return &provider.PairErrors{Failed: failed, Total: processed}
+	}
+	slog.Info("Indexing complete", "processed", processed, "skipped", skipped)
+	return nil
+}
+
+// Update re-lists releases and fills in missing pairs; IndexAll already
+// skips pairs that are cached.
+func (f *Fetcher) Update(db cache.Store) error {
+	return f.IndexAll(db)
+}
+
+// FetchReleases lists the project's releases newest first, within the range
+// set by SetRange.
+func (f *Fetcher) FetchReleases() ([]*cache.Release, error) {
+	releases, err := f.listReleases()
+	if err != nil {
+		return nil, err
+	}
+	return f.releaseRange.Apply(releases)
+}
+
+// SetRange limits IndexAll to part of the release history.
+func (f *Fetcher) SetRange(r provider.Range) {
+	f.releaseRange = r
+}
+
+func (f *Fetcher) listReleases() ([]*cache.Release, error) {
+	var all []*cache.Release
+	page := "1"
+
+	for page != "" {
+		var releases []release
+		resp, err := f.get("/releases", url.Values{"per_page": {"100"}, "page": {page}}, &releases)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range releases {
+			all = append(all, &cache.Release{
+				TagName:     r.TagName,
+				Name:        r.Name,
+				PublishedAt: r.ReleasedAt,
+				CommitSHA:   r.Commit.ID,
+				Body:        r.Description,
+				Owner:       f.owner,
+				Repo:        f.repo,
+			})
+		}
+
+		page = resp.Header.Get("X-Next-Page")
+	}
+
+	return all, nil
+}
+
+func (f *Fetcher) compare(fromSHA, toSHA string) (*comparison, error) {
+	var cmp comparison
+	if fromSHA == "" || toSHA == "" {
+		return &cmp, nil
+	}
+	if _, err := f.get("/repository/compare", url.Values{"from": {fromSHA}, "to": {toSHA}}, &cmp); err != nil {
+		return nil, err
+	}
+	return &cmp, nil
+}
+
+func (f *Fetcher) get(path string, query url.Values, out interface{}) (*http.Response, error) {
+	project := url.PathEscape(f.owner + "/" + f.repo)
+	u := f.baseURL + "/api/v4/projects/" + project + path + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", f.token)
+	}
+
+	resp, err := retry.Do(f.ctx, f.client, f.retryPolicy, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return resp, json.NewDecoder(resp.Body).Decode(out)
+}
+
+// CallsPerPair is 1, as a comparison carries both the commits and the diffs
+// of a release pair.
+func (f *Fetcher) CallsPerPair() int {
+	return 1
+}
+
+// RateLimit returns nil; GitLab's limits vary by instance and are not
+// reported up front.
+func (f *Fetcher) RateLimit() (*provider.Quota, error) {
+	return nil, nil
+}
+
+// SetRetryPolicy sets how requests that fail with a server error or a
+// network failure are retried.
+func (f *Fetcher) SetRetryPolicy(p retry.Policy) {
+	f.retryPolicy = p
+}
+
+var mergeRequestRe = regexp.MustCompile(`!(\d+)\b`)
+
+func (f *Fetcher) toCommit(c commit) *cache.Commit {
+	commit := &cache.Commit{
+		SHA:         c.ID,
+		Message:     c.Message,
+		Author:      c.AuthorName,
+		AuthorEmail: c.AuthorEmail,
+		Date:        c.AuthoredDate,
+		URL:         c.WebURL,
+		Owner:       f.owner,
+		Repo:        f.repo,
+		Parents:     append([]string{}, c.ParentIDs...),
+	}
+	if m := mergeRequestRe.FindStringSubmatch(c.Message); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		commit.PrNumber = &n
+	}
+	return commit
+}
+
+func (f *Fetcher) toFileChange(d diff) *cache.FileChange {
+	status := "modified"
+	switch {
+	case d.NewFile:
+		status = "added"
+	case d.DeletedFile:
+		status = "removed"
+	case d.RenamedFile:
+		status = "renamed"
+	}
+
+	additions, deletions := 0, 0
+	for _, line := range strings.Split(d.Diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			additions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+
+	filename, previous := d.NewPath, ""
+	switch {
+	case d.DeletedFile:
+		filename = d.OldPath
+	case d.RenamedFile:
+		previous = d.OldPath
+	}
+
+	return &cache.FileChange{
+		Filename:         filename,
+		PreviousFilename: previous,
+		Additions:        additions,
+		Deletions:        deletions,
+		Changes:          additions + deletions,
+		Status:           status,
+		Patch:            d.Diff,
+		Owner:            f.owner,
+		Repo:             f.repo,
+	}
+}
@@ -0,0 +1,161 @@
+// Package hotspot ranks the files and directories of a repository by how
+// much and how often they changed across the cached release pairs. Paths
+// that change a lot in many releases are where regressions tend to come
+// from.
+package hotspot
+
+import (
+	"math"
+	"sort"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/filter"
+)
+
+// Orders accepted by Rank.
+const (
+	ByScore     = "score"
+	ByChurn     = "churn"
+	ByFrequency = "frequency"
+)
+
+// Hotspot is the cumulative change of one file or directory.
+type Hotspot struct {
+	Path        string  `json:"path"`
+	Score       float64 `json:"score"`
+	Pairs       int     `json:"release_pairs"`
+	Files       int     `json:"files"`
+	Additions   int     `json:"additions"`
+	Deletions   int     `json:"deletions"`
+	LastChanged string  `json:"last_changed"`
+}
+
+// Churn is the number of changed lines.
+func (h Hotspot) Churn() int {
+	return h.Additions + h.Deletions
+}
+
+// Window keeps the file changes of adjacent release pairs whose newer
+// release comes after since, or of every adjacent pair when since is empty.
+// Releases are newest first, as returned by the cache. Comparisons cached
+// for other ranges are left out so no change is counted twice. It also
+// returns the number of pairs in the window.
+func Window(files []cache.FileChange, releases []cache.Release, since string) ([]cache.FileChange, int) {
+	end := len(releases) - 1
+	for i, r := range releases {
+		if r.TagName == since {
+			end = i
+			break
+		}
+	}
+
+	pairs := map[[2]string]bool{}
+	for i := 0; i < end; i++ {
+		pairs[[2]string{releases[i+1].TagName, releases[i].TagName}] = true
+	}
+
+	var kept []cache.FileChange
+	for _, f := range files {
+		if pairs[[2]string{f.FromRelease, f.ToRelease}] {
+			kept = append(kept, f)
+		}
+	}
+	return kept, len(pairs)
+}
+
+// Rank aggregates file changes per file, or per directory of up to depth
+// path segments when depth is positive, and scores each path from 0 to 100
+// as the geometric mean of its churn and the number of pairs that changed
+// it, both relative to the highest. The result is sorted by order: ByScore,
+// ByChurn or ByFrequency. Releases are newest first and date LastChanged.
+func Rank(files []cache.FileChange, releases []cache.Release, depth int, order string) []Hotspot {
+	age := map[string]int{}
+	for i, r := range releases {
+		age[r.TagName] = i
+	}
+
+	// A renamed file keeps the changes made under its previous paths.
+	renames := map[string]rename{}
+	for _, f := range files {
+		if f.Status == "renamed" && f.PreviousFilename != "" && f.PreviousFilename != f.Filename {
+			renames[f.PreviousFilename] = rename{f.Filename, age[f.ToRelease]}
+		}
+	}
+
+	byPath := map[string]*Hotspot{}
+	pairs := map[string]map[[2]string]bool{}
+	filenames := map[string]map[string]bool{}
+	for _, f := range files {
+		name := currentName(renames, f.Filename, age[f.ToRelease])
+		p := name
+		if depth > 0 {
+			p = filter.Dir(p, depth)
+		}
+
+		h, ok := byPath[p]
+		if !ok {
+			h = &Hotspot{Path: p, LastChanged: f.ToRelease}
+			byPath[p] = h
+			pairs[p] = map[[2]string]bool{}
+			filenames[p] = map[string]bool{}
+		}
+		h.Additions += f.Additions
+		h.Deletions += f.Deletions
+		pairs[p][[2]string{f.FromRelease, f.ToRelease}] = true
+		filenames[p][name] = true
+		if age[f.ToRelease] < age[h.LastChanged] {
+			h.LastChanged = f.ToRelease
+		}
+	}
+
+	maxPairs, maxChurn := 0, 0
+	for p, h := range byPath {
+		h.Pairs = len(pairs[p])
+		h.Files = len(filenames[p])
+		maxPairs = max(maxPairs, h.Pairs)
+		maxChurn = max(maxChurn, h.Churn())
+	}
+
+	hotspots := make([]Hotspot, 0, len(byPath))
+	for _, h := range byPath {
+		if maxPairs > 0 && maxChurn > 0 {
+			score := 100 * math.Sqrt(float64(h.Pairs)/float64(maxPairs)*float64(h.Churn())/float64(maxChurn))
+			h.Score = math.Round(score*10) / 10
+		}
+		hotspots = append(hotspots, *h)
+	}
+
+	sort.Slice(hotspots, func(i, j int) bool {
+		a, b := hotspots[i], hotspots[j]
+		switch {
+		case order == ByChurn && a.Churn() != b.Churn():
+			return a.Churn() > b.Churn()
+		case order == ByFrequency && a.Pairs != b.Pairs:
+			return a.Pairs > b.Pairs
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case a.Churn() != b.Churn():
+			return a.Churn() > b.Churn()
+		}
+		return a.Path < b.Path
+	})
+	return hotspots
+}
+
+type rename struct {
+	to  string
+	age int
+}
+
+// currentName follows the renames made after a change to find the path the
+// file has now. A path reused after its file moved away is left alone.
+func currentName(renames map[string]rename, name string, age int) string {
+	for i := 0; i < len(renames); i++ {
+		r, ok := renames[name]
+		if !ok || r.age >= age {
+			break
+		}
+		name = r.to
+	}
+	return name
+}
@@ -0,0 +1,225 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"ordiff/internal/deps"
+)
+
+// DefaultPlatform is the platform picked from multi-platform images.
+const DefaultPlatform = "linux/amd64"
+
+// Config is the image config of a repository: the image name, a template
+// turning a release tag into an image tag, the platform to compare and the
+// registry credentials. The template is executed with Tag, Version (the
+// tag without a leading v), Owner and Repo.
+type Config struct {
+	Name     string `mapstructure:"name"`
+	Tag      string `mapstructure:"tag"`
+	Platform string `mapstructure:"platform"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// Resolve returns the image published for a release tag. An image name
+// defaults to owner/repo on Docker Hub and a tag to the release tag.
+func (c Config) Resolve(owner, repo, tag string) (Ref, error) {
+	name := c.Name
+	if name == "" {
+		name = owner + "/" + repo
+	}
+	tmpl := c.Tag
+	if tmpl == "" {
+		tmpl = "{{.Tag}}"
+	}
+	t, err := template.New("tag").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return Ref{}, fmt.Errorf("invalid image tag template: %w", err)
+	}
+	var b bytes.Buffer
+	err = t.Execute(&b, map[string]string{
+		"Tag":     tag,
+		"Version": strings.TrimPrefix(tag, "v"),
+		"Owner":   owner,
+		"Repo":    repo,
+	})
+	if err != nil {
+		return Ref{}, fmt.Errorf("invalid image tag template: %w", err)
+	}
+	return ParseRef(name + ":" + b.String())
+}
+
+// Image is one platform's manifest of an image, with its layers bottom
+// first.
+type Image struct {
+	Ref      Ref       `json:"ref"`
+	Digest   string    `json:"digest"`
+	Platform string    `json:"platform"`
+	Created  time.Time `json:"created"`
+	Size     int64     `json:"size"`
+	Layers   []Layer   `json:"-"`
+}
+
+// Layer is a filesystem layer, with the build step that created it when the
+// image config records one.
+type Layer struct {
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	MediaType string `json:"-"`
+	CreatedBy string `json:"created_by,omitempty"`
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant"`
+	} `json:"platform"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+}
+
+type imageConfig struct {
+	Created time.Time `json:"created"`
+	History []struct {
+		CreatedBy  string `json:"created_by"`
+		EmptyLayer bool   `json:"empty_layer"`
+	} `json:"history"`
+}
+
+// Fetch reads an image's manifest and config. Multi-platform images are
+// narrowed to platform, such as linux/amd64 or linux/arm64/v8.
+func (c *Client) Fetch(ref Ref, platform string) (*Image, error) {
+	var m manifest
+	mediaType, digest, err := c.getJSON(ref, "manifests/"+ref.Reference, &m, mediaOCIIndex, mediaDockerList, mediaOCIManifest, mediaDockerV2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the manifest of %s: %w", ref, err)
+	}
+	if m.MediaType != "" {
+		mediaType = m.MediaType
+	}
+
+	img := &Image{Ref: ref, Digest: digest, Platform: platform}
+	if mediaType == mediaOCIIndex || mediaType == mediaDockerList || (mediaType == "" && len(m.Manifests) > 0) {
+		var chosen *descriptor
+		for i, d := range m.Manifests {
+			if d.Platform != nil && platformMatches(platform, d.Platform.OS, d.Platform.Architecture, d.Platform.Variant) {
+				chosen = &m.Manifests[i]
+				break
+			}
+		}
+		if chosen == nil {
+			return nil, fmt.Errorf("%s has no %s image", ref, platform)
+		}
+		img.Digest = chosen.Digest
+		m = manifest{}
+		if _, _, err := c.getJSON(ref, "manifests/"+chosen.Digest, &m, mediaOCIManifest, mediaDockerV2); err != nil {
+			return nil, fmt.Errorf("failed to fetch the %s manifest of %s: %w", platform, ref, err)
+		}
+	}
+
+	var cfg imageConfig
+	if m.Config.Digest != "" {
+		if _, _, err := c.getJSON(ref, "blobs/"+m.Config.Digest, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to fetch the config of %s: %w", ref, err)
+		}
+	}
+	img.Created = cfg.Created
+
+	var steps []string
+	for _, h := range cfg.History {
+		if !h.EmptyLayer {
+			steps = append(steps, h.CreatedBy)
+		}
+	}
+	for i, d := range m.Layers {
+		l := Layer{Digest: d.Digest, Size: d.Size, MediaType: d.MediaType}
+		if len(steps) == len(m.Layers) {
+			l.CreatedBy = strings.TrimSpace(strings.TrimPrefix(steps[i], "/bin/sh -c #(nop) "))
+		}
+		img.Layers = append(img.Layers, l)
+		img.Size += d.Size
+	}
+	return img, nil
+}
+
+// platformMatches reports whether a manifest's platform is the one asked
+// for as os/arch or os/arch/variant.
+func platformMatches(platform, os, arch, variant string) bool {
+	want := strings.Split(platform, "/")
+	if len(want) < 2 || want[0] != os || want[1] != arch {
+		return false
+	}
+	return len(want) < 3 || want[2] == variant
+}
+
+// Layer statuses of a Diff.
+const (
+	LayerShared  = "shared"
+	LayerAdded   = "added"
+	LayerRemoved = "removed"
+)
+
+// LayerChange is a layer of either image, shared by both or only in one.
+type LayerChange struct {
+	Layer
+	Status string `json:"status"`
+}
+
+// Diff is what changed between two images. Packages is nil when they were
+// not compared and PackageManager names the database they were read from.
+type Diff struct {
+	From           *Image        `json:"from"`
+	To             *Image        `json:"to"`
+	Layers         []LayerChange `json:"layers"`
+	PackageManager string        `json:"package_manager,omitempty"`
+	Packages       []deps.Change `json:"packages,omitempty"`
+}
+
+// Compare lines up the layers of two images: the newer image's in order,
+// shared or added, then the layers only the older one had.
+func Compare(from, to *Image) *Diff {
+	d := &Diff{From: from, To: to, Layers: []LayerChange{}}
+	inFrom := map[string]bool{}
+	for _, l := range from.Layers {
+		inFrom[l.Digest] = true
+	}
+	inTo := map[string]bool{}
+	for _, l := range to.Layers {
+		inTo[l.Digest] = true
+		status := LayerAdded
+		if inFrom[l.Digest] {
+			status = LayerShared
+		}
+		d.Layers = append(d.Layers, LayerChange{Layer: l, Status: status})
+	}
+	for _, l := range from.Layers {
+		if !inTo[l.Digest] {
+			d.Layers = append(d.Layers, LayerChange{Layer: l, Status: LayerRemoved})
+		}
+	}
+	return d
+}
+
+// Count returns how many layers have a status.
+func (d *Diff) Count(status string) int {
+	n := 0
+	for _, l := range d.Layers {
+		if l.Status == status {
+			n++
+		}
+	}
+	return n
+}
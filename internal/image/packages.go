@@ -0,0 +1,304 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Package databases read from images. Distroless images keep one dpkg
+// status file per package under status.d instead of a single one.
+const (
+	dpkgStatus    = "var/lib/dpkg/status"
+	dpkgStatusDir = "var/lib/dpkg/status.d/"
+	apkInstalled  = "lib/apk/db/installed"
+)
+
+// layerFiles is what a layer holds of the package databases: their files,
+// and the whiteouts that delete files of the layers below.
+type layerFiles struct {
+	files   map[string][]byte
+	deleted map[string]bool
+	opaque  []string
+}
+
+// Packages reads the OS packages installed in an image from its dpkg or apk
+// database, returning the package manager and each package's version. The
+// layers are read top down until one holds a whole database, so the large
+// base layers are usually skipped. Both are empty when the image has no
+// database, as with scratch images.
+func (c *Client) Packages(img *Image) (string, map[string]string, error) {
+	seen := map[string]bool{}
+	var hidden []string
+	found := map[string][]byte{}
+	for i := len(img.Layers) - 1; i >= 0; i-- {
+		lf, err := c.layerFiles(img.Ref, img.Layers[i])
+		if err != nil {
+			return "", nil, err
+		}
+		for p, data := range lf.files {
+			if !seen[p] && !under(p, hidden) {
+				found[p] = data
+			}
+			seen[p] = true
+		}
+		for p := range lf.deleted {
+			seen[p] = true
+			hidden = append(hidden, p)
+		}
+		hidden = append(hidden, lf.opaque...)
+		if found[dpkgStatus] != nil || found[apkInstalled] != nil {
+			break
+		}
+	}
+
+	packages := map[string]string{}
+	manager := ""
+	for p, data := range found {
+		switch {
+		case p == apkInstalled:
+			manager = "apk"
+			parseAPK(data, packages)
+		case p == dpkgStatus || strings.HasPrefix(p, dpkgStatusDir):
+			manager = "dpkg"
+			parseDpkg(data, packages)
+		}
+	}
+	return manager, packages, nil
+}
+
+// under reports whether p is inside one of dirs.
+func under(p string, dirs []string) bool {
+	for _, d := range dirs {
+		if strings.HasPrefix(p, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// layerFiles downloads a layer and keeps the package database files in it.
+// Layers are cached by digest, so images sharing a base read it once.
+func (c *Client) layerFiles(ref Ref, l Layer) (*layerFiles, error) {
+	if lf := c.files[l.Digest]; lf != nil {
+		return lf, nil
+	}
+	slog.Info("Reading image layer", "digest", l.Digest, "size", l.Size)
+	resp, err := c.get(ref, "blobs/"+l.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download layer %s: %w", l.Digest, err)
+	}
+	defer resp.Body.Close()
+
+	lf, err := readLayer(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layer %s: %w", l.Digest, err)
+	}
+	c.files[l.Digest] = lf
+	return lf, nil
+}
+
+// readLayer reads a layer tarball, gzip- or zstd-compressed or not at all.
+func readLayer(r io.Reader) (*layerFiles, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	var src io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		src = gz
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		src = zr
+	}
+
+	lf := &layerFiles{files: map[string][]byte{}, deleted: map[string]bool{}}
+	tr := tar.NewReader(src)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return lf, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		p := strings.TrimPrefix(path.Clean("/"+h.Name), "/")
+		dir, base := path.Split(p)
+		dir = strings.TrimSuffix(dir, "/")
+		switch {
+		case base == ".wh..wh..opq":
+			if isDatabase(dir + "/") {
+				lf.opaque = append(lf.opaque, dir)
+			}
+		case strings.HasPrefix(base, ".wh."):
+			if target := path.Join(dir, strings.TrimPrefix(base, ".wh.")); isDatabase(target) || isDatabase(target+"/") {
+				lf.deleted[target] = true
+			}
+		case h.Typeflag == tar.TypeReg && isDatabase(p):
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			lf.files[p] = data
+		}
+	}
+}
+
+// isDatabase reports whether a path is, or is a directory holding, a
+// package database file.
+func isDatabase(p string) bool {
+	for _, db := range []string{dpkgStatus, dpkgStatusDir, apkInstalled} {
+		if p == db || strings.HasPrefix(p, dpkgStatusDir) || strings.HasSuffix(p, "/") && strings.HasPrefix(db, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDpkg reads the installed packages of a dpkg status file, paragraphs
+// of Package, Version and Status fields.
+func parseDpkg(data []byte, packages map[string]string) {
+	for _, para := range strings.Split(string(data), "\n\n") {
+		var name, version string
+		installed := true
+		for _, line := range strings.Split(para, "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok || strings.HasPrefix(line, " ") {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch key {
+			case "Package":
+				name = value
+			case "Version":
+				version = value
+			case "Status":
+				installed = strings.HasSuffix(value, " installed")
+			}
+		}
+		if name != "" && version != "" && installed {
+			packages[name] = version
+		}
+	}
+}
+
+// parseAPK reads the packages of an apk installed database, entries of
+// single-letter fields where P is the name and V the version.
+func parseAPK(data []byte, packages map[string]string) {
+	var name string
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case line == "":
+			name = ""
+		case strings.HasPrefix(line, "P:"):
+			name = line[2:]
+		case strings.HasPrefix(line, "V:") && name != "":
+			packages[name] = line[2:]
+		}
+	}
+}
+
+// CompareVersions orders package versions as dpkg does: by epoch, then the
+// upstream version and the revision, comparing runs of digits numerically
+// and the rest character by character, with ~ sorting before anything. apk
+// versions such as 1.36.1-r2 order the same way.
+func CompareVersions(a, b string) int {
+	epochA, restA := splitEpoch(a)
+	epochB, restB := splitEpoch(b)
+	if epochA != epochB {
+		return cmpInt(epochA, epochB)
+	}
+	upA, revA := splitRevision(restA)
+	upB, revB := splitRevision(restB)
+	if c := compareFragment(upA, upB); c != 0 {
+		return c
+	}
+	return compareFragment(revA, revB)
+}
+
+func splitEpoch(v string) (int, string) {
+	if e, rest, ok := strings.Cut(v, ":"); ok {
+		if n, err := strconv.Atoi(e); err == nil {
+			return n, rest
+		}
+	}
+	return 0, v
+}
+
+func splitRevision(v string) (string, string) {
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+// compareFragment is dpkg's verrevcmp.
+func compareFragment(a, b string) int {
+	for a != "" || b != "" {
+		for a != "" && !isDigit(a[0]) || b != "" && !isDigit(b[0]) {
+			if c := cmpInt(charOrder(a), charOrder(b)); c != 0 {
+				return c
+			}
+			a, b = a[min(1, len(a)):], b[min(1, len(b)):]
+		}
+		na, nb := 0, 0
+		for a != "" && isDigit(a[0]) {
+			na = na*10 + int(a[0]-'0')
+			a = a[1:]
+		}
+		for b != "" && isDigit(b[0]) {
+			nb = nb*10 + int(b[0]-'0')
+			b = b[1:]
+		}
+		if na != nb {
+			return cmpInt(na, nb)
+		}
+	}
+	return 0
+}
+
+// charOrder ranks the next non-digit character of a version: ~ first, then
+// the end of the fragment or a digit, letters, and other characters last.
+func charOrder(s string) int {
+	switch {
+	case s == "" || isDigit(s[0]):
+		return 0
+	case s[0] == '~':
+		return -1
+	case s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z':
+		return int(s[0])
+	default:
+		return int(s[0]) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
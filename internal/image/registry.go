@@ -0,0 +1,234 @@
+// Package image compares the container images published for two releases:
+// their layers, sizes and the OS packages installed in them. It speaks the
+// OCI distribution API directly, authenticating with the bearer tokens
+// registries hand out anonymously or for basic credentials.
+package image
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"ordiff/internal/retry"
+)
+
+// Manifest media types a registry is asked for.
+const (
+	mediaOCIIndex      = "application/vnd.oci.image.index.v1+json"
+	mediaOCIManifest   = "application/vnd.oci.image.manifest.v1+json"
+	mediaDockerList    = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaDockerV2      = "application/vnd.docker.distribution.manifest.v2+json"
+	defaultRegistry    = "docker.io"
+	dockerHubRegistry  = "registry-1.docker.io"
+	defaultDockerSpace = "library/"
+)
+
+// Client reads manifests and blobs from container registries.
+type Client struct {
+	client   *http.Client
+	ctx      context.Context
+	username string
+	password string
+	// tokens caches the Authorization header of each registry and repository.
+	tokens map[string]string
+	// files caches what was read from each layer, as images of consecutive
+	// releases share most of them.
+	files map[string]*layerFiles
+
+	retryPolicy retry.Policy
+}
+
+// NewClient creates a registry client. Empty credentials request anonymous
+// tokens, which public images need.
+func NewClient(username, password string) *Client {
+	return &Client{
+		client:      http.DefaultClient,
+		ctx:         context.Background(),
+		username:    username,
+		password:    password,
+		tokens:      map[string]string{},
+		files:       map[string]*layerFiles{},
+		retryPolicy: retry.Default,
+	}
+}
+
+// SetRetryPolicy replaces retry.Default for the client's requests.
+func (c *Client) SetRetryPolicy(p retry.Policy) {
+	c.retryPolicy = p
+}
+
+// Ref names an image in a registry, by tag or by digest.
+type Ref struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	Reference  string `json:"reference"`
+}
+
+func (r Ref) String() string {
+	sep := ":"
+	if strings.HasPrefix(r.Reference, "sha256:") {
+		sep = "@"
+	}
+	name := r.Repository
+	if r.Registry != defaultRegistry {
+		name = r.Registry + "/" + name
+	} else {
+		name = strings.TrimPrefix(name, defaultDockerSpace)
+	}
+	return name + sep + r.Reference
+}
+
+// ParseRef reads an image reference as docker does: an optional registry
+// host, the repository, and a :tag or @digest, latest when neither is given.
+// Images without a registry are on Docker Hub, under library/ when the
+// repository has a single component.
+func ParseRef(s string) (Ref, error) {
+	r := Ref{Registry: defaultRegistry, Reference: "latest"}
+	name := s
+	if n, digest, ok := strings.Cut(s, "@"); ok {
+		name, r.Reference = n, digest
+	} else if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		name, r.Reference = s[:i], s[i+1:]
+	}
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		r.Registry, name = first, rest
+	}
+	if r.Registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = defaultDockerSpace + name
+	}
+	if name == "" || r.Reference == "" {
+		return Ref{}, fmt.Errorf("invalid image reference %q", s)
+	}
+	r.Repository = name
+	return r, nil
+}
+
+// baseURL is where the registry serves the distribution API. Registries on
+// the local machine are reached over plain HTTP.
+func (r Ref) baseURL() string {
+	host := r.Registry
+	if host == defaultRegistry {
+		host = dockerHubRegistry
+	}
+	if h, _, _ := strings.Cut(host, ":"); h == "localhost" || h == "127.0.0.1" {
+		return "http://" + host + "/v2/"
+	}
+	return "https://" + host + "/v2/"
+}
+
+// get fetches a path of a repository, answering the registry's auth
+// challenge once. accept lists the media types asked for.
+func (c *Client) get(r Ref, path string, accept ...string) (*http.Response, error) {
+	u := r.baseURL() + r.Repository + "/" + path
+	key := r.Registry + "/" + r.Repository
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if token := c.tokens[key]; token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := retry.Do(c.ctx, c.client, c.retryPolicy, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if c.tokens[key], err = c.authorize(challenge); err != nil {
+				return nil, fmt.Errorf("failed to authenticate to %s: %w", r.Registry, err)
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+		}
+		return resp, nil
+	}
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize answers a WWW-Authenticate challenge with the Authorization
+// header to send: basic credentials as is, or a bearer token from the realm
+// the challenge names.
+func (c *Client) authorize(challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.username == "" {
+			return "", fmt.Errorf("the registry needs credentials")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported auth challenge %q", challenge)
+	}
+
+	values := url.Values{}
+	realm := ""
+	for _, m := range challengeParam.FindAllStringSubmatch(params, -1) {
+		if m[1] == "realm" {
+			realm = m[2]
+		} else {
+			values.Set(m[1], m[2])
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("auth challenge without a realm: %q", challenge)
+	}
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := retry.Do(c.ctx, c.client, c.retryPolicy, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request: %s", resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// getJSON fetches a path and decodes it, returning the media type and the
+// digest the registry reported.
+func (c *Client) getJSON(r Ref, path string, out interface{}, accept ...string) (mediaType, digest string, err error) {
+	resp, err := c.get(r, path, accept...)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	mediaType, _, _ = strings.Cut(resp.Header.Get("Content-Type"), ";")
+	return mediaType, resp.Header.Get("Docker-Content-Digest"), json.Unmarshal(body, out)
+}
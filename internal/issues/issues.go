@@ -0,0 +1,70 @@
+// Package issues links the closed issues of a repository to the releases
+// that closed them, through the closing keywords (fixes #12, closes
+// owner/repo#12) of the commits and merged pull requests in a release range.
+package issues
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ordiff/internal/cache"
+)
+
+var closingRe = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+(?:([\w.-]+/[\w.-]+)#|https?://[^/\s]+/([\w.-]+/[\w.-]+)/issues/|#)(\d+)\b`)
+
+// Closed is a closed issue with the pull request (#N) or commit (short SHA)
+// whose closing keyword closed it.
+type Closed struct {
+	cache.Issue
+	ClosedBy string `json:"closed_by"`
+}
+
+// ClosingRefs returns the issue numbers of owner/repo that text closes, in
+// the order they appear. References to other repositories are skipped.
+func ClosingRefs(text, owner, repo string) []int {
+	var numbers []int
+	for _, m := range closingRe.FindAllStringSubmatch(text, -1) {
+		if other := m[1] + m[2]; other != "" && !strings.EqualFold(other, owner+"/"+repo) {
+			continue
+		}
+		if n, err := strconv.Atoi(m[3]); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
+
+// Between returns the cached closed issues that the merged pull requests
+// and commits of a release range close, ordered by number. An issue closed
+// by both a pull request and a commit is credited to the pull request.
+func Between(db cache.Store, owner, repo string, commits []cache.Commit, prs []cache.PullRequest) ([]Closed, error) {
+	closedBy := map[int]string{}
+	var numbers []int
+	link := func(text, by string) {
+		for _, n := range ClosingRefs(text, owner, repo) {
+			if _, ok := closedBy[n]; !ok {
+				closedBy[n] = by
+				numbers = append(numbers, n)
+			}
+		}
+	}
+	for _, pr := range prs {
+		link(pr.Title+"\n"+pr.Body, "#"+strconv.Itoa(pr.Number))
+	}
+	for _, c := range commits {
+		link(c.Message, c.SHA[:min(7, len(c.SHA))])
+	}
+
+	cached, err := db.GetIssues(owner, repo, numbers)
+	if err != nil {
+		return nil, err
+	}
+	closed := make([]Closed, 0, len(cached))
+	for _, i := range cached {
+		closed = append(closed, Closed{Issue: i, ClosedBy: closedBy[i.Number]})
+	}
+	sort.Slice(closed, func(i, j int) bool { return closed[i].Number < closed[j].Number })
+	return closed, nil
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/provider"
+)
+
+// Index describes an index run as a job: the fetcher of the repository, the
+// provider it is recorded under, and what started it.
+type Index struct {
+	Fetcher  provider.Fetcher
+	Provider string
+	// Update fetches only what changed since the last index instead of
+	// re-listing every release.
+	Update bool
+	// Source is recorded in the cache's job history, such as "mcp" or "api".
+	Source string
+	// After, when set, runs once the release pairs are cached, even when
+	// some failed, before the job ends.
+	After func()
+}
+
+// RunIndex indexes the job's repository and ends the job with the outcome.
+// The job is recorded in the cache's job history, its progress follows the
+// release pairs fetched, and cancelling it stops the index between pairs;
+// the pairs fetched so far stay cached and the index run is left unfinished,
+// so 'ordiff index --resume' can complete it.
+func RunIndex(job *Job, db cache.Store, idx Index) {
+	status := job.Status()
+	owner, repo := status.Owner, status.Repo
+
+	kind := "index"
+	if idx.Update {
+		kind = "update"
+	}
+	host, _ := os.Hostname()
+	record := &cache.IndexJob{Owner: owner, Repo: repo, Kind: kind, Source: idx.Source, Host: host, PID: os.Getpid()}
+	if err := db.StartIndexJob(record); err != nil {
+		slog.Warn("Failed to record index job", "err", err)
+	}
+	defer func() {
+		if record.ID == 0 {
+			return
+		}
+		status := job.Status()
+		if err := db.EndIndexJob(record.ID, status.State, status.Error); err != nil {
+			slog.Warn("Failed to record index job", "err", err)
+		}
+	}()
+
+	if c, ok := idx.Fetcher.(provider.Cancellable); ok {
+		c.SetContext(job.Context())
+	}
+	if p, ok := idx.Fetcher.(provider.Progressing); ok {
+		p.SetProgress(func(done, total int) {
+			job.Progress(done, total, "Fetched "+strconv.Itoa(done)+" of "+strconv.Itoa(total)+" release pairs")
+		})
+	}
+
+	job.SetMessage("Fetching releases...")
+	var err error
+	if idx.Update {
+		err = idx.Fetcher.Update(db)
+	} else {
+		err = idx.Fetcher.IndexAll(db)
+	}
+	if job.Context().Err() != nil {
+		job.Stop("Cancelled; the release pairs fetched so far are cached, run 'ordiff index --resume' or index again to fetch the rest")
+		return
+	}
+	if err != nil && !provider.Partial(err) {
+		job.Fail(err.Error())
+		return
+	}
+
+	if err := db.SaveRepository(owner, repo, idx.Provider, ""); err != nil {
+		slog.Warn("Failed to record repository", "err", err)
+	}
+	if idx.After != nil {
+		idx.After()
+	}
+
+	if err != nil {
+		job.Fail(err.Error() + "; the other pairs are cached, update again or run 'ordiff index --resume' to fetch the missing ones")
+		return
+	}
+	job.Finish("Indexed " + owner + "/" + repo)
+}
@@ -0,0 +1,223 @@
+// Package jobs runs background indexing jobs. Every job has an ID and a
+// context that is cancelled by Cancel; each repository has at most one
+// running job at a time.
+package jobs
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"ordiff/internal/cache"
+)
+
+// Job states, the same as those recorded in the cache's job history.
+const (
+	Running   = cache.JobRunning
+	Completed = cache.JobCompleted
+	Failed    = cache.JobFailed
+	Cancelled = cache.JobCancelled
+)
+
+// Status is a snapshot of a job.
+type Status struct {
+	ID        string    `json:"job_id"`
+	Owner     string    `json:"owner"`
+	Repo      string    `json:"repo"`
+	State     string    `json:"state"`
+	IsRunning bool      `json:"is_running"`
+	Progress  int       `json:"progress"`
+	Total     int       `json:"total"`
+	Message   string    `json:"message"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Job is one background run. Its methods are safe to call from any goroutine.
+type Job struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	status Status
+}
+
+// Manager keeps the jobs started in this process.
+type Manager struct {
+	mu     sync.Mutex
+	next   int
+	byID   map[string]*Job
+	byRepo map[string]*Job // the latest job of each owner/repo
+
+	running sync.WaitGroup
+}
+
+func NewManager() *Manager {
+	return &Manager{byID: map[string]*Job{}, byRepo: map[string]*Job{}}
+}
+
+// Start creates a running job for a repository. If one is already running
+// for it, that job is returned with false.
+func (m *Manager) Start(owner, repo, message string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := owner + "/" + repo
+	if job, ok := m.byRepo[key]; ok && job.Status().IsRunning {
+		return job, false
+	}
+
+	m.next++
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{ctx: ctx, cancel: cancel, status: Status{
+		ID:        "job-" + strconv.Itoa(m.next),
+		Owner:     owner,
+		Repo:      repo,
+		State:     Running,
+		IsRunning: true,
+		Total:     100,
+		Message:   message,
+		StartedAt: time.Now(),
+	}}
+	m.byID[job.status.ID] = job
+	m.byRepo[key] = job
+	return job, true
+}
+
+// Go runs fn, the work of a started job, in the background. Shutdown waits
+// for it to return.
+func (m *Manager) Go(fn func()) {
+	m.running.Add(1)
+	go func() {
+		defer m.running.Done()
+		fn()
+	}()
+}
+
+// Shutdown cancels every running job and waits for the functions passed to
+// Go to return, so they can record how far they got, or until ctx is done.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	for _, job := range m.byID {
+		job.Cancel()
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Get returns a job by ID.
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.byID[id]
+	return job, ok
+}
+
+// ForRepo returns the latest job of a repository.
+func (m *Manager) ForRepo(owner, repo string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.byRepo[owner+"/"+repo]
+	return job, ok
+}
+
+// List returns the latest job of every repository, running jobs first.
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	statuses := make([]Status, 0, len(m.byRepo))
+	for _, job := range m.byRepo {
+		statuses = append(statuses, job.Status())
+	}
+	m.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].IsRunning != statuses[j].IsRunning {
+			return statuses[i].IsRunning
+		}
+		return statuses[i].Owner+"/"+statuses[i].Repo < statuses[j].Owner+"/"+statuses[j].Repo
+	})
+	return statuses
+}
+
+// Context is cancelled when the job is.
+func (j *Job) Context() context.Context {
+	return j.ctx
+}
+
+func (j *Job) ID() string {
+	return j.status.ID
+}
+
+func (j *Job) Status() Status {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.status
+}
+
+// Cancel asks a running job to stop; the job marks itself Cancelled once it
+// has. It returns false if the job already ended.
+func (j *Job) Cancel() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.status.IsRunning {
+		return false
+	}
+	j.status.Message = "Cancelling..."
+	j.cancel()
+	return true
+}
+
+func (j *Job) SetMessage(message string) {
+	j.mu.Lock()
+	j.status.Message = message
+	j.mu.Unlock()
+}
+
+func (j *Job) Progress(progress, total int, message string) {
+	j.mu.Lock()
+	j.status.Progress = progress
+	j.status.Total = total
+	j.status.Message = message
+	j.mu.Unlock()
+}
+
+// Fail ends the job with an error.
+func (j *Job) Fail(err string) {
+	j.end(Failed, err, err)
+}
+
+// Finish ends the job successfully, leaving message as its summary.
+func (j *Job) Finish(message string) {
+	j.end(Completed, message, "")
+}
+
+// Stop ends a cancelled job; progress stays where it stopped.
+func (j *Job) Stop(message string) {
+	j.end(Cancelled, message, "")
+}
+
+func (j *Job) end(state, message, err string) {
+	j.mu.Lock()
+	j.status.State = state
+	j.status.IsRunning = false
+	j.status.Message = message
+	j.status.Error = err
+	if state == Completed {
+		j.status.Progress = j.status.Total
+	}
+	j.mu.Unlock()
+	j.cancel()
+}
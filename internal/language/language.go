@@ -0,0 +1,184 @@
+// Package language classifies changed files by programming language, with
+// docs, CI and build files set apart so a release can be judged at a glance
+// as core code or housekeeping.
+package language
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/submodule"
+)
+
+// Categories that are not a programming language.
+const (
+	Docs      = "Docs"
+	CI        = "CI"
+	Build     = "Build"
+	Config    = "Config"
+	Submodule = "Submodule"
+	Other     = "Other"
+)
+
+var byExtension = map[string]string{
+	".go":     "Go",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hpp":    "C++",
+	".hh":     "C++",
+	".cu":     "CUDA",
+	".cuh":    "CUDA",
+	".metal":  "Metal",
+	".m":      "Objective-C",
+	".mm":     "Objective-C",
+	".py":     "Python",
+	".js":     "JavaScript",
+	".jsx":    "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".rs":     "Rust",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".swift":  "Swift",
+	".rb":     "Ruby",
+	".php":    "PHP",
+	".cs":     "C#",
+	".scala":  "Scala",
+	".zig":    "Zig",
+	".lua":    "Lua",
+	".dart":   "Dart",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".ps1":    "PowerShell",
+	".sql":    "SQL",
+	".proto":  "Protobuf",
+	".html":   "HTML",
+	".css":    "CSS",
+	".scss":   "CSS",
+	".vue":    "Vue",
+	".svelte": "Svelte",
+	".md":     Docs,
+	".mdx":    Docs,
+	".rst":    Docs,
+	".adoc":   Docs,
+	".txt":    Docs,
+	".yaml":   Config,
+	".yml":    Config,
+	".json":   Config,
+	".toml":   Config,
+	".ini":    Config,
+	".cmake":  Build,
+	".mk":     Build,
+	".gradle": Build,
+}
+
+var byName = map[string]string{
+	"Makefile":       Build,
+	"GNUmakefile":    Build,
+	"CMakeLists.txt": Build,
+	"Dockerfile":     Build,
+	"go.mod":         Build,
+	"go.sum":         Build,
+	"package.json":   Build,
+	"Cargo.toml":     Build,
+	"LICENSE":        Docs,
+	"NOTICE":         Docs,
+	"AUTHORS":        Docs,
+	"CODEOWNERS":     Config,
+}
+
+// Of returns the language or category of a file. CI configuration and
+// everything under a docs directory are classified by place rather than
+// extension.
+func Of(filename string) string {
+	switch {
+	case strings.HasPrefix(filename, ".github/workflows/"), strings.HasPrefix(filename, ".circleci/"),
+		filename == ".gitlab-ci.yml", filename == ".travis.yml", filename == "azure-pipelines.yml":
+		return CI
+	case strings.HasPrefix(filename, "docs/"), strings.HasPrefix(filename, "doc/"), strings.Contains(filename, "/docs/"):
+		return Docs
+	}
+	base := path.Base(filename)
+	if l, ok := byName[base]; ok {
+		return l
+	}
+	switch {
+	case strings.HasPrefix(base, "Dockerfile"):
+		return Build
+	case strings.HasPrefix(base, "README"), strings.HasPrefix(base, "CHANGELOG"):
+		return Docs
+	}
+	if l, ok := byExtension[strings.ToLower(path.Ext(base))]; ok {
+		return l
+	}
+	return Other
+}
+
+// Stat is the changes to the files of one language.
+type Stat struct {
+	Language  string `json:"language"`
+	Files     int    `json:"files"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// Breakdown sums files by language, most changed lines first. Submodules
+// are told apart by their patch, when it is loaded.
+func Breakdown(files []cache.FileChange) []Stat {
+	index := map[string]int{}
+	stats := []Stat{}
+	for _, fc := range files {
+		l := Of(fc.Filename)
+		if submodule.IsGitlink(fc) {
+			l = Submodule
+		}
+		i, ok := index[l]
+		if !ok {
+			i = len(stats)
+			index[l] = i
+			stats = append(stats, Stat{Language: l})
+		}
+		stats[i].Files++
+		stats[i].Additions += fc.Additions
+		stats[i].Deletions += fc.Deletions
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Additions+stats[i].Deletions > stats[j].Additions+stats[j].Deletions
+	})
+	return stats
+}
+
+// Summary renders a breakdown on one line, such as
+// "Go +1.2k/-300, C++ +4k/-2k, Docs +200".
+func Summary(stats []Stat) string {
+	parts := make([]string, len(stats))
+	for i, s := range stats {
+		parts[i] = s.Language + " +" + Compact(s.Additions)
+		if s.Deletions > 0 {
+			parts[i] += "/-" + Compact(s.Deletions)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Compact abbreviates a line count above a thousand, as 1.2k or 14k.
+func Compact(n int) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprint(n)
+	case n < 10000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1000), ".0") + "k"
+	default:
+		return fmt.Sprintf("%dk", (n+500)/1000)
+	}
+}
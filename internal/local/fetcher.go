@@ -0,0 +1,581 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/codeowners"
+	"ordiff/internal/provider"
+	"ordiff/internal/semver"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Fetcher indexes tags and commits from a git repository on disk, so no
+// API access or tokens are needed.
+type Fetcher struct {
+	owner string
+	repo  string
+	path  string
+	git   *git.Repository
+	ctx   context.Context
+
+	// reach holds the commits reachable from reachOf, the newer release of
+	// the last pair walked, which is the older release of the next one.
+	reach   map[plumbing.Hash]bool
+	reachOf plumbing.Hash
+
+	releaseRange provider.Range
+	onProgress   func(done, total int)
+}
+
+func NewFetcher(owner, repo, path string) (*Fetcher, error) {
+	r, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository %s: %w", path, err)
+	}
+	return &Fetcher{owner: owner, repo: repo, path: path, git: r, ctx: context.Background()}, nil
+}
+
+// SetContext makes indexing stop when ctx is cancelled: a diff in progress
+// is abandoned, no further release pairs are started, and the index is left
+// unfinished so Resume can pick it up.
+func (f *Fetcher) SetContext(ctx context.Context) {
+	f.ctx = ctx
+}
+
+// SetProgress registers a callback told how many of the release pairs being
+// indexed are done, once before the first and after each one.
+func (f *Fetcher) SetProgress(fn func(done, total int)) {
+	f.onProgress = fn
+}
+
+func (f *Fetcher) IndexAll(db cache.Store) error {
+	slog.Info("Reading tags", "path", f.path)
+
+	releases, err := f.FetchReleases()
+	if err != nil {
+		return fmt.Errorf("failed to read tags: %w", err)
+	}
+
+	slog.Info("Found tags, caching", "count", len(releases))
+
+	defer func() {
+		if err := db.ClearCompareCache(f.owner, f.repo); err != nil {
+			slog.Warn("Failed to invalidate compare cache", "err", err)
+		}
+	}()
+
+	for _, r := range releases {
+		if err := db.SaveRelease(r); err != nil {
+			return fmt.Errorf("failed to save release %s: %w", r.TagName, err)
+		}
+	}
+
+	pairsErr := f.indexPairs(db, releases, false)
+	if pairsErr != nil && !provider.Partial(pairsErr) {
+		return pairsErr
+	}
+	if err := f.indexCodeowners(db); err != nil {
+		slog.Warn("Failed to read CODEOWNERS", "err", err)
+	}
+	return pairsErr
+}
+
+// Resume continues an index that was interrupted, without reading tags
+// again: the pairs of the releases cached by the aborted run that are still
+// missing are walked.
+func (f *Fetcher) Resume(db cache.Store) error {
+	run, err := db.GetIndexRun(f.owner, f.repo)
+	if err != nil {
+		return fmt.Errorf("failed to read index progress: %w", err)
+	}
+	if run == nil || run.Finished {
+		return fmt.Errorf("no interrupted index of %s/%s to resume", f.owner, f.repo)
+	}
+
+	slog.Info("Resuming index", "repo", f.owner+"/"+f.repo, "started", run.StartedAt.Local().Format("2006-01-02 15:04"),
+		"done", run.DonePairs, "total", run.TotalPairs)
+
+	cached, err := db.GetReleases(f.owner, f.repo)
+	if err != nil {
+		return fmt.Errorf("failed to read cached releases: %w", err)
+	}
+	releases := make([]*cache.Release, len(cached))
+	for i := range cached {
+		releases[i] = &cached[i]
+	}
+	f.sortReleases(releases)
+
+	defer func() {
+		if err := db.ClearCompareCache(f.owner, f.repo); err != nil {
+			slog.Warn("Failed to invalidate compare cache", "err", err)
+		}
+	}()
+	return f.indexPairs(db, releases, true)
+}
+
+// indexPairs walks every release pair that is not cached yet, oldest first,
+// so that each walk starts from the commits the previous one reached
+// instead of the whole history. Progress is recorded as an index run, which
+// is left open for Resume when the context is cancelled or a pair fails.
+func (f *Fetcher) indexPairs(db cache.Store, releases []*cache.Release, resuming bool) error {
+	var pending [][2]*cache.Release
+	skipped := 0
+	for i := len(releases) - 2; i >= 0; i-- {
+		from := releases[i+1]
+		to := releases[i]
+
+		alreadyCached, err := db.HasFileChangesCached(f.owner, f.repo, from.TagName, to.TagName)
+		if err != nil {
+			slog.Warn("Failed to check cache", "err", err)
+		}
+		if alreadyCached && db.IsPairStale(f.owner, f.repo, from.TagName, to.TagName) {
+			if err := db.DeleteFileChanges(f.owner, f.repo, from.TagName, to.TagName); err != nil {
+				slog.Warn("Failed to clear stale file changes", "err", err)
+			}
+			alreadyCached = false
+		}
+		if alreadyCached {
+			skipped++
+			slog.Debug("Skipping pair, already cached", "from", from.TagName, "to", to.TagName)
+			continue
+		}
+		pending = append(pending, [2]*cache.Release{from, to})
+	}
+
+	if resuming {
+		slog.Info("Resuming", "pairs_left", len(pending))
+	} else if err := db.StartIndexRun(f.owner, f.repo, len(pending)); err != nil {
+		slog.Warn("Failed to record index progress", "err", err)
+	}
+
+	saved := 0
+	var failed []provider.PairFailure
+	progress := func() {
+		if f.onProgress != nil {
+			f.onProgress(saved+len(failed), len(pending))
+		}
+	}
+	progress()
+
+	for i, pair := range pending {
+		if err := f.ctx.Err(); err != nil {
+			slog.Warn("Indexing cancelled", "done", saved, "total", len(pending))
+			return err
+		}
+		from, to := pair[0], pair[1]
+		slog.Info("Processing pair", "from", from.TagName, "to", to.TagName, "done", i+1, "total", len(pending), "skipped", skipped)
+
+		if err := f.indexPair(db, from, to); err != nil {
+			if f.ctx.Err() != nil {
+				continue
+			}
+			slog.Error("Failed to fetch pair", "from", from.TagName, "to", to.TagName, "err", err)
+			failed = append(failed, provider.PairFailure{From: from.TagName, To: to.TagName, Err: err})
+			progress()
+			continue
+		}
+
+		saved++
+		progress()
+		if err := db.AdvanceIndexRun(f.owner, f.repo); err != nil {
+			slog.Warn("Failed to record index progress", "err", err)
+		}
+	}
+
+	if err := f.ctx.Err(); err != nil {
+		slog.Warn("Indexing cancelled", "done", saved, "total", len(pending))
+		return err
+	}
+
+	// The run stays open, so --resume walks just the pairs that failed.
+	if len(failed) > 0 {
+		slog.Warn("Indexing incomplete", "failed", len(failed), "total", len(pending))
+		return &provider.PairErrors{Failed: failed, Total: len(pending)}
+	}
+
+	if err := db.FinishIndexRun(f.owner, f.repo); err != nil {
+		slog.Warn("Failed to record index progress", "err", err)
+	}
+	slog.Info("Indexing complete", "processed", len(pending), "skipped", skipped)
+	return nil
+}
+
+// indexPair walks the commits and diffs the trees of a release pair and
+// saves them.
+func (f *Fetcher) indexPair(db cache.Store, from, to *cache.Release) error {
+	commits, err := f.commitsBetween(from.CommitSHA, to.CommitSHA)
+	if err != nil {
+		return fmt.Errorf("failed to walk commits: %w", err)
+	}
+
+	files, err := f.fileChanges(from.CommitSHA, to.CommitSHA)
+	if err != nil {
+		return fmt.Errorf("failed to diff trees: %w", err)
+	}
+	for _, fc := range files {
+		fc.FromRelease = from.TagName
+		fc.ToRelease = to.TagName
+	}
+
+	if err := db.SavePairContext(f.ctx, &cache.PairData{
+		Owner:       f.owner,
+		Repo:        f.repo,
+		FromRelease: from.TagName,
+		ToRelease:   to.TagName,
+		Commits:     commits,
+		Files:       files,
+	}); err != nil {
+		return fmt.Errorf("failed to save release pair: %w", err)
+	}
+	return nil
+}
+
+// indexCodeowners caches the CODEOWNERS file of HEAD, from the first of
+// codeowners.Locations that exists, or an empty path if there is none.
+func (f *Fetcher) indexCodeowners(db cache.Store) error {
+	head, err := f.git.Head()
+	if err != nil {
+		return err
+	}
+	commit, err := f.git.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	for _, path := range codeowners.Locations {
+		file, err := commit.File(path)
+		if err == object.ErrFileNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		return db.SaveCodeowners(f.owner, f.repo, path, content)
+	}
+	return db.SaveCodeowners(f.owner, f.repo, "", "")
+}
+
+// Update is the same as IndexAll: reading tags is cheap and cached pairs are
+// skipped either way.
+func (f *Fetcher) Update(db cache.Store) error {
+	return f.IndexAll(db)
+}
+
+// FetchReleases treats every tag as a release, newest first, within the
+// range set by SetRange. Annotated tags use their tagger date and message;
+// lightweight tags use the commit date.
+func (f *Fetcher) FetchReleases() ([]*cache.Release, error) {
+	releases, err := f.listReleases()
+	if err != nil {
+		return nil, err
+	}
+	return f.releaseRange.Apply(releases)
+}
+
+// SetRange limits IndexAll to part of the release history.
+func (f *Fetcher) SetRange(r provider.Range) {
+	f.releaseRange = r
+}
+
+func (f *Fetcher) listReleases() ([]*cache.Release, error) {
+	tags, err := f.git.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []*cache.Release
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		release := &cache.Release{
+			TagName: ref.Name().Short(),
+			Name:    ref.Name().Short(),
+			Owner:   f.owner,
+			Repo:    f.repo,
+		}
+
+		var commit *object.Commit
+		if tag, err := f.git.TagObject(ref.Hash()); err == nil {
+			commit, err = tag.Commit()
+			if err != nil {
+				// Tags pointing at trees or blobs are not releases.
+				return nil
+			}
+			release.PublishedAt = tag.Tagger.When
+			release.Body = strings.TrimSpace(tag.Message)
+		} else {
+			commit, err = f.git.CommitObject(ref.Hash())
+			if err != nil {
+				return nil
+			}
+			release.PublishedAt = commit.Committer.When
+		}
+		release.CommitSHA = commit.Hash.String()
+
+		releases = append(releases, release)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f.sortReleases(releases)
+	return releases, nil
+}
+
+// sortReleases orders releases newest first. Tags with the same date, as
+// when several point at one commit or one script created them, are ordered
+// by ancestry, then by version, then by name.
+func (f *Fetcher) sortReleases(releases []*cache.Release) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		a, b := releases[i], releases[j]
+		if !a.PublishedAt.Equal(b.PublishedAt) {
+			return a.PublishedAt.After(b.PublishedAt)
+		}
+		if a.CommitSHA != b.CommitSHA {
+			if f.isAncestor(b.CommitSHA, a.CommitSHA) {
+				return true
+			}
+			if f.isAncestor(a.CommitSHA, b.CommitSHA) {
+				return false
+			}
+		}
+		if c := semver.CompareStrings(a.TagName, b.TagName); c != 0 {
+			return c > 0
+		}
+		return a.TagName > b.TagName
+	})
+}
+
+// isAncestor reports whether commit a is an ancestor of commit b.
+func (f *Fetcher) isAncestor(a, b string) bool {
+	ca, err := f.git.CommitObject(plumbing.NewHash(a))
+	if err != nil {
+		return false
+	}
+	cb, err := f.git.CommitObject(plumbing.NewHash(b))
+	if err != nil {
+		return false
+	}
+	ok, err := ca.IsAncestor(cb)
+	return err == nil && ok
+}
+
+// commitsBetween returns the commits reachable from toSHA but not from fromSHA.
+// The ancestry of fromSHA is only walked when it is not the toSHA of the
+// previous call, or that call's fromSHA was not an ancestor of its toSHA:
+// the commits reached then include a side branch that toSHA does not.
+func (f *Fetcher) commitsBetween(fromSHA, toSHA string) ([]*cache.Commit, error) {
+	from, err := f.git.CommitObject(plumbing.NewHash(fromSHA))
+	if err != nil {
+		return nil, err
+	}
+	to, err := f.git.CommitObject(plumbing.NewHash(toSHA))
+	if err != nil {
+		return nil, err
+	}
+
+	if f.reach == nil || f.reachOf != from.Hash {
+		f.reach, f.reachOf = map[plumbing.Hash]bool{}, plumbing.ZeroHash
+		err = object.NewCommitPreorderIter(from, nil, nil).ForEach(func(c *object.Commit) error {
+			f.reach[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			f.reach = nil
+			return nil, err
+		}
+	}
+
+	// from is an ancestor of to when a commit walked has it as a parent;
+	// a path from to into from's history has to enter it through from.
+	descends := from.Hash == to.Hash
+	var commits []*cache.Commit
+	err = object.NewCommitPreorderIter(to, f.reach, nil).ForEach(func(c *object.Commit) error {
+		f.reach[c.Hash] = true
+		for _, p := range c.ParentHashes {
+			descends = descends || p == from.Hash
+		}
+		commits = append(commits, &cache.Commit{
+			SHA:         c.Hash.String(),
+			Message:     c.Message,
+			Author:      c.Author.Name,
+			AuthorEmail: c.Author.Email,
+			Date:        c.Author.When,
+			Owner:       f.owner,
+			Repo:        f.repo,
+			PrNumber:    extractPrNumber(c.Message),
+			Parents:     parents(c),
+		})
+		return nil
+	})
+	if err != nil {
+		f.reach = nil
+		return nil, err
+	}
+	if descends {
+		f.reachOf = to.Hash
+	} else {
+		f.reach = nil
+	}
+	return commits, nil
+}
+
+func (f *Fetcher) fileChanges(fromSHA, toSHA string) ([]*cache.FileChange, error) {
+	from, err := f.git.CommitObject(plumbing.NewHash(fromSHA))
+	if err != nil {
+		return nil, err
+	}
+	to, err := f.git.CommitObject(plumbing.NewHash(toSHA))
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := from.PatchContext(f.ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]object.FileStat{}
+	for _, s := range patch.Stats() {
+		stats[s.Name] = s
+	}
+	hunks := splitPatch(patch.String())
+
+	var changes []*cache.FileChange
+	for _, fp := range patch.FilePatches() {
+		src, dst := fp.Files()
+
+		status := "modified"
+		name, previous, stat := "", "", ""
+		switch {
+		case src == nil:
+			status = "added"
+			name = dst.Path()
+		case dst == nil:
+			status = "removed"
+			name = src.Path()
+		default:
+			name = dst.Path()
+			if src.Path() != dst.Path() {
+				status = "renamed"
+				previous = src.Path()
+				// go-git names the stats of a rename "old => new".
+				stat = previous + " => " + name
+			}
+		}
+		if stat == "" {
+			stat = name
+		}
+
+		st := stats[stat]
+		changes = append(changes, &cache.FileChange{
+			Filename:         name,
+			PreviousFilename: previous,
+			Additions:        st.Addition,
+			Deletions:        st.Deletion,
+			Changes:          st.Addition + st.Deletion,
+			Status:           status,
+			Patch:            hunks[name],
+			Owner:            f.owner,
+			Repo:             f.repo,
+		})
+	}
+	return changes, nil
+}
+
+// splitPatch breaks a unified diff into per-file hunks without the file
+// headers, matching the patch format the GitHub API returns.
+func splitPatch(patch string) map[string]string {
+	hunks := map[string]string{}
+	for _, section := range strings.Split(patch, "diff --git ")[1:] {
+		lines := strings.Split(section, "\n")
+
+		name := ""
+		start := -1
+		for i, line := range lines {
+			switch {
+			case strings.HasPrefix(line, "+++ b/"):
+				name = strings.TrimPrefix(line, "+++ b/")
+			case strings.HasPrefix(line, "--- a/") && name == "":
+				name = strings.TrimPrefix(line, "--- a/")
+			case strings.HasPrefix(line, "@@"):
+				start = i
+			}
+			if start != -1 {
+				break
+			}
+		}
+		if name == "" || start == -1 {
+			continue
+		}
+		hunks[name] = strings.TrimRight(strings.Join(lines[start:], "\n"), "\n")
+	}
+	return hunks
+}
+
+func extractPrNumber(msg string) *int {
+	idx := strings.Index(msg, "#")
+	if idx == -1 {
+		return nil
+	}
+	n := 0
+	digits := 0
+	for _, c := range msg[idx+1:] {
+		if c < '0' || c > '9' {
+			break
+		}
+		n = n*10 + int(c-'0')
+		digits++
+	}
+	if digits == 0 {
+		return nil
+	}
+	return &n
+}
+
+// SourceFiles reads the files of a commit for which keep returns true,
+// keyed by their path in the repository.
+func (f *Fetcher) SourceFiles(sha string, keep func(path string) bool) (map[string][]byte, error) {
+	commit, err := f.git.CommitObject(plumbing.NewHash(sha))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", sha, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", sha, err)
+	}
+
+	files := map[string][]byte{}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		if !keep(file.Name) {
+			return nil
+		}
+		contents, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		files[file.Name] = []byte(contents)
+		return nil
+	})
+	return files, err
+}
+
+// parents returns the SHAs of a commit's parents.
+func parents(c *object.Commit) []string {
+	shas := make([]string, len(c.ParentHashes))
+	for i, h := range c.ParentHashes {
+		shas[i] = h.String()
+	}
+	return shas
+}
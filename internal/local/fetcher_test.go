@@ -0,0 +1,267 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"ordiff/internal/cache"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testRepo is a git repository on disk whose commits are made one file
+// write at a time.
+type testRepo struct {
+	t    *testing.T
+	dir  string
+	git  *git.Repository
+	tree *git.Worktree
+	n    int
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testRepo{t: t, dir: dir, git: r, tree: w}
+}
+
+// commit writes a new file and commits it on top of parents, the first of
+// which is checked out; with none it commits on HEAD.
+func (r *testRepo) commit(when time.Time, parents ...plumbing.Hash) plumbing.Hash {
+	r.t.Helper()
+	if len(parents) > 0 {
+		if err := r.tree.Checkout(&git.CheckoutOptions{Hash: parents[0], Force: true}); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.n++
+	name := filepath.Join("f", string(rune('a'+r.n)))
+	if err := os.MkdirAll(filepath.Join(r.dir, "f"), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, name), []byte(name+"\n"), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+	if _, err := r.tree.Add(name); err != nil {
+		r.t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "Ada", Email: "ada@example.com", When: when}
+	h, err := r.tree.Commit("change "+name, &git.CommitOptions{Author: sig, Committer: sig, Parents: parents})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return h
+}
+
+// tag creates an annotated tag dated when, or a lightweight one when when
+// is zero.
+func (r *testRepo) tag(name string, h plumbing.Hash, when time.Time) {
+	r.t.Helper()
+	var opts *git.CreateTagOptions
+	if !when.IsZero() {
+		opts = &git.CreateTagOptions{Tagger: &object.Signature{Name: "Ada", Email: "ada@example.com", When: when}, Message: name}
+	}
+	if _, err := r.git.CreateTag(name, h, opts); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) index(t *testing.T) (*Fetcher, *cache.DB) {
+	t.Helper()
+	db, err := cache.NewMemoryDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	f, err := NewFetcher("local", "repo", r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.IndexAll(db); err != nil {
+		t.Fatal(err)
+	}
+	return f, db
+}
+
+func tagNames(releases []cache.Release) []string {
+	var names []string
+	for _, r := range releases {
+		names = append(names, r.TagName)
+	}
+	return names
+}
+
+func pairCommits(t *testing.T, db *cache.DB, from, to string) []string {
+	t.Helper()
+	commits, err := db.GetCommitsBetween("local", "repo", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shas []string
+	for _, c := range commits {
+		shas = append(shas, c.SHA)
+	}
+	sort.Strings(shas)
+	return shas
+}
+
+func TestIndexOrdersTagsAcrossTimeZones(t *testing.T) {
+	r := newTestRepo(t)
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	c1 := r.commit(base)
+	c2 := r.commit(base.Add(time.Hour))
+	c3 := r.commit(base.Add(2 * time.Hour))
+	// 08:00Z, 10:00Z and 12:00Z, which sort the other way round as written.
+	r.tag("v1.0.0", c1, time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("", 2*3600)))
+	r.tag("v1.1.0", c2, time.Date(2024, 3, 1, 5, 0, 0, 0, time.FixedZone("", -5*3600)))
+	r.tag("v1.2.0", c3, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	_, db := r.index(t)
+
+	releases, err := db.GetReleases("local", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tagNames(releases), []string{"v1.2.0", "v1.1.0", "v1.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("releases %v, want %v", got, want)
+	}
+	files, err := db.GetFileChanges("local", "repo", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("v1.0.0..v1.1.0: %d files changed, want 1", len(files))
+	}
+	if got, want := pairCommits(t, db, "v1.0.0", "v1.2.0"), sorted(c2.String(), c3.String()); !reflect.DeepEqual(got, want) {
+		t.Errorf("v1.0.0..v1.2.0: commits %v, want %v", got, want)
+	}
+}
+
+func TestIndexFollowsSideBranches(t *testing.T) {
+	r := newTestRepo(t)
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return base.AddDate(0, 0, n) }
+
+	c1 := r.commit(day(0))
+	r.tag("v1.0.0", c1, time.Time{})
+	c2 := r.commit(day(1))
+	c3 := r.commit(day(2))
+	r.tag("v1.5.0", c3, time.Time{})
+	// A maintenance release branched off v1.0.0 after v1.5.0 was out.
+	b1 := r.commit(day(3), c1)
+	r.tag("v1.0.1", b1, time.Time{})
+	c4 := r.commit(day(4), c3, b1)
+	r.tag("v1.6.0", c4, time.Time{})
+
+	_, db := r.index(t)
+
+	for _, tc := range []struct {
+		from, to string
+		want     []string
+	}{
+		{"v1.0.0", "v1.5.0", sorted(c2.String(), c3.String())},
+		{"v1.5.0", "v1.0.1", sorted(b1.String())},
+		{"v1.0.1", "v1.6.0", sorted(c2.String(), c3.String(), c4.String())},
+	} {
+		if got := pairCommits(t, db, tc.from, tc.to); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s..%s: commits %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+func TestFetchReleasesBreaksDateTies(t *testing.T) {
+	r := newTestRepo(t)
+	when := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	c1 := r.commit(when)
+	c2 := r.commit(when)
+	// Same date: the descendant is newer even with the lower version, and
+	// tags on one commit go by version rather than by name.
+	r.tag("v2.0.0", c1, time.Time{})
+	r.tag("v1.2.0", c2, time.Time{})
+	r.tag("v1.9.0", c1, time.Time{})
+	r.tag("v1.10.0", c1, time.Time{})
+
+	f, err := NewFetcher("local", "repo", r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases, err := f.FetchReleases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rel := range releases {
+		got = append(got, rel.TagName)
+	}
+	if want := []string{"v1.2.0", "v2.0.0", "v1.10.0", "v1.9.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("releases %v, want %v", got, want)
+	}
+}
+
+func TestResumeCompletesCancelledIndex(t *testing.T) {
+	r := newTestRepo(t)
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, tag := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		r.tag(tag, r.commit(base.AddDate(0, 0, i)), time.Time{})
+	}
+
+	db, err := cache.NewMemoryDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	f, err := NewFetcher("local", "repo", r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f.SetContext(ctx)
+	if err := f.IndexAll(db); err != context.Canceled {
+		t.Fatalf("cancelled index returned %v", err)
+	}
+	run, err := db.GetIndexRun("local", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run == nil || run.Finished || run.TotalPairs != 2 {
+		t.Fatalf("index run after cancelling: %+v", run)
+	}
+
+	f.SetContext(context.Background())
+	var done, total int
+	f.SetProgress(func(d, t int) { done, total = d, t })
+	if err := f.Resume(db); err != nil {
+		t.Fatal(err)
+	}
+	if done != 2 || total != 2 {
+		t.Errorf("progress %d of %d, want 2 of 2", done, total)
+	}
+	if run, err = db.GetIndexRun("local", "repo"); err != nil || run == nil || !run.Finished || run.DonePairs != 2 {
+		t.Errorf("index run after resuming: %+v, %v", run, err)
+	}
+	if n, err := db.GetReleasePairCount("local", "repo"); err != nil || n != 2 {
+		t.Errorf("%d release pairs cached, want 2 (%v)", n, err)
+	}
+}
+
+func sorted(shas ...string) []string {
+	sort.Strings(shas)
+	return shas
+}
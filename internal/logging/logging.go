@@ -0,0 +1,59 @@
+// Package logging sets up the leveled, structured logger ordiff reports
+// progress and warnings with. Messages go through log/slog; fatal errors
+// still raised with the log package come out at error level.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Verbose, Quiet and JSON are set by the --verbose, --quiet and --log-json
+// flags.
+var (
+	Verbose bool
+	Quiet   bool
+	JSON    bool
+)
+
+// Level returns the lowest level logged: debug with --verbose, warnings
+// with --quiet, else info.
+func Level() slog.Level {
+	switch {
+	case Verbose:
+		return slog.LevelDebug
+	case Quiet:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// Setup makes a logger writing to w the default of log/slog and of the log
+// package.
+func Setup(w io.Writer) {
+	opts := &slog.HandlerOptions{Level: Level()}
+	var h slog.Handler = slog.NewTextHandler(w, opts)
+	if JSON {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(h))
+	// The log package is left for log.Fatal, which must show even with
+	// --quiet.
+	slog.SetLogLoggerLevel(slog.LevelError)
+}
+
+// SetupFile sends the log to the file at path, appending to it, and returns
+// the file for the caller to close.
+func SetupFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	Setup(f)
+	return f, nil
+}
@@ -0,0 +1,159 @@
+// Package mailmap merges author identities the way git's .mailmap does, so
+// one person committing under several names or emails is counted once.
+package mailmap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type identity struct {
+	name, email string
+}
+
+// Mailmap maps commit identities to canonical ones. The zero value and nil
+// map nothing.
+type Mailmap struct {
+	byEmail     map[string]identity
+	byNameEmail map[identity]identity
+}
+
+func New() *Mailmap {
+	return &Mailmap{byEmail: map[string]identity{}, byNameEmail: map[identity]identity{}}
+}
+
+// ParseFile reads a .mailmap file.
+func ParseFile(path string) (*Mailmap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := New()
+	if err := m.Read(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Read adds every entry of a .mailmap file. Blank lines and # comments are
+// skipped.
+func (m *Mailmap) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		if err := m.Add(scanner.Text()); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Add adds one .mailmap entry, in any of git's forms:
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+func (m *Mailmap) Add(line string) error {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	var names, emails []string
+	rest := line
+	for {
+		open := strings.IndexByte(rest, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '>')
+		if end < 0 {
+			return fmt.Errorf("unterminated email in %q", line)
+		}
+		names = append(names, strings.TrimSpace(rest[:open]))
+		emails = append(emails, strings.TrimSpace(rest[open+1:open+end]))
+		rest = rest[open+end+1:]
+	}
+
+	switch len(emails) {
+	case 1:
+		// Proper Name <commit@email>
+		if names[0] == "" {
+			return fmt.Errorf("no name in %q", line)
+		}
+		m.set(identity{email: emails[0]}, identity{name: names[0]})
+	case 2:
+		m.set(identity{name: names[1], email: emails[1]}, identity{name: names[0], email: emails[0]})
+	default:
+		return fmt.Errorf("expected one or two emails in %q", line)
+	}
+	return nil
+}
+
+func (m *Mailmap) set(from, to identity) {
+	if m.byEmail == nil {
+		*m = *New()
+	}
+	from.email = strings.ToLower(from.email)
+
+	// Entries for the same commit identity merge, like in git: a later line
+	// can fill in the name or email an earlier one left out.
+	var prev identity
+	if from.name != "" {
+		prev = m.byNameEmail[from]
+	} else {
+		prev = m.byEmail[from.email]
+	}
+	if to.name == "" {
+		to.name = prev.name
+	}
+	if to.email == "" {
+		to.email = prev.email
+	}
+
+	if from.name != "" {
+		m.byNameEmail[from] = to
+	} else {
+		m.byEmail[from.email] = to
+	}
+}
+
+// Len returns the number of entries.
+func (m *Mailmap) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.byEmail) + len(m.byNameEmail)
+}
+
+// Resolve returns the canonical name and email of a commit author. Emails
+// match case-insensitively and names exactly; entries naming the commit
+// author win over entries matching only the email.
+func (m *Mailmap) Resolve(name, email string) (string, string) {
+	if m.Len() == 0 {
+		return name, email
+	}
+
+	key := identity{name: name, email: strings.ToLower(email)}
+	to, ok := m.byNameEmail[key]
+	if !ok {
+		to, ok = m.byEmail[key.email]
+	}
+	if !ok {
+		return name, email
+	}
+	if to.name != "" {
+		name = to.name
+	}
+	if to.email != "" {
+		email = to.email
+	}
+	return name, email
+}
@@ -0,0 +1,191 @@
+// Package metrics runs the user's measurement hooks against releases, such
+// as a script that builds a tag and reports the binary size or one that runs
+// benchmarks, and compares what they measured for two releases.
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/command"
+)
+
+// DefaultTimeout bounds a hook run without a timeout of its own.
+const DefaultTimeout = 30 * time.Minute
+
+// Hook is a metrics entry of the config: a shell command run once per
+// release in Dir, typically a checkout of the repository. The release is
+// passed in ORDIFF_OWNER, ORDIFF_REPO, ORDIFF_TAG and ORDIFF_COMMIT.
+type Hook struct {
+	Name    string        `mapstructure:"name" json:"name"`
+	Command string        `mapstructure:"command" json:"command"`
+	Dir     string        `mapstructure:"dir" json:"dir,omitempty"`
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout,omitempty"`
+}
+
+// Validate fills in the defaults of h and checks the rest.
+func (h *Hook) Validate() error {
+	if h.Name == "" || h.Command == "" {
+		return fmt.Errorf("every metrics hook needs a name and a command")
+	}
+	if rest, ok := strings.CutPrefix(h.Dir, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			h.Dir = filepath.Join(home, rest)
+		}
+	}
+	if h.Timeout <= 0 {
+		h.Timeout = DefaultTimeout
+	}
+	return nil
+}
+
+// Run runs a hook for a release and returns what it measured.
+func Run(ctx context.Context, h Hook, r *cache.Release) ([]cache.Metric, error) {
+	out, err := command.Command{
+		Path: "sh",
+		Args: []string{"-c", h.Command},
+		Dir:  h.Dir,
+		Env: []string{
+			"ORDIFF_OWNER=" + r.Owner,
+			"ORDIFF_REPO=" + r.Repo,
+			"ORDIFF_TAG=" + r.TagName,
+			"ORDIFF_COMMIT=" + r.CommitSHA,
+		},
+		Timeout: h.Timeout,
+	}.Output(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := Parse(bytes.NewReader(out))
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no metrics in the output")
+	}
+	now := time.Now()
+	for i := range metrics {
+		metrics[i].Hook = h.Name
+		metrics[i].CollectedAt = now
+	}
+	return metrics, nil
+}
+
+// Parse reads the metrics a hook printed, one "<name> <value> [unit]" per
+// line, such as "binary_size 48213504 B". Go benchmark results are read as
+// well, one metric per value and unit of each benchmark. Other lines are
+// ignored, so hooks may print progress.
+func Parse(r io.Reader) []cache.Metric {
+	var metrics []cache.Metric
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 4 && strings.HasPrefix(fields[0], "Benchmark") {
+			metrics = append(metrics, parseBenchmark(fields)...)
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		m := cache.Metric{Name: fields[0], Value: v}
+		if len(fields) == 3 {
+			m.Unit = fields[2]
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// parseBenchmark reads a line of go test -bench output: the name with its
+// GOMAXPROCS suffix, the iterations, then value and unit pairs.
+func parseBenchmark(fields []string) []cache.Metric {
+	name := fields[0]
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	if _, err := strconv.Atoi(fields[1]); err != nil {
+		return nil
+	}
+	var metrics []cache.Metric
+	for i := 2; i+1 < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			break
+		}
+		metrics = append(metrics, cache.Metric{Name: name, Value: v, Unit: fields[i+1]})
+	}
+	return metrics
+}
+
+// Delta is a metric of either release. From or To is nil when only one
+// release has it, and Change and Percent are then 0.
+type Delta struct {
+	Hook    string   `json:"hook"`
+	Name    string   `json:"name"`
+	Unit    string   `json:"unit,omitempty"`
+	From    *float64 `json:"from"`
+	To      *float64 `json:"to"`
+	Change  float64  `json:"change"`
+	Percent float64  `json:"percent"`
+}
+
+// Compare pairs the metrics of two releases by hook, name and unit, largest
+// relative change first and metrics only one release has last.
+func Compare(from, to []cache.Metric) []Delta {
+	key := func(m cache.Metric) string { return m.Hook + "\x00" + m.Name + "\x00" + m.Unit }
+	deltas := map[string]*Delta{}
+	var keys []string
+	for _, m := range from {
+		v := m.Value
+		keys = append(keys, key(m))
+		deltas[key(m)] = &Delta{Hook: m.Hook, Name: m.Name, Unit: m.Unit, From: &v}
+	}
+	for _, m := range to {
+		v := m.Value
+		d, ok := deltas[key(m)]
+		if !ok {
+			keys = append(keys, key(m))
+			d = &Delta{Hook: m.Hook, Name: m.Name, Unit: m.Unit}
+			deltas[key(m)] = d
+		}
+		d.To = &v
+	}
+
+	var out []Delta
+	for _, k := range keys {
+		d := deltas[k]
+		if d.From != nil && d.To != nil {
+			d.Change = *d.To - *d.From
+			if *d.From != 0 {
+				d.Percent = d.Change * 100 / math.Abs(*d.From)
+			}
+		}
+		out = append(out, *d)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		bothI, bothJ := out[i].From != nil && out[i].To != nil, out[j].From != nil && out[j].To != nil
+		if bothI != bothJ {
+			return bothI
+		}
+		if math.Abs(out[i].Percent) != math.Abs(out[j].Percent) {
+			return math.Abs(out[i].Percent) > math.Abs(out[j].Percent)
+		}
+		return out[i].Hook+out[i].Name < out[j].Hook+out[j].Name
+	})
+	return out
+}
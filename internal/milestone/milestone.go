@@ -0,0 +1,84 @@
+// Package milestone cross-checks the issues and pull requests assigned to a
+// milestone against what a release range actually shipped.
+package milestone
+
+import (
+	"ordiff/internal/cache"
+	"ordiff/internal/issues"
+)
+
+// Statuses of a milestone item relative to a release range.
+const (
+	// StatusShipped is a pull request merged in the range, or an issue
+	// that one of its pull requests or commits closes.
+	StatusShipped = "shipped"
+	// StatusOutside is closed or merged, but not by anything in the range:
+	// it shipped in another release, or was closed without a closing
+	// keyword.
+	StatusOutside = "outside"
+	// StatusSlipped is still open.
+	StatusSlipped = "slipped"
+	// StatusDropped is an issue closed as not planned, or a pull request
+	// closed without merging.
+	StatusDropped = "dropped"
+)
+
+type Item struct {
+	cache.MilestoneItem
+	Status string `json:"status"`
+}
+
+// Report is a milestone checked against a release range. Completion is the
+// percentage of the items that were not dropped that shipped in the range.
+type Report struct {
+	cache.Milestone
+	Total      int     `json:"total"`
+	Shipped    int     `json:"shipped"`
+	Outside    int     `json:"outside"`
+	Slipped    int     `json:"slipped"`
+	Dropped    int     `json:"dropped"`
+	Completion float64 `json:"completion"`
+	Items      []Item  `json:"items"`
+}
+
+// Check classifies the items of m against the merged pull requests, commits
+// and closed issues of a release range.
+func Check(m *cache.Milestone, commits []cache.Commit, prs []cache.PullRequest, closed []issues.Closed) *Report {
+	merged := map[int]bool{}
+	for _, pr := range prs {
+		merged[pr.Number] = true
+	}
+	for _, c := range commits {
+		if c.PrNumber != nil {
+			merged[*c.PrNumber] = true
+		}
+	}
+	fixed := map[int]bool{}
+	for _, i := range closed {
+		fixed[i.Number] = true
+	}
+
+	r := &Report{Milestone: *m, Total: len(m.Items), Items: make([]Item, 0, len(m.Items))}
+	for _, i := range m.Items {
+		item := Item{MilestoneItem: i}
+		switch {
+		case i.PullRequest && merged[i.Number], !i.PullRequest && fixed[i.Number]:
+			item.Status = StatusShipped
+			r.Shipped++
+		case i.State == "open":
+			item.Status = StatusSlipped
+			r.Slipped++
+		case i.PullRequest && i.MergedAt == nil, !i.PullRequest && i.StateReason == "not_planned":
+			item.Status = StatusDropped
+			r.Dropped++
+		default:
+			item.Status = StatusOutside
+			r.Outside++
+		}
+		r.Items = append(r.Items, item)
+	}
+	if planned := r.Total - r.Dropped; planned > 0 {
+		r.Completion = float64(r.Shipped) * 100 / float64(planned)
+	}
+	return r
+}
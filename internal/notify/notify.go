@@ -0,0 +1,150 @@
+// Package notify posts release comparisons to chat channels and webhooks.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/report"
+)
+
+const (
+	maxFiles   = 5
+	maxPRs     = 10
+	maxCommits = 5
+)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Event is the JSON body posted to generic webhooks.
+type Event struct {
+	Repo         string `json:"repo"`
+	From         string `json:"from_release"`
+	To           string `json:"to_release"`
+	Commits      int    `json:"commit_count"`
+	PRs          int    `json:"pr_count"`
+	FilesChanged int    `json:"files_changed"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	Summary      string `json:"summary"`
+}
+
+func NewEvent(r *github.CompareResult) Event {
+	e := Event{
+		Repo:         r.ToRelease.Owner + "/" + r.ToRelease.Repo,
+		From:         r.FromRelease.TagName,
+		To:           r.ToRelease.TagName,
+		Commits:      len(r.Commits),
+		PRs:          r.PrCount,
+		FilesChanged: len(r.Files),
+		Summary:      Summary(r),
+	}
+	for _, f := range r.Files {
+		e.Additions += f.Additions
+		e.Deletions += f.Deletions
+	}
+	return e
+}
+
+// Summary renders a comparison as a short message in Slack's mrkdwn, which
+// reads fine as plain text too: counts, the most changed files, merged PRs
+// and, when there are no PRs, the latest commits.
+func Summary(r *github.CompareResult) string {
+	return "*" + title(r) + "*\n" + body(r)
+}
+
+func title(r *github.CompareResult) string {
+	return fmt.Sprintf("%s/%s %s → %s", r.ToRelease.Owner, r.ToRelease.Repo, r.FromRelease.TagName, r.ToRelease.TagName)
+}
+
+// body is the summary below the title. It sticks to the markup Slack and
+// Discord share: inline code and bullets.
+func body(r *github.CompareResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Commits: %d · PRs: %d · Files changed: %d\n", len(r.Commits), r.PrCount, len(r.Files))
+
+	if len(r.Files) > 0 {
+		files := append([]cache.FileChange{}, r.Files...)
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].Changes > files[j].Changes
+		})
+		sb.WriteString("\nTop files:\n")
+		for _, f := range files[:min(maxFiles, len(files))] {
+			fmt.Fprintf(&sb, "• `%s` +%d -%d\n", f.Filename, f.Additions, f.Deletions)
+		}
+	}
+
+	if len(r.PullRequests) > 0 {
+		sb.WriteString("\nMerged PRs:\n")
+		for _, pr := range r.PullRequests[:min(maxPRs, len(r.PullRequests))] {
+			fmt.Fprintf(&sb, "• #%d %s (@%s)\n", pr.Number, pr.Title, pr.Author)
+		}
+		if len(r.PullRequests) > maxPRs {
+			fmt.Fprintf(&sb, "… and %d more\n", len(r.PullRequests)-maxPRs)
+		}
+	} else if len(r.Commits) > 0 {
+		sb.WriteString("\nCommits:\n")
+		for _, c := range r.Commits[len(r.Commits)-min(maxCommits, len(r.Commits)):] {
+			fmt.Fprintf(&sb, "• %s\n", report.Subject(c.Message))
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// Slack posts text to a Slack incoming webhook.
+func Slack(url, text string) error {
+	return post(url, map[string]string{"text": text})
+}
+
+// discordLimit is the length Discord allows for an embed description.
+const discordLimit = 4096
+
+// Discord posts a comparison to a Discord webhook as an embed.
+func Discord(url string, r *github.CompareResult) error {
+	description := body(r)
+	if len(description) > discordLimit {
+		description = description[:strings.LastIndexByte(description[:discordLimit-len("\n…")], '\n')] + "\n…"
+	}
+	return post(url, map[string]interface{}{
+		"embeds": []map[string]interface{}{{
+			"title":       title(r),
+			"description": description,
+		}},
+	})
+}
+
+// Webhook posts the event as JSON to any URL.
+func Webhook(url string, e Event) error {
+	return post(url, e)
+}
+
+func post(url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if len(bytes.TrimSpace(msg)) == 0 {
+			return fmt.Errorf("%s", resp.Status)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
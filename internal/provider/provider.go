@@ -0,0 +1,182 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/retry"
+)
+
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+	Local  = "local"
+)
+
+// Fetcher is implemented by every forge backend. All backends write to the
+// same cache schema, so compare and the other read commands work unchanged.
+type Fetcher interface {
+	IndexAll(db cache.Store) error
+	Update(db cache.Store) error
+	FetchReleases() ([]*cache.Release, error)
+}
+
+// Concurrent is implemented by backends that can fetch several release pairs
+// in parallel.
+type Concurrent interface {
+	SetConcurrency(n int)
+}
+
+// Resumer is implemented by backends that can continue an interrupted index
+// from the progress recorded in the cache.
+type Resumer interface {
+	Resume(db cache.Store) error
+}
+
+// Cancellable is implemented by backends whose indexing stops cleanly when
+// the context is cancelled, leaving an unfinished run for Resume.
+type Cancellable interface {
+	SetContext(ctx context.Context)
+}
+
+// TagReleaser is implemented by backends that can index plain git tags as
+// releases, for projects that tag versions without publishing releases.
+type TagReleaser interface {
+	SetTagReleases(on bool)
+}
+
+// HTTPCached is implemented by backends that can revalidate API responses
+// stored in the cache with conditional requests.
+type HTTPCached interface {
+	SetHTTPCache(db cache.Store)
+}
+
+// Sources is implemented by backends that can read the files of a commit,
+// which source-level comparisons such as apidiff need.
+type Sources interface {
+	SourceFiles(sha string, keep func(path string) bool) (map[string][]byte, error)
+}
+
+// Retrying is implemented by backends that retry API calls failing with a
+// server error or a network failure.
+type Retrying interface {
+	SetRetryPolicy(p retry.Policy)
+}
+
+// Progressing is implemented by backends that report how many of the
+// release pairs an index fetches are done while it runs.
+type Progressing interface {
+	SetProgress(fn func(done, total int))
+}
+
+// Ranged is implemented by backends that can index part of the release
+// history instead of all of it.
+type Ranged interface {
+	SetRange(r Range)
+}
+
+// Range limits an index to part of the release history.
+type Range struct {
+	FromTag, ToTag string    // the oldest and newest release to index
+	Since          time.Time // releases published before it are left out
+}
+
+func (r Range) IsZero() bool {
+	return r.FromTag == "" && r.ToTag == "" && r.Since.IsZero()
+}
+
+// Apply returns the releases within the range, from releases listed newest
+// first as every backend lists them.
+func (r Range) Apply(releases []*cache.Release) ([]*cache.Release, error) {
+	if r.IsZero() {
+		return releases, nil
+	}
+	find := func(tag string) (int, error) {
+		for i, rel := range releases {
+			if rel.TagName == tag {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("release %s not found", tag)
+	}
+
+	start, end := 0, len(releases)
+	if r.ToTag != "" {
+		i, err := find(r.ToTag)
+		if err != nil {
+			return nil, err
+		}
+		start = i
+	}
+	if r.FromTag != "" {
+		i, err := find(r.FromTag)
+		if err != nil {
+			return nil, err
+		}
+		if i < start {
+			return nil, fmt.Errorf("release %s is newer than %s", r.FromTag, r.ToTag)
+		}
+		end = i + 1
+	}
+
+	var kept []*cache.Release
+	for _, rel := range releases[start:end] {
+		if !r.Since.IsZero() && rel.PublishedAt.Before(r.Since) {
+			continue
+		}
+		kept = append(kept, rel)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no releases in the range")
+	}
+	return kept, nil
+}
+
+// Quota is the API rate limit of a backend.
+type Quota struct {
+	Limit, Remaining int
+	Reset            time.Time
+}
+
+// Estimator is implemented by backends whose indexing costs API requests,
+// so 'index --estimate' can tell what an index would take.
+type Estimator interface {
+	// CallsPerPair is the least number of requests fetching one release
+	// pair takes.
+	CallsPerPair() int
+	// RateLimit returns the current quota, or nil if there is none.
+	RateLimit() (*Quota, error)
+}
+
+// PairFailure is a release pair that could not be fetched.
+type PairFailure struct {
+	From, To string
+	Err      error
+}
+
+// PairErrors is returned by an index that fetched every release pair it
+// could but gave up on some, which stay missing until the next index or
+// resume fetches them.
+type PairErrors struct {
+	Failed []PairFailure
+	Total  int
+}
+
+func (e *PairErrors) Error() string {
+	first := e.Failed[0]
+	msg := fmt.Sprintf("%d of %d release pairs failed; %s → %s: %v", len(e.Failed), e.Total, first.From, first.To, first.Err)
+	if len(e.Failed) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Failed)-1)
+	}
+	return msg
+}
+
+// Partial reports whether err only says that some release pairs failed, so
+// the steps of an index after fetching pairs can still run.
+func Partial(err error) bool {
+	var pe *PairErrors
+	return errors.As(err, &pe)
+}
@@ -0,0 +1,39 @@
+package report
+
+import (
+	"regexp"
+	"strings"
+)
+
+// BaseURL is the web root used for autolinks. It is overridden for GitHub
+// Enterprise instances.
+var BaseURL = "https://github.com"
+
+var (
+	issueRefRe = regexp.MustCompile(`(^|[\s(\[,;:])(?:([\w.-]+/[\w.-]+))?#(\d+)\b`)
+	mentionRe  = regexp.MustCompile(`(^|[\s(\[,;:])@([A-Za-z0-9](?:[A-Za-z0-9-]{0,38}))\b`)
+)
+
+// Autolink turns #123, owner/repo#123 and @user references into Markdown
+// links the way GitHub renders them. Plain #N links to the issues URL, which
+// GitHub redirects to the pull request when N is a PR.
+func Autolink(text, owner, repo string) string {
+	base := strings.TrimSuffix(BaseURL, "/")
+
+	text = issueRefRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := issueRefRe.FindStringSubmatch(m)
+		prefix, target, num := sub[1], sub[2], sub[3]
+		label := "#" + num
+		if target == "" {
+			target = owner + "/" + repo
+		} else {
+			label = target + label
+		}
+		return prefix + "[" + label + "](" + base + "/" + target + "/issues/" + num + ")"
+	})
+
+	return mentionRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := mentionRe.FindStringSubmatch(m)
+		return sub[1] + "[@" + sub[2] + "](" + base + "/" + sub[2] + ")"
+	})
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"ordiff/internal/cache"
+)
+
+// NewCSVWriter returns a CSV writer, or a TSV writer when tabs is set.
+func NewCSVWriter(w io.Writer, tabs bool) *csv.Writer {
+	cw := csv.NewWriter(w)
+	if tabs {
+		cw.Comma = '\t'
+	}
+	return cw
+}
+
+func ReleasesCSV(w *csv.Writer, releases []cache.Release) error {
+	w.Write([]string{"owner", "repo", "tag_name", "name", "published_at", "commit_sha"})
+	for _, r := range releases {
+		w.Write([]string{r.Owner, r.Repo, r.TagName, r.Name, r.PublishedAt.UTC().Format(time.RFC3339), r.CommitSHA})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func CommitsCSV(w *csv.Writer, commits []cache.Commit) error {
+	w.Write([]string{"owner", "repo", "sha", "date", "author", "author_email", "pr_number", "subject", "url"})
+	for _, c := range commits {
+		pr := ""
+		if c.PrNumber != nil {
+			pr = strconv.Itoa(*c.PrNumber)
+		}
+		w.Write([]string{c.Owner, c.Repo, c.SHA, c.Date.UTC().Format(time.RFC3339), c.Author, c.AuthorEmail, pr, Subject(c.Message), c.URL})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// FileChangesCSV writes one row per file and release pair. The patch column
+// is only included when withPatch is set.
+func FileChangesCSV(w *csv.Writer, files []cache.FileChange, withPatch bool) error {
+	header := []string{"owner", "repo", "from_release", "to_release", "filename", "status", "additions", "deletions", "changes"}
+	if withPatch {
+		header = append(header, "patch")
+	}
+	w.Write(header)
+	for _, f := range files {
+		row := []string{f.Owner, f.Repo, f.FromRelease, f.ToRelease, f.Filename, f.Status,
+			strconv.Itoa(f.Additions), strconv.Itoa(f.Deletions), strconv.Itoa(f.Changes)}
+		if withPatch {
+			row = append(row, f.Patch)
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"ordiff/internal/cache"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorBold  = "\033[1m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+)
+
+// UnifiedDiff writes a file change as a git-style unified diff. The cached
+// patch only holds the hunks, so the file headers are reconstructed from the
+// change status. With color set, the output uses ANSI colors like git diff.
+func UnifiedDiff(w io.Writer, fc cache.FileChange, color bool) {
+	paint := func(c, line string) string {
+		if !color {
+			return line
+		}
+		return c + line + colorReset
+	}
+
+	oldName, newName := "a/"+fc.Filename, "b/"+fc.Filename
+	switch fc.Status {
+	case "added":
+		oldName = "/dev/null"
+	case "removed":
+		newName = "/dev/null"
+	}
+
+	fmt.Fprintln(w, paint(colorBold, fmt.Sprintf("diff --git a/%s b/%s", fc.Filename, fc.Filename)))
+	fmt.Fprintln(w, paint(colorBold, "--- "+oldName))
+	fmt.Fprintln(w, paint(colorBold, "+++ "+newName))
+
+	if fc.Patch == "" {
+		fmt.Fprintf(w, "(no patch available: binary file, too large for the compare API or indexed with --no-patches, +%d -%d)\n", fc.Additions, fc.Deletions)
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(fc.Patch, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			line = paint(colorCyan, line)
+		case strings.HasPrefix(line, "+"):
+			line = paint(colorGreen, line)
+		case strings.HasPrefix(line, "-"):
+			line = paint(colorRed, line)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,157 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+)
+
+type htmlData struct {
+	*github.CompareResult
+	TopFiles   []cache.FileChange
+	MoreFiles  int
+	Files      []cache.FileChange
+	Additions  int
+	Deletions  int
+	Labels     []string
+	LabeledPRs map[string][]cache.PullRequest
+}
+
+type patchLine struct {
+	Class string
+	Text  string
+}
+
+var htmlFuncs = template.FuncMap{
+	"short":   shortSHA,
+	"subject": Subject,
+	"patch": func(patch string) []patchLine {
+		var lines []patchLine
+		for _, line := range strings.Split(strings.TrimRight(patch, "\n"), "\n") {
+			class := ""
+			switch {
+			case strings.HasPrefix(line, "@@"):
+				class = "hunk"
+			case strings.HasPrefix(line, "+"):
+				class = "add"
+			case strings.HasPrefix(line, "-"):
+				class = "del"
+			}
+			lines = append(lines, patchLine{Class: class, Text: line})
+		}
+		return lines
+	},
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.ToRelease.Owner}}/{{.ToRelease.Repo}} {{.FromRelease.TagName}} → {{.ToRelease.TagName}}</title>
+<style>
+body { margin: 0 auto; padding: 16px 32px; max-width: 1100px; font: 14px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #d0d7de; }
+td.num { text-align: right; }
+code, pre { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 12px; }
+.stats { display: flex; gap: 24px; margin: 12px 0; }
+.stats div { padding: 8px 16px; border: 1px solid #d0d7de; border-radius: 6px; }
+.stats b { display: block; font-size: 20px; }
+.add { color: #1a7f37; }
+.del { color: #cf222e; }
+.note { color: #656d76; font-style: italic; }
+details { border: 1px solid #d0d7de; border-radius: 6px; margin: 4px 0; }
+details summary { padding: 4px 8px; cursor: pointer; }
+details pre { margin: 0; padding: 8px; overflow-x: auto; background: #f6f8fa; }
+pre span { display: block; }
+pre .add { background: #dafbe1; }
+pre .del { background: #ffebe9; }
+pre .hunk { color: #0969da; }
+</style>
+</head>
+<body>
+<h1>{{.ToRelease.Owner}}/{{.ToRelease.Repo}}: {{.FromRelease.TagName}} → {{.ToRelease.TagName}}</h1>
+
+<div class="stats">
+<div><b>{{len .Commits}}</b>commits</div>
+<div><b>{{.PrCount}}</b>PRs</div>
+<div><b>{{len .Files}}</b>files changed</div>
+<div><b><span class="add">+{{.Additions}}</span> <span class="del">-{{.Deletions}}</span></b>lines</div>
+</div>
+{{- if eq .FileSource "aggregated"}}
+<p class="note">Releases are not adjacent; file changes aggregated across intervening release pairs.</p>
+{{- end}}
+{{- if .TopFiles}}
+
+<h2>Top changed files</h2>
+<table>
+<tr><th>File</th><th>+</th><th>-</th></tr>
+{{- range .TopFiles}}
+<tr><td><code>{{.Filename}}</code></td><td class="num add">{{.Additions}}</td><td class="num del">{{.Deletions}}</td></tr>
+{{- end}}
+</table>
+{{- if .MoreFiles}}
+<p class="note">… and {{.MoreFiles}} more files</p>
+{{- end}}
+{{- end}}
+{{- if .Labels}}
+
+<h2>Merged PRs</h2>
+{{- range $label := .Labels}}
+<h3>{{$label}}</h3>
+<ul>
+{{- range index $.LabeledPRs $label}}
+<li><a href="{{.URL}}">#{{.Number}}</a> {{.Title}} (@{{.Author}})</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+{{- if .Commits}}
+
+<h2>Commits</h2>
+<ul>
+{{- range .Commits}}
+<li>{{if .URL}}<a href="{{.URL}}"><code>{{short .SHA}}</code></a>{{else}}<code>{{short .SHA}}</code>{{end}} {{subject .Message}}{{if .Author}} ({{.Author}}){{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Files}}
+
+<h2>Diffs</h2>
+{{- range .Files}}
+<details>
+<summary><span class="add">+{{.Additions}}</span> <span class="del">-{{.Deletions}}</span> <code>{{.Filename}}</code></summary>
+{{- if .Patch}}
+<pre>{{range patch .Patch}}<span{{if .Class}} class="{{.Class}}"{{end}}>{{.Text}}</span>{{end}}</pre>
+{{- else}}
+<p class="note">No patch available.</p>
+{{- end}}
+</details>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML renders a self-contained report page with summary stats, top files,
+// merged PRs, commits and the diff of every file.
+func HTML(w io.Writer, r *github.CompareResult) error {
+	files := byChanges(r.Files)
+	data := htmlData{
+		CompareResult: r,
+		Files:         files,
+		TopFiles:      files[:min(maxMarkdownFiles, len(files))],
+		MoreFiles:     max(len(files)-maxMarkdownFiles, 0),
+	}
+	for _, f := range files {
+		data.Additions += f.Additions
+		data.Deletions += f.Deletions
+	}
+	if len(r.PullRequests) > 0 {
+		data.Labels, data.LabeledPRs = r.PullRequestsByLabel()
+	}
+	return htmlTemplate.Execute(w, data)
+}
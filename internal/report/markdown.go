@@ -0,0 +1,137 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/changelog"
+	"ordiff/internal/github"
+)
+
+const maxMarkdownFiles = 20
+
+func Markdown(w io.Writer, r *github.CompareResult) {
+	fmt.Fprintf(w, "## %s → %s\n\n", r.FromRelease.TagName, r.ToRelease.TagName)
+	fmt.Fprintf(w, "**Commits:** %d · **PRs:** %d · **Files changed:** %d\n\n", len(r.Commits), r.PrCount, len(r.Files))
+
+	kind := ""
+	for _, n := range changelog.Notices(r.Commits, r.PullRequests) {
+		if n.Kind != kind {
+			if kind != "" {
+				fmt.Fprintln(w)
+			}
+			kind = n.Kind
+			if kind == changelog.KindBreaking {
+				fmt.Fprint(w, "### ⚠ Breaking changes\n\n")
+			} else {
+				fmt.Fprint(w, "### Deprecations\n\n")
+			}
+		}
+		if n.URL != "" {
+			fmt.Fprintf(w, "- [`%s`](%s) %s\n", n.Ref, n.URL, n.Title)
+		} else {
+			fmt.Fprintf(w, "- `%s` %s\n", n.Ref, n.Title)
+		}
+	}
+	if kind != "" {
+		fmt.Fprintln(w)
+	}
+
+	if len(r.Files) > 0 {
+		files := byChanges(r.Files)
+
+		fmt.Fprintln(w, "### Top changed files")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| File | + | - |")
+		fmt.Fprintln(w, "|------|--:|--:|")
+		for _, f := range files[:min(maxMarkdownFiles, len(files))] {
+			fmt.Fprintf(w, "| `%s` | %d | %d |\n", f.Filename, f.Additions, f.Deletions)
+		}
+		if len(files) > maxMarkdownFiles {
+			fmt.Fprintf(w, "\n_… and %d more files_\n", len(files)-maxMarkdownFiles)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.PullRequests) > 0 {
+		fmt.Fprintln(w, "### Merged PRs")
+		fmt.Fprintln(w)
+		labels, groups := r.PullRequestsByLabel()
+		for _, label := range labels {
+			fmt.Fprintf(w, "**%s**\n\n", label)
+			for _, pr := range groups[label] {
+				fmt.Fprintf(w, "- [#%d](%s) %s (@%s)\n", pr.Number, pr.URL, pr.Title, pr.Author)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	if len(r.Commits) > 0 {
+		fmt.Fprintln(w, "### Commits")
+		fmt.Fprintln(w)
+		for _, c := range r.Commits {
+			subject := Autolink(Subject(c.Message), r.ToRelease.Owner, r.ToRelease.Repo)
+			if c.URL != "" {
+				fmt.Fprintf(w, "- [`%s`](%s) %s", shortSHA(c.SHA), c.URL, subject)
+			} else {
+				fmt.Fprintf(w, "- `%s` %s", shortSHA(c.SHA), subject)
+			}
+			if c.Author != "" {
+				fmt.Fprintf(w, " (%s)", c.Author)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// MarkdownDiffs renders the patch of every file as a collapsed diff block,
+// largest change first.
+func MarkdownDiffs(w io.Writer, files []cache.FileChange) {
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "### Diffs")
+	fmt.Fprintln(w)
+	for _, f := range byChanges(files) {
+		fmt.Fprintf(w, "<details>\n<summary><code>%s</code> +%d -%d</summary>\n\n", f.Filename, f.Additions, f.Deletions)
+		if f.Patch == "" {
+			fmt.Fprintln(w, "_No patch available._")
+		} else {
+			fence := "```"
+			for strings.Contains(f.Patch, fence) {
+				fence += "`"
+			}
+			fmt.Fprintf(w, "%sdiff\n%s\n%s\n", fence, strings.TrimRight(f.Patch, "\n"), fence)
+		}
+		fmt.Fprintln(w, "\n</details>")
+		fmt.Fprintln(w)
+	}
+}
+
+func byChanges(files []cache.FileChange) []cache.FileChange {
+	sorted := make([]cache.FileChange, len(files))
+	copy(sorted, files)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Changes > sorted[j].Changes
+	})
+	return sorted
+}
+
+func Subject(msg string) string {
+	if i := strings.IndexByte(msg, '\n'); i != -1 {
+		msg = msg[:i]
+	}
+	return strings.TrimSpace(msg)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
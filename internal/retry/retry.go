@@ -0,0 +1,129 @@
+// Package retry decides when an API call that failed on the way to a forge
+// is worth trying again, and how long to wait before doing so.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Policy says how often a call that failed with a transient error is
+// retried. Delays grow exponentially from BaseDelay up to MaxDelay, with
+// jitter so concurrent workers don't retry in lockstep.
+type Policy struct {
+	Attempts  int // retries after the first try; 0 disables retrying
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Default is used unless the config sets retry.
+var Default = Policy{Attempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// Delay returns how long to wait before retry n, counted from 0. A
+// Retry-After header in resp takes precedence over the backoff.
+func (p Policy) Delay(n int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait := RetryAfter(resp.Header); wait > 0 {
+			return wait
+		}
+	}
+	d := p.MaxDelay
+	if n < 30 && p.BaseDelay<<n < p.MaxDelay {
+		d = p.BaseDelay << n
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Wait at least half the backoff, the rest at random.
+	return d/2 + rand.N(d/2+1)
+}
+
+// RetryAfter parses a Retry-After header, given in seconds or as an HTTP
+// date. It returns 0 if there is none.
+func RetryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// Status reports whether a response status is worth retrying: too many
+// requests, and server errors other than 501 Not Implemented.
+func Status(code int) bool {
+	return code == http.StatusTooManyRequests || (code >= 500 && code != http.StatusNotImplemented)
+}
+
+// Network reports whether err is a network failure worth retrying, such as
+// a timeout or a connection reset mid-response. Cancellation is not.
+func Network(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// Wait sleeps for d, or returns ctx's error once it is done.
+func Wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Do sends req with client, retrying server errors and network failures as
+// p allows. Requests with a body must set GetBody to be retried.
+func Do(ctx context.Context, client *http.Client, p Policy, req *http.Request) (*http.Response, error) {
+	for n := 0; ; n++ {
+		if n > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := client.Do(req)
+		if err == nil && !Status(resp.StatusCode) {
+			return resp, nil
+		}
+		if n >= p.Attempts || ctx.Err() != nil || (err != nil && !Network(err)) {
+			return resp, err
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			resp.Body.Close()
+		}
+		wait := p.Delay(n, resp)
+		slog.Warn("Request failed, retrying", "reason", reason, "wait", wait.Round(time.Millisecond), "attempt", n+1, "attempts", p.Attempts)
+		if err := Wait(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
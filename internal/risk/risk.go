@@ -0,0 +1,217 @@
+// Package risk scores how risky an upgrade across a range of releases is,
+// from heuristics over the commits, pull requests and file changes.
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/changelog"
+	"ordiff/internal/deps"
+	"ordiff/internal/filter"
+)
+
+// Names of the factors, also their keys under risk.factors in the config.
+const (
+	FactorCoreChurn    = "core_churn"
+	FactorBreaking     = "breaking_changes"
+	FactorMajorBumps   = "major_bumps"
+	FactorContributors = "contributors"
+)
+
+// FactorConfig weighs one factor. A factor contributes its full weight once
+// its value reaches Saturation, and proportionally less below it.
+type FactorConfig struct {
+	Weight     float64 `mapstructure:"weight"`
+	Saturation float64 `mapstructure:"saturation"`
+}
+
+// Config tunes the heuristics. Weights are relative; the score is scaled so
+// that all factors at saturation make 100.
+type Config struct {
+	// CorePaths are the paths whose churn counts (see filter.MatchPath).
+	// When empty, every file counts except those matching NonCorePaths.
+	CorePaths        []string                `mapstructure:"core_paths"`
+	NonCorePaths     []string                `mapstructure:"non_core_paths"`
+	BreakingKeywords []string                `mapstructure:"breaking_keywords"`
+	Factors          map[string]FactorConfig `mapstructure:"factors"`
+}
+
+// DefaultConfig returns the weights used when the config has no risk key.
+func DefaultConfig() Config {
+	return Config{
+		NonCorePaths: append([]string{
+			"*_test.go", "test/", "tests/", "testdata/", "docs/", "examples/", ".github/", "*.md",
+		}, filter.DefaultGeneratedPatterns...),
+		BreakingKeywords: []string{
+			"breaking change", "breaking-change", "backwards incompatible", "backward incompatible", "backwards-incompatible",
+		},
+		Factors: map[string]FactorConfig{
+			FactorCoreChurn:    {Weight: 35, Saturation: 5000},
+			FactorBreaking:     {Weight: 35, Saturation: 3},
+			FactorMajorBumps:   {Weight: 15, Saturation: 3},
+			FactorContributors: {Weight: 15, Saturation: 30},
+		},
+	}
+}
+
+// Factor is what one heuristic contributed to a Score.
+type Factor struct {
+	Name   string   `json:"name"`
+	Points float64  `json:"points"`
+	Max    float64  `json:"max"`
+	Value  int      `json:"value"`
+	Detail string   `json:"detail"`
+	Items  []string `json:"items,omitempty"`
+}
+
+// Score is an upgrade risk from 0 (trivial) to 100.
+type Score struct {
+	Score   int      `json:"score"`
+	Level   string   `json:"level"`
+	Factors []Factor `json:"factors"`
+}
+
+// Assess scores the changes of a release range.
+func Assess(cfg Config, commits []cache.Commit, prs []cache.PullRequest, files []cache.FileChange) Score {
+	churn := 0
+	for _, f := range files {
+		if isCore(cfg, f.Filename) {
+			churn += f.Additions + f.Deletions
+		}
+	}
+
+	breaking := breakingChanges(cfg.BreakingKeywords, commits, prs)
+
+	var bumps []string
+	for _, d := range deps.ManifestDiffs(files) {
+		for _, c := range d.Changes {
+			if deps.IsMajorBump(c) {
+				bumps = append(bumps, fmt.Sprintf("%s %s → %s", c.Name, c.From, c.To))
+			}
+		}
+	}
+
+	authors := map[string]bool{}
+	for _, c := range commits {
+		authors[c.Author] = true
+	}
+
+	values := []struct {
+		name   string
+		value  int
+		detail string
+		items  []string
+	}{
+		{FactorCoreChurn, churn, fmt.Sprintf("%d lines changed in core paths", churn), nil},
+		{FactorBreaking, len(breaking), fmt.Sprintf("%d breaking changes", len(breaking)), breaking},
+		{FactorMajorBumps, len(bumps), fmt.Sprintf("%d dependency major version bumps", len(bumps)), bumps},
+		{FactorContributors, len(authors), fmt.Sprintf("%d contributors", len(authors)), nil},
+	}
+
+	total := 0.0
+	for _, v := range values {
+		total += math.Max(cfg.Factors[v.name].Weight, 0)
+	}
+
+	s := Score{Factors: []Factor{}}
+	sum := 0.0
+	for _, v := range values {
+		fc := cfg.Factors[v.name]
+		if fc.Weight <= 0 || total == 0 {
+			continue
+		}
+		max := 100 * fc.Weight / total
+		points := max
+		if fc.Saturation > 0 {
+			points = max * math.Min(float64(v.value)/fc.Saturation, 1)
+		}
+		sum += points
+		s.Factors = append(s.Factors, Factor{
+			Name:   v.name,
+			Points: math.Round(points*10) / 10,
+			Max:    math.Round(max*10) / 10,
+			Value:  v.value,
+			Detail: v.detail,
+			Items:  v.items,
+		})
+	}
+	sort.SliceStable(s.Factors, func(i, j int) bool { return s.Factors[i].Points > s.Factors[j].Points })
+
+	s.Score = int(math.Round(sum))
+	switch {
+	case s.Score >= 60:
+		s.Level = "high"
+	case s.Score >= 25:
+		s.Level = "medium"
+	default:
+		s.Level = "low"
+	}
+	return s
+}
+
+func isCore(cfg Config, filename string) bool {
+	if len(cfg.CorePaths) > 0 {
+		for _, p := range cfg.CorePaths {
+			if filter.MatchPath(p, filename) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range cfg.NonCorePaths {
+		if filter.MatchPath(p, filename) {
+			return false
+		}
+	}
+	return true
+}
+
+// breakingChanges lists the pull requests, and the commits not merged
+// through a cached one, that are marked breaking: a conventional-commit "!"
+// or BREAKING CHANGE footer, or one of the keywords in the message, PR
+// title, body or labels.
+func breakingChanges(keywords []string, commits []cache.Commit, prs []cache.PullRequest) []string {
+	byNumber := map[int]cache.PullRequest{}
+	for _, pr := range prs {
+		byNumber[pr.Number] = pr
+	}
+
+	var items []string
+	seen := map[int]bool{}
+	for _, c := range commits {
+		text := c.Message
+		marked := c.Breaking || changelog.Parse(c.Message).Breaking
+		item := c.SHA[:min(7, len(c.SHA))] + " " + changelog.Parse(c.Message).Subject
+
+		if c.PrNumber != nil {
+			if pr, ok := byNumber[*c.PrNumber]; ok {
+				if seen[pr.Number] {
+					continue
+				}
+				seen[pr.Number] = true
+				text += "\n" + pr.Title + "\n" + pr.Body + "\n" + strings.Join(pr.Labels, "\n")
+				marked = marked || pr.Breaking || changelog.Parse(pr.Title).Breaking
+				item = fmt.Sprintf("#%d %s", pr.Number, pr.Title)
+			}
+		}
+
+		if marked || containsAny(text, keywords) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsAny(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, k := range keywords {
+		if k != "" && strings.Contains(text, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+// Package schema holds the JSON Schemas of ordiff's machine-readable output,
+// the contract downstream tools can validate against. A schema only changes
+// compatibly within a version: fields may be added, never removed or
+// retyped. Anything else bumps Version and adds a new set of files.
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+)
+
+// Version of the output schemas.
+const Version = 1
+
+// Outputs with a schema.
+const (
+	Compare   = "compare"
+	List      = "list"
+	Summary   = "summary"
+	Changelog = "changelog"
+)
+
+//go:embed *.json
+var files embed.FS
+
+// Names lists the outputs with a schema.
+func Names() []string {
+	return []string{Compare, List, Summary, Changelog}
+}
+
+// Get returns the JSON Schema of an output at the current Version.
+func Get(name string) ([]byte, error) {
+	b, err := files.ReadFile(fmt.Sprintf("%s.v%d.json", name, Version))
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return b, nil
+}
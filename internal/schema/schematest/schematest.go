@@ -0,0 +1,167 @@
+// Package schematest checks output against the JSON Schemas of package
+// schema, for the tests of the commands that write it.
+package schematest
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/schema"
+)
+
+// Validate marshals v as the output name would be written and fails the
+// test when it does not match that output's schema. Top-level fields the
+// schema does not declare fail it too, so new output is documented.
+func Validate(t testing.TB, name string, v interface{}) {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal %s output: %v", name, err)
+	}
+	ValidateJSON(t, name, b)
+}
+
+// ValidateJSON is Validate for output that is already JSON.
+func ValidateJSON(t testing.TB, name string, b []byte) {
+	t.Helper()
+
+	raw, err := schema.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse %s schema: %v", name, err)
+	}
+	id, _ := doc.(map[string]interface{})["$id"].(string)
+
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(id, doc); err != nil {
+		t.Fatalf("add %s schema: %v", name, err)
+	}
+	sch, err := c.Compile(id)
+	if err != nil {
+		t.Fatalf("compile %s schema: %v", name, err)
+	}
+
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("parse %s output: %v", name, err)
+	}
+	if err := sch.Validate(inst); err != nil {
+		t.Fatalf("%s output does not match its schema: %v\n%s", name, err, b)
+	}
+
+	if undeclared := undeclaredFields(doc, inst); len(undeclared) > 0 {
+		t.Errorf("%s output has fields its schema does not declare: %v", name, undeclared)
+	}
+}
+
+// undeclaredFields returns the fields of an object output, or of the
+// objects of an array output, that are missing from the schema's
+// properties.
+func undeclaredFields(doc, inst interface{}) []string {
+	s, _ := doc.(map[string]interface{})
+	objects := []interface{}{inst}
+	if items, ok := inst.([]interface{}); ok {
+		s, _ = s["items"].(map[string]interface{})
+		objects = items
+	}
+	props, _ := s["properties"].(map[string]interface{})
+
+	seen := map[string]bool{}
+	for _, o := range objects {
+		fields, _ := o.(map[string]interface{})
+		for f := range fields {
+			if _, ok := props[f]; !ok {
+				seen[f] = true
+			}
+		}
+	}
+	var undeclared []string
+	for f := range seen {
+		undeclared = append(undeclared, f)
+	}
+	sort.Strings(undeclared)
+	return undeclared
+}
+
+// Fixture repository and releases of NewStore.
+const (
+	Owner = "acme"
+	Repo  = "widget"
+	From  = "v1.0.0"
+	To    = "v1.1.0"
+)
+
+// NewStore returns an in-memory cache holding one indexed release pair of
+// Owner/Repo, From to To, with what the outputs have optional fields for: a
+// merged pull request, a breaking change, a renamed file with its patch, a
+// dependency bump, release assets, tags and metrics.
+func NewStore(t testing.TB) *cache.DB {
+	t.Helper()
+
+	db, err := cache.NewMemoryDB()
+	if err != nil {
+		t.Fatalf("open cache: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	pr := 7
+	merged := day(10)
+
+	releases := []*cache.Release{
+		{TagName: From, Name: "1.0.0", PublishedAt: day(1), CommitSHA: "a1", Owner: Owner, Repo: Repo,
+			Assets: []cache.ReleaseAsset{{Name: "widget-1.0.0-linux-amd64.tar.gz", Size: 1200 << 10, DownloadCount: 40, ContentType: "application/gzip", URL: "https://github.com/acme/widget/releases/download/v1.0.0/widget-1.0.0-linux-amd64.tar.gz"}}},
+		{TagName: To, Name: "1.1.0", PublishedAt: day(20), CommitSHA: "c3", Body: "## Changes\n\n- New config format\n", Owner: Owner, Repo: Repo,
+			Assets: []cache.ReleaseAsset{{Name: "widget-1.1.0-linux-amd64.tar.gz", Size: 1350 << 10, DownloadCount: 3, ContentType: "application/gzip", Digest: "sha256:0f1e", URL: "https://github.com/acme/widget/releases/download/v1.1.0/widget-1.1.0-linux-amd64.tar.gz"}}},
+	}
+	for _, r := range releases {
+		if err := db.SaveRelease(r); err != nil {
+			t.Fatalf("save release: %v", err)
+		}
+	}
+	if err := db.SaveCommit(&cache.Commit{SHA: "a1", Message: "Initial commit", Author: "Ada", AuthorEmail: "ada@example.com", Date: day(1), Owner: Owner, Repo: Repo, Parents: []string{}}); err != nil {
+		t.Fatalf("save commit: %v", err)
+	}
+	if err := db.SaveTags(Owner, Repo, []cache.Tag{
+		{Name: From, CommitSHA: "a1", FetchedAt: day(21)},
+		{Name: To, CommitSHA: "c3", Annotated: true, Tagger: "Ada", TaggerEmail: "ada@example.com", TaggedAt: &releases[1].PublishedAt, Message: "Release 1.1.0", FetchedAt: day(21)},
+	}); err != nil {
+		t.Fatalf("save tags: %v", err)
+	}
+
+	err = db.SavePair(&cache.PairData{
+		Owner: Owner, Repo: Repo, FromRelease: From, ToRelease: To,
+		Commits: []*cache.Commit{
+			{SHA: "b2", Message: "feat(config)!: read config.toml instead of config.yaml (#7)", Author: "Ada", AuthorEmail: "ada@example.com", Date: day(9), URL: "https://github.com/acme/widget/commit/b2", Owner: Owner, Repo: Repo, PrNumber: &pr, Parents: []string{"a1"}},
+			{SHA: "c3", Message: "fix: bump the yaml parser", Author: "Grace", AuthorEmail: "grace@example.com", Date: day(15), Owner: Owner, Repo: Repo, Parents: []string{"b2"}},
+		},
+		PullRequests: []*cache.PullRequest{
+			{Number: pr, Title: "Move config to TOML", Body: "BREAKING CHANGE: config.yaml is no longer read.", State: "closed", MergedAt: &merged, Author: "Ada", URL: "https://github.com/acme/widget/pull/7", Owner: Owner, Repo: Repo, Labels: []string{"breaking"}, MergeCommitSHA: "b2"},
+		},
+		Files: []*cache.FileChange{
+			{Filename: "config.toml", PreviousFilename: "config.yaml", Additions: 2, Deletions: 2, Changes: 4, Status: "renamed", Patch: "@@ -1,2 +1,2 @@\n-name: widget\n-port: 80\n+name = \"widget\"\n+port = 80", Owner: Owner, Repo: Repo, FromRelease: From, ToRelease: To},
+			{Filename: "go.mod", Additions: 1, Deletions: 1, Changes: 2, Status: "modified", Patch: "@@ -3,3 +3,3 @@\n require (\n-\tgopkg.in/yaml.v2 v2.4.0\n+\tgopkg.in/yaml.v3 v3.0.1\n )", Owner: Owner, Repo: Repo, FromRelease: From, ToRelease: To},
+		},
+	})
+	if err != nil {
+		t.Fatalf("save pair: %v", err)
+	}
+
+	for tag, size := range map[string]float64{From: 1200, To: 1350} {
+		if err := db.SaveMetrics(Owner, Repo, tag, "size", []cache.Metric{{Hook: "size", Name: "binary", Unit: "KB", Value: size, CollectedAt: day(21)}}); err != nil {
+			t.Fatalf("save metrics: %v", err)
+		}
+	}
+	return db
+}
@@ -0,0 +1,165 @@
+package semver
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Original   string
+}
+
+func Parse(s string) (Version, bool) {
+	v := Version{Original: s}
+
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	if i := strings.IndexByte(s, '+'); i != -1 {
+		s = s[:i]
+	}
+
+	core := s
+	if i := strings.IndexByte(s, '-'); i != -1 {
+		core = s[:i]
+		v.Prerelease = s[i+1:]
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, false
+	}
+
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		digits := p
+		if i == len(parts)-1 && v.Prerelease == "" {
+			// PEP 440 style pre-releases such as 1.0.0rc1
+			end := 0
+			for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+				end++
+			}
+			digits = p[:end]
+			v.Prerelease = p[end:]
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			return v, false
+		}
+		*nums[i] = n
+	}
+
+	return v, true
+}
+
+func Compare(a, b Version) int {
+	if c := cmpInt(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := cmpInt(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := cmpInt(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(a.Prerelease, b.Prerelease)
+}
+
+// CompareStrings compares two version strings, falling back to a plain
+// string comparison when either side is not a recognizable version.
+func CompareStrings(a, b string) int {
+	va, okA := Parse(a)
+	vb, okB := Parse(b)
+	if !okA || !okB {
+		return strings.Compare(a, b)
+	}
+	return Compare(va, vb)
+}
+
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, errA := strconv.Atoi(as[i])
+		bn, errB := strconv.Atoi(bs[i])
+		switch {
+		case errA == nil && errB == nil:
+			if c := cmpInt(an, bn); c != 0 {
+				return c
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmpInt(len(as), len(bs))
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isWildcard(s string) bool {
+	return s == "x" || s == "X" || s == "*"
+}
+
+// IsPattern reports whether s is a version range such as v1.2.x or 1.*.
+func IsPattern(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "v"), "V")
+	for _, p := range strings.Split(s, ".") {
+		if isWildcard(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether tag is a release version inside the range pattern.
+// Components from the first wildcard on match anything; pre-releases never
+// match.
+func Match(pattern, tag string) bool {
+	v, ok := Parse(tag)
+	if !ok || v.Prerelease != "" {
+		return false
+	}
+
+	pattern = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(pattern), "v"), "V")
+	nums := []int{v.Major, v.Minor, v.Patch}
+	for i, p := range strings.Split(pattern, ".") {
+		if i >= len(nums) {
+			return false
+		}
+		if isWildcard(p) {
+			return true
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n != nums[i] {
+			return false
+		}
+	}
+	return true
+}
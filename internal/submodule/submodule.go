@@ -0,0 +1,267 @@
+// Package submodule finds the git submodules a release range moved. A
+// submodule shows up in a diff as a gitlink, a file whose patch is just the
+// commit it points at, and its URL is read from .gitmodules.
+package submodule
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ordiff/internal/cache"
+)
+
+// File is where git records the submodules of a repository.
+const File = ".gitmodules"
+
+// Statuses of a Change.
+const (
+	StatusAdded   = "added"
+	StatusRemoved = "removed"
+	StatusMoved   = "moved"
+)
+
+var gitlinkLine = regexp.MustCompile(`^[-+]Subproject commit ([0-9a-f]{40})(-dirty)?$`)
+
+// Module is a submodule declared in .gitmodules.
+type Module struct {
+	Name string
+	Path string
+	URL  string
+}
+
+// Change is a submodule added, removed or moved to another commit. Owner
+// and Repo name the submodule's repository when its URL is known, and Range
+// summarizes the commits between From and To when that repository is in the
+// cache.
+type Change struct {
+	Path   string `json:"path"`
+	URL    string `json:"url,omitempty"`
+	Owner  string `json:"owner,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	Status string `json:"status"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Range  *Range `json:"range,omitempty"`
+}
+
+// Range is the cached history of a submodule between the commits it moved
+// from and to, oldest first. Backward is set when it moved to an older
+// commit, in which case Commits are the ones it left behind. The releases
+// are those of the submodule's repository that first shipped each commit,
+// empty when none did yet.
+type Range struct {
+	FromRelease  string         `json:"from_release,omitempty"`
+	ToRelease    string         `json:"to_release,omitempty"`
+	Backward     bool           `json:"backward,omitempty"`
+	Commits      []cache.Commit `json:"commits"`
+	PullRequests int            `json:"pull_requests"`
+}
+
+// Parse reads the submodules of a .gitmodules file, in order.
+func Parse(content string) []Module {
+	var modules []Module
+	var cur *Module
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			cur = nil
+			name, ok := strings.CutPrefix(strings.Trim(line, "[]"), "submodule ")
+			if ok {
+				modules = append(modules, Module{Name: strings.Trim(strings.TrimSpace(name), `"`)})
+				cur = &modules[len(modules)-1]
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || cur == nil {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "path":
+			cur.Path = strings.TrimSpace(value)
+		case "url":
+			cur.URL = strings.TrimSpace(value)
+		}
+	}
+	return modules
+}
+
+// ParsePatch reads the submodules a patch of .gitmodules shows on either
+// side. Declarations the hunks only partly cover are missing fields.
+func ParsePatch(patch string) []Module {
+	var before, after strings.Builder
+	for _, line := range strings.Split(patch, "\n") {
+		if line == "" || strings.HasPrefix(line, "@@") {
+			continue
+		}
+		switch line[0] {
+		case '-':
+			before.WriteString(line[1:] + "\n")
+		case '+':
+			after.WriteString(line[1:] + "\n")
+		default:
+			before.WriteString(line[1:] + "\n")
+			after.WriteString(line[1:] + "\n")
+		}
+	}
+	return append(Parse(after.String()), Parse(before.String())...)
+}
+
+// IsGitlink reports whether a file change is a submodule moving, going by
+// its patch.
+func IsGitlink(fc cache.FileChange) bool {
+	from, to := commits(fc.Patch)
+	return from != "" || to != ""
+}
+
+// maxGitlinkLines bounds the lines a gitlink changes on each side: one per
+// release pair whose changes were aggregated.
+const maxGitlinkLines = 32
+
+// MaybeGitlink reports whether a file change could be a gitlink before its
+// patch is loaded, going by its size.
+func MaybeGitlink(fc cache.FileChange) bool {
+	return fc.Additions <= maxGitlinkLines && fc.Deletions <= maxGitlinkLines && fc.Filename != File
+}
+
+// commits returns the commits a gitlink patch moves from and to, or empty
+// strings when the patch is something else. Patches of several release
+// pairs joined oldest first move from the first and to the last.
+func commits(patch string) (from, to string) {
+	for _, line := range strings.Split(patch, "\n") {
+		if line == "" || strings.HasPrefix(line, "@@") || strings.HasPrefix(line, `\`) {
+			continue
+		}
+		m := gitlinkLine.FindStringSubmatch(line)
+		if m == nil {
+			return "", ""
+		}
+		if line[0] == '-' {
+			if from == "" {
+				from = m[1]
+			}
+		} else {
+			to = m[1]
+		}
+	}
+	return from, to
+}
+
+// Changes lists the gitlinks among files, in file order. URLs are taken
+// from the first of modules declaring the path, and the repository from
+// the URL; relative URLs are resolved against owner, the superproject's.
+func Changes(files []cache.FileChange, modules []Module, owner string) []Change {
+	var changes []Change
+	for _, fc := range files {
+		from, to := commits(fc.Patch)
+		if from == "" && to == "" {
+			continue
+		}
+		c := Change{Path: fc.Filename, From: from, To: to, Status: StatusMoved}
+		switch {
+		case from == "":
+			c.Status = StatusAdded
+		case to == "":
+			c.Status = StatusRemoved
+		}
+		for _, m := range modules {
+			if m.Path == fc.Filename && m.URL != "" {
+				c.URL = m.URL
+				c.Owner, c.Repo, _ = ParseURL(m.URL, owner)
+				break
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// ParseURL returns the owner and name of the repository a submodule URL
+// points at: https and ssh URLs, scp-like git@host:owner/name addresses and
+// paths relative to the superproject, whose owner relative URLs resolve
+// against.
+func ParseURL(url, owner string) (string, string, bool) {
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	if rest, ok := strings.CutPrefix(url, "../"); ok {
+		if name, ok := strings.CutPrefix(rest, "../"); ok {
+			o, r, ok := strings.Cut(name, "/")
+			return o, r, ok && o != "" && r != "" && !strings.Contains(r, "/")
+		}
+		return owner, rest, rest != "" && !strings.Contains(rest, "/")
+	}
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	} else if _, rest, ok := strings.Cut(url, ":"); ok {
+		url = rest
+	}
+	parts := strings.Split(url, "/")
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	o, r := parts[len(parts)-2], parts[len(parts)-1]
+	return o, r, o != "" && r != ""
+}
+
+// Summarize reads the range of a moved submodule from the cache, or returns
+// nil when either commit is not cached, such as when the submodule's
+// repository was never indexed. Commits between the two are found by date.
+func Summarize(db cache.Store, c Change) (*Range, error) {
+	if c.Status != StatusMoved || c.Owner == "" {
+		return nil, nil
+	}
+	from, err := db.GetCommit(c.Owner, c.Repo, c.From)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	to, err := db.GetCommit(c.Owner, c.Repo, c.To)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Range{Commits: []cache.Commit{}}
+	if to.Date.Before(from.Date) {
+		r.Backward = true
+		from, to = to, from
+	}
+	all, err := db.GetCommits(c.Owner, c.Repo)
+	if err != nil {
+		return nil, err
+	}
+	prs := map[int]bool{}
+	for _, commit := range all {
+		if commit.Date.After(from.Date) && !commit.Date.After(to.Date) {
+			r.Commits = append(r.Commits, commit)
+			if commit.PrNumber != nil {
+				prs[*commit.PrNumber] = true
+			}
+		}
+	}
+	sort.SliceStable(r.Commits, func(i, j int) bool { return r.Commits[i].Date.Before(r.Commits[j].Date) })
+	r.PullRequests = len(prs)
+
+	if r.FromRelease, err = firstRelease(db, c.Owner, c.Repo, c.From); err != nil {
+		return nil, err
+	}
+	if r.ToRelease, err = firstRelease(db, c.Owner, c.Repo, c.To); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// firstRelease returns the oldest cached release that shipped a commit.
+func firstRelease(db cache.Store, owner, repo, sha string) (string, error) {
+	tags, err := db.ReleasesContaining(owner, repo, []string{sha})
+	if err != nil || len(tags) == 0 {
+		return "", err
+	}
+	return tags[0], nil
+}
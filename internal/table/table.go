@@ -0,0 +1,186 @@
+// Package table renders aligned text tables for the terminal, with ANSI
+// colors and the widest columns truncated to fit the terminal width.
+package table
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Colors for Column.Color.
+const (
+	Red    = "\033[31m"
+	Green  = "\033[32m"
+	Yellow = "\033[33m"
+	Cyan   = "\033[36m"
+	Dim    = "\033[2m"
+
+	bold  = "\033[1m"
+	reset = "\033[0m"
+)
+
+// Truncation is how a value too wide for the terminal is shortened.
+type Truncation int
+
+const (
+	// Keep never shortens the column.
+	Keep Truncation = iota
+	// TrimEnd cuts the end of long values, for titles and messages.
+	TrimEnd
+	// TrimStart cuts the start of long values, which keeps the file name
+	// of a path.
+	TrimStart
+)
+
+// minWidth is the narrowest a truncated column gets.
+const minWidth = 12
+
+// Column describes one column of a table. Signed colors values starting
+// with + green and those starting with - red, like a diff, except for +0
+// and -0; otherwise Color is used for the whole column.
+type Column struct {
+	Header   string
+	Right    bool
+	Color    string
+	Signed   bool
+	Truncate Truncation
+}
+
+// Options are the terminal a table is rendered for. Width 0 never
+// truncates.
+type Options struct {
+	Color bool
+	Width int
+}
+
+// Table is a list of rows under a set of columns. Every line is prefixed
+// with Indent.
+type Table struct {
+	Columns []Column
+	Indent  string
+	rows    [][]string
+}
+
+// New returns an empty table indented by two spaces.
+func New(columns ...Column) *Table {
+	return &Table{Columns: columns, Indent: "  "}
+}
+
+// Row appends a row. Missing values are left empty.
+func (t *Table) Row(values ...string) {
+	t.rows = append(t.rows, values)
+}
+
+// Len returns the number of rows.
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+// Render writes the table, with a header line when any column has one.
+func (t *Table) Render(w io.Writer, o Options) {
+	header := false
+	widths := make([]int, len(t.Columns))
+	for i, c := range t.Columns {
+		widths[i] = utf8.RuneCountInString(c.Header)
+		header = header || c.Header != ""
+	}
+	for _, row := range t.rows {
+		for i := range t.Columns {
+			if i < len(row) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+			}
+		}
+	}
+	t.fit(widths, o.Width)
+
+	if header {
+		headers := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			headers[i] = c.Header
+		}
+		t.line(w, headers, widths, o, true)
+	}
+	for _, row := range t.rows {
+		t.line(w, row, widths, o, false)
+	}
+}
+
+// fit narrows the truncatable columns, widest first, until the table fits
+// in width.
+func (t *Table) fit(widths []int, width int) {
+	if width <= 0 {
+		return
+	}
+	total := utf8.RuneCountInString(t.Indent) + 2*(len(widths)-1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > width {
+		widest := -1
+		for i, c := range t.Columns {
+			if c.Truncate != Keep && widths[i] > minWidth && (widest == -1 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest == -1 {
+			return
+		}
+		cut := min(total-width, widths[widest]-minWidth)
+		widths[widest] -= cut
+		total -= cut
+	}
+}
+
+func (t *Table) line(w io.Writer, values []string, widths []int, o Options, header bool) {
+	var b strings.Builder
+	b.WriteString(t.Indent)
+	for i, c := range t.Columns {
+		v := ""
+		if i < len(values) {
+			v = truncate(values[i], widths[i], c.Truncate)
+		}
+		pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v))
+		if i == len(t.Columns)-1 && !c.Right {
+			pad = ""
+		}
+
+		color := c.Color
+		switch {
+		case header:
+			color = bold
+		case c.Signed && (v == "" || v[1:] == "0"):
+		case c.Signed && strings.HasPrefix(v, "+"):
+			color = Green
+		case c.Signed && strings.HasPrefix(v, "-"):
+			color = Red
+		}
+		if o.Color && color != "" && v != "" {
+			v = color + v + reset
+		}
+
+		if i > 0 {
+			b.WriteString("  ")
+		}
+		if c.Right {
+			b.WriteString(pad + v)
+		} else {
+			b.WriteString(v + pad)
+		}
+	}
+	fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+}
+
+// truncate shortens s to width runes, marking the cut with an ellipsis.
+func truncate(s string, width int, mode Truncation) string {
+	n := utf8.RuneCountInString(s)
+	if n <= width || mode == Keep {
+		return s
+	}
+	r := []rune(s)
+	if mode == TrimStart {
+		return "…" + string(r[n-width+1:])
+	}
+	return string(r[:width-1]) + "…"
+}
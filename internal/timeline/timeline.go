@@ -0,0 +1,97 @@
+// Package timeline summarizes the release history of a cached repository:
+// the cadence of releases and the commits, contributors and churn of each.
+package timeline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ordiff/internal/cache"
+)
+
+// Entry describes one release and what changed since the release before it.
+// The oldest release has nothing to compare against and only carries its
+// tag and date.
+type Entry struct {
+	Tag          string    `json:"tag"`
+	Date         time.Time `json:"date"`
+	Previous     string    `json:"previous,omitempty"`
+	Days         float64   `json:"days_since_previous"`
+	Commits      int       `json:"commits"`
+	Contributors int       `json:"contributors"`
+	Files        int       `json:"files_changed"`
+	Additions    int       `json:"additions"`
+	Deletions    int       `json:"deletions"`
+}
+
+// Churn is the number of changed lines.
+func (e Entry) Churn() int {
+	return e.Additions + e.Deletions
+}
+
+// Build walks the cached releases of a repository oldest first.
+func Build(db cache.Store, owner, repo string) ([]Entry, error) {
+	releases, err := db.GetReleases(owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get releases: %w", err)
+	}
+
+	churn, err := db.GetPairChurn(owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get churn: %w", err)
+	}
+	byPair := make(map[[2]string]cache.PairChurn, len(churn))
+	for _, c := range churn {
+		byPair[[2]string{c.FromRelease, c.ToRelease}] = c
+	}
+
+	entries := make([]Entry, 0, len(releases))
+	for i := len(releases) - 1; i >= 0; i-- {
+		r := releases[i]
+		e := Entry{Tag: r.TagName, Date: r.PublishedAt}
+
+		if i < len(releases)-1 {
+			prev := releases[i+1]
+			e.Previous = prev.TagName
+			e.Days = r.PublishedAt.Sub(prev.PublishedAt).Hours() / 24
+
+			commits, err := db.GetCommitsBetween(owner, repo, prev.TagName, r.TagName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get commits for %s: %w", r.TagName, err)
+			}
+			authors := map[string]bool{}
+			for _, c := range commits {
+				authors[c.Author] = true
+			}
+			e.Commits = len(commits)
+			e.Contributors = len(authors)
+
+			c := byPair[[2]string{prev.TagName, r.TagName}]
+			e.Files, e.Additions, e.Deletions = c.Files, c.Additions, c.Deletions
+		}
+
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as one block character each, scaled to the largest.
+func Sparkline(values []int) string {
+	peak := 0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		if peak == 0 {
+			sb.WriteRune(sparks[0])
+			continue
+		}
+		sb.WriteRune(sparks[v*(len(sparks)-1)/peak])
+	}
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+// Package tmpl executes the Go text/templates that --template passes to
+// compare, list and changelog, with a shared set of helper functions.
+package tmpl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Funcs are the helper functions available to every template.
+var Funcs = template.FuncMap{
+	// short abbreviates a commit SHA to 7 characters.
+	"short": func(sha string) string {
+		if len(sha) > 7 {
+			return sha[:7]
+		}
+		return sha
+	},
+	// subject is the first line of a commit message.
+	"subject": func(msg string) string {
+		subject, _, _ := strings.Cut(msg, "\n")
+		return subject
+	},
+	// date formats a time.Time or *time.Time as 2006-01-02, or with the
+	// layout given as a second argument. A nil time is empty.
+	"date": func(t interface{}, layout ...string) string {
+		l := "2006-01-02"
+		if len(layout) > 0 {
+			l = layout[0]
+		}
+		switch t := t.(type) {
+		case time.Time:
+			return t.Format(l)
+		case *time.Time:
+			if t != nil {
+				return t.Format(l)
+			}
+		}
+		return ""
+	},
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"trim":     strings.TrimSpace,
+	"join":     func(sep string, s []string) string { return strings.Join(s, sep) },
+	"contains": func(substr, s string) bool { return strings.Contains(s, substr) },
+	"replace":  func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	// indent prefixes every line of s with n spaces.
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"add": func(a, b int) int { return a + b },
+	// json encodes a value, for embedding data in the output.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Execute parses text as a template named name and executes it against
+// data.
+func Execute(w io.Writer, name, text string, data interface{}) error {
+	t, err := template.New(name).Funcs(Funcs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	return t.Execute(w, data)
+}
@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	addedMarker   = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	removedMarker = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+	hunkStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+)
+
+// highlightPatch colors a unified diff: hunk headers in cyan, +/- markers in
+// green and red, and the code itself with the lexer for filename. Lines are
+// highlighted one at a time, so constructs spanning lines such as block
+// comments may be colored as code.
+func highlightPatch(filename, patch string) string {
+	lexer := lexers.Match(filename)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+	formatter := formatters.Get("terminal256")
+	style := styles.Get("monokai")
+
+	lines := strings.Split(patch, "\n")
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			lines[i] = hunkStyle.Render(line)
+			continue
+		}
+
+		marker, code := line[:1], line[1:]
+		switch marker {
+		case "+":
+			marker = addedMarker.Render(marker)
+		case "-":
+			marker = removedMarker.Render(marker)
+		}
+
+		it, err := lexer.Tokenise(nil, code)
+		if err != nil {
+			continue
+		}
+		var sb strings.Builder
+		if err := formatter.Format(&sb, style, it); err != nil {
+			continue
+		}
+		lines[i] = marker + strings.TrimRight(sb.String(), "\n")
+	}
+	return strings.Join(lines, "\n")
+}
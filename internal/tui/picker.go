@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ordiff/internal/cache"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pickerHeight is the number of releases the picker lists at once.
+const pickerHeight = 10
+
+// ErrCancelled is returned by PickRange when the picker is closed without
+// picking two releases.
+var ErrCancelled = errors.New("no releases picked")
+
+type picker struct {
+	releases []cache.Release
+	query    string
+	matches  []int // indexes into releases, best match first
+	cursor   int
+	from     int // release picked first, or -1
+	to       int
+	done     bool
+}
+
+// PickRange lets the user pick two of the releases, newest first, with a
+// fuzzy search over their tags and names, and returns the older one as from.
+// The picker is drawn on stderr so that stdout can be piped.
+func PickRange(releases []cache.Release) (from, to string, err error) {
+	p := picker{releases: releases, from: -1, to: -1}
+	p.filter()
+	final, err := tea.NewProgram(p, tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		return "", "", err
+	}
+	p = final.(picker)
+	if !p.done {
+		return "", "", ErrCancelled
+	}
+	// Releases are listed newest first; the lower one is older.
+	older, newer := max(p.from, p.to), min(p.from, p.to)
+	return releases[older].TagName, releases[newer].TagName, nil
+}
+
+func (p picker) Init() tea.Cmd {
+	return nil
+}
+
+func (p picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+	switch key.Type {
+	case tea.KeyCtrlC:
+		return p, tea.Quit
+	case tea.KeyEsc:
+		if p.from == -1 {
+			return p, tea.Quit
+		}
+		p.from = -1
+		p.query = ""
+		p.filter()
+	case tea.KeyUp, tea.KeyCtrlP:
+		p.cursor = max(p.cursor-1, 0)
+	case tea.KeyDown, tea.KeyCtrlN:
+		p.cursor = max(min(p.cursor+1, len(p.matches)-1), 0)
+	case tea.KeyBackspace:
+		if r := []rune(p.query); len(r) > 0 {
+			p.query = string(r[:len(r)-1])
+			p.filter()
+		}
+	case tea.KeyEnter:
+		if len(p.matches) == 0 {
+			break
+		}
+		if p.from == -1 {
+			p.from = p.matches[p.cursor]
+			p.query = ""
+			p.filter()
+			break
+		}
+		p.to = p.matches[p.cursor]
+		p.done = true
+		return p, tea.Quit
+	case tea.KeyRunes, tea.KeySpace:
+		p.query += string(key.Runes)
+		p.filter()
+	}
+	return p, nil
+}
+
+// filter lists the releases matching the query, except the one already
+// picked, best match first.
+func (p *picker) filter() {
+	type match struct{ index, score int }
+	var matches []match
+	for i, r := range p.releases {
+		if i == p.from {
+			continue
+		}
+		if score, ok := fuzzyScore(r.TagName+" "+r.Name, p.query); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	p.matches = nil
+	for _, m := range matches {
+		p.matches = append(p.matches, m.index)
+	}
+	p.cursor = 0
+}
+
+// fuzzyScore reports whether every character of query appears in s in
+// order, ignoring case, and scores the match: consecutive characters and a
+// match at the start score higher. An empty query matches everything
+// equally.
+func fuzzyScore(s, query string) (int, bool) {
+	text := []rune(strings.ToLower(s))
+	score, pos, last := 0, 0, -2
+	for _, q := range strings.ToLower(query) {
+		if unicode.IsSpace(q) {
+			continue
+		}
+		for pos < len(text) && text[pos] != q {
+			pos++
+		}
+		if pos == len(text) {
+			return 0, false
+		}
+		switch {
+		case pos == 0:
+			score += 3
+		case pos == last+1:
+			score += 2
+		default:
+			score++
+		}
+		last = pos
+		pos++
+	}
+	return score, true
+}
+
+func (p picker) View() string {
+	if p.done {
+		return ""
+	}
+
+	var sb strings.Builder
+	if p.from == -1 {
+		sb.WriteString(titleStyle.Render("Pick the first release to compare"))
+	} else {
+		sb.WriteString(titleStyle.Render("Pick the release to compare " + p.releases[p.from].TagName + " with"))
+	}
+	fmt.Fprintf(&sb, "\n> %s█\n", p.query)
+
+	start, end := window(p.cursor, len(p.matches), pickerHeight)
+	for i := start; i < end; i++ {
+		r := p.releases[p.matches[i]]
+		line := fmt.Sprintf("  %-24s %s", truncate(r.TagName, 24), r.PublishedAt.Format("2006-01-02"))
+		if r.Name != "" && r.Name != r.TagName {
+			line += "  " + dimStyle.Render(truncate(r.Name, 40))
+		}
+		if i == p.cursor {
+			line = selectedStyle.Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
+	if len(p.matches) == 0 {
+		sb.WriteString(dimStyle.Render("  no matching releases") + "\n")
+	}
+
+	help := "type to search · ↑/↓ move · enter pick · esc cancel"
+	if p.from != -1 {
+		help = "type to search · ↑/↓ move · enter pick · esc start over"
+	}
+	return sb.String() + dimStyle.Render(help)
+}
@@ -0,0 +1,306 @@
+// Package tui is a keyboard-driven browser for the cache: pick two releases,
+// look through the commits and files between them, and read the cached
+// patch of a file.
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/github"
+	"ordiff/internal/report"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const releasesWidth = 28
+
+type focus int
+
+const (
+	focusReleases focus = iota
+	focusChanges
+	focusPatch
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	markedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Bold(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, true, false, false).PaddingRight(1)
+)
+
+type model struct {
+	db          cache.Store
+	owner, repo string
+	releases    []cache.Release
+
+	focus  focus
+	cursor int
+	marked int // release picked as one end of the comparison, or -1
+
+	result     *github.CompareResult
+	patched    bool // whether the patches of result are loaded
+	showFiles  bool
+	itemCursor int
+	patch      viewport.Model
+	patchTitle string
+	err        error
+	width      int
+	height     int
+}
+
+// Run starts the browser for a repository and blocks until it is closed.
+// Only cached data is shown; nothing is fetched.
+func Run(db cache.Store, owner, repo string) error {
+	releases, err := db.GetReleases(owner, repo)
+	if err != nil {
+		return fmt.Errorf("failed to get releases: %w", err)
+	}
+	if len(releases) == 0 {
+		return fmt.Errorf("no cached releases for %s/%s", owner, repo)
+	}
+
+	m := model{db: db, owner: owner, repo: repo, releases: releases, marked: -1}
+	_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m model) Init() tea.Cmd {
+	return nil
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.patch.Width, m.patch.Height = msg.Width, m.bodyHeight()-1
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" || (msg.String() == "q" && m.focus != focusPatch) {
+			return m, tea.Quit
+		}
+		switch m.focus {
+		case focusReleases:
+			return m.updateReleases(msg), nil
+		case focusChanges:
+			return m.updateChanges(msg), nil
+		case focusPatch:
+			if msg.String() == "esc" || msg.String() == "q" || msg.String() == "left" {
+				m.focus = focusChanges
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.patch, cmd = m.patch.Update(msg)
+			return m, cmd
+		}
+	}
+	return m, nil
+}
+
+func (m model) updateReleases(msg tea.KeyMsg) model {
+	switch msg.String() {
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(m.releases)-1)
+	case "esc":
+		m.marked = -1
+	case "right", "l", "tab":
+		if m.result != nil {
+			m.focus = focusChanges
+		}
+	case "enter", " ":
+		switch m.marked {
+		case -1:
+			m.marked = m.cursor
+		case m.cursor:
+			m.marked = -1
+		default:
+			// Releases are listed newest first; the lower one is older.
+			older, newer := max(m.marked, m.cursor), min(m.marked, m.cursor)
+			m.compare(m.releases[older].TagName, m.releases[newer].TagName)
+			m.marked = -1
+		}
+	}
+	return m
+}
+
+func (m *model) compare(from, to string) {
+	result, err := github.NewFetcher(m.owner, m.repo, nil).GetCompareData(m.db, from, to)
+	m.err = err
+	if err != nil {
+		return
+	}
+	m.result = result
+	m.patched = false
+	m.itemCursor = 0
+	m.showFiles = false
+	m.focus = focusChanges
+}
+
+func (m model) updateChanges(msg tea.KeyMsg) model {
+	n := len(m.result.Commits)
+	if m.showFiles {
+		n = len(m.result.Files)
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		m.itemCursor = max(m.itemCursor-1, 0)
+	case "down", "j":
+		m.itemCursor = max(min(m.itemCursor+1, n-1), 0)
+	case "tab", "f", "c":
+		m.showFiles = !m.showFiles
+		m.itemCursor = 0
+	case "esc", "left", "h":
+		m.focus = focusReleases
+	case "enter", "right", "l":
+		if !m.showFiles || n == 0 {
+			break
+		}
+		if !m.patched {
+			m.err = github.NewFetcher(m.owner, m.repo, nil).LoadPatches(m.db, m.result)
+			m.patched = m.err == nil
+		}
+		fc := m.result.Files[m.itemCursor]
+		content := dimStyle.Render("(no patch cached for this file)")
+		if fc.Patch != "" {
+			content = highlightPatch(fc.Filename, fc.Patch)
+		}
+		m.patch = viewport.New(m.width, m.bodyHeight()-1)
+		m.patch.SetContent(content)
+		m.patchTitle = fmt.Sprintf("%s  %s → %s  +%d -%d", fc.Filename, m.result.FromRelease.TagName, m.result.ToRelease.TagName, fc.Additions, fc.Deletions)
+		m.focus = focusPatch
+	}
+	return m
+}
+
+// bodyHeight is the screen height left after the header and help lines.
+func (m model) bodyHeight() int {
+	return max(m.height-2, 1)
+}
+
+func (m model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	header := titleStyle.Render(fmt.Sprintf("ordiff  %s/%s", m.owner, m.repo))
+	if m.err != nil {
+		header += "  " + errorStyle.Render(m.err.Error())
+	}
+
+	var body, help string
+	switch m.focus {
+	case focusPatch:
+		body = titleStyle.Render(m.patchTitle) + "\n" + m.patch.View()
+		help = "↑/↓ pgup/pgdn scroll · esc back"
+	default:
+		left := paneStyle.Height(m.bodyHeight()).Render(m.viewReleases())
+		right := lipgloss.NewStyle().PaddingLeft(1).Width(max(m.width-releasesWidth-3, 10)).Render(m.viewChanges())
+		body = lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+		if m.focus == focusReleases {
+			help = "↑/↓ move · enter pick two releases to compare · esc clear · tab changes · q quit"
+		} else {
+			help = "↑/↓ move · tab commits/files · enter show patch · esc releases · q quit"
+		}
+	}
+
+	return header + "\n" + body + "\n" + dimStyle.Render(help)
+}
+
+func (m model) viewReleases() string {
+	height := m.bodyHeight()
+	start, end := window(m.cursor, len(m.releases), height)
+
+	var sb strings.Builder
+	for i := start; i < end; i++ {
+		r := m.releases[i]
+		line := fmt.Sprintf("%-*s %s", releasesWidth-13, truncate(r.TagName, releasesWidth-13), r.PublishedAt.Format("2006-01-02"))
+		switch {
+		case i == m.marked:
+			line = markedStyle.Render("● " + line)
+		case m.result != nil && (r.TagName == m.result.FromRelease.TagName || r.TagName == m.result.ToRelease.TagName):
+			line = "◆ " + line
+		default:
+			line = "  " + line
+		}
+		if i == m.cursor && m.focus == focusReleases {
+			line = selectedStyle.Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (m model) viewChanges() string {
+	if m.result == nil {
+		return dimStyle.Render("Pick two releases with enter to compare them.")
+	}
+
+	r := m.result
+	commitsTab, filesTab := fmt.Sprintf("Commits (%d)", len(r.Commits)), fmt.Sprintf("Files (%d)", len(r.Files))
+	if m.showFiles {
+		filesTab = titleStyle.Underline(true).Render(filesTab)
+	} else {
+		commitsTab = titleStyle.Underline(true).Render(commitsTab)
+	}
+	title := fmt.Sprintf("%s → %s   %s  %s", r.FromRelease.TagName, r.ToRelease.TagName, commitsTab, filesTab)
+
+	var items []string
+	if m.showFiles {
+		for _, f := range r.Files {
+			items = append(items, fmt.Sprintf("%6s %-6s %s", fmt.Sprintf("+%d", f.Additions), fmt.Sprintf("-%d", f.Deletions), f.Filename))
+		}
+	} else {
+		for _, c := range r.Commits {
+			sha := c.SHA
+			if len(sha) > 7 {
+				sha = sha[:7]
+			}
+			items = append(items, fmt.Sprintf("%s  %s (%s)", sha, report.Subject(c.Message), c.Author))
+		}
+	}
+	if len(items) == 0 {
+		return title + "\n\n" + dimStyle.Render("Nothing cached.")
+	}
+
+	width := max(m.width-releasesWidth-5, 10)
+	start, end := window(m.itemCursor, len(items), m.bodyHeight()-2)
+	var sb strings.Builder
+	sb.WriteString(title + "\n\n")
+	for i := start; i < end; i++ {
+		line := truncate(items[i], width)
+		if i == m.itemCursor && m.focus == focusChanges {
+			line = selectedStyle.Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// window returns the range of n items to show in height lines so that the
+// cursor stays visible.
+func window(cursor, n, height int) (int, int) {
+	height = max(height, 1)
+	start := max(min(cursor-height/2, n-height), 0)
+	return start, min(start+height, n)
+}
+
+func truncate(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && lipgloss.Width(string(r)) > width-1 {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
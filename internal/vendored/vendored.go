@@ -0,0 +1,157 @@
+// Package vendored follows dependencies copied into a repository from an
+// upstream one, such as llama.cpp in Ollama, through the upstream commit a
+// file of the repository records.
+package vendored
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ordiff/internal/cache"
+	"ordiff/internal/filter"
+)
+
+// SubmodulePattern matches the commit of a git submodule in its patch,
+// which is the default when a dependency has no version file.
+const SubmodulePattern = `^Subproject commit ([0-9a-f]{7,40})`
+
+// Dependency is a vendored entry of the config. VersionFile
+// records the upstream commit or tag, found by the first group of Pattern;
+// without one Path is taken to be a git submodule.
+type Dependency struct {
+	Name        string `mapstructure:"name" json:"name"`
+	Path        string `mapstructure:"path" json:"path"`
+	Upstream    string `mapstructure:"upstream" json:"upstream"`
+	VersionFile string `mapstructure:"version_file" json:"version_file,omitempty"`
+	Pattern     string `mapstructure:"pattern" json:"pattern,omitempty"`
+}
+
+// Validate fills in the defaults of d and checks the rest.
+func (d *Dependency) Validate() error {
+	if d.Path == "" {
+		return fmt.Errorf("every vendored dependency needs a path")
+	}
+	owner, repo, ok := strings.Cut(d.Upstream, "/")
+	if !ok || owner == "" || repo == "" {
+		return fmt.Errorf("vendored dependency %s: upstream must be owner/name", d.Path)
+	}
+	if d.Name == "" {
+		d.Name = repo
+	}
+	if d.VersionFile == "" {
+		d.VersionFile = strings.TrimSuffix(d.Path, "/")
+		if d.Pattern == "" {
+			d.Pattern = SubmodulePattern
+		}
+	}
+	if d.Pattern == "" {
+		return fmt.Errorf("vendored dependency %s: a version_file needs a pattern", d.Name)
+	}
+	re, err := regexp.Compile(d.Pattern)
+	if err != nil {
+		return fmt.Errorf("vendored dependency %s: %w", d.Name, err)
+	}
+	if re.NumSubexp() < 1 {
+		return fmt.Errorf("vendored dependency %s: pattern needs a group capturing the commit", d.Name)
+	}
+	return nil
+}
+
+// Bump is what happened to a vendored dependency in a release range: From
+// and To are the upstream commits or tags recorded before and after, empty
+// when the version file did not change. Files, Additions and Deletions sum
+// the other changes under its path, such as patches carried on top of
+// upstream.
+// UpstreamCommits is the number of upstream commits between From and To,
+// negative when it moved back, and nil when unknown.
+type Bump struct {
+	Name            string `json:"name"`
+	Path            string `json:"path"`
+	Upstream        string `json:"upstream"`
+	From            string `json:"from,omitempty"`
+	To              string `json:"to,omitempty"`
+	UpstreamCommits *int   `json:"upstream_commits,omitempty"`
+	URL             string `json:"url,omitempty"`
+	Files           int    `json:"files"`
+	Additions       int    `json:"additions"`
+	Deletions       int    `json:"deletions"`
+	NoPatch         bool   `json:"no_patch,omitempty"`
+}
+
+// Bumped reports whether the recorded upstream version changed.
+func (b Bump) Bumped() bool {
+	return b.From != b.To
+}
+
+// IsVersionFile returns a matcher of the version files of deps, for
+// loading just the patches Bumps reads.
+func IsVersionFile(deps []Dependency) func(filename string) bool {
+	return func(filename string) bool {
+		for _, d := range deps {
+			if filename == d.VersionFile {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Bumps reports the vendored dependencies whose version file or files
+// changed, in config order. deps must be validated. The version is read
+// from the patch of the version file: the first removed match is the old
+// one and the last added match the new one, which also holds for the
+// patches of several release pairs joined oldest first.
+func Bumps(deps []Dependency, files []cache.FileChange, baseURL string) []Bump {
+	var bumps []Bump
+	for _, d := range deps {
+		b := Bump{Name: d.Name, Path: d.Path, Upstream: d.Upstream}
+		re := regexp.MustCompile(d.Pattern)
+
+		for _, fc := range files {
+			if fc.Filename == d.VersionFile {
+				if fc.Patch == "" {
+					b.NoPatch = true
+				}
+				b.From, b.To = versions(re, fc.Patch)
+				continue
+			}
+			if filter.MatchPath(d.Path, fc.Filename) {
+				b.Files++
+				b.Additions += fc.Additions
+				b.Deletions += fc.Deletions
+			}
+		}
+		if !b.Bumped() && b.Files == 0 && !b.NoPatch {
+			continue
+		}
+		if b.From != "" && b.To != "" && b.Bumped() {
+			b.URL = fmt.Sprintf("%s/%s/compare/%s...%s", strings.TrimSuffix(baseURL, "/"), d.Upstream, b.From, b.To)
+		}
+		bumps = append(bumps, b)
+	}
+	return bumps
+}
+
+// versions returns the first version a patch removes and the last one it
+// adds.
+func versions(re *regexp.Regexp, patch string) (from, to string) {
+	for _, line := range strings.Split(patch, "\n") {
+		if line == "" {
+			continue
+		}
+		m := re.FindStringSubmatch(line[1:])
+		if m == nil {
+			continue
+		}
+		switch line[0] {
+		case '-':
+			if from == "" {
+				from = m[1]
+			}
+		case '+':
+			to = m[1]
+		}
+	}
+	return from, to
+}
@@ -0,0 +1,43 @@
+package version
+
+// Version is the ordiff release, set at build time with
+// -ldflags "-X ordiff/internal/version.Version=v1.2.3".
+var Version = "dev"
+
+// DataVersion identifies the format and mapping logic of cached rows. Bump it
+// whenever the fetcher changes what it stores.
+const DataVersion = 4
+
+type Fix struct {
+	DataVersion int
+	Description string
+}
+
+// Fixes lists data versions that corrected previously cached data. Rows
+// stamped with an older data version should be re-indexed.
+var Fixes = []Fix{
+	{2, "Commits are linked to the release pairs they were fetched for, and GitHub releases resolve to their tagged commit"},
+	{3, "Commits record their parents, so ranges follow the commit graph across release branches and backports"},
+	{4, "Renamed files record their previous path, so history, compare and hotspots follow them across renames"},
+}
+
+// StaleBefore returns the data version below which cached rows are known to
+// be affected by a later fix.
+func StaleBefore() int {
+	v := 0
+	for _, f := range Fixes {
+		v = max(v, f.DataVersion)
+	}
+	return v
+}
+
+// FixesSince returns the fixes newer than the given data version.
+func FixesSince(dataVersion int) []Fix {
+	var fixes []Fix
+	for _, f := range Fixes {
+		if f.DataVersion > dataVersion {
+			fixes = append(fixes, f)
+		}
+	}
+	return fixes
+}
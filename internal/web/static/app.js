@@ -0,0 +1,196 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+let current = null;
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (k === "class") node.className = v;
+    else node.setAttribute(k, v);
+  }
+  for (const c of children) {
+    node.append(c instanceof Node ? c : document.createTextNode(String(c)));
+  }
+  return node;
+}
+
+async function api(path) {
+  const resp = await fetch("api" + path);
+  const body = await resp.json();
+  if (!resp.ok) throw new Error(body.error || resp.statusText);
+  return body;
+}
+
+async function loadRepos() {
+  const repos = await api("/repos");
+  const list = $("repos");
+  list.replaceChildren();
+  for (const r of repos) {
+    const item = el("li", {}, `${r.Owner}/${r.Repo} `, el("small", {}, `${r.Releases}`));
+    item.onclick = () => selectRepo(r, item);
+    list.append(item);
+  }
+}
+
+async function selectRepo(r, item) {
+  for (const li of $("repos").children) li.classList.remove("active");
+  item.classList.add("active");
+
+  current = r;
+  $("empty").hidden = true;
+  $("repo").hidden = false;
+  $("repo-title").textContent = `${r.Owner}/${r.Repo}`;
+  $("result").replaceChildren();
+
+  const base = `/repos/${r.Owner}/${r.Repo}`;
+  const [releases, churn] = await Promise.all([api(base + "/releases"), api(base + "/churn")]);
+
+  const rows = $("releases");
+  rows.replaceChildren();
+  $("from").replaceChildren();
+  $("to").replaceChildren();
+  releases.forEach((rel, i) => {
+    rows.append(el("tr", {},
+      el("td", {}, rel.TagName),
+      el("td", {}, rel.PublishedAt.slice(0, 10)),
+      el("td", {}, el("code", {}, (rel.CommitSHA || "").slice(0, 7)))));
+    $("from").append(el("option", {}, rel.TagName));
+    $("to").append(el("option", {}, rel.TagName));
+  });
+  if (releases.length > 1) $("from").selectedIndex = 1;
+
+  drawChart(churn);
+}
+
+function drawChart(churn) {
+  const chart = $("chart");
+  chart.replaceChildren();
+  if (churn.length === 0) {
+    chart.append(el("p", { class: "note" }, "No indexed release pairs."));
+    return;
+  }
+
+  const ns = "http://www.w3.org/2000/svg";
+  const width = 1000, height = 220, pad = 30;
+  const max = Math.max(...churn.map((c) => Math.max(c.Additions, c.Deletions)), 1);
+  const step = (width - pad) / churn.length;
+  const bar = Math.max(step / 2 - 2, 1);
+  const mid = height / 2;
+
+  const svg = document.createElementNS(ns, "svg");
+  svg.setAttribute("viewBox", `0 0 ${width} ${height}`);
+  svg.setAttribute("preserveAspectRatio", "none");
+
+  churn.forEach((c, i) => {
+    const x = pad + i * step;
+    const add = ((mid - 12) * c.Additions) / max;
+    const del = ((mid - 12) * c.Deletions) / max;
+
+    const up = document.createElementNS(ns, "rect");
+    up.setAttribute("class", "bar-add");
+    up.setAttribute("x", x);
+    up.setAttribute("y", mid - add);
+    up.setAttribute("width", bar);
+    up.setAttribute("height", add);
+
+    const down = document.createElementNS(ns, "rect");
+    down.setAttribute("class", "bar-del");
+    down.setAttribute("x", x + bar);
+    down.setAttribute("y", mid);
+    down.setAttribute("width", bar);
+    down.setAttribute("height", del);
+
+    const title = document.createElementNS(ns, "title");
+    title.textContent = `${c.FromRelease} → ${c.ToRelease}: +${c.Additions} -${c.Deletions} in ${c.Files} files`;
+    const group = document.createElementNS(ns, "g");
+    group.append(title, up, down);
+    svg.append(group);
+  });
+
+  for (const [y, label] of [[12, `+${max}`], [height - 2, `-${max}`]]) {
+    const text = document.createElementNS(ns, "text");
+    text.setAttribute("x", 0);
+    text.setAttribute("y", y);
+    text.textContent = label;
+    svg.append(text);
+  }
+
+  chart.append(svg);
+}
+
+function renderPatch(patch) {
+  const pre = el("pre");
+  for (const line of patch.split("\n")) {
+    let cls = "";
+    if (line.startsWith("@@")) cls = "hunk";
+    else if (line.startsWith("+")) cls = "add";
+    else if (line.startsWith("-")) cls = "del";
+    pre.append(cls ? el("span", { class: cls }, line) : line + "\n");
+  }
+  return pre;
+}
+
+const notes = {
+  aggregated: "Releases are not adjacent; file changes aggregated across intervening release pairs.",
+  live: "File changes fetched live from the GitHub compare API.",
+  none: "No cached file changes for this pair.",
+};
+
+async function compare(event) {
+  event.preventDefault();
+  const out = $("result");
+  out.replaceChildren(el("p", { class: "note" }, "Comparing…"));
+
+  const from = encodeURIComponent($("from").value);
+  const to = encodeURIComponent($("to").value);
+  let r;
+  try {
+    r = await api(`/repos/${current.Owner}/${current.Repo}/compare?from=${from}&to=${to}`);
+  } catch (err) {
+    out.replaceChildren(el("p", { class: "del" }, err.message));
+    return;
+  }
+
+  const files = (r.Files || []).slice().sort((a, b) => b.Changes - a.Changes);
+  const commits = r.Commits || [];
+  out.replaceChildren(el("p", { class: "summary" },
+    `${commits.length} commits · ${r.PrCount} PRs · ${files.length} files changed`));
+  if (notes[r.FileSource]) out.append(el("p", { class: "note" }, notes[r.FileSource]));
+
+  const prs = r.PullRequests || [];
+  if (prs.length > 0) {
+    out.append(el("h4", {}, "Merged PRs"));
+    const list = el("ul");
+    for (const pr of prs) {
+      const labels = (pr.Labels || []).join(", ");
+      list.append(el("li", {}, el("a", { href: pr.URL }, `#${pr.Number}`), ` ${pr.Title} (@${pr.Author})`,
+        labels ? el("small", {}, ` [${labels}]`) : ""));
+    }
+    out.append(list);
+  }
+
+  if (files.length > 0) {
+    out.append(el("h4", {}, "Files"));
+    for (const f of files) {
+      const summary = el("summary", {},
+        el("span", { class: "add" }, `+${f.Additions}`), " ",
+        el("span", { class: "del" }, `-${f.Deletions}`), " ",
+        el("code", {}, f.Filename));
+      out.append(el("details", {}, summary, f.Patch ? renderPatch(f.Patch) : el("p", { class: "note" }, "No patch available.")));
+    }
+  }
+
+  if (commits.length > 0) {
+    out.append(el("h4", {}, "Commits"));
+    const list = el("ul");
+    for (const c of commits) {
+      list.append(el("li", {}, el("code", {}, c.SHA.slice(0, 7)), " ", c.Message.split("\n")[0], el("small", {}, ` ${c.Author}`)));
+    }
+    out.append(list);
+  }
+}
+
+$("compare-form").addEventListener("submit", compare);
+loadRepos().catch((err) => $("repos").append(el("li", { class: "del" }, err.message)));
@@ -3,14 +3,32 @@ package main
 import (
 	"ordiff/cmd/cli"
 	"ordiff/cmd/mcp"
+	"ordiff/internal/config"
+	"ordiff/internal/logging"
+	"ordiff/internal/version"
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
 func main() {
-	rootCmd := &cobra.Command{Use: "ordiff"}
-	rootCmd.AddCommand(cli.IndexCmd, cli.ListCmd, cli.CompareCmd)
+	rootCmd := &cobra.Command{Use: "ordiff", Version: version.Version}
+	rootCmd.PersistentFlags().StringVar(&config.File, "config", "", "Config file (default .ordiff.yaml if present, else $XDG_CONFIG_HOME/ordiff/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&config.DB, "db", "", "Cache database (default ordiff.db if present, else $XDG_CACHE_HOME/ordiff/ordiff.db)")
+	rootCmd.PersistentFlags().StringVar(&config.APIURL, "api-url", "", "GitHub Enterprise Server API URL, e.g. https://ghe.example.com/api/v3 (default api_base_url in the config, else github.com)")
+	rootCmd.PersistentFlags().BoolVar(&cli.NoColor, "no-color", false, "Disable colored output (also when NO_COLOR is set)")
+	rootCmd.PersistentFlags().BoolVarP(&logging.Verbose, "verbose", "v", false, "Log debug messages too")
+	rootCmd.PersistentFlags().BoolVarP(&logging.Quiet, "quiet", "q", false, "Log only warnings and errors")
+	rootCmd.PersistentFlags().BoolVar(&logging.JSON, "log-json", false, "Log as JSON lines")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		logging.Setup(os.Stderr)
+		cli.ConfigureGitHubHost()
+	}
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(cli.IndexCmd, cli.ListCmd, cli.CompareCmd, cli.LockdiffCmd, cli.ImageDiffCmd, cli.MetricsCmd, cli.CrossCompareCmd, cli.BatchCompareCmd, cli.WarmCmd, cli.DiffReleasesCmd, cli.UpdateCmd, cli.WatchCmd, cli.JobsCmd)
+	rootCmd.AddCommand(cli.LogCmd, cli.CommitsCmd, cli.SearchCmd, cli.PrCmd, cli.ContainsCmd, cli.ContributorsCmd, cli.RollupCmd, cli.OwnersCmd, cli.AssetsCmd, cli.DownloadsCmd, cli.StatsCmd, cli.ChangelogCmd, cli.DiffCmd, cli.HistoryCmd, cli.HotspotsCmd, cli.ApidiffCmd, cli.ExportCmd, cli.ComponentsCmd, cli.TimelineCmd, cli.NotifyCmd, cli.ActionCmd)
+	rootCmd.AddCommand(cli.ServeCmd, cli.WebCmd, cli.TuiCmd, cli.DBCmd, cli.CacheCmd, cli.DoctorCmd, cli.CompletionCmd)
 	rootCmd.AddCommand(mcp.McpCmd)
 
 	if err := rootCmd.Execute(); err != nil {